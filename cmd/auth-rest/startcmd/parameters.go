@@ -7,7 +7,9 @@ SPDX-License-Identifier: Apache-2.0
 package startcmd
 
 import (
+	"crypto/tls"
 	"net/url"
+	"time"
 
 	oidcmodel "github.com/trustbloc/auth/pkg/restapi/common/oidc"
 )
@@ -15,6 +17,7 @@ import (
 type authRestParameters struct {
 	hostURL          string
 	externalURL      string
+	proxyParams      *proxyParams
 	logLevel         string
 	databaseType     string
 	databaseURL      string
@@ -31,11 +34,17 @@ type authRestParameters struct {
 	gnap             *gnapParameters
 }
 
+type proxyParams struct {
+	trustedProxies []string
+	publicPrefixes map[string]string
+}
+
 type tlsParams struct {
 	useSystemCertPool bool
 	caCerts           []string
 	serveCertPath     string
 	serveKeyPath      string
+	clientAuth        tls.ClientAuthType
 }
 
 type deviceCertParams struct {
@@ -78,6 +87,14 @@ type keyParameters struct {
 }
 
 type gnapParameters struct {
-	disableHTTPSigVerify   bool
-	accessPolicyConfigPath string
+	disableHTTPSigVerify     bool
+	replayProtection         bool
+	httpSigLabel             string
+	httpSigComponents        []string
+	clockSkew                time.Duration
+	accessPolicyConfigPath   string
+	clientRegistryConfigPath string
+	clientCACerts            []string
+	idTokenSigningKeyPath    string
+	pushAllowlist            []string
 }
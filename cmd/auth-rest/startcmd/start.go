@@ -6,8 +6,12 @@ SPDX-License-Identifier: Apache-2.0
 package startcmd
 
 import (
+	"crypto"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"encoding/pem"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -29,7 +33,13 @@ import (
 	"gopkg.in/yaml.v2"
 
 	"github.com/trustbloc/auth/pkg/gnap/accesspolicy"
+	gnapapi "github.com/trustbloc/auth/pkg/gnap/api"
+	"github.com/trustbloc/auth/pkg/gnap/authhandler"
+	"github.com/trustbloc/auth/pkg/gnap/clientregistry"
+	"github.com/trustbloc/auth/pkg/gnap/interact/app"
+	"github.com/trustbloc/auth/pkg/gnap/interact/push"
 	"github.com/trustbloc/auth/pkg/gnap/interact/redirect"
+	"github.com/trustbloc/auth/pkg/gnap/interact/usercode"
 	"github.com/trustbloc/auth/pkg/restapi"
 	"github.com/trustbloc/auth/pkg/restapi/common/hydra"
 	oidcmodel "github.com/trustbloc/auth/pkg/restapi/common/oidc"
@@ -54,6 +64,18 @@ const (
 	externalURLFlagUsage = "URL that the auth-rest instance is exposed on. " +
 		" Alternatively, this can be set with the following environment variable: " + externalURLEnvKey
 
+	trustedProxiesFlagName  = "trusted-proxies"
+	trustedProxiesFlagUsage = "Comma-Separated list of IP addresses or CIDR ranges of reverse proxies, whose" +
+		" Forwarded and X-Forwarded-* headers are used to reconstruct the public URL of client requests." +
+		" Alternatively, this can be set with the following environment variable: " + trustedProxiesEnvKey
+	trustedProxiesEnvKey = "AUTH_REST_TRUSTED_PROXIES"
+
+	publicPrefixesFlagName  = "public-prefixes"
+	publicPrefixesFlagUsage = "Comma-Separated list of path prefix mappings, in the format <path-prefix>=<public-url>," +
+		" mapping request path prefixes to the public URL prefix they are served under." +
+		" Alternatively, this can be set with the following environment variable: " + publicPrefixesEnvKey
+	publicPrefixesEnvKey = "AUTH_REST_PUBLIC_PREFIXES"
+
 	tlsSystemCertPoolFlagName  = "tls-systemcertpool"
 	tlsSystemCertPoolFlagUsage = "Use system certificate pool." +
 		" Possible values [true] [false]. Defaults to false if not set." +
@@ -75,6 +97,14 @@ const (
 		" Alternatively, this can be set with the following environment variable: " + tlsServeKeyPathFlagEnvKey
 	tlsServeKeyPathFlagEnvKey = "AUTH_REST_TLS_SERVE_KEY"
 
+	tlsClientAuthFlagName  = "tls-client-auth"
+	tlsClientAuthFlagUsage = "TLS client certificate authentication mode to use when serving HTTPS, for GNAP" +
+		" clients using the mtls proof method. Supported options: none, request, require, verify-if-given," +
+		" require-and-verify. Defaults to none. The verifying modes check client certificates against --" +
+		tlsCACertsFlagName + "." +
+		" Alternatively, this can be set with the following environment variable: " + tlsClientAuthEnvKey
+	tlsClientAuthEnvKey = "AUTH_REST_TLS_CLIENT_AUTH"
+
 	logLevelFlagName        = "log-level"
 	logLevelEnvKey          = "AUTH_REST_LOG_LEVEL"
 	logLevelFlagShorthand   = "l"
@@ -209,6 +239,57 @@ const (
 	gnapDevModeFlagUsage = "Run GNAP server in dev mode, disabling http signature verification." +
 		" Alternatively, this can be set with the following environment variable: " + gnapDevModeEnvKey
 	gnapDevModeEnvKey = "GNAP_DEV_MODE"
+
+	gnapReplayProtectionFlagName  = "gnap-replay-protection"
+	gnapReplayProtectionFlagUsage = "Reject replayed GNAP http-signature requests, by recording seen signatures" +
+		" in the database. Possible values [true] [false]. Defaults to false if not set." +
+		" Alternatively, this can be set with the following environment variable: " + gnapReplayProtectionEnvKey
+	gnapReplayProtectionEnvKey = "GNAP_REPLAY_PROTECTION"
+
+	gnapHTTPSigLabelFlagName  = "gnap-httpsig-label"
+	gnapHTTPSigLabelFlagUsage = "Label of the client http-signature to verify. If not set, any valid signature" +
+		" is accepted. Alternatively, this can be set with the following environment variable: " +
+		gnapHTTPSigLabelEnvKey
+	gnapHTTPSigLabelEnvKey = "GNAP_HTTPSIG_LABEL"
+
+	gnapHTTPSigComponentsFlagName  = "gnap-httpsig-components"
+	gnapHTTPSigComponentsFlagUsage = "Comma-Separated list of components that client http-signatures must cover," +
+		" in addition to the method, target uri, authorization and content-digest, e.g. @authority,content-type." +
+		" Alternatively, this can be set with the following environment variable: " + gnapHTTPSigComponentsEnvKey
+	gnapHTTPSigComponentsEnvKey = "GNAP_HTTPSIG_COMPONENTS"
+
+	gnapClockSkewFlagName  = "gnap-clock-skew"
+	gnapClockSkewFlagUsage = "Largest allowed difference between the current time and the created time of client" +
+		" http-signature and JWS proofs, e.g. 2m. Defaults to 5m if not set." +
+		" Alternatively, this can be set with the following environment variable: " + gnapClockSkewEnvKey
+	gnapClockSkewEnvKey = "GNAP_CLOCK_SKEW"
+
+	gnapClientRegistryFlagName  = "gnap-client-registry"
+	gnapClientRegistryFlagUsage = "Path to the JSON config of pre-registered GNAP clients, which send their" +
+		" instance identifier by reference, and whose keys are given by a jwks_uri or a static jwks." +
+		" A client may have verified display information, which users see instead of what the client sends." +
+		" Alternatively, this can be set with the following environment variable: " + gnapClientRegistryEnvKey
+	gnapClientRegistryEnvKey = "GNAP_CLIENT_REGISTRY"
+
+	gnapClientCACertsFlagName  = "gnap-client-ca-certs"
+	gnapClientCACertsFlagUsage = "Comma-Separated list of CA certificate paths, which x5c and certificate-bound" +
+		" GNAP client keys are validated against. If not set, client certificates aren't validated." +
+		" Alternatively, this can be set with the following environment variable: " + gnapClientCACertsEnvKey
+	gnapClientCACertsEnvKey = "GNAP_CLIENT_CA_CERTS"
+
+	gnapIDTokenSigningKeyFlagName  = "gnap-id-token-signing-key"
+	gnapIDTokenSigningKeyFlagUsage = "Path to the PEM-encoded private key that signs the id_tokens and login" +
+		" credentials that GNAP clients can request as subject assertions." +
+		" Its public key is published at " + gnap.JWKSPath + ". If not set, subject assertions aren't issued." +
+		" Alternatively, this can be set with the following environment variable: " + gnapIDTokenSigningKeyEnvKey
+	gnapIDTokenSigningKeyEnvKey = "GNAP_ID_TOKEN_SIGNING_KEY"
+
+	gnapPushAllowlistFlagName  = "gnap-push-allowlist"
+	gnapPushAllowlistFlagUsage = "Comma-Separated list of client URIs that GNAP interaction results may be pushed" +
+		" to, for clients that request the push finish method. A client URI is allowed if it has the scheme and" +
+		" host of an allowed URI, and its path starts with the allowed URI's path. If not set, push isn't supported." +
+		" Alternatively, this can be set with the following environment variable: " + gnapPushAllowlistEnvKey
+	gnapPushAllowlistEnvKey = "GNAP_PUSH_ALLOWLIST"
 )
 
 const (
@@ -224,20 +305,35 @@ type healthCheckResp struct {
 	CurrentTime time.Time `json:"currentTime"`
 }
 
+// nolint:gochecknoglobals
+var tlsClientAuthModes = map[string]tls.ClientAuthType{
+	"none":               tls.NoClientCert,
+	"request":            tls.RequestClientCert,
+	"require":            tls.RequireAnyClientCert,
+	"verify-if-given":    tls.VerifyClientCertIfGiven,
+	"require-and-verify": tls.RequireAndVerifyClientCert,
+}
+
 type server interface {
-	ListenAndServe(host string, certFile, keyFile string, router http.Handler) error
+	ListenAndServe(host string, certFile, keyFile string, tlsConfig *tls.Config, router http.Handler) error
 }
 
 // HTTPServer represents an actual HTTP server implementation.
 type HTTPServer struct{}
 
 // ListenAndServe starts the server using the standard Go HTTP server implementation.
-func (s *HTTPServer) ListenAndServe(host, certFile, keyFile string, router http.Handler) error {
+func (s *HTTPServer) ListenAndServe(host, certFile, keyFile string, tlsConfig *tls.Config, router http.Handler) error {
 	if certFile == "" || keyFile == "" {
 		return http.ListenAndServe(host, router)
 	}
 
-	return http.ListenAndServeTLS(host, certFile, keyFile, router)
+	srv := &http.Server{ // nolint:gosec
+		Addr:      host,
+		Handler:   router,
+		TLSConfig: tlsConfig,
+	}
+
+	return srv.ListenAndServeTLS(certFile, keyFile)
 }
 
 // GetStartCmd returns the Cobra start command.
@@ -281,6 +377,11 @@ func getAuthRestParameters(cmd *cobra.Command) (*authRestParameters, error) { //
 		return nil, err
 	}
 
+	proxyParams, err := getProxyParams(cmd)
+	if err != nil {
+		return nil, err
+	}
+
 	loggingLevel, err := cmdutils.GetUserSetVarFromString(cmd, logLevelFlagName, logLevelEnvKey, true)
 	if err != nil {
 		return nil, err
@@ -333,7 +434,10 @@ func getAuthRestParameters(cmd *cobra.Command) (*authRestParameters, error) { //
 		return nil, err
 	}
 
-	gnapParams := getGNAPParams(cmd)
+	gnapParams, err := getGNAPParams(cmd)
+	if err != nil {
+		return nil, err
+	}
 
 	secretsToken, err := cmdutils.GetUserSetVarFromString(cmd, secretsAPITokenFlagName, secretsAPITokenEnvKey, false)
 	if err != nil {
@@ -353,6 +457,7 @@ func getAuthRestParameters(cmd *cobra.Command) (*authRestParameters, error) { //
 	return &authRestParameters{
 		hostURL:          hostURL,
 		externalURL:      externalURL,
+		proxyParams:      proxyParams,
 		tlsParams:        tlsParams,
 		logLevel:         loggingLevel,
 		databaseType:     databaseType,
@@ -414,17 +519,37 @@ func getTLS(cmd *cobra.Command) (*tlsParams, error) {
 
 	params.serveKeyPath, err = cmdutils.GetUserSetVarFromString(cmd,
 		tlsServeKeyPathFlagName, tlsServeKeyPathFlagEnvKey, true)
+	if err != nil {
+		return nil, err
+	}
 
-	return params, err
+	clientAuth, err := cmdutils.GetUserSetVarFromString(cmd, tlsClientAuthFlagName, tlsClientAuthEnvKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if clientAuth != "" {
+		mode, ok := tlsClientAuthModes[clientAuth]
+		if !ok {
+			return nil, fmt.Errorf("invalid value for %s: '%s'", tlsClientAuthFlagName, clientAuth)
+		}
+
+		params.clientAuth = mode
+	}
+
+	return params, nil
 }
 
 func createFlags(startCmd *cobra.Command) {
 	startCmd.Flags().StringP(hostURLFlagName, hostURLFlagShorthand, "", hostURLFlagUsage)
 	startCmd.Flags().StringP(externalURLFlagName, "", "", externalURLFlagUsage)
+	startCmd.Flags().StringArrayP(trustedProxiesFlagName, "", []string{}, trustedProxiesFlagUsage)
+	startCmd.Flags().StringArrayP(publicPrefixesFlagName, "", []string{}, publicPrefixesFlagUsage)
 	startCmd.Flags().StringP(tlsSystemCertPoolFlagName, "", "", tlsSystemCertPoolFlagUsage)
 	startCmd.Flags().StringArrayP(tlsCACertsFlagName, "", []string{}, tlsCACertsFlagUsage)
 	startCmd.Flags().StringP(tlsServeCertPathFlagName, "", "", tlsServeCertPathFlagUsage)
 	startCmd.Flags().StringP(tlsServeKeyPathFlagName, "", "", tlsServeKeyPathFlagUsage)
+	startCmd.Flags().StringP(tlsClientAuthFlagName, "", "", tlsClientAuthFlagUsage)
 	startCmd.Flags().StringP(logLevelFlagName, logLevelFlagShorthand, "", logLevelPrefixFlagUsage)
 	startCmd.Flags().StringP(staticFilesPathFlagName, "", "", staticFilesPathFlagUsage)
 	startCmd.Flags().StringP(databaseTypeFlagName, databaseTypeFlagShorthand, "", databaseTypeFlagUsage)
@@ -445,7 +570,15 @@ func createFlags(startCmd *cobra.Command) {
 	startCmd.Flags().StringP(sessionCookieAuthKeyFlagName, "", "", sessionCookieAuthKeyFlagUsage)
 	startCmd.Flags().StringP(sessionCookieEncKeyFlagName, "", "", sessionCookieEncKeyFlagUsage)
 	startCmd.Flags().StringP(gnapAccessPolicyFlagName, "", "", gnapAccessPolicyFlagUsage)
+	startCmd.Flags().StringP(gnapClientRegistryFlagName, "", "", gnapClientRegistryFlagUsage)
+	startCmd.Flags().StringArrayP(gnapClientCACertsFlagName, "", []string{}, gnapClientCACertsFlagUsage)
+	startCmd.Flags().StringP(gnapIDTokenSigningKeyFlagName, "", "", gnapIDTokenSigningKeyFlagUsage)
 	startCmd.Flags().StringP(gnapDevModeFlagName, "", "", gnapDevModeFlagUsage)
+	startCmd.Flags().StringP(gnapReplayProtectionFlagName, "", "", gnapReplayProtectionFlagUsage)
+	startCmd.Flags().StringP(gnapHTTPSigLabelFlagName, "", "", gnapHTTPSigLabelFlagUsage)
+	startCmd.Flags().StringArrayP(gnapHTTPSigComponentsFlagName, "", []string{}, gnapHTTPSigComponentsFlagUsage)
+	startCmd.Flags().StringP(gnapClockSkewFlagName, "", "", gnapClockSkewFlagUsage)
+	startCmd.Flags().StringArrayP(gnapPushAllowlistFlagName, "", []string{}, gnapPushAllowlistFlagUsage)
 }
 
 // nolint:funlen
@@ -481,15 +614,108 @@ func startAuthService(parameters *authRestParameters, srv server) error {
 		return fmt.Errorf("loading GNAP configs: %w", err)
 	}
 
-	// TODO: support creating multiple GNAP user interaction handlers
+	gnapClientRegistryConfig, err := loadClientRegistryConfig(parameters.gnap)
+	if err != nil {
+		return fmt.Errorf("loading GNAP client registry: %w", err)
+	}
+
+	var gnapClientCAs *x509.CertPool
+
+	if len(parameters.gnap.clientCACerts) > 0 {
+		gnapClientCAs, err = tlsutils.GetCertPool(false, parameters.gnap.clientCACerts)
+		if err != nil {
+			return fmt.Errorf("loading GNAP client CA certs: %w", err)
+		}
+	}
+
+	gnapIDTokenSigningKey, err := loadIDTokenSigningKey(parameters.gnap)
+	if err != nil {
+		return fmt.Errorf("loading GNAP id_token signing key: %w", err)
+	}
+
+	var pusher *push.Pusher
+
+	if len(parameters.gnap.pushAllowlist) > 0 {
+		pusher, err = push.New(&push.Config{
+			AllowedURIs: parameters.gnap.pushAllowlist,
+			HTTPClient: &http.Client{Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{RootCAs: rootCAs, MinVersion: tls.VersionTLS12},
+			}},
+		})
+		if err != nil {
+			return fmt.Errorf("initializing GNAP push finish: %w", err)
+		}
+	}
+
 	interact, err := redirect.New(&redirect.Config{
-		StoreProvider:    provider,
-		InteractBasePath: parameters.externalURL + gnap.InteractPath,
+		StoreProvider: provider,
+		InteractPath:  gnap.InteractPath,
+		Pusher:        pusher,
 	})
 	if err != nil {
 		return fmt.Errorf("initializing GNAP interaction handler: %w", err)
 	}
 
+	userCodes, err := usercode.New(&usercode.Config{
+		StoreProvider:      provider,
+		InteractionHandler: interact,
+		UserCodePath:       gnap.UserCodePath,
+	})
+	if err != nil {
+		return fmt.Errorf("initializing GNAP user code interaction handler: %w", err)
+	}
+
+	appTemplates := map[string]string{}
+	appClients := map[string]string{}
+
+	for _, client := range gnapClientRegistryConfig.Clients {
+		if client.AppURITemplate != "" {
+			appTemplates[client.ID] = client.AppURITemplate
+		}
+
+		if client.AppClientID != "" {
+			appClients[client.ID] = client.AppClientID
+		}
+	}
+
+	apps, err := app.New(&app.Config{
+		InteractionHandler: interact,
+		Templates:          appTemplates,
+		AppClients:         appClients,
+	})
+	if err != nil {
+		return fmt.Errorf("initializing GNAP app interaction handler: %w", err)
+	}
+
+	finishMethods := []string{gnapapi.FinishMethodRedirect}
+
+	if pusher != nil {
+		finishMethods = append(finishMethods, gnapapi.FinishMethodPush)
+	}
+
+	// the app and user code handlers run the interaction of the redirect handler, and also return its redirect url
+	// to clients that can start with it. Clients without an app fall through to the next handler they can start.
+	interactionHandlers := []*authhandler.InteractionHandlerConfig{
+		{
+			Name:          gnapapi.StartModeApp,
+			Handler:       apps,
+			StartModes:    []string{gnapapi.StartModeApp},
+			FinishMethods: finishMethods,
+		},
+		{
+			Name:          gnapapi.StartModeUserCode,
+			Handler:       userCodes,
+			StartModes:    []string{gnapapi.StartModeUserCode, gnapapi.StartModeUserCodeURI},
+			FinishMethods: finishMethods,
+		},
+		{
+			Name:          gnapapi.StartModeRedirect,
+			Handler:       interact,
+			StartModes:    []string{gnapapi.StartModeRedirect},
+			FinishMethods: finishMethods,
+		},
+	}
+
 	svc, err := restapi.New(&operation.Config{
 		TransientStoreProvider: provider,
 		StoreProvider:          provider,
@@ -514,13 +740,20 @@ func startAuthService(parameters *authRestParameters, srv server) error {
 		StartupTimeout: parameters.startupTimeout,
 		SecretsToken:   parameters.secretsAPIToken,
 	}, &gnap.Config{
-		StoreProvider:      provider,
-		BaseURL:            parameters.externalURL,
-		AccessPolicyConfig: gnapAPConfig,
-		InteractionHandler: interact,
-		UIEndpoint:         uiEndpoint,
-		ClosePopupHTML:     parameters.staticFiles + "/gnapRedirect.html",
-		StartupTimeout:     parameters.startupTimeout,
+		StoreProvider:        provider,
+		BaseURL:              parameters.externalURL,
+		TrustedProxies:       parameters.proxyParams.trustedProxies,
+		PublicPrefixes:       parameters.proxyParams.publicPrefixes,
+		AccessPolicyConfig:   gnapAPConfig,
+		ClientRegistryConfig: gnapClientRegistryConfig,
+		ClientCAs:            gnapClientCAs,
+		IDTokenSigningKey:    gnapIDTokenSigningKey,
+		InteractionHandler:   interact,
+		InteractionHandlers:  interactionHandlers,
+		UserCodes:            userCodes,
+		UIEndpoint:           uiEndpoint,
+		ClosePopupHTML:       parameters.staticFiles + "/gnapRedirect.html",
+		StartupTimeout:       parameters.startupTimeout,
 		OIDC: &oidcmodel.Config{
 			CallbackURL: parameters.oidcParams.callbackURL,
 			Providers:   parameters.oidcParams.providers,
@@ -533,6 +766,16 @@ func startAuthService(parameters *authRestParameters, srv server) error {
 		TransientStoreProvider: provider,
 		TLSConfig:              &tls.Config{RootCAs: rootCAs}, //nolint:gosec
 		DisableHTTPSigVerify:   parameters.gnap.disableHTTPSigVerify,
+		ReplayProtection:       parameters.gnap.replayProtection,
+		HTTPSigConfig: &gnap.HTTPSigConfig{
+			Label:              parameters.gnap.httpSigLabel,
+			RequiredComponents: parameters.gnap.httpSigComponents,
+			ClockSkew:          parameters.gnap.clockSkew,
+		},
+		Cookies: &gnap.CookieConfig{
+			AuthKey: parameters.keys.sessionCookieAuthKey,
+			EncKey:  parameters.keys.sessionCookieEncKey,
+		},
 	})
 	if err != nil {
 		return err
@@ -556,6 +799,11 @@ Database prefix: %s`, parameters.hostURL, parameters.databaseType, parameters.da
 		parameters.hostURL,
 		parameters.tlsParams.serveCertPath,
 		parameters.tlsParams.serveKeyPath,
+		&tls.Config{
+			ClientAuth: parameters.tlsParams.clientAuth,
+			ClientCAs:  rootCAs,
+			MinVersion: tls.VersionTLS12,
+		},
 		constructCORSHandler(router),
 	)
 }
@@ -578,6 +826,61 @@ func loadGNAPConfigs(params *gnapParameters) (*accesspolicy.Config, error) {
 	return conf, nil
 }
 
+func loadClientRegistryConfig(params *gnapParameters) (*clientregistry.Config, error) {
+	conf := &clientregistry.Config{}
+
+	if params.clientRegistryConfigPath != "" {
+		bytes, err := ioutil.ReadFile(path.Clean(params.clientRegistryConfigPath))
+		if err != nil {
+			return nil, err
+		}
+
+		err = json.Unmarshal(bytes, conf)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return conf, nil
+}
+
+// loadIDTokenSigningKey loads the PEM-encoded PKCS #8, SEC 1 or PKCS #1 private key that signs GNAP id_tokens.
+func loadIDTokenSigningKey(params *gnapParameters) (crypto.Signer, error) {
+	if params.idTokenSigningKeyPath == "" {
+		return nil, nil
+	}
+
+	bytes, err := ioutil.ReadFile(path.Clean(params.idTokenSigningKeyPath))
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(bytes)
+	if block == nil {
+		return nil, errors.New("no PEM-encoded key found")
+	}
+
+	if key, e := x509.ParseECPrivateKey(block.Bytes); e == nil {
+		return key, nil
+	}
+
+	if key, e := x509.ParsePKCS1PrivateKey(block.Bytes); e == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing private key: %w", err)
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+
+	return signer, nil
+}
+
 func uiHandler(
 	basePath string,
 	fileServer func(http.ResponseWriter, *http.Request, string)) func(http.ResponseWriter, *http.Request) {
@@ -709,7 +1012,39 @@ func getDeviceCertParams(cmd *cobra.Command) (*deviceCertParams, error) {
 	return params, err
 }
 
-func getGNAPParams(cmd *cobra.Command) *gnapParameters {
+func getProxyParams(cmd *cobra.Command) (*proxyParams, error) {
+	params := &proxyParams{
+		publicPrefixes: map[string]string{},
+	}
+
+	var err error
+
+	params.trustedProxies, err = cmdutils.GetUserSetVarFromArrayString(cmd, trustedProxiesFlagName,
+		trustedProxiesEnvKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	prefixes, err := cmdutils.GetUserSetVarFromArrayString(cmd, publicPrefixesFlagName, publicPrefixesEnvKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, mapping := range prefixes {
+		kv := strings.SplitN(mapping, "=", 2) // nolint:gomnd
+
+		if len(kv) != 2 || kv[1] == "" { // nolint:gomnd
+			return nil, fmt.Errorf("invalid value for %s: '%s' is not in the format <path-prefix>=<public-url>",
+				publicPrefixesFlagName, mapping)
+		}
+
+		params.publicPrefixes[kv[0]] = kv[1]
+	}
+
+	return params, nil
+}
+
+func getGNAPParams(cmd *cobra.Command) (*gnapParameters, error) {
 	params := &gnapParameters{}
 
 	apConfPath := cmdutils.GetUserSetOptionalVarFromString(cmd, gnapAccessPolicyFlagName, gnapAccessPolicyEnvKey)
@@ -718,10 +1053,50 @@ func getGNAPParams(cmd *cobra.Command) *gnapParameters {
 
 	devModeBool := strings.EqualFold(devMode, "true")
 
+	replayProtection := cmdutils.GetUserSetOptionalVarFromString(cmd, gnapReplayProtectionFlagName,
+		gnapReplayProtectionEnvKey)
+
 	params.accessPolicyConfigPath = apConfPath
+	params.clientRegistryConfigPath = cmdutils.GetUserSetOptionalVarFromString(cmd, gnapClientRegistryFlagName,
+		gnapClientRegistryEnvKey)
 	params.disableHTTPSigVerify = devModeBool
+	params.replayProtection = strings.EqualFold(replayProtection, "true")
 
-	return params
+	params.httpSigLabel = cmdutils.GetUserSetOptionalVarFromString(cmd, gnapHTTPSigLabelFlagName,
+		gnapHTTPSigLabelEnvKey)
+
+	components, err := cmdutils.GetUserSetVarFromArrayString(cmd, gnapHTTPSigComponentsFlagName,
+		gnapHTTPSigComponentsEnvKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	params.httpSigComponents = components
+
+	clockSkew := cmdutils.GetUserSetOptionalVarFromString(cmd, gnapClockSkewFlagName, gnapClockSkewEnvKey)
+	if clockSkew != "" {
+		params.clockSkew, err = time.ParseDuration(clockSkew)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %w", gnapClockSkewFlagName, err)
+		}
+	}
+
+	params.clientCACerts, err = cmdutils.GetUserSetVarFromArrayString(cmd, gnapClientCACertsFlagName,
+		gnapClientCACertsEnvKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	params.idTokenSigningKeyPath = cmdutils.GetUserSetOptionalVarFromString(cmd, gnapIDTokenSigningKeyFlagName,
+		gnapIDTokenSigningKeyEnvKey)
+
+	params.pushAllowlist, err = cmdutils.GetUserSetVarFromArrayString(cmd, gnapPushAllowlistFlagName,
+		gnapPushAllowlistEnvKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return params, nil
 }
 
 func getKeyParams(cmd *cobra.Command) (*keyParameters, error) {
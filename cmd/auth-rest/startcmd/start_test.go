@@ -6,17 +6,25 @@ SPDX-License-Identifier: Apache-2.0
 package startcmd
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/spf13/cobra"
@@ -28,7 +36,7 @@ type mockServer struct {
 	err error
 }
 
-func (s *mockServer) ListenAndServe(host, certFile, keyFile string, handler http.Handler) error {
+func (s *mockServer) ListenAndServe(host, certFile, keyFile string, tlsConfig *tls.Config, handler http.Handler) error {
 	return s.err
 }
 
@@ -464,6 +472,355 @@ func TestTLSSystemCertPoolInvalidArgsEnvVar(t *testing.T) {
 	require.Contains(t, err.Error(), "invalid syntax")
 }
 
+func TestTLSClientAuth(t *testing.T) {
+	t.Run("valid mode", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+
+		startCmd.SetArgs(append(allArgs(t), "--"+tlsClientAuthFlagName, "require"))
+
+		require.NoError(t, startCmd.Execute())
+
+		params, err := getTLS(startCmd)
+		require.NoError(t, err)
+		require.Equal(t, tls.RequireAnyClientCert, params.clientAuth)
+	})
+
+	t.Run("invalid mode", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+
+		startCmd.SetArgs(append(allArgs(t), "--"+tlsClientAuthFlagName, "foo"))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid value for "+tlsClientAuthFlagName)
+	})
+}
+
+func TestProxyParams(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+
+		startCmd.SetArgs(append(allArgs(t),
+			"--"+trustedProxiesFlagName, "10.0.0.0/8",
+			"--"+publicPrefixesFlagName, "/gnap=https://example.com/auth/gnap",
+		))
+
+		require.NoError(t, startCmd.Execute())
+
+		params, err := getProxyParams(startCmd)
+		require.NoError(t, err)
+		require.Equal(t, []string{"10.0.0.0/8"}, params.trustedProxies)
+		require.Equal(t, map[string]string{"/gnap": "https://example.com/auth/gnap"}, params.publicPrefixes)
+	})
+
+	t.Run("invalid public prefix mapping", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+
+		startCmd.SetArgs(append(allArgs(t), "--"+publicPrefixesFlagName, "/gnap"))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid value for "+publicPrefixesFlagName)
+	})
+
+	t.Run("invalid trusted proxy", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+
+		startCmd.SetArgs(append(allArgs(t), "--"+trustedProxiesFlagName, "foo"))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid trusted proxy address")
+	})
+}
+
+func TestGNAPReplayProtection(t *testing.T) {
+	startCmd := GetStartCmd(&mockServer{})
+
+	startCmd.SetArgs(append(allArgs(t), "--"+gnapReplayProtectionFlagName, "true"))
+
+	require.NoError(t, startCmd.Execute())
+
+	params, err := getGNAPParams(startCmd)
+	require.NoError(t, err)
+	require.True(t, params.replayProtection)
+}
+
+func TestGNAPHTTPSigPolicy(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+
+		startCmd.SetArgs(append(allArgs(t),
+			"--"+gnapHTTPSigLabelFlagName, "gnap",
+			"--"+gnapHTTPSigComponentsFlagName, "@authority",
+			"--"+gnapHTTPSigComponentsFlagName, "content-type",
+		))
+
+		require.NoError(t, startCmd.Execute())
+
+		params, err := getGNAPParams(startCmd)
+		require.NoError(t, err)
+		require.Equal(t, "gnap", params.httpSigLabel)
+		require.Equal(t, []string{"@authority", "content-type"}, params.httpSigComponents)
+	})
+
+	t.Run("clock skew", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+
+		startCmd.SetArgs(append(allArgs(t), "--"+gnapClockSkewFlagName, "2m"))
+
+		require.NoError(t, startCmd.Execute())
+
+		params, err := getGNAPParams(startCmd)
+		require.NoError(t, err)
+		require.Equal(t, 2*time.Minute, params.clockSkew)
+	})
+
+	t.Run("invalid clock skew", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+
+		startCmd.SetArgs(append(allArgs(t), "--"+gnapClockSkewFlagName, "foo"))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid value for "+gnapClockSkewFlagName)
+	})
+
+	t.Run("from env", func(t *testing.T) {
+		t.Setenv(gnapHTTPSigComponentsEnvKey, "@authority,content-type")
+
+		startCmd := GetStartCmd(&mockServer{})
+
+		startCmd.SetArgs(allArgs(t))
+
+		require.NoError(t, startCmd.Execute())
+
+		params, err := getGNAPParams(startCmd)
+		require.NoError(t, err)
+		require.Equal(t, []string{"@authority", "content-type"}, params.httpSigComponents)
+	})
+}
+
+func TestGNAPClientRegistry(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		file, err := ioutil.TempFile("", "*.json")
+		require.NoError(t, err)
+
+		t.Cleanup(func() {
+			require.NoError(t, file.Close())
+		})
+
+		registry := `{"clients": [{"id": "foo", "jwks_uri": "https://foo.example.com/jwks"}]}`
+
+		err = ioutil.WriteFile(file.Name(), []byte(registry), os.ModeAppend)
+		require.NoError(t, err)
+
+		startCmd := GetStartCmd(&mockServer{})
+
+		startCmd.SetArgs(append(allArgs(t), "--"+gnapClientRegistryFlagName, file.Name()))
+
+		require.NoError(t, startCmd.Execute())
+
+		params, err := getGNAPParams(startCmd)
+		require.NoError(t, err)
+		require.Equal(t, file.Name(), params.clientRegistryConfigPath)
+
+		conf, err := loadClientRegistryConfig(params)
+		require.NoError(t, err)
+		require.Len(t, conf.Clients, 1)
+		require.Equal(t, "https://foo.example.com/jwks", conf.Clients[0].JWKSURI)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+
+		startCmd.SetArgs(append(allArgs(t), "--"+gnapClientRegistryFlagName, "INVALID"))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "loading GNAP client registry")
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		file, err := ioutil.TempFile("", "*.json")
+		require.NoError(t, err)
+
+		t.Cleanup(func() {
+			require.NoError(t, file.Close())
+		})
+
+		err = ioutil.WriteFile(file.Name(), []byte("}INVALID"), os.ModeAppend)
+		require.NoError(t, err)
+
+		_, err = loadClientRegistryConfig(&gnapParameters{clientRegistryConfigPath: file.Name()})
+		require.Error(t, err)
+	})
+
+	t.Run("invalid app template", func(t *testing.T) {
+		file, err := ioutil.TempFile("", "*.json")
+		require.NoError(t, err)
+
+		t.Cleanup(func() {
+			require.NoError(t, file.Close())
+		})
+
+		registry := `{"clients": [{"id": "foo", "jwks_uri": "https://foo.example.com/jwks",` +
+			` "app_uri_template": "wallet-authenticator://interact"}]}`
+
+		err = ioutil.WriteFile(file.Name(), []byte(registry), os.ModeAppend)
+		require.NoError(t, err)
+
+		startCmd := GetStartCmd(&mockServer{})
+
+		startCmd.SetArgs(append(allArgs(t), "--"+gnapClientRegistryFlagName, file.Name()))
+
+		err = startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "initializing GNAP app interaction handler")
+	})
+}
+
+func TestGNAPClientCACerts(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		template := &x509.Certificate{
+			SerialNumber:          big.NewInt(1),
+			Subject:               pkix.Name{CommonName: "client-ca"},
+			NotBefore:             time.Now(),
+			NotAfter:              time.Now().Add(time.Hour),
+			IsCA:                  true,
+			BasicConstraintsValid: true,
+		}
+
+		der, err := x509.CreateCertificate(rand.Reader, template, template, &priv.PublicKey, priv)
+		require.NoError(t, err)
+
+		file, err := ioutil.TempFile("", "*.pem")
+		require.NoError(t, err)
+
+		t.Cleanup(func() {
+			require.NoError(t, file.Close())
+		})
+
+		err = ioutil.WriteFile(file.Name(), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+			os.ModeAppend)
+		require.NoError(t, err)
+
+		startCmd := GetStartCmd(&mockServer{})
+
+		startCmd.SetArgs(append(allArgs(t), "--"+gnapClientCACertsFlagName, file.Name()))
+
+		require.NoError(t, startCmd.Execute())
+
+		params, err := getGNAPParams(startCmd)
+		require.NoError(t, err)
+		require.Equal(t, []string{file.Name()}, params.clientCACerts)
+	})
+
+	t.Run("invalid cert", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+
+		startCmd.SetArgs(append(allArgs(t), "--"+gnapClientCACertsFlagName, "INVALID"))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "loading GNAP client CA certs")
+	})
+}
+
+func TestGNAPIDTokenSigningKey(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	secDER, err := x509.MarshalECPrivateKey(ecKey)
+	require.NoError(t, err)
+
+	pkcs8DER, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	require.NoError(t, err)
+
+	t.Run("success", func(t *testing.T) {
+		for _, block := range []*pem.Block{
+			{Type: "EC PRIVATE KEY", Bytes: secDER},
+			{Type: "PRIVATE KEY", Bytes: pkcs8DER},
+		} {
+			file := tempFile(t, pem.EncodeToMemory(block))
+
+			startCmd := GetStartCmd(&mockServer{})
+
+			startCmd.SetArgs(append(allArgs(t), "--"+gnapIDTokenSigningKeyFlagName, file))
+
+			require.NoError(t, startCmd.Execute())
+
+			params, err := getGNAPParams(startCmd)
+			require.NoError(t, err)
+			require.Equal(t, file, params.idTokenSigningKeyPath)
+
+			key, err := loadIDTokenSigningKey(params)
+			require.NoError(t, err)
+			require.True(t, ecKey.PublicKey.Equal(key.Public()))
+		}
+	})
+
+	t.Run("invalid key", func(t *testing.T) {
+		for _, file := range []string{
+			"INVALID",
+			tempFile(t, []byte("not pem")),
+			tempFile(t, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("foo")})),
+		} {
+			startCmd := GetStartCmd(&mockServer{})
+
+			startCmd.SetArgs(append(allArgs(t), "--"+gnapIDTokenSigningKeyFlagName, file))
+
+			err := startCmd.Execute()
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "loading GNAP id_token signing key")
+		}
+	})
+}
+
+func TestGNAPPushAllowlist(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+
+		startCmd.SetArgs(append(allArgs(t), "--"+gnapPushAllowlistFlagName, "https://client.example.com/push"))
+
+		require.NoError(t, startCmd.Execute())
+
+		params, err := getGNAPParams(startCmd)
+		require.NoError(t, err)
+		require.Equal(t, []string{"https://client.example.com/push"}, params.pushAllowlist)
+	})
+
+	t.Run("invalid uri", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+
+		startCmd.SetArgs(append(allArgs(t), "--"+gnapPushAllowlistFlagName, "client.example.com"))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "initializing GNAP push finish")
+	})
+}
+
+func tempFile(t *testing.T, data []byte) string {
+	t.Helper()
+
+	file, err := ioutil.TempFile("", "*.pem")
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, file.Close())
+		require.NoError(t, os.Remove(file.Name()))
+	})
+
+	_, err = file.Write(data)
+	require.NoError(t, err)
+
+	return file.Name()
+}
+
 func Test_createProvider(t *testing.T) {
 	t.Run("Empty CouchDB URL", func(t *testing.T) {
 		provider, err := createProvider(&authRestParameters{
@@ -528,6 +885,7 @@ func unsetEnvVars(t *testing.T) {
 
 	vars := []string{
 		hostURLEnvKey,
+		tlsSystemCertPoolEnvKey,
 		databaseTypeEnvKey,
 		oidcCallbackURLEnvKey,
 		oidcProvidersConfigFileEnvKey,
@@ -45,6 +45,16 @@ export default [
           txnID: route.query.txnID,
         }),
       },
+      {
+        path: 'device',
+        name: 'UserCode',
+        component: load('UserCode'),
+      },
+      {
+        path: 'done',
+        name: 'InteractionDone',
+        component: load('InteractionDone'),
+      },
       {
         path: 'provider',
         name: 'ProviderPopup',
@@ -10,16 +10,18 @@ package as
 
 import (
 	"bytes"
+	"context"
 	"crypto"
+	_ "crypto/sha256" // init sha-256 hash.
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"time"
 
 	"github.com/trustbloc/edge-core/pkg/log"
-	_ "golang.org/x/crypto/sha3" // nolint:gci // init sha3 hash.
 
 	gnaprest "github.com/trustbloc/auth/pkg/restapi/gnap"
 	"github.com/trustbloc/auth/spi/gnap"
@@ -28,13 +30,24 @@ import (
 //nolint:gochecknoglobals
 var logger = log.New("auth-server-client")
 
-const contentType = "application/json"
+const (
+	contentType = "application/json"
+
+	errTooFast = "too_fast"
+)
+
+// DefaultPollWait is the time that Poll waits between continue requests, if the AS doesn't say how long to wait.
+const DefaultPollWait = 5 * time.Second
+
+// ErrTooFast signifies that the AS refused a continue request, since the client polled before its wait time passed.
+var ErrTooFast = errors.New("polled too fast")
 
 // Client requesting Gnap tokens from the Authorization Server.
 type Client struct {
 	signer            gnap.Signer
 	httpClient        *http.Client
 	gnapAuthServerURL string
+	after             func(time.Duration) <-chan time.Time
 }
 
 // NewClient creates a new GNAP authorization client. It requires a signer for HTTP Signature header, an HTTP client
@@ -56,6 +69,7 @@ func NewClient(signer gnap.Signer, httpClient *http.Client, gnapAuthServerURL st
 		signer:            signer,
 		httpClient:        httpClient,
 		gnapAuthServerURL: gnapAuthServerURL,
+		after:             time.After,
 	}, nil
 }
 
@@ -127,7 +141,8 @@ func (c *Client) RequestAccess(req *gnap.AuthRequest) (*gnap.AuthResponse, error
 // ErrInvalidInteractHash signifies that the provided interaction hash is invalid.
 var ErrInvalidInteractHash = errors.New("invalid interact hash")
 
-// ValidateInteractHash returns whether the given interaction hash is valid for the given hash parameters.
+// ValidateInteractHash returns whether the given interaction hash is valid for the given hash parameters. The hash
+// uses the RFC 9635 default hash method, sha-256, of requests that don't set a hash method.
 func ValidateInteractHash(hash, myNonce, theirNonce, interactRef, reqURI string) error {
 	expectedHash, err := responseHash(myNonce, theirNonce, interactRef, reqURI)
 	if err != nil {
@@ -144,7 +159,7 @@ func ValidateInteractHash(hash, myNonce, theirNonce, interactRef, reqURI string)
 func responseHash(clientNonce, serverNonce, interactRef, requestURI string) (string, error) {
 	hashBase := clientNonce + "\n" + serverNonce + "\n" + interactRef + "\n" + requestURI
 
-	hasher := crypto.SHA3_512.New()
+	hasher := crypto.SHA256.New()
 
 	_, err := hasher.Write([]byte(hashBase))
 	if err != nil {
@@ -197,12 +212,19 @@ func (c *Client) Continue(req *gnap.ContinueRequest, token string) (*gnap.AuthRe
 		}
 	}()
 
+	respBody, err := ioutil.ReadAll(r.Body)
+
 	if r.StatusCode != http.StatusOK {
+		errResp := &gnap.ErrorResponse{}
+
+		if err == nil && json.Unmarshal(respBody, errResp) == nil && errResp.Error == errTooFast {
+			return nil, fmt.Errorf("%w [%s]", ErrTooFast, gnaprest.AuthContinuePath)
+		}
+
 		return nil, fmt.Errorf("auth server replied with invalid status [%s]: %v",
 			gnaprest.AuthContinuePath, r.Status)
 	}
 
-	respBody, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		return nil, fmt.Errorf("read response failed [%s, %w]", gnaprest.AuthContinuePath, err)
 	}
@@ -217,3 +239,47 @@ func (c *Client) Continue(req *gnap.ContinueRequest, token string) (*gnap.AuthRe
 
 	return gnapResp, nil
 }
+
+// Poll continues a grant request until the AS finishes it, for clients that didn't request to be notified when the
+// user completes the interaction. Before each continue request, it waits for the time that the AS asked for in its
+// last response, or DefaultPollWait, and it waits longer each time that the AS says it polls too fast.
+// Returns the response that finishes the grant request, or the context's error if it ends first.
+func (c *Client) Poll(ctx context.Context, resp *gnap.AuthResponse) (*gnap.AuthResponse, error) {
+	if resp == nil {
+		return nil, fmt.Errorf("empty response")
+	}
+
+	wait := pollWait(resp.Continue)
+
+	for resp.Continue != nil {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-c.after(wait):
+		}
+
+		next, err := c.Continue(&gnap.ContinueRequest{}, resp.Continue.AccessToken.Value)
+		if errors.Is(err, ErrTooFast) {
+			wait += DefaultPollWait
+
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		resp = next
+		wait = pollWait(resp.Continue)
+	}
+
+	return resp, nil
+}
+
+func pollWait(cont *gnap.ResponseContinue) time.Duration {
+	if cont == nil || cont.Wait <= 0 {
+		return DefaultPollWait
+	}
+
+	return time.Duration(cont.Wait) * time.Second
+}
@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package as
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
@@ -31,6 +32,7 @@ import (
 
 	gnaprest "github.com/trustbloc/auth/pkg/restapi/gnap"
 	"github.com/trustbloc/auth/spi/gnap"
+	"github.com/trustbloc/auth/spi/gnap/proof/httpsig"
 )
 
 const (
@@ -198,6 +200,52 @@ func TestRequestAccess(t *testing.T) {
 	}
 }
 
+func TestRequestAccessWithKeySigner(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	pub := &jwk.JWK{
+		JSONWebKey: jose.JSONWebKey{
+			Key:       &priv.PublicKey,
+			KeyID:     "key1",
+			Algorithm: "ES256",
+		},
+		Kty: "EC",
+		Crv: "P-256",
+	}
+
+	grantResp := &gnap.AuthResponse{AccessToken: []gnap.AccessToken{{Value: "foo"}}}
+
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.URL.Scheme = "https"
+		r.URL.Host = r.Host
+
+		err := httpsig.NewVerifier(r).Verify(&gnap.ClientKey{JWK: *pub})
+		require.NoError(t, err)
+
+		err = processPOSTAuthAccessRequest(w, r, grantResp)
+		require.NoError(t, err)
+	})
+
+	server, serverURL, httpClient := CreateMockHTTPServerAndClient(t, hf)
+
+	defer func() {
+		require.NoError(t, server.Close())
+	}()
+
+	// the private key is only used through its crypto.Signer interface, as with a PKCS#11 or KMS-backed key.
+	c, err := NewClient(httpsig.NewCryptoSigner(priv, pub), httpClient, serverURL)
+	require.NoError(t, err)
+
+	response, err := c.RequestAccess(&gnap.AuthRequest{
+		Client: &gnap.RequestClient{
+			Key: &gnap.ClientKey{JWK: *pub},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "foo", response.AccessToken[0].Value)
+}
+
 func TestValidateHash(t *testing.T) {
 	clientNonce := "foo"
 	serverNonce := "bar"
@@ -339,6 +387,104 @@ func TestContinue(t *testing.T) {
 	}
 }
 
+func TestPoll(t *testing.T) {
+	pending := &gnap.AuthResponse{Continue: &gnap.ResponseContinue{
+		URI:         "https://as.example.com/gnap/continue",
+		AccessToken: gnap.AccessToken{Value: "continue-token"},
+		Wait:        2,
+	}}
+
+	t.Run("success", func(t *testing.T) {
+		responses := []interface{}{
+			&gnap.ErrorResponse{Error: "too_fast"},
+			&gnap.AuthResponse{Continue: &gnap.ResponseContinue{
+				URI:         "https://as.example.com/gnap/continue",
+				AccessToken: gnap.AccessToken{Value: "continue-token"},
+			}},
+			&gnap.AuthResponse{AccessToken: []gnap.AccessToken{{Value: "access-token"}}},
+		}
+
+		hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, gnaprest.AuthContinuePath, r.URL.Path)
+			require.Equal(t, "GNAP continue-token", r.Header.Get("Authorization"))
+
+			res := responses[0]
+			responses = responses[1:]
+
+			if _, ok := res.(*gnap.ErrorResponse); ok {
+				w.WriteHeader(http.StatusBadRequest)
+			}
+
+			require.NoError(t, json.NewEncoder(w).Encode(res))
+		})
+
+		server, url, httpClient := CreateMockHTTPServerAndClient(t, hf)
+
+		defer func() {
+			require.NoError(t, server.Close())
+		}()
+
+		c, err := NewClient(&mockSigner{}, httpClient, url)
+		require.NoError(t, err)
+
+		var waits []time.Duration
+
+		c.after = func(d time.Duration) <-chan time.Time {
+			waits = append(waits, d)
+
+			return time.After(0)
+		}
+
+		resp, err := c.Poll(context.Background(), pending)
+		require.NoError(t, err)
+		require.Equal(t, "access-token", resp.AccessToken[0].Value)
+
+		// the wait of the AS, longer after polling too fast, then the default when the AS doesn't send one.
+		require.Equal(t, []time.Duration{2 * time.Second, 7 * time.Second, DefaultPollWait}, waits)
+	})
+
+	t.Run("finished grant", func(t *testing.T) {
+		c, err := NewClient(&mockSigner{}, &http.Client{}, "https://as.example.com")
+		require.NoError(t, err)
+
+		granted := &gnap.AuthResponse{AccessToken: []gnap.AccessToken{{Value: "access-token"}}}
+
+		resp, err := c.Poll(context.Background(), granted)
+		require.NoError(t, err)
+		require.Equal(t, granted, resp)
+
+		_, err = c.Poll(context.Background(), nil)
+		require.EqualError(t, err, "empty response")
+	})
+
+	t.Run("context ends", func(t *testing.T) {
+		c, err := NewClient(&mockSigner{}, &http.Client{}, "https://as.example.com")
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err = c.Poll(ctx, pending)
+		require.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("continue error", func(t *testing.T) {
+		server, url, httpClient := CreateMockHTTPServerAndClientNotOKStatusCode(t, http.StatusUnauthorized)
+
+		defer func() {
+			require.NoError(t, server.Close())
+		}()
+
+		c, err := NewClient(&mockSigner{}, httpClient, url)
+		require.NoError(t, err)
+
+		c.after = func(time.Duration) <-chan time.Time { return time.After(0) }
+
+		_, err = c.Poll(context.Background(), pending)
+		require.EqualError(t, err, "auth server replied with invalid status [/gnap/continue]: 401 Unauthorized")
+	})
+}
+
 func processPOSTAuthAccessRequest(w http.ResponseWriter, r *http.Request, expectedGnapResp *gnap.AuthResponse) error {
 	if valid := validateHTTPMethod(w, r); !valid {
 		return errors.New("http method invalid")
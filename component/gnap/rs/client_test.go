@@ -84,7 +84,6 @@ func TestRequestAccess(t *testing.T) {
 					IsReference: true,
 					Ref:         "test Success Value",
 					Type:        "",
-					Raw:         nil,
 				}},
 				Key:   clientKey(t),
 				Flags: []gnap.AccessFlag{},
@@ -7,9 +7,8 @@ SPDX-License-Identifier: Apache-2.0
 package accesspolicy
 
 import (
-	"encoding/json"
 	"errors"
-	"fmt"
+	"sort"
 	"time"
 
 	"github.com/trustbloc/auth/pkg/gnap/api"
@@ -21,8 +20,8 @@ import (
 // granted, what requires user consent, and what is forbidden.
 type AccessPolicy struct {
 	refToType map[string]string
-	// accessDescriptors maps each supported TokenAccess.Type to the tokenAccessMap form of the TokenAccess.
-	accessDescriptors map[string]tokenAccessMap
+	// accessDescriptors maps each supported TokenAccess.Type to its TokenAccess.
+	accessDescriptors map[string]*gnap.TokenAccess
 	// basePermissions holds the base permission of given TokenAccess.Type values, if no other permission is granted.
 	basePermissions map[string]permissionLevel
 	// lifetime number of seconds that a given token access should be valid for.
@@ -33,22 +32,17 @@ type AccessPolicy struct {
 func New(config *Config) (*AccessPolicy, error) {
 	ap := &AccessPolicy{
 		refToType:         map[string]string{},
-		accessDescriptors: map[string]tokenAccessMap{},
+		accessDescriptors: map[string]*gnap.TokenAccess{},
 		basePermissions:   map[string]permissionLevel{},
 		lifetime:          map[string]int{},
 	}
 
 	for _, accessType := range config.AccessTypes {
-		typeStr := accessType.Access.Type
+		access := accessType.Access
 
-		accessMap := tokenAccessMap{}
+		typeStr := access.Type
 
-		err := json.Unmarshal(accessType.Access.Raw, &accessMap)
-		if err != nil {
-			return nil, err
-		}
-
-		ap.accessDescriptors[typeStr] = accessMap
+		ap.accessDescriptors[typeStr] = &access
 
 		if accessType.Ref != "" {
 			ap.refToType[accessType.Ref] = typeStr
@@ -67,8 +61,6 @@ func New(config *Config) (*AccessPolicy, error) {
 	return ap, nil
 }
 
-type tokenAccessMap map[string]interface{}
-
 type permissionLevel int
 
 const (
@@ -79,7 +71,6 @@ const (
 
 const (
 	subjectKeyFieldName = "subject-keys"
-	typeFieldName       = "type"
 )
 
 var (
@@ -190,19 +181,19 @@ func (ap *AccessPolicy) getTokenAccessPermission(access gnap.TokenAccess, client
 		granted      bool
 	)
 
-	accessMap, err := ap.parse(access)
+	requested, err := ap.parse(access)
 	if err != nil {
 		return permissionDenied, nil, err
 	}
 
 	for _, grantedToken := range clientSession.Tokens {
 		for _, grantedAccess := range grantedToken.Access {
-			grantedMap, err := ap.parse(grantedAccess)
+			grantedDescriptor, err := ap.parse(grantedAccess)
 			if err != nil {
 				continue
 			}
 
-			ok := isTokenAccessSuperset(grantedMap, accessMap)
+			ok := requested.IsSubsetOf(grantedDescriptor)
 			if ok {
 				granted = true
 
@@ -222,10 +213,10 @@ func (ap *AccessPolicy) getTokenAccessPermission(access gnap.TokenAccess, client
 		}, nil
 	}
 
-	return ap.defaultTokenAccessPermission(access, accessMap)
+	return ap.defaultTokenAccessPermission(access, requested)
 }
 
-func (ap *AccessPolicy) defaultTokenAccessPermission(access gnap.TokenAccess, accessMap tokenAccessMap,
+func (ap *AccessPolicy) defaultTokenAccessPermission(access gnap.TokenAccess, requested *gnap.TokenAccess,
 ) (permissionLevel, *expirableTokenAccess, error) {
 	// if the given TokenAccess wasn't a subset of a granted token's access,
 	// we use the AccessPolicy's default permissions
@@ -233,7 +224,7 @@ func (ap *AccessPolicy) defaultTokenAccessPermission(access gnap.TokenAccess, ac
 	defaultLifetime := 0
 
 	for defaultType, defaultAccess := range ap.accessDescriptors {
-		ok := isTokenAccessSuperset(defaultAccess, accessMap)
+		ok := requested.IsSubsetOf(defaultAccess)
 		if ok {
 			perm := ap.basePermissions[defaultType]
 			lifetime := ap.lifetime[defaultType]
@@ -294,6 +285,9 @@ func mergePermissions(perms []*Permissions) *Permissions {
 		out.Allowed.SubjectKeys = append(out.Allowed.SubjectKeys, al)
 	}
 
+	sort.Strings(out.NeedsConsent.SubjectKeys)
+	sort.Strings(out.Allowed.SubjectKeys)
+
 	return out
 }
 
@@ -316,32 +310,42 @@ func (ap *AccessPolicy) AllowedSubjectKeys(tokAccess []gnap.TokenAccess) (map[st
 }
 
 func (ap *AccessPolicy) subKeys(tok gnap.TokenAccess) ([]string, error) {
-	tokMap, err := ap.parse(tok)
+	access, err := ap.parse(tok)
 	if err != nil {
 		return nil, err
 	}
 
-	subKeysInterface, ok := tokMap[subjectKeyFieldName]
+	subKeysValue, ok := access.Extensions[subjectKeyFieldName]
 	if !ok {
 		return nil, nil
 	}
 
-	subKeys, ok := subKeysInterface.([]interface{})
+	// subject keys set with TokenAccess.WithExtension aren't parsed from JSON, and can be a []string.
+	if subKeys, ok := subKeysValue.([]string); ok {
+		return subKeys, nil
+	}
+
+	subKeys, ok := subKeysValue.([]interface{})
 	if !ok {
 		return nil, errUnsupportedAccessType
 	}
 
-	out, extra := toStringSlice(subKeys)
-	if len(extra) > 0 {
-		return nil, errUnsupportedAccessType
+	out := []string{}
+
+	for _, v := range subKeys {
+		s, ok := v.(string)
+		if !ok {
+			return nil, errUnsupportedAccessType
+		}
+
+		out = append(out, s)
 	}
 
 	return out, nil
 }
 
-func (ap *AccessPolicy) parse(tok gnap.TokenAccess) (tokenAccessMap, error) {
-	var err error
-
+// parse resolves the given TokenAccess to the access descriptor object it stands for.
+func (ap *AccessPolicy) parse(tok gnap.TokenAccess) (*gnap.TokenAccess, error) {
 	if tok.IsReference {
 		tokType, ok := ap.refToType[tok.Ref]
 		if !ok {
@@ -356,89 +360,5 @@ func (ap *AccessPolicy) parse(tok gnap.TokenAccess) (tokenAccessMap, error) {
 		return out, nil
 	}
 
-	tokMap := tokenAccessMap{}
-
-	err = json.Unmarshal(tok.Raw, &tokMap)
-	if err != nil {
-		return nil, fmt.Errorf("parsing TokenAccess data: %w", err)
-	}
-
-	return tokMap, nil
-}
-
-// isTokenAccessSuperset returns true iff TokenAccess super is a superset of sub.
-//
-// Note: only supports maps with values that are string and/or []string.
-func isTokenAccessSuperset(super, sub tokenAccessMap) bool { // nolint:gocyclo
-	for k, subV := range sub {
-		if k == typeFieldName {
-			continue
-		}
-
-		superV, ok := super[k]
-		if !ok {
-			return false
-		}
-
-		switch subValue := subV.(type) {
-		case string:
-			superValue, ok := superV.(string)
-			if !ok || superValue != subValue {
-				return false
-			}
-		case []interface{}:
-			// assume order unimportant
-			superValue, ok := superV.([]interface{})
-			if !ok {
-				return false
-			}
-
-			subVStrings, extraSub := toStringSlice(subValue)
-			superVStrings, extraSuper := toStringSlice(superValue)
-
-			if len(extraSub) > 0 || len(extraSuper) > 0 {
-				return false
-			}
-
-			if !isSubset(superVStrings, subVStrings) {
-				return false
-			}
-		default:
-			return false
-		}
-	}
-
-	return true
-}
-
-func toStringSlice(a []interface{}) ([]string, []interface{}) {
-	out := []string{}
-	skip := []interface{}{}
-
-	for _, v := range a {
-		s, ok := v.(string)
-		if ok {
-			out = append(out, s)
-		} else {
-			skip = append(skip, v)
-		}
-	}
-
-	return out, skip
-}
-
-func isSubset(super, sub []string) bool {
-	superSet := map[string]struct{}{}
-
-	for _, s := range super {
-		superSet[s] = struct{}{}
-	}
-
-	for _, s := range sub {
-		if _, ok := superSet[s]; !ok {
-			return false
-		}
-	}
-
-	return true
+	return &tok, nil
 }
@@ -82,23 +82,6 @@ func TestNew(t *testing.T) {
 		require.Len(t, ap.accessDescriptors, 0)
 	})
 
-	t.Run("invalid config", func(t *testing.T) {
-		conf := &Config{
-			AccessTypes: []TokenAccessConfig{
-				{
-					Access: gnap.TokenAccess{
-						Type: "foo",
-						Raw:  []byte("foo bar baz"),
-					},
-				},
-			},
-		}
-
-		ap, err := New(conf)
-		require.Error(t, err)
-		require.Nil(t, ap)
-	})
-
 	t.Run("valid config", func(t *testing.T) {
 		conf := &Config{}
 
@@ -253,7 +236,7 @@ func TestAccessPolicy_DeterminePermissions(t *testing.T) {
 					AccessToken: gnap.AccessToken{
 						Access: []gnap.TokenAccess{
 							{
-								Raw: []byte("garble garble"),
+								Extensions: map[string]interface{}{"subject-keys": 12345},
 							},
 						},
 					},
@@ -294,17 +277,11 @@ func TestAccessPolicy_AllowedSubjectKeys(t *testing.T) {
 		require.Contains(t, keys, "client-id")
 	})
 
-	t.Run("error parsing", func(t *testing.T) {
+	t.Run("unknown reference", func(t *testing.T) {
 		ap := makeAccessPolicy(t)
 
-		keys, err := ap.AllowedSubjectKeys([]gnap.TokenAccess{
-			{
-				IsReference: false,
-				Raw:         []byte("garble garble"),
-			},
-		})
-		require.Error(t, err)
-		require.Contains(t, err.Error(), "parsing TokenAccess data")
+		keys, err := ap.AllowedSubjectKeys([]gnap.TokenAccess{*gnap.NewTokenAccessRef("unknown")})
+		require.ErrorIs(t, err, errReferenceNotFound)
 		require.Nil(t, keys)
 	})
 
@@ -313,20 +290,14 @@ func TestAccessPolicy_AllowedSubjectKeys(t *testing.T) {
 
 		// should be an array
 		keys, err := ap.AllowedSubjectKeys([]gnap.TokenAccess{
-			{
-				IsReference: false,
-				Raw:         []byte(`{"subject-keys": 12345}`),
-			},
+			*gnap.NewTokenAccess("foo").WithExtension("subject-keys", 12345),
 		})
 		require.ErrorIs(t, err, errUnsupportedAccessType)
 		require.Nil(t, keys)
 
 		// should be an array of strings
 		keys, err = ap.AllowedSubjectKeys([]gnap.TokenAccess{
-			{
-				IsReference: false,
-				Raw:         []byte(`{"subject-keys": [12345]}`),
-			},
+			*gnap.NewTokenAccess("foo").WithExtension("subject-keys", []interface{}{12345}),
 		})
 		require.ErrorIs(t, err, errUnsupportedAccessType)
 		require.Nil(t, keys)
@@ -338,7 +309,7 @@ func TestAccessPolicy_parse(t *testing.T) {
 		ap := makeAccessPolicy(t)
 
 		// empty this map, to break the AccessPolicy
-		ap.accessDescriptors = map[string]tokenAccessMap{}
+		ap.accessDescriptors = map[string]*gnap.TokenAccess{}
 
 		_, err := ap.parse(gnap.TokenAccess{
 			IsReference: true,
@@ -346,183 +317,6 @@ func TestAccessPolicy_parse(t *testing.T) {
 		})
 		require.ErrorIs(t, err, errInternal)
 	})
-
-	t.Run("error parsing invalid TokenAccess data", func(t *testing.T) {
-		ap := makeAccessPolicy(t)
-
-		_, err := ap.parse(gnap.TokenAccess{
-			IsReference: false,
-			Raw:         []byte("foo abc not json"),
-		})
-		require.Error(t, err)
-		require.Contains(t, err.Error(), "parsing TokenAccess data")
-	})
-}
-
-func Test_isTokenAccessSubset(t *testing.T) {
-	testcases := []struct {
-		name   string
-		super  tokenAccessMap
-		sub    tokenAccessMap
-		result bool
-	}{
-		{
-			name: "empty set is subset of empty set",
-			super: tokenAccessMap{
-				"type": "foo",
-			},
-			sub: tokenAccessMap{
-				"type": "bar",
-			},
-			result: true,
-		},
-		{
-			name: "subset field missing from superset",
-			super: tokenAccessMap{
-				"type": "foo",
-			},
-			sub: tokenAccessMap{
-				"type": "bar",
-				"foo":  "foo",
-			},
-			result: false,
-		},
-		{
-			name: "field has unsupported type",
-			super: tokenAccessMap{
-				"type": "foo",
-				"foo":  5,
-			},
-			sub: tokenAccessMap{
-				"type": "bar",
-				"foo":  5,
-			},
-			result: false,
-		},
-		{
-			name: "superset field is not string",
-			super: tokenAccessMap{
-				"type": "foo",
-				"foo":  []interface{}{"foo"},
-			},
-			sub: tokenAccessMap{
-				"type": "bar",
-				"foo":  "foo",
-			},
-			result: false,
-		},
-		{
-			name: "superset string has different value",
-			super: tokenAccessMap{
-				"type": "foo",
-				"foo":  "foo",
-			},
-			sub: tokenAccessMap{
-				"type": "bar",
-				"foo":  "bar",
-			},
-			result: false,
-		},
-		{
-			name: "superset field is not []interface{}",
-			super: tokenAccessMap{
-				"type": "foo",
-				"foo":  "foo",
-			},
-			sub: tokenAccessMap{
-				"type": "bar",
-				"foo":  []interface{}{"bar"},
-			},
-			result: false,
-		},
-		{
-			name: "subset array contains non-strings",
-			super: tokenAccessMap{
-				"type": "foo",
-				"foo":  []interface{}{"foo"},
-			},
-			sub: tokenAccessMap{
-				"type": "bar",
-				"foo":  []interface{}{123},
-			},
-			result: false,
-		},
-		{
-			name: "superset array contains non-strings",
-			super: tokenAccessMap{
-				"type": "foo",
-				"foo":  []interface{}{123},
-			},
-			sub: tokenAccessMap{
-				"type": "bar",
-				"foo":  []interface{}{"bar"},
-			},
-			result: false,
-		},
-		{
-			name: "subset array not subset of superset's",
-			super: tokenAccessMap{
-				"type": "foo",
-				"foo":  []interface{}{"foo", "blah"},
-			},
-			sub: tokenAccessMap{
-				"type": "bar",
-				"foo":  []interface{}{"foo", "bar"},
-			},
-			result: false,
-		},
-		{
-			name: "subset array ignores order",
-			super: tokenAccessMap{
-				"type": "foo",
-				"foo":  []interface{}{"bar", "foo"},
-			},
-			sub: tokenAccessMap{
-				"type": "bar",
-				"foo":  []interface{}{"foo", "bar"},
-			},
-			result: true,
-		},
-		{
-			name: "example pass",
-			super: tokenAccessMap{
-				"type":      "rw-all-dbs",
-				"actions":   []interface{}{"read", "write", "delete"},
-				"databases": []interface{}{"secret-store", "userdata", "localdb"},
-				"userkey":   "foo123",
-			},
-			sub: tokenAccessMap{
-				"type":      "read-localdb",
-				"actions":   []interface{}{"read"},
-				"databases": []interface{}{"localdb"},
-				"userkey":   "foo123",
-			},
-			result: true,
-		},
-		{
-			name: "example fail",
-			super: tokenAccessMap{
-				"type":      "read-localdb",
-				"actions":   []interface{}{"read"},
-				"databases": []interface{}{"localdb"},
-				"userkey":   "foo123",
-			},
-			sub: tokenAccessMap{
-				"type":      "rw-all-dbs",
-				"actions":   []interface{}{"read", "write", "delete"},
-				"databases": []interface{}{"secret-store", "userdata", "localdb"},
-				"userkey":   "foo123",
-			},
-			result: false,
-		},
-	}
-
-	for _, tc := range testcases {
-		t.Run(tc.name, func(t *testing.T) {
-			res := isTokenAccessSuperset(tc.super, tc.sub)
-			require.Equal(t, tc.result, res)
-		})
-	}
 }
 
 func makeAccessPolicy(t *testing.T) *AccessPolicy {
@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package api
 
 import (
+	"errors"
 	"time"
 
 	"github.com/trustbloc/auth/spi/gnap"
@@ -21,6 +22,52 @@ type Verifier interface {
 	Verify(key *gnap.ClientKey) error
 }
 
+// KeyIdentifier is implemented by Verifiers that can read the id of the key that a client request claims to be
+// signed with, which is used to dereference the key of a client that sends its instance identifier by reference.
+type KeyIdentifier interface {
+	// KeyID returns the id of the key that signed the request, using the given proof method.
+	KeyID(proof string) (string, error)
+}
+
+// Interaction start modes https://www.rfc-editor.org/rfc/rfc9635.html#section-2.5.1
+const (
+	// StartModeRedirect sends the user's browser to an interaction url of the AS.
+	StartModeRedirect = "redirect"
+	// StartModeUserCode lets the user type a short code in at a page of the AS, on any device.
+	StartModeUserCode = "user_code"
+	// StartModeUserCodeURI lets the user type a short code in at a page of the AS, whose url the client shows.
+	StartModeUserCodeURI = "user_code_uri"
+	// StartModeApp launches an application on the user's device, which runs the interaction.
+	StartModeApp = "app"
+)
+
+// HasStartMode returns true iff the client can start its interaction with the given start mode.
+func HasStartMode(clientInteract *gnap.RequestInteract, mode string) bool {
+	if clientInteract == nil {
+		return false
+	}
+
+	for _, start := range clientInteract.Start {
+		if start == mode {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Interaction finish methods https://www.rfc-editor.org/rfc/rfc9635.html#section-2.5.2
+const (
+	// FinishMethodRedirect redirects the user's browser back to the client when the user completes the interaction.
+	FinishMethodRedirect = "redirect"
+	// FinishMethodPush sends the interaction result to the client's server when the user completes the interaction.
+	FinishMethodPush = "push"
+)
+
+// ErrInvalidInteraction is returned by an InteractionHandler that doesn't support the interaction that a client
+// requests.
+var ErrInvalidInteraction = errors.New("invalid interaction")
+
 /*
 InteractionHandler handles user login & consent for a given set of access requests.
 
@@ -36,8 +83,18 @@ type InteractionHandler interface {
 	// PrepareLoginConsentFlow takes a set of requested access tokens and subject
 	// data, prepares a login & consent flow, and returns parameters for the user
 	// client to initiate the login & consent flow.
-	PrepareInteraction(clientInteract *gnap.RequestInteract, requestURI string, requestedTokens []*ExpiringTokenRequest,
-	) (*gnap.ResponseInteract, error)
+	//
+	// requestURI is the public url of the grant request, and baseURL is the public
+	// url of the server as seen by the client, which interaction urls are relative to.
+	// details describe the interaction to the user who completes it.
+	//
+	// Returns: client interact response, flow ID, error
+	PrepareInteraction(clientInteract *gnap.RequestInteract, requestURI, baseURL string,
+		requestedTokens []*ExpiringTokenRequest, details *InteractionDetails) (*gnap.ResponseInteract, string, error)
+
+	// QueryDetails returns the details of the interaction with the given flow ID,
+	// for the user who completes it.
+	QueryDetails(flowID string) (*InteractionDetails, error)
 
 	// CompleteLoginConsentFlow takes a set of access requests that the user
 	// consented to, and the ID of the flow where this was performed, creates an
@@ -77,8 +134,88 @@ type ExpiringToken struct {
 	Expires time.Time `json:"expiry"`
 }
 
+// Keys of the ConsentResult.SubjectData entries that identify the user who completed the interaction.
+const (
+	// SubjectDataSub is the user's subject identifier at the AS.
+	SubjectDataSub = "sub"
+	// SubjectDataIssuer is the issuer of the identity provider that authenticated the user.
+	SubjectDataIssuer = "iss"
+	// SubjectDataEmail is the user's email address.
+	SubjectDataEmail = "email"
+	// SubjectDataDID is the user's DID.
+	SubjectDataDID = "did"
+	// SubjectDataProvider is the id of the identity provider that authenticated the user.
+	SubjectDataProvider = "provider"
+)
+
+// InteractionDetails describe a pending interaction to the user who completes it.
+type InteractionDetails struct {
+	// Tokens are the requested access tokens that need the user's consent. They are set by the InteractionHandler.
+	Tokens []gnap.TokenRequest `json:"access_token,omitempty"`
+	// SubjectKeys are the keys of the user's subject data that the requested access tokens give access to, or that
+	// the client requested as subject information.
+	SubjectKeys []string `json:"subject_keys,omitempty"`
+	// Expires is when the interaction expires. It is set by the InteractionHandler.
+	Expires time.Time `json:"expires"`
+	// ClientID is the instance identifier of the client that requested the grant.
+	ClientID string `json:"-"`
+	// App is true iff the client started the interaction with the app start mode. It is set by the app
+	// InteractionHandler.
+	App bool `json:"-"`
+	// AppClientID is the instance identifier of the client's companion app, whose access tokens can complete the
+	// interaction if App is true. It is set by the app InteractionHandler.
+	AppClientID string `json:"-"`
+	// Client is the display information of the client that requested the grant. It may be nil.
+	Client *gnap.ClientDisplay `json:"client,omitempty"`
+	// ClientVerified is true iff the client's display information was registered with the AS, instead of being
+	// sent by the client itself.
+	ClientVerified bool `json:"client_verified,omitempty"`
+	// User identifies the user that the client expects to complete the interaction. It may be nil.
+	User *UserHint `json:"user,omitempty"`
+}
+
+// UserHint identifies the user that a client expects to complete an interaction, so that the interaction can
+// pre-select the identity provider that the user logs in with, and pre-fill the user's login.
+type UserHint struct {
+	// Sub is the user's subject identifier at the AS.
+	Sub string `json:"sub,omitempty"`
+	// Email is the user's email address.
+	Email string `json:"email,omitempty"`
+	// Provider is the id of the identity provider that the user logged in with before.
+	Provider string `json:"provider,omitempty"`
+}
+
+// LoginHint returns the login_hint to pass to the identity provider: the user's email address if it's known, and
+// otherwise the user's subject identifier.
+func (u *UserHint) LoginHint() string {
+	if u == nil {
+		return ""
+	}
+
+	if u.Email != "" {
+		return u.Email
+	}
+
+	return u.Sub
+}
+
 // ConsentResult holds access token descriptors and subject data that were granted by a user consent interaction.
 type ConsentResult struct {
 	Tokens      []*ExpiringTokenRequest `json:"tok,omitempty"`
 	SubjectData map[string]string       `json:"sub,omitempty"`
+	// DeniedTokens are the requested access tokens that the user didn't approve.
+	DeniedTokens []*ExpiringTokenRequest `json:"denied-tok,omitempty"`
+	// DeniedSubjectKeys are the keys of the requested subject data that the user didn't approve.
+	DeniedSubjectKeys []string `json:"denied-sub,omitempty"`
+	// Selection is the part of the requested access that the user approved, as passed to CompleteInteraction. If
+	// nil, the user approved all requested access.
+	Selection *ConsentSelection `json:"-"`
+}
+
+// ConsentSelection is the part of an interaction's requested access that the user approved.
+type ConsentSelection struct {
+	// Tokens are the indexes of the approved token requests in InteractionDetails.Tokens.
+	Tokens []int `json:"access_token"`
+	// SubjectKeys are the approved keys of InteractionDetails.SubjectKeys.
+	SubjectKeys []string `json:"subject_keys"`
 }
@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package authhandler
 
 import (
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"time"
@@ -17,6 +18,8 @@ import (
 
 	"github.com/trustbloc/auth/pkg/gnap/accesspolicy"
 	"github.com/trustbloc/auth/pkg/gnap/api"
+	"github.com/trustbloc/auth/pkg/gnap/clientregistry"
+	"github.com/trustbloc/auth/pkg/gnap/idtoken"
 	"github.com/trustbloc/auth/pkg/gnap/session"
 	"github.com/trustbloc/auth/spi/gnap"
 )
@@ -55,21 +58,95 @@ type AuthHandler struct {
 	continuePath   string
 	accessPolicy   *accesspolicy.AccessPolicy
 	sessionStore   *session.Manager
-	loginConsent   api.InteractionHandler
+	clients        *clientregistry.Registry
+	clientCAs      *x509.CertPool
+	interactions   []*InteractionHandlerConfig
+	idTokens       *idtoken.Issuer
 	disableHTTPSig bool
+	pollInterval   time.Duration
+	now            func() time.Time
 }
 
 // Config holds AuthHandler constructor configuration.
 type Config struct {
 	AccessPolicyConfig *accesspolicy.Config
-	ContinuePath       string
-	InteractionHandler api.InteractionHandler
-	StoreProvider      storage.Provider
-	DisableHTTPSig     bool
+	// ContinuePath is the path of the continue endpoint, relative to the server's public base url.
+	ContinuePath string
+	// InteractionHandlers are the handlers of the login & consent interactions, in order of preference. Each grant
+	// request that needs consent is handled by the first handler that supports the client's interaction, and that
+	// doesn't reject the particular client with api.ErrInvalidInteraction.
+	InteractionHandlers []*InteractionHandlerConfig
+	StoreProvider       storage.Provider
+	DisableHTTPSig      bool
+	// ClientRegistryConfig holds pre-registered clients, which send their instance identifier by reference and whose
+	// keys are dereferenced by key id. It may be nil.
+	ClientRegistryConfig *clientregistry.Config
+	// ClientCAs are the trust anchors that certificates bound to client and resource server keys are validated
+	// against. If nil, certificates aren't validated, and only the key they hold is used.
+	ClientCAs *x509.CertPool
+	// IDTokenIssuer mints the id_token and vc subject assertions that clients can request. If nil, no assertion
+	// formats are supported.
+	IDTokenIssuer *idtoken.Issuer
+	// PollInterval is the time that clients wait between continue requests, while they poll for the result of an
+	// interaction. Defaults to DefaultPollInterval.
+	PollInterval time.Duration
+}
+
+// DefaultPollInterval is the default time that polling clients wait between continue requests.
+const DefaultPollInterval = 5 * time.Second
+
+var (
+	// ErrTooFast is returned when a client polls for the result of an interaction before its wait time passed.
+	ErrTooFast = errors.New("client polls too fast")
+	// ErrInvalidContinuation is returned when a client continues a grant request that isn't pending.
+	ErrInvalidContinuation = errors.New("invalid continuation")
+)
+
+// InteractionHandlerConfig registers an InteractionHandler for the interactions that it supports.
+type InteractionHandlerConfig struct {
+	// Name identifies the handler in the sessions of its interactions, so it must be unique and stay the same across
+	// restarts.
+	Name    string
+	Handler api.InteractionHandler
+	// StartModes are the interaction start modes that the handler supports. It handles the interactions of clients
+	// that can start with any of them.
+	StartModes []string
+	// FinishMethods are the interaction finish methods that the handler supports. Clients that don't request a finish
+	// method poll for the result of the interaction, which every handler supports.
+	FinishMethods []string
+}
+
+// supports returns true iff the handler can run the interaction that the client requests.
+func (c *InteractionHandlerConfig) supports(clientInteract *gnap.RequestInteract) bool {
+	if clientInteract.Finish != nil && !contains(c.FinishMethods, clientInteract.Finish.Method) {
+		return false
+	}
+
+	for _, mode := range clientInteract.Start {
+		if contains(c.StartModes, mode) {
+			return true
+		}
+	}
+
+	return false
 }
 
 // New returns new AuthHandler.
 func New(config *Config) (*AuthHandler, error) {
+	names := map[string]bool{}
+
+	for _, handler := range config.InteractionHandlers {
+		if handler.Name == "" || handler.Handler == nil {
+			return nil, errors.New("interaction handlers need a name and a handler")
+		}
+
+		if names[handler.Name] {
+			return nil, fmt.Errorf("duplicate interaction handler %s", handler.Name)
+		}
+
+		names[handler.Name] = true
+	}
+
 	accessPolicy, err := accesspolicy.New(config.AccessPolicyConfig)
 	if err != nil {
 		return nil, err
@@ -80,20 +157,36 @@ func New(config *Config) (*AuthHandler, error) {
 		return nil, err
 	}
 
+	clients, err := clientregistry.New(config.ClientRegistryConfig)
+	if err != nil {
+		return nil, fmt.Errorf("initializing client registry: %w", err)
+	}
+
+	pollInterval := config.PollInterval
+	if pollInterval == 0 {
+		pollInterval = DefaultPollInterval
+	}
+
 	return &AuthHandler{
 		continuePath:   config.ContinuePath,
 		accessPolicy:   accessPolicy,
 		sessionStore:   sessionHandler,
-		loginConsent:   config.InteractionHandler,
+		clients:        clients,
+		clientCAs:      config.ClientCAs,
+		interactions:   config.InteractionHandlers,
+		idTokens:       config.IDTokenIssuer,
 		disableHTTPSig: config.DisableHTTPSig,
+		pollInterval:   pollInterval,
+		now:            time.Now,
 	}, nil
 }
 
-// HandleAccessRequest handles GNAP access requests.
+// HandleAccessRequest handles GNAP access requests. reqURL is the public url of the request, and baseURL is the
+// public url of the server as seen by the client, which the continue and interaction urls are relative to.
 func (h *AuthHandler) HandleAccessRequest( // nolint: funlen,gocyclo
 	req *gnap.AuthRequest,
 	reqVerifier api.Verifier,
-	reqURL string,
+	reqURL, baseURL string,
 ) (*gnap.AuthResponse, error) {
 	var (
 		s   *session.Session
@@ -105,12 +198,22 @@ func (h *AuthHandler) HandleAccessRequest( // nolint: funlen,gocyclo
 		return nil, errors.New("missing client")
 	}
 
+	err = h.validateSubjectRequest(req.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subject request: %w", err)
+	}
+
 	if req.Client.IsReference {
-		s, err = h.sessionStore.GetByID(req.Client.Ref)
+		s, err = h.referencedClientSession(req.Client.Ref, reqVerifier)
 		if err != nil {
-			return nil, fmt.Errorf("getting client session by client ID: %w", err)
+			return nil, err
 		}
 	} else {
+		err = h.validateCertificate(req.Client.Key)
+		if err != nil {
+			return nil, err
+		}
+
 		s, err = h.sessionStore.GetOrCreateByKey(req.Client.Key)
 		if err != nil {
 			return nil, fmt.Errorf("getting client session by key: %w", err)
@@ -126,14 +229,30 @@ func (h *AuthHandler) HandleAccessRequest( // nolint: funlen,gocyclo
 		}
 	}
 
+	h.setClientDisplay(req.Client, s)
+
+	user, assertedData, err := h.userHint(req.User, s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user information: %w", err)
+	}
+
+	if assertedData != nil && s.SubjectData[api.SubjectDataSub] == "" {
+		// the client proved who the user is, so the user is known to the client without logging in again.
+		s.AddSubjectData(assertedData)
+	}
+
 	permissions, err := h.accessPolicy.DeterminePermissions(req.AccessToken, s)
 	if err != nil {
 		return nil, fmt.Errorf("failed to determine permissions for access request: %w", err)
 	}
 
+	s.SubjectRequest = req.Subject
+
+	unconsentedKeys := unconsentedSubjectKeys(req.Subject, permissions.Allowed.SubjectKeys, s)
+
 	// TODO: smarter access policy logic, to recognize if a token is being re-requested, and send that token instead of
 	//   creating fresh access tokens every time.
-	if permissions.NeedsConsent.IsEmpty() && !permissions.Allowed.IsEmpty() {
+	if canGrantWithoutInteraction(permissions, req.Subject, unconsentedKeys, user, s) {
 		// nothing needs consent, but something is allowed, so create tokens for all allowed, and return
 		var resp *gnap.AuthResponse
 
@@ -158,42 +277,168 @@ func (h *AuthHandler) HandleAccessRequest( // nolint: funlen,gocyclo
 
 	s.NeedsConsent = permissions.NeedsConsent
 
-	s.AllowedRequest = permissions.Allowed
-
-	// TODO: support selecting one of multiple interaction handlers
-	interact, err := h.loginConsent.PrepareInteraction(req.Interact, reqURL, permissions.NeedsConsent.Tokens)
-	if err != nil {
-		return nil, fmt.Errorf("creating response interaction parameters: %w", err)
+	// the user approves releasing the requested subject data in the interaction.
+	for _, k := range unconsentedKeys {
+		if !contains(s.NeedsConsent.SubjectKeys, k) {
+			s.NeedsConsent.SubjectKeys = append(s.NeedsConsent.SubjectKeys, k)
+		}
 	}
 
-	err = h.sessionStore.Save(s)
+	s.AllowedRequest = permissions.Allowed
+
+	loginConsent, interact, flowID, err := h.prepareInteraction(req.Interact, reqURL, baseURL,
+		permissions.NeedsConsent.Tokens,
+		&api.InteractionDetails{
+			SubjectKeys:    s.NeedsConsent.SubjectKeys,
+			ClientID:       s.ClientID,
+			Client:         s.ClientDisplay,
+			ClientVerified: s.DisplayVerified,
+			User:           user,
+		})
 	if err != nil {
 		return nil, err
 	}
 
+	s.InteractHandler = loginConsent.Name
+	s.InteractFlowID = flowID
+	s.InteractRef = ""
+
 	resp := &gnap.AuthResponse{
-		Continue: gnap.ResponseContinue{
-			URI:         h.continuePath,
+		Continue: &gnap.ResponseContinue{
+			URI:         baseURL + h.continuePath,
 			AccessToken: continueToken,
 		},
-		Interact:   *interact,
+		Interact:   interact,
 		InstanceID: s.ClientID,
 	}
 
+	if req.Interact.Finish == nil {
+		// the client isn't notified when the user completes the interaction, so it polls the continue endpoint.
+		resp.Continue.Wait = h.wait(s)
+	}
+
+	err = h.sessionStore.Save(s)
+	if err != nil {
+		return nil, err
+	}
+
 	return resp, nil
 }
 
-// HandleContinueRequest handles GNAP continue requests.
-func (h *AuthHandler) HandleContinueRequest(
+// prepareInteraction prepares the interaction that the client requests with the first interaction handler that
+// supports it, returning the handler with the handler's interaction parameters and flow ID. A handler that rejects
+// the interaction of the particular client with api.ErrInvalidInteraction, such as the app handler for a client
+// without an app, passes the interaction on to the next handler that supports it.
+func (h *AuthHandler) prepareInteraction(
+	clientInteract *gnap.RequestInteract,
+	reqURL, baseURL string,
+	requestedTokens []*api.ExpiringTokenRequest,
+	details *api.InteractionDetails,
+) (*InteractionHandlerConfig, *gnap.ResponseInteract, string, error) {
+	handlers, err := h.selectInteractions(clientInteract)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	for _, handler := range handlers {
+		var (
+			interact *gnap.ResponseInteract
+			flowID   string
+		)
+
+		interact, flowID, err = handler.Handler.PrepareInteraction(clientInteract, reqURL, baseURL, requestedTokens,
+			details)
+		if err == nil {
+			return handler, interact, flowID, nil
+		}
+
+		if !errors.Is(err, api.ErrInvalidInteraction) {
+			break
+		}
+	}
+
+	return nil, nil, "", fmt.Errorf("creating response interaction parameters: %w", err)
+}
+
+// selectInteractions returns the interaction handlers that support the interaction that the client requests, in
+// order of preference.
+func (h *AuthHandler) selectInteractions(clientInteract *gnap.RequestInteract) ([]*InteractionHandlerConfig, error) {
+	if clientInteract == nil {
+		return nil, fmt.Errorf("%w: access needs consent, but the client can't start an interaction",
+			api.ErrInvalidInteraction)
+	}
+
+	var handlers []*InteractionHandlerConfig
+
+	for _, handler := range h.interactions {
+		if handler.supports(clientInteract) {
+			handlers = append(handlers, handler)
+		}
+	}
+
+	if len(handlers) > 0 {
+		return handlers, nil
+	}
+
+	finish := ""
+	if clientInteract.Finish != nil {
+		finish = clientInteract.Finish.Method
+	}
+
+	return nil, fmt.Errorf("%w: no interaction handler supports start modes %v with finish method '%s'",
+		api.ErrInvalidInteraction, clientInteract.Start, finish)
+}
+
+// sessionInteraction returns the interaction handler that runs the interaction of the session. Sessions saved before
+// their handler was recorded use the first handler.
+func (h *AuthHandler) sessionInteraction(s *session.Session) (api.InteractionHandler, error) {
+	for _, handler := range h.interactions {
+		if handler.Name == s.InteractHandler || s.InteractHandler == "" {
+			return handler.Handler, nil
+		}
+	}
+
+	return nil, fmt.Errorf("interaction handler %s isn't registered", s.InteractHandler)
+}
+
+// canGrantWithoutInteraction returns true iff nothing requested needs user consent, something is requested, the
+// client didn't identify a different user than the session's, and if the client requested subject information, the
+// user is already known from an earlier interaction of the client or from an assertion, and the access policy or the
+// user's earlier consent releases all the requested subject data.
+func canGrantWithoutInteraction(permissions *accesspolicy.Permissions, subReq *gnap.RequestSubject,
+	unconsentedKeys []string, user *api.UserHint, s *session.Session) bool {
+	if !permissions.NeedsConsent.IsEmpty() || !isSessionUser(user, s) {
+		return false
+	}
+
+	if !requestsSubject(subReq) {
+		return !permissions.Allowed.IsEmpty()
+	}
+
+	return s.SubjectData[api.SubjectDataSub] != "" && len(unconsentedKeys) == 0
+}
+
+// HandleContinueRequest handles GNAP continue requests. baseURL is the public url of the server as seen by the
+// client, which the continue url is relative to.
+func (h *AuthHandler) HandleContinueRequest( // nolint:funlen
 	req *gnap.ContinueRequest,
 	continueToken string,
 	reqVerifier api.Verifier,
+	baseURL string,
 ) (*gnap.AuthResponse, error) {
 	s, err := h.sessionStore.GetByContinueToken(continueToken)
 	if err != nil {
 		return nil, fmt.Errorf("getting session for continue token: %w", err)
 	}
 
+	if h.clients.IsRegistered(s.ClientID) {
+		// the registered client may have rotated its key since the grant request
+		s.ClientKey, err = h.registeredClientKey(s.ClientID, reqVerifier)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if h.disableHTTPSig {
 		logger.Warnf("server running in dev mode: http signature verification disabled")
 	} else {
@@ -203,12 +448,38 @@ func (h *AuthHandler) HandleContinueRequest(
 		}
 	}
 
-	consent, err := h.loginConsent.QueryInteraction(req.InteractRef)
+	loginConsent, err := h.sessionInteraction(s)
+	if err != nil {
+		return nil, err
+	}
+
+	interactRef := req.InteractRef
+
+	if interactRef == "" {
+		// the client polls for the result of an interaction that the user completes through another channel.
+		if s.InteractFlowID == "" {
+			return nil, fmt.Errorf("%w: no grant request is pending", ErrInvalidContinuation)
+		}
+
+		if h.now().Before(s.NextPoll) {
+			return nil, ErrTooFast
+		}
+
+		if s.InteractRef == "" {
+			return h.pendingResponse(s, loginConsent, baseURL)
+		}
+
+		interactRef = s.InteractRef
+	}
+
+	consent, err := loginConsent.QueryInteraction(interactRef)
 	if err != nil {
 		return nil, err
 	}
 
 	s.AddSubjectData(consent.SubjectData)
+	s.ConsentedSubjectKeys = consentedSubjectKeys(s, consent)
+	s.DeniedSubjectKeys = deniedSubjectKeys(s, consent)
 
 	var tokReqs []*api.ExpiringTokenRequest
 
@@ -226,16 +497,22 @@ func (h *AuthHandler) HandleContinueRequest(
 		return nil, err
 	}
 
+	resp.Denied = deniedAccess(consent)
+
 	// clear request metadata, since these are now granted
 	s.AllowedRequest = nil
 	s.NeedsConsent = nil
+	s.InteractHandler = ""
+	s.InteractFlowID = ""
+	s.InteractRef = ""
+	s.NextPoll = time.Time{}
 
 	err = h.sessionStore.Save(s)
 	if err != nil {
 		return nil, err
 	}
 
-	err = h.loginConsent.DeleteInteraction(req.InteractRef)
+	err = loginConsent.DeleteInteraction(interactRef)
 	if err != nil {
 		return nil, err
 	}
@@ -243,6 +520,212 @@ func (h *AuthHandler) HandleContinueRequest(
 	return resp, nil
 }
 
+// pendingResponse tells a polling client to continue its grant request later, since the user hasn't completed the
+// interaction yet. It fails if the interaction expired, so that the client stops polling.
+func (h *AuthHandler) pendingResponse(
+	s *session.Session,
+	loginConsent api.InteractionHandler,
+	baseURL string,
+) (*gnap.AuthResponse, error) {
+	_, err := loginConsent.QueryDetails(s.InteractFlowID)
+	if err != nil {
+		return nil, fmt.Errorf("querying pending interaction: %w", err)
+	}
+
+	wait := h.wait(s)
+
+	err = h.sessionStore.Save(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return &gnap.AuthResponse{
+		Continue: &gnap.ResponseContinue{
+			URI:         baseURL + h.continuePath,
+			AccessToken: s.ContinueToken.AccessToken,
+			Wait:        wait,
+		},
+		InstanceID: s.ClientID,
+	}, nil
+}
+
+// wait sets the earliest time that the session's client may poll again, returning the seconds it has to wait.
+func (h *AuthHandler) wait(s *session.Session) int {
+	s.NextPoll = h.now().Add(h.pollInterval)
+
+	return int((h.pollInterval + time.Second - 1) / time.Second)
+}
+
+// HandleInteractionCompleted records that the user completed the interaction with the given flow ID, so that a
+// client that didn't request an interaction finish method gets the result when it polls the continue endpoint.
+func (h *AuthHandler) HandleInteractionCompleted(flowID, interactRef string) error {
+	s, err := h.sessionStore.GetByInteractFlowID(flowID)
+	if err != nil {
+		return fmt.Errorf("getting session for interaction: %w", err)
+	}
+
+	s.InteractRef = interactRef
+
+	return h.sessionStore.Save(s)
+}
+
+// deniedSubjectKeys returns the subject keys that the user denied to the session's client, after the given consent:
+// the keys denied in the consent, and the keys denied before that the consent didn't ask for again.
+func deniedSubjectKeys(s *session.Session, consent *api.ConsentResult) []string {
+	asked := map[string]bool{}
+
+	if s.NeedsConsent != nil {
+		for _, k := range s.NeedsConsent.SubjectKeys {
+			asked[k] = true
+		}
+	}
+
+	denied := append([]string{}, consent.DeniedSubjectKeys...)
+
+	for _, k := range s.DeniedSubjectKeys {
+		if !asked[k] {
+			denied = append(denied, k)
+		}
+	}
+
+	if len(denied) == 0 {
+		return nil
+	}
+
+	return denied
+}
+
+// consentedSubjectKeys returns the subject keys that the user approved releasing to the session's client, after the
+// given consent: the keys that the consent asked for and the user didn't deny, and the keys approved before that the
+// user didn't deny in the consent.
+func consentedSubjectKeys(s *session.Session, consent *api.ConsentResult) []string {
+	var consented []string
+
+	for _, k := range s.ConsentedSubjectKeys {
+		if !contains(consent.DeniedSubjectKeys, k) {
+			consented = append(consented, k)
+		}
+	}
+
+	if s.NeedsConsent != nil {
+		for _, k := range s.NeedsConsent.SubjectKeys {
+			if !contains(consent.DeniedSubjectKeys, k) && !contains(consented, k) {
+				consented = append(consented, k)
+			}
+		}
+	}
+
+	return consented
+}
+
+// deniedAccess returns the requested access that the user didn't approve in the given consent, or nil if the user
+// approved all of it.
+func deniedAccess(consent *api.ConsentResult) *gnap.DeniedAccess {
+	if len(consent.DeniedTokens) == 0 && len(consent.DeniedSubjectKeys) == 0 {
+		return nil
+	}
+
+	denied := &gnap.DeniedAccess{SubjectKeys: consent.DeniedSubjectKeys}
+
+	for _, tok := range consent.DeniedTokens {
+		denied.AccessToken = append(denied.AccessToken, tok.TokenRequest)
+	}
+
+	return denied
+}
+
+// referencedClientSession gets the session of a client that sends its instance identifier by reference. The session
+// of a registered client is created on its first request, and is bound to the client key that signed the request.
+func (h *AuthHandler) referencedClientSession(clientID string, reqVerifier api.Verifier) (*session.Session, error) {
+	if !h.clients.IsRegistered(clientID) {
+		s, err := h.sessionStore.GetByID(clientID)
+		if err != nil {
+			return nil, fmt.Errorf("getting client session by client ID: %w", err)
+		}
+
+		return s, nil
+	}
+
+	s, err := h.sessionStore.GetOrCreateByID(clientID)
+	if err != nil {
+		return nil, fmt.Errorf("getting registered client session: %w", err)
+	}
+
+	s.ClientKey, err = h.registeredClientKey(clientID, reqVerifier)
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// setClientDisplay saves the display information of the session's client, to show to the user. A registered
+// client's display information is verified by the AS, and overrides what the client sends about itself.
+func (h *AuthHandler) setClientDisplay(client *gnap.RequestClient, s *session.Session) {
+	if display := h.clients.Display(s.ClientID); display != nil {
+		s.ClientDisplay = display
+		s.DisplayVerified = true
+
+		return
+	}
+
+	if client.Display != nil {
+		s.ClientDisplay = client.Display
+		s.DisplayVerified = false
+	}
+}
+
+// registeredClientKey dereferences the key of a registered client, using the key id of the key that the client
+// request claims to be signed with.
+func (h *AuthHandler) registeredClientKey(clientID string, reqVerifier api.Verifier) (*gnap.ClientKey, error) {
+	var keyID string
+
+	if keyIdentifier, ok := reqVerifier.(api.KeyIdentifier); ok {
+		var err error
+
+		keyID, err = keyIdentifier.KeyID(h.clients.Proof(clientID))
+		if err != nil && !h.disableHTTPSig {
+			return nil, fmt.Errorf("client request verification failure: reading key id: %w", err)
+		}
+	}
+
+	key, err := h.clients.ClientKey(clientID, keyID)
+	if err != nil {
+		return nil, fmt.Errorf("dereferencing client key: %w", err)
+	}
+
+	return key, nil
+}
+
+// validateCertificate validates the certificate chain bound to the given key against the configured trust anchors.
+func (h *AuthHandler) validateCertificate(key *gnap.ClientKey) error {
+	if h.clientCAs == nil || key == nil || (key.Cert == "" && len(key.X5C) == 0) {
+		return nil
+	}
+
+	chain, err := key.CertificateChain()
+	if err != nil {
+		return fmt.Errorf("invalid client key: %w", err)
+	}
+
+	intermediates := x509.NewCertPool()
+
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err = chain[0].Verify(x509.VerifyOptions{
+		Roots:         h.clientCAs,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		return fmt.Errorf("validating client certificate: %w", err)
+	}
+
+	return nil
+}
+
 func (h *AuthHandler) tokensGranted(
 	tokReqs []*api.ExpiringTokenRequest,
 	s *session.Session,
@@ -264,13 +747,13 @@ func (h *AuthHandler) tokensGranted(
 		return nil, nil, err
 	}
 
-	if sub, ok := subjectData["sub"]; ok {
-		resp.Subject.SubIDs = []gnap.SubjectID{
-			{
-				Format: "opaque",
-				ID:     sub,
-			},
-		}
+	subject, err := h.subject(s, subjectData)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(subject.SubIDs) > 0 || len(subject.Assertions) > 0 {
+		resp.Subject = subject
 	}
 
 	return resp, s, nil
@@ -328,6 +811,10 @@ func (h *AuthHandler) getSubjectData(
 		return nil, fmt.Errorf("error fetching subject-data keys: %w", err)
 	}
 
+	for _, k := range clientSession.DeniedSubjectKeys {
+		delete(subjectKeys, k)
+	}
+
 	subjectData := map[string]string{}
 
 	for k := range subjectKeys {
@@ -360,6 +847,11 @@ func (h *AuthHandler) HandleIntrospection( // nolint:gocyclo
 			return nil, fmt.Errorf("getting rs session by rs ID: %w", err)
 		}
 	} else {
+		err = h.validateCertificate(req.ResourceServer.Key)
+		if err != nil {
+			return nil, err
+		}
+
 		// TODO: if we create a new session for an unfamiliar resource server, we're implicitly using a TOFU policy.
 		serverSession, err = h.sessionStore.GetOrCreateByKey(req.ResourceServer.Key)
 		if err != nil {
@@ -396,6 +888,7 @@ func (h *AuthHandler) HandleIntrospection( // nolint:gocyclo
 		Key:         clientSession.ClientKey,
 		Flags:       clientToken.Flags,
 		SubjectData: subjectData,
+		InstanceID:  clientSession.ClientID,
 	}
 
 	return resp, err
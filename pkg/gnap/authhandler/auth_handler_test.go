@@ -7,10 +7,18 @@ SPDX-License-Identifier: Apache-2.0
 package authhandler
 
 import (
+	"crypto"
+	"crypto/ecdsa"
 	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"math/big"
 	"testing"
 	"time"
 
@@ -21,6 +29,7 @@ import (
 
 	"github.com/trustbloc/auth/pkg/gnap/accesspolicy"
 	"github.com/trustbloc/auth/pkg/gnap/api"
+	"github.com/trustbloc/auth/pkg/gnap/clientregistry"
 	"github.com/trustbloc/auth/pkg/gnap/session"
 	"github.com/trustbloc/auth/pkg/internal/common/mockinteract"
 	"github.com/trustbloc/auth/pkg/internal/common/mockstorage"
@@ -35,34 +44,45 @@ func TestNew(t *testing.T) {
 		require.NotNil(t, h)
 	})
 
-	t.Run("fail to initialize access policy", func(t *testing.T) {
+	t.Run("fail to initialize session manager", func(t *testing.T) {
 		conf := config(t)
 
-		conf.AccessPolicyConfig = &accesspolicy.Config{
-			AccessTypes: []accesspolicy.TokenAccessConfig{{
-				Access: gnap.TokenAccess{
-					Type: "foo",
-					Raw:  []byte("foo bar baz"),
-				},
-			}},
+		expectErr := errors.New("expected error")
+
+		conf.StoreProvider = &mockstorage.Provider{ErrOpenStoreHandle: expectErr}
+
+		h, err := New(conf)
+		require.Error(t, err)
+		require.ErrorIs(t, err, expectErr)
+		require.Nil(t, h)
+	})
+	t.Run("fail to initialize client registry", func(t *testing.T) {
+		conf := config(t)
+
+		conf.ClientRegistryConfig = &clientregistry.Config{
+			Clients: []clientregistry.ClientConfig{{ID: "foo"}},
 		}
 
 		h, err := New(conf)
 		require.Error(t, err)
+		require.Contains(t, err.Error(), "initializing client registry")
 		require.Nil(t, h)
 	})
 
-	t.Run("fail to initialize session manager", func(t *testing.T) {
+	t.Run("invalid interaction handlers", func(t *testing.T) {
 		conf := config(t)
+		conf.InteractionHandlers = append(conf.InteractionHandlers, &InteractionHandlerConfig{Name: "app"})
 
-		expectErr := errors.New("expected error")
+		_, err := New(conf)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "need a name and a handler")
 
-		conf.StoreProvider = &mockstorage.Provider{ErrOpenStoreHandle: expectErr}
+		conf = config(t)
+		conf.InteractionHandlers = append(conf.InteractionHandlers, conf.InteractionHandlers[0])
 
-		h, err := New(conf)
+		_, err = New(conf)
 		require.Error(t, err)
-		require.ErrorIs(t, err, expectErr)
-		require.Nil(t, h)
+		require.Contains(t, err.Error(), "duplicate interaction handler redirect")
 	})
 }
 
@@ -74,7 +94,7 @@ func TestAuthHandler_HandleAccessRequest(t *testing.T) {
 		req := &gnap.AuthRequest{}
 		v := &mockverifier.MockVerifier{}
 
-		_, err = h.HandleAccessRequest(req, v, "")
+		_, err = h.HandleAccessRequest(req, v, "", "")
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "missing client")
 	})
@@ -84,6 +104,7 @@ func TestAuthHandler_HandleAccessRequest(t *testing.T) {
 		require.NoError(t, err)
 
 		req := &gnap.AuthRequest{
+			Interact: &gnap.RequestInteract{Start: []string{"redirect"}},
 			Client: &gnap.RequestClient{
 				IsReference: true,
 				Ref:         "foo",
@@ -91,16 +112,95 @@ func TestAuthHandler_HandleAccessRequest(t *testing.T) {
 		}
 		v := &mockverifier.MockVerifier{}
 
-		_, err = h.HandleAccessRequest(req, v, "")
+		_, err = h.HandleAccessRequest(req, v, "", "")
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "getting client session by client ID")
 	})
 
+	t.Run("registered client", func(t *testing.T) {
+		conf := config(t)
+		conf.ClientRegistryConfig = registeredClients(t, "client1", "key1", "key2")
+
+		h, err := New(conf)
+		require.NoError(t, err)
+
+		h.interactions[0].Handler = &mockinteract.InteractHandler{
+			PrepareVal: &gnap.ResponseInteract{Redirect: "foo.com"},
+		}
+
+		req := &gnap.AuthRequest{
+			Interact: &gnap.RequestInteract{Start: []string{"redirect"}},
+			Client: &gnap.RequestClient{
+				IsReference: true,
+				Ref:         "client1",
+			},
+		}
+
+		resp, err := h.HandleAccessRequest(req, &mockverifier.MockVerifier{KeyIDVal: "key2"}, "", "")
+		require.NoError(t, err)
+		require.Equal(t, "client1", resp.InstanceID)
+
+		s, err := h.sessionStore.GetByID("client1")
+		require.NoError(t, err)
+		require.Equal(t, "key2", s.ClientKey.JWK.KeyID)
+
+		// the client's session is kept when it signs with another key
+		resp, err = h.HandleAccessRequest(req, &mockverifier.MockVerifier{KeyIDVal: "key1"}, "", "")
+		require.NoError(t, err)
+		require.Equal(t, "client1", resp.InstanceID)
+
+		s, err = h.sessionStore.GetByID("client1")
+		require.NoError(t, err)
+		require.Equal(t, "key1", s.ClientKey.JWK.KeyID)
+	})
+
+	t.Run("registered client key not found", func(t *testing.T) {
+		conf := config(t)
+		conf.ClientRegistryConfig = registeredClients(t, "client1", "key1")
+
+		h, err := New(conf)
+		require.NoError(t, err)
+
+		req := &gnap.AuthRequest{
+			Interact: &gnap.RequestInteract{Start: []string{"redirect"}},
+			Client: &gnap.RequestClient{
+				IsReference: true,
+				Ref:         "client1",
+			},
+		}
+
+		_, err = h.HandleAccessRequest(req, &mockverifier.MockVerifier{KeyIDVal: "key2"}, "", "")
+		require.ErrorIs(t, err, clientregistry.ErrKeyNotFound)
+	})
+
+	t.Run("registered client request without key id", func(t *testing.T) {
+		conf := config(t)
+		conf.ClientRegistryConfig = registeredClients(t, "client1", "key1")
+
+		h, err := New(conf)
+		require.NoError(t, err)
+
+		req := &gnap.AuthRequest{
+			Interact: &gnap.RequestInteract{Start: []string{"redirect"}},
+			Client: &gnap.RequestClient{
+				IsReference: true,
+				Ref:         "client1",
+			},
+		}
+
+		expectErr := errors.New("expected error")
+
+		_, err = h.HandleAccessRequest(req, &mockverifier.MockVerifier{ErrKeyID: expectErr}, "", "")
+		require.ErrorIs(t, err, expectErr)
+		require.Contains(t, err.Error(), "reading key id")
+	})
+
 	t.Run("getting session by client key", func(t *testing.T) {
 		h, err := New(config(t))
 		require.NoError(t, err)
 
 		req := &gnap.AuthRequest{
+			Interact: &gnap.RequestInteract{Start: []string{"redirect"}},
 			Client: &gnap.RequestClient{
 				IsReference: false,
 				Key:         &gnap.ClientKey{},
@@ -108,11 +208,89 @@ func TestAuthHandler_HandleAccessRequest(t *testing.T) {
 		}
 		v := &mockverifier.MockVerifier{}
 
-		_, err = h.HandleAccessRequest(req, v, "")
+		_, err = h.HandleAccessRequest(req, v, "", "")
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "getting client session by key")
 	})
 
+	t.Run("client certificate chain", func(t *testing.T) {
+		caCert, caKey := testCert(t, nil, nil, true)
+		intermediateCert, intermediateKey := testCert(t, caCert, caKey, true)
+		leafCert, _ := testCert(t, intermediateCert, intermediateKey, false)
+		untrustedCert, _ := testCert(t, nil, nil, false)
+
+		clientCAs := x509.NewCertPool()
+		clientCAs.AddCert(caCert)
+
+		encode := func(cert *x509.Certificate) string {
+			return base64.StdEncoding.EncodeToString(cert.Raw)
+		}
+
+		tests := []struct {
+			name      string
+			clientCAs *x509.CertPool
+			key       *gnap.ClientKey
+			err       string
+		}{
+			{
+				name:      "trusted chain",
+				clientCAs: clientCAs,
+				key:       &gnap.ClientKey{Proof: "mtls", X5C: []string{encode(leafCert), encode(intermediateCert)}},
+			},
+			{
+				name:      "missing intermediate",
+				clientCAs: clientCAs,
+				key:       &gnap.ClientKey{Proof: "mtls", Cert: encode(leafCert)},
+				err:       "validating client certificate",
+			},
+			{
+				name:      "untrusted certificate",
+				clientCAs: clientCAs,
+				key:       &gnap.ClientKey{Proof: "mtls", Cert: encode(untrustedCert)},
+				err:       "validating client certificate",
+			},
+			{
+				name:      "malformed certificate",
+				clientCAs: clientCAs,
+				key:       &gnap.ClientKey{Proof: "mtls", Cert: "Zm9v"},
+				err:       "invalid client key",
+			},
+			{
+				name: "certificates aren't validated without trust anchors",
+				key:  &gnap.ClientKey{Proof: "mtls", Cert: encode(untrustedCert)},
+			},
+		}
+
+		for _, tt := range tests {
+			tc := tt
+
+			t.Run(tc.name, func(t *testing.T) {
+				conf := config(t)
+				conf.ClientCAs = tc.clientCAs
+
+				h, err := New(conf)
+				require.NoError(t, err)
+
+				h.interactions[0].Handler = &mockinteract.InteractHandler{
+					PrepareVal: &gnap.ResponseInteract{Redirect: "foo.com"},
+				}
+
+				req := &gnap.AuthRequest{
+					Interact: &gnap.RequestInteract{Start: []string{"redirect"}},
+					Client:   &gnap.RequestClient{Key: tc.key},
+				}
+
+				_, err = h.HandleAccessRequest(req, &mockverifier.MockVerifier{}, "", "")
+				if tc.err == "" {
+					require.NoError(t, err)
+				} else {
+					require.Error(t, err)
+					require.Contains(t, err.Error(), tc.err)
+				}
+			})
+		}
+	})
+
 	t.Run("request verification failure", func(t *testing.T) {
 		h, err := New(config(t))
 		require.NoError(t, err)
@@ -120,6 +298,7 @@ func TestAuthHandler_HandleAccessRequest(t *testing.T) {
 		expectedErr := errors.New("expected error")
 
 		req := &gnap.AuthRequest{
+			Interact: &gnap.RequestInteract{Start: []string{"redirect"}},
 			Client: &gnap.RequestClient{
 				IsReference: false,
 				Key:         clientKey(t),
@@ -129,7 +308,7 @@ func TestAuthHandler_HandleAccessRequest(t *testing.T) {
 			ErrVerify: expectedErr,
 		}
 
-		_, err = h.HandleAccessRequest(req, v, "")
+		_, err = h.HandleAccessRequest(req, v, "", "")
 		require.Error(t, err)
 		require.ErrorIs(t, err, expectedErr)
 		require.Contains(t, err.Error(), "verification failure")
@@ -149,7 +328,7 @@ func TestAuthHandler_HandleAccessRequest(t *testing.T) {
 		h, err := New(conf)
 		require.NoError(t, err)
 
-		h.loginConsent = &mockinteract.InteractHandler{
+		h.interactions[0].Handler = &mockinteract.InteractHandler{
 			PrepareVal: &gnap.ResponseInteract{
 				Redirect: "foo.com",
 				Finish:   "barbazqux",
@@ -157,6 +336,7 @@ func TestAuthHandler_HandleAccessRequest(t *testing.T) {
 		}
 
 		req := &gnap.AuthRequest{
+			Interact: &gnap.RequestInteract{Start: []string{"redirect"}},
 			Client: &gnap.RequestClient{
 				IsReference: false,
 				Key:         clientKey(t),
@@ -164,7 +344,7 @@ func TestAuthHandler_HandleAccessRequest(t *testing.T) {
 		}
 		v := &mockverifier.MockVerifier{}
 
-		resp, err := h.HandleAccessRequest(req, v, "")
+		resp, err := h.HandleAccessRequest(req, v, "", "")
 		require.Error(t, err)
 		require.ErrorIs(t, err, expectErr)
 
@@ -177,11 +357,12 @@ func TestAuthHandler_HandleAccessRequest(t *testing.T) {
 
 		expectErr := errors.New("expected error")
 
-		h.loginConsent = &mockinteract.InteractHandler{
+		h.interactions[0].Handler = &mockinteract.InteractHandler{
 			PrepareErr: expectErr,
 		}
 
 		req := &gnap.AuthRequest{
+			Interact: &gnap.RequestInteract{Start: []string{"redirect"}},
 			Client: &gnap.RequestClient{
 				IsReference: false,
 				Key:         clientKey(t),
@@ -189,7 +370,7 @@ func TestAuthHandler_HandleAccessRequest(t *testing.T) {
 		}
 		v := &mockverifier.MockVerifier{}
 
-		resp, err := h.HandleAccessRequest(req, v, "")
+		resp, err := h.HandleAccessRequest(req, v, "", "")
 		require.ErrorIs(t, err, expectErr)
 		require.Nil(t, resp)
 	})
@@ -201,7 +382,7 @@ func TestAuthHandler_HandleAccessRequest(t *testing.T) {
 		h, err := New(conf)
 		require.NoError(t, err)
 
-		h.loginConsent = &mockinteract.InteractHandler{
+		h.interactions[0].Handler = &mockinteract.InteractHandler{
 			PrepareVal: &gnap.ResponseInteract{
 				Redirect: "foo.com",
 				Finish:   "barbazqux",
@@ -209,6 +390,7 @@ func TestAuthHandler_HandleAccessRequest(t *testing.T) {
 		}
 
 		req := &gnap.AuthRequest{
+			Interact: &gnap.RequestInteract{Start: []string{"redirect"}},
 			Client: &gnap.RequestClient{
 				IsReference: false,
 				Key:         clientKey(t),
@@ -219,83 +401,544 @@ func TestAuthHandler_HandleAccessRequest(t *testing.T) {
 			ErrVerify: errors.New("this is ignored"),
 		}
 
-		_, err = h.HandleAccessRequest(req, v, "")
+		_, err = h.HandleAccessRequest(req, v, "", "")
+		require.NoError(t, err)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		h, err := New(config(t))
+		require.NoError(t, err)
+
+		h.interactions[0].Handler = &mockinteract.InteractHandler{
+			PrepareVal: &gnap.ResponseInteract{
+				Redirect: "foo.com",
+				Finish:   "barbazqux",
+			},
+		}
+
+		req := &gnap.AuthRequest{
+			Interact: &gnap.RequestInteract{Start: []string{"redirect"}},
+			Client: &gnap.RequestClient{
+				IsReference: false,
+				Key:         clientKey(t),
+			},
+		}
+		v := &mockverifier.MockVerifier{}
+
+		resp, err := h.HandleAccessRequest(req, v, "", "")
+		require.NoError(t, err)
+
+		require.Equal(t, "foo.com", resp.Interact.Redirect)
+	})
+
+	t.Run("success - requested data already allowed", func(t *testing.T) {
+		h, err := New(config(t))
+		require.NoError(t, err)
+
+		h.interactions[0].Handler = &mockinteract.InteractHandler{
+			PrepareVal: &gnap.ResponseInteract{
+				Redirect: "foo.com",
+				Finish:   "barbazqux",
+			},
+		}
+
+		tokReq := gnap.TokenRequest{
+			Access: []gnap.TokenAccess{
+				{
+					IsReference: true,
+					Ref:         "other-access",
+				},
+			},
+			Label: "example",
+		}
+
+		userKey := clientKey(t)
+
+		s, err := h.sessionStore.GetOrCreateByKey(userKey)
+		require.NoError(t, err)
+
+		expTime := time.Now().Add(time.Hour)
+
+		tok := CreateToken(&api.ExpiringTokenRequest{TokenRequest: tokReq, Expires: expTime})
+
+		s.Tokens = append(s.Tokens, &api.ExpiringToken{AccessToken: *tok, Expires: expTime})
+
+		require.NoError(t, h.sessionStore.Save(s))
+
+		req := &gnap.AuthRequest{
+			Interact: &gnap.RequestInteract{Start: []string{"redirect"}},
+			Client: &gnap.RequestClient{
+				IsReference: false,
+				Key:         userKey,
+			},
+			AccessToken: []*gnap.TokenRequest{&tokReq},
+		}
+
+		v := &mockverifier.MockVerifier{}
+
+		resp, err := h.HandleAccessRequest(req, v, "", "")
+		require.NoError(t, err)
+
+		require.Equal(t, "example", resp.AccessToken[0].Label)
+	})
+}
+
+func TestAuthHandler_HandleAccessRequest_subject(t *testing.T) {
+	t.Run("unsupported subject format", func(t *testing.T) {
+		h, err := New(config(t))
+		require.NoError(t, err)
+
+		req := &gnap.AuthRequest{
+			Interact: &gnap.RequestInteract{Start: []string{"redirect"}},
+			Client: &gnap.RequestClient{
+				IsReference: false,
+				Key:         clientKey(t),
+			},
+			Subject: &gnap.RequestSubject{SubIDFormats: []string{"phone_number"}},
+		}
+
+		_, err = h.HandleAccessRequest(req, &mockverifier.MockVerifier{}, "", "")
+		require.ErrorIs(t, err, ErrUnsupportedSubjectFormat)
+	})
+
+	t.Run("unknown user needs interaction", func(t *testing.T) {
+		h, err := New(config(t))
+		require.NoError(t, err)
+
+		h.interactions[0].Handler = &mockinteract.InteractHandler{
+			PrepareVal: &gnap.ResponseInteract{Redirect: "foo.com"},
+		}
+
+		req := &gnap.AuthRequest{
+			Interact: &gnap.RequestInteract{Start: []string{"redirect"}},
+			Client: &gnap.RequestClient{
+				IsReference: false,
+				Key:         clientKey(t),
+			},
+			Subject: &gnap.RequestSubject{SubIDFormats: []string{"opaque"}},
+		}
+
+		resp, err := h.HandleAccessRequest(req, &mockverifier.MockVerifier{}, "", "")
+		require.NoError(t, err)
+		require.Equal(t, "foo.com", resp.Interact.Redirect)
+		require.Nil(t, resp.Subject)
+	})
+
+	t.Run("known user needs consent for unreleased data", func(t *testing.T) {
+		h, err := New(config(t))
+		require.NoError(t, err)
+
+		interact := &mockinteract.InteractHandler{PrepareVal: &gnap.ResponseInteract{Redirect: "foo.com"}}
+		h.interactions[0].Handler = interact
+
+		key := clientKey(t)
+
+		s, err := h.sessionStore.GetOrCreateByKey(key)
+		require.NoError(t, err)
+
+		s.AddSubjectData(map[string]string{"sub": "user-123", "email": "user@example.com"})
+		s.ConsentedSubjectKeys = []string{"sub"}
+
+		require.NoError(t, h.sessionStore.Save(s))
+
+		req := &gnap.AuthRequest{
+			Interact: &gnap.RequestInteract{Start: []string{"redirect"}},
+			Client: &gnap.RequestClient{
+				IsReference: false,
+				Key:         key,
+			},
+			Subject: &gnap.RequestSubject{SubIDFormats: []string{"opaque", "email"}},
+		}
+
+		resp, err := h.HandleAccessRequest(req, &mockverifier.MockVerifier{}, "", "")
+		require.NoError(t, err)
+		require.Equal(t, "foo.com", resp.Interact.Redirect)
+		require.Nil(t, resp.Subject)
+		require.Equal(t, []string{"email"}, interact.PrepareArgs.SubjectKeys)
+	})
+
+	t.Run("known user", func(t *testing.T) {
+		h, err := New(config(t))
+		require.NoError(t, err)
+
+		key := clientKey(t)
+
+		s, err := h.sessionStore.GetOrCreateByKey(key)
+		require.NoError(t, err)
+
+		s.AddSubjectData(map[string]string{
+			"sub":   "user-123",
+			"iss":   "https://idp.example.com",
+			"email": "user@example.com",
+		})
+		s.ConsentedSubjectKeys = []string{"sub", "iss"}
+
+		require.NoError(t, h.sessionStore.Save(s))
+
+		req := &gnap.AuthRequest{
+			Interact: &gnap.RequestInteract{Start: []string{"redirect"}},
+			Client: &gnap.RequestClient{
+				IsReference: false,
+				Key:         key,
+			},
+			Subject: &gnap.RequestSubject{SubIDFormats: []string{"iss_sub"}},
+		}
+
+		resp, err := h.HandleAccessRequest(req, &mockverifier.MockVerifier{}, "", "")
+		require.NoError(t, err)
+		require.Nil(t, resp.Interact)
+		require.Empty(t, resp.AccessToken)
+		require.Equal(t, []gnap.SubjectID{
+			{Format: "iss_sub", Iss: "https://idp.example.com", Sub: "user-123"},
+		}, resp.Subject.SubIDs)
+	})
+}
+
+func TestAuthHandler_HandleAccessRequest_display(t *testing.T) {
+	t.Run("self-asserted display", func(t *testing.T) {
+		h, err := New(config(t))
+		require.NoError(t, err)
+
+		interact := &mockinteract.InteractHandler{PrepareVal: &gnap.ResponseInteract{Redirect: "foo.com"}}
+		h.interactions[0].Handler = interact
+
+		display := &gnap.ClientDisplay{Name: "Wallet", URI: "https://wallet.example.com"}
+
+		req := &gnap.AuthRequest{
+			Interact: &gnap.RequestInteract{Start: []string{"redirect"}},
+			Client:   &gnap.RequestClient{Key: clientKey(t), Display: display},
+		}
+
+		resp, err := h.HandleAccessRequest(req, &mockverifier.MockVerifier{}, "", "")
+		require.NoError(t, err)
+		require.Equal(t, display, interact.PrepareArgs.Client)
+		require.False(t, interact.PrepareArgs.ClientVerified)
+
+		// the display is kept for later requests of the client, which reference its instance
+		req.Client = &gnap.RequestClient{IsReference: true, Ref: resp.InstanceID}
+
+		_, err = h.HandleAccessRequest(req, &mockverifier.MockVerifier{}, "", "")
+		require.NoError(t, err)
+		require.Equal(t, display, interact.PrepareArgs.Client)
+	})
+
+	t.Run("registered client display is verified", func(t *testing.T) {
+		conf := config(t)
+		conf.ClientRegistryConfig = registeredClients(t, "client1", "key1")
+		conf.ClientRegistryConfig.Clients[0].Display = &gnap.ClientDisplay{Name: "Registered Wallet"}
+
+		h, err := New(conf)
+		require.NoError(t, err)
+
+		interact := &mockinteract.InteractHandler{PrepareVal: &gnap.ResponseInteract{Redirect: "foo.com"}}
+		h.interactions[0].Handler = interact
+
+		req := &gnap.AuthRequest{
+			Interact: &gnap.RequestInteract{Start: []string{"redirect"}},
+			Client:   &gnap.RequestClient{IsReference: true, Ref: "client1"},
+		}
+
+		_, err = h.HandleAccessRequest(req, &mockverifier.MockVerifier{KeyIDVal: "key1"}, "", "")
+		require.NoError(t, err)
+		require.Equal(t, &gnap.ClientDisplay{Name: "Registered Wallet"}, interact.PrepareArgs.Client)
+		require.True(t, interact.PrepareArgs.ClientVerified)
+		require.Equal(t, "client1", interact.PrepareArgs.ClientID)
+
+		s, err := h.sessionStore.GetByID("client1")
+		require.NoError(t, err)
+		require.True(t, s.DisplayVerified)
+	})
+}
+
+func TestAuthHandler_HandleAccessRequest_user(t *testing.T) {
+	issuer := idTokenIssuer(t)
+
+	setup := func(t *testing.T) (*AuthHandler, *gnap.ClientKey, *session.Session, *mockinteract.InteractHandler) {
+		t.Helper()
+
+		conf := config(t)
+		conf.IDTokenIssuer = issuer
+
+		h, err := New(conf)
+		require.NoError(t, err)
+
+		interact := &mockinteract.InteractHandler{PrepareVal: &gnap.ResponseInteract{Redirect: "foo.com"}}
+		h.interactions[0].Handler = interact
+
+		key := clientKey(t)
+
+		s, err := h.sessionStore.GetOrCreateByKey(key)
+		require.NoError(t, err)
+
+		return h, key, s, interact
+	}
+
+	t.Run("valid assertion grants without interaction", func(t *testing.T) {
+		h, key, s, _ := setup(t)
+
+		// the user approved releasing the requested data to the client before.
+		s.ConsentedSubjectKeys = []string{"sub", "email"}
+		require.NoError(t, h.sessionStore.Save(s))
+
+		idToken, err := issuer.Issue("user-123", s.ClientID, map[string]string{"email": "user@example.com"})
+		require.NoError(t, err)
+
+		req := &gnap.AuthRequest{
+			Interact: &gnap.RequestInteract{Start: []string{"redirect"}},
+			Client:   &gnap.RequestClient{Key: key},
+			Subject:  &gnap.RequestSubject{SubIDFormats: []string{"opaque", "email"}},
+			User: &gnap.RequestUser{
+				Assertions: []gnap.SubjectAssertion{{Format: "id_token", Value: idToken}},
+			},
+		}
+
+		resp, err := h.HandleAccessRequest(req, &mockverifier.MockVerifier{}, "", "")
+		require.NoError(t, err)
+		require.Nil(t, resp.Interact)
+		require.Equal(t, []gnap.SubjectID{
+			{Format: "opaque", ID: "user-123"},
+			{Format: "email", Email: "user@example.com"},
+		}, resp.Subject.SubIDs)
+	})
+
+	t.Run("unverified sub_ids aren't kept as subject data", func(t *testing.T) {
+		h, key, s, _ := setup(t)
+
+		s.ConsentedSubjectKeys = []string{"sub", "email"}
+		require.NoError(t, h.sessionStore.Save(s))
+
+		idToken, err := issuer.Issue("user-123", s.ClientID, nil)
+		require.NoError(t, err)
+
+		req := &gnap.AuthRequest{
+			Interact: &gnap.RequestInteract{Start: []string{"redirect"}},
+			Client:   &gnap.RequestClient{Key: key},
+			Subject:  &gnap.RequestSubject{SubIDFormats: []string{"opaque", "email"}},
+			User: &gnap.RequestUser{
+				Assertions: []gnap.SubjectAssertion{{Format: "id_token", Value: idToken}},
+				SubIDs:     []gnap.SubjectID{{Format: "email", Email: "attacker@example.com"}},
+			},
+		}
+
+		resp, err := h.HandleAccessRequest(req, &mockverifier.MockVerifier{}, "", "")
+		require.NoError(t, err)
+		require.Equal(t, []gnap.SubjectID{{Format: "opaque", ID: "user-123"}}, resp.Subject.SubIDs)
+
+		s, err = h.sessionStore.GetByID(s.ClientID)
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{"sub": "user-123"}, s.SubjectData)
+	})
+
+	t.Run("assertion of another user needs interaction", func(t *testing.T) {
+		h, key, s, interact := setup(t)
+
+		s.AddSubjectData(map[string]string{"sub": "user-123"})
+		require.NoError(t, h.sessionStore.Save(s))
+
+		idToken, err := issuer.Issue("user-456", s.ClientID, nil)
+		require.NoError(t, err)
+
+		req := &gnap.AuthRequest{
+			Interact: &gnap.RequestInteract{Start: []string{"redirect"}},
+			Client:   &gnap.RequestClient{Key: key},
+			Subject:  &gnap.RequestSubject{SubIDFormats: []string{"opaque"}},
+			User: &gnap.RequestUser{
+				Assertions: []gnap.SubjectAssertion{{Format: "id_token", Value: idToken}},
+			},
+		}
+
+		resp, err := h.HandleAccessRequest(req, &mockverifier.MockVerifier{}, "", "")
+		require.NoError(t, err)
+		require.Equal(t, "foo.com", resp.Interact.Redirect)
+		require.Nil(t, resp.Subject)
+		require.Equal(t, &api.UserHint{Sub: "user-456"}, interact.PrepareArgs.User)
+	})
+
+	t.Run("sub_ids pre-fill the interaction", func(t *testing.T) {
+		h, key, _, interact := setup(t)
+
+		req := &gnap.AuthRequest{
+			Interact: &gnap.RequestInteract{Start: []string{"redirect"}},
+			Client:   &gnap.RequestClient{Key: key},
+			Subject:  &gnap.RequestSubject{SubIDFormats: []string{"opaque"}},
+			User: &gnap.RequestUser{
+				SubIDs: []gnap.SubjectID{{Format: "email", Email: "user@example.com"}},
+			},
+		}
+
+		resp, err := h.HandleAccessRequest(req, &mockverifier.MockVerifier{}, "", "")
+		require.NoError(t, err)
+		require.Equal(t, "foo.com", resp.Interact.Redirect)
+		require.Equal(t, &api.UserHint{Email: "user@example.com"}, interact.PrepareArgs.User)
+	})
+
+	t.Run("invalid assertion", func(t *testing.T) {
+		h, key, _, _ := setup(t)
+
+		idToken, err := issuer.Issue("user-123", "other-client", nil)
+		require.NoError(t, err)
+
+		req := &gnap.AuthRequest{
+			Interact: &gnap.RequestInteract{Start: []string{"redirect"}},
+			Client:   &gnap.RequestClient{Key: key},
+			User: &gnap.RequestUser{
+				Assertions: []gnap.SubjectAssertion{{Format: "id_token", Value: idToken}},
+			},
+		}
+
+		_, err = h.HandleAccessRequest(req, &mockverifier.MockVerifier{}, "", "")
+		require.ErrorIs(t, err, ErrUnknownUser)
+	})
+}
+
+func TestAuthHandler_interactionHandlers(t *testing.T) {
+	setup := func(t *testing.T) (*AuthHandler, *mockinteract.InteractHandler, *mockinteract.InteractHandler) {
+		t.Helper()
+
+		redirect := &mockinteract.InteractHandler{
+			PrepareVal:    &gnap.ResponseInteract{Redirect: "foo.com"},
+			PrepareFlowID: "redirect-flow",
+		}
+		app := &mockinteract.InteractHandler{
+			PrepareVal:    &gnap.ResponseInteract{App: "wallet://interact"},
+			PrepareFlowID: "app-flow",
+			DetailsVal:    &api.InteractionDetails{},
+			QueryVal:      &api.ConsentResult{},
+		}
+
+		conf := config(t)
+		conf.InteractionHandlers = []*InteractionHandlerConfig{
+			{Name: "app", Handler: app, StartModes: []string{"app"}, FinishMethods: []string{"redirect"}},
+			{Name: "redirect", Handler: redirect, StartModes: []string{"redirect"}, FinishMethods: []string{"push"}},
+		}
+
+		h, err := New(conf)
+		require.NoError(t, err)
+
+		return h, redirect, app
+	}
+
+	request := func(t *testing.T, interact *gnap.RequestInteract) *gnap.AuthRequest {
+		t.Helper()
+
+		return &gnap.AuthRequest{
+			Interact: interact,
+			Client:   &gnap.RequestClient{Key: clientKey(t)},
+		}
+	}
+
+	t.Run("first handler that supports a start mode", func(t *testing.T) {
+		h, _, _ := setup(t)
+
+		resp, err := h.HandleAccessRequest(request(t, &gnap.RequestInteract{Start: []string{"redirect", "app"}}),
+			&mockverifier.MockVerifier{}, "", "")
+		require.NoError(t, err)
+		require.Equal(t, "wallet://interact", resp.Interact.App)
+
+		s, err := h.sessionStore.GetByInteractFlowID("app-flow")
+		require.NoError(t, err)
+		require.Equal(t, "app", s.InteractHandler)
+	})
+
+	t.Run("handler that supports the finish method", func(t *testing.T) {
+		h, _, _ := setup(t)
+
+		resp, err := h.HandleAccessRequest(request(t, &gnap.RequestInteract{
+			Start:  []string{"redirect", "app"},
+			Finish: &gnap.RequestFinish{Method: "push"},
+		}), &mockverifier.MockVerifier{}, "", "")
 		require.NoError(t, err)
+		require.Equal(t, "foo.com", resp.Interact.Redirect)
 	})
 
-	t.Run("success", func(t *testing.T) {
-		h, err := New(config(t))
-		require.NoError(t, err)
+	t.Run("next handler when the client can't use a handler", func(t *testing.T) {
+		h, redirect, app := setup(t)
 
-		h.loginConsent = &mockinteract.InteractHandler{
-			PrepareVal: &gnap.ResponseInteract{
-				Redirect: "foo.com",
-				Finish:   "barbazqux",
-			},
-		}
+		h.interactions[1].FinishMethods = []string{"redirect"}
+		app.PrepareErr = fmt.Errorf("%w: client has no app", api.ErrInvalidInteraction)
 
-		req := &gnap.AuthRequest{
-			Client: &gnap.RequestClient{
-				IsReference: false,
-				Key:         clientKey(t),
-			},
-		}
-		v := &mockverifier.MockVerifier{}
+		resp, err := h.HandleAccessRequest(request(t, &gnap.RequestInteract{Start: []string{"app", "redirect"}}),
+			&mockverifier.MockVerifier{}, "", "")
+		require.NoError(t, err)
+		require.Equal(t, "foo.com", resp.Interact.Redirect)
+		require.NotNil(t, redirect.PrepareArgs)
 
-		resp, err := h.HandleAccessRequest(req, v, "")
+		s, err := h.sessionStore.GetByInteractFlowID("redirect-flow")
 		require.NoError(t, err)
+		require.Equal(t, "redirect", s.InteractHandler)
+	})
 
-		require.Equal(t, "foo.com", resp.Interact.Redirect)
+	t.Run("no other handler when a handler fails", func(t *testing.T) {
+		h, redirect, app := setup(t)
+
+		h.interactions[1].FinishMethods = []string{"redirect"}
+		app.PrepareErr = errors.New("expected error")
+
+		_, err := h.HandleAccessRequest(request(t, &gnap.RequestInteract{Start: []string{"app", "redirect"}}),
+			&mockverifier.MockVerifier{}, "", "")
+		require.ErrorIs(t, err, app.PrepareErr)
+		require.Nil(t, redirect.PrepareArgs)
 	})
 
-	t.Run("success - requested data already allowed", func(t *testing.T) {
-		h, err := New(config(t))
-		require.NoError(t, err)
+	t.Run("every handler rejects the client", func(t *testing.T) {
+		h, redirect, app := setup(t)
 
-		h.loginConsent = &mockinteract.InteractHandler{
-			PrepareVal: &gnap.ResponseInteract{
-				Redirect: "foo.com",
-				Finish:   "barbazqux",
-			},
-		}
+		h.interactions[1].FinishMethods = []string{"redirect"}
+		app.PrepareErr = fmt.Errorf("%w: client has no app", api.ErrInvalidInteraction)
+		redirect.PrepareErr = fmt.Errorf("%w: unsupported finish method", api.ErrInvalidInteraction)
 
-		tokReq := gnap.TokenRequest{
-			Access: []gnap.TokenAccess{
-				{
-					IsReference: true,
-					Ref:         "other-access",
-				},
-			},
-			Label: "example",
-		}
+		_, err := h.HandleAccessRequest(request(t, &gnap.RequestInteract{Start: []string{"app", "redirect"}}),
+			&mockverifier.MockVerifier{}, "", "")
+		require.ErrorIs(t, err, api.ErrInvalidInteraction)
+		require.Contains(t, err.Error(), "creating response interaction parameters")
+	})
 
-		userKey := clientKey(t)
+	t.Run("no handler supports the interaction", func(t *testing.T) {
+		h, _, _ := setup(t)
 
-		s, err := h.sessionStore.GetOrCreateByKey(userKey)
-		require.NoError(t, err)
+		_, err := h.HandleAccessRequest(request(t, &gnap.RequestInteract{
+			Start:  []string{"app"},
+			Finish: &gnap.RequestFinish{Method: "push"},
+		}), &mockverifier.MockVerifier{}, "", "")
+		require.ErrorIs(t, err, api.ErrInvalidInteraction)
+		require.Contains(t, err.Error(), "no interaction handler supports start modes [app] with finish method 'push'")
 
-		expTime := time.Now().Add(time.Hour)
+		_, err = h.HandleAccessRequest(request(t, &gnap.RequestInteract{Start: []string{"user_code"}}),
+			&mockverifier.MockVerifier{}, "", "")
+		require.ErrorIs(t, err, api.ErrInvalidInteraction)
 
-		tok := CreateToken(&api.ExpiringTokenRequest{TokenRequest: tokReq, Expires: expTime})
+		_, err = h.HandleAccessRequest(request(t, nil), &mockverifier.MockVerifier{}, "", "")
+		require.ErrorIs(t, err, api.ErrInvalidInteraction)
+	})
 
-		s.Tokens = append(s.Tokens, &api.ExpiringToken{AccessToken: *tok, Expires: expTime})
+	t.Run("continue with the session's handler", func(t *testing.T) {
+		h, redirect, _ := setup(t)
 
-		require.NoError(t, h.sessionStore.Save(s))
+		redirect.QueryErr = errors.New("unexpected handler")
 
-		req := &gnap.AuthRequest{
-			Client: &gnap.RequestClient{
-				IsReference: false,
-				Key:         userKey,
-			},
-			AccessToken: []*gnap.TokenRequest{&tokReq},
-		}
+		resp, err := h.HandleAccessRequest(request(t, &gnap.RequestInteract{Start: []string{"app"}}),
+			&mockverifier.MockVerifier{}, "", "")
+		require.NoError(t, err)
 
-		v := &mockverifier.MockVerifier{}
+		resp, err = h.HandleContinueRequest(&gnap.ContinueRequest{InteractRef: "ref"}, resp.Continue.AccessToken.Value,
+			&mockverifier.MockVerifier{}, "")
+		require.NoError(t, err)
+		require.Nil(t, resp.Interact)
+	})
+
+	t.Run("session's handler isn't registered", func(t *testing.T) {
+		h, _, _ := setup(t)
 
-		resp, err := h.HandleAccessRequest(req, v, "")
+		resp, err := h.HandleAccessRequest(request(t, &gnap.RequestInteract{Start: []string{"app"}}),
+			&mockverifier.MockVerifier{}, "", "")
 		require.NoError(t, err)
 
-		require.Equal(t, "example", resp.AccessToken[0].Label)
+		h.interactions = h.interactions[1:]
+
+		_, err = h.HandleContinueRequest(&gnap.ContinueRequest{InteractRef: "ref"}, resp.Continue.AccessToken.Value,
+			&mockverifier.MockVerifier{}, "")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "interaction handler app isn't registered")
 	})
 }
 
@@ -304,7 +947,7 @@ func TestAuthHandler_HandleContinueRequest(t *testing.T) {
 		h, err := New(config(t))
 		require.NoError(t, err)
 
-		_, err = h.HandleContinueRequest(nil, "", nil)
+		_, err = h.HandleContinueRequest(nil, "", nil, "")
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "getting session for continue token")
 	})
@@ -326,19 +969,54 @@ func TestAuthHandler_HandleContinueRequest(t *testing.T) {
 
 		_, err = h.HandleContinueRequest(nil, "foo", &mockverifier.MockVerifier{
 			ErrVerify: expectErr,
-		})
+		}, "")
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "client request verification failure")
 		require.ErrorIs(t, err, expectErr)
 	})
 
+	t.Run("registered client rotated key", func(t *testing.T) {
+		conf := config(t)
+		conf.ClientRegistryConfig = registeredClients(t, "client1", "key2")
+
+		h, err := New(conf)
+		require.NoError(t, err)
+
+		h.interactions[0].Handler = &mockinteract.InteractHandler{
+			QueryVal: &api.ConsentResult{},
+		}
+
+		s, err := h.sessionStore.GetOrCreateByID("client1")
+		require.NoError(t, err)
+
+		s.ClientKey = clientKey(t)
+		s.ClientKey.JWK.KeyID = "key1"
+		s.ContinueToken = &api.ExpiringToken{AccessToken: gnap.AccessToken{
+			Value: "foo",
+		}}
+
+		require.NoError(t, h.sessionStore.Save(s))
+
+		_, err = h.HandleContinueRequest(&gnap.ContinueRequest{InteractRef: "interact-ref"}, "foo",
+			&mockverifier.MockVerifier{KeyIDVal: "key1"}, "")
+		require.ErrorIs(t, err, clientregistry.ErrKeyNotFound)
+
+		_, err = h.HandleContinueRequest(&gnap.ContinueRequest{InteractRef: "interact-ref"}, "foo",
+			&mockverifier.MockVerifier{KeyIDVal: "key2"}, "")
+		require.NoError(t, err)
+
+		s, err = h.sessionStore.GetByID("client1")
+		require.NoError(t, err)
+		require.Equal(t, "key2", s.ClientKey.JWK.KeyID)
+	})
+
 	t.Run("failed interaction query", func(t *testing.T) {
 		h, err := New(config(t))
 		require.NoError(t, err)
 
 		expectErr := errors.New("expected error")
 
-		h.loginConsent = &mockinteract.InteractHandler{
+		h.interactions[0].Handler = &mockinteract.InteractHandler{
 			QueryErr: expectErr,
 		}
 
@@ -351,7 +1029,8 @@ func TestAuthHandler_HandleContinueRequest(t *testing.T) {
 
 		require.NoError(t, h.sessionStore.Save(s))
 
-		_, err = h.HandleContinueRequest(&gnap.ContinueRequest{}, "foo", &mockverifier.MockVerifier{})
+		_, err = h.HandleContinueRequest(&gnap.ContinueRequest{InteractRef: "interact-ref"}, "foo",
+			&mockverifier.MockVerifier{}, "")
 		require.Error(t, err)
 		require.ErrorIs(t, err, expectErr)
 	})
@@ -363,7 +1042,7 @@ func TestAuthHandler_HandleContinueRequest(t *testing.T) {
 		h, err := New(conf)
 		require.NoError(t, err)
 
-		h.loginConsent = &mockinteract.InteractHandler{
+		h.interactions[0].Handler = &mockinteract.InteractHandler{
 			QueryVal: &api.ConsentResult{
 				Tokens: nil,
 			},
@@ -382,7 +1061,7 @@ func TestAuthHandler_HandleContinueRequest(t *testing.T) {
 			ErrVerify: errors.New("this is ignored"),
 		}
 
-		_, err = h.HandleContinueRequest(&gnap.ContinueRequest{}, "foo", v)
+		_, err = h.HandleContinueRequest(&gnap.ContinueRequest{InteractRef: "interact-ref"}, "foo", v, "")
 		require.NoError(t, err)
 	})
 
@@ -392,7 +1071,7 @@ func TestAuthHandler_HandleContinueRequest(t *testing.T) {
 
 		subID := "JohnDoe12341234"
 
-		h.loginConsent = &mockinteract.InteractHandler{
+		h.interactions[0].Handler = &mockinteract.InteractHandler{
 			QueryVal: &api.ConsentResult{
 				Tokens: []*api.ExpiringTokenRequest{
 					{
@@ -439,7 +1118,8 @@ func TestAuthHandler_HandleContinueRequest(t *testing.T) {
 
 		require.NoError(t, h.sessionStore.Save(s))
 
-		resp, err := h.HandleContinueRequest(&gnap.ContinueRequest{}, "foo", &mockverifier.MockVerifier{})
+		resp, err := h.HandleContinueRequest(&gnap.ContinueRequest{InteractRef: "interact-ref"}, "foo",
+			&mockverifier.MockVerifier{}, "")
 		require.NoError(t, err)
 		require.NotNil(t, resp)
 		require.Len(t, resp.AccessToken, 2)
@@ -450,6 +1130,288 @@ func TestAuthHandler_HandleContinueRequest(t *testing.T) {
 		require.Len(t, resp.Subject.SubIDs, 1)
 		require.Equal(t, subID, resp.Subject.SubIDs[0].ID)
 	})
+
+	t.Run("success with subject request", func(t *testing.T) {
+		h, err := New(config(t))
+		require.NoError(t, err)
+
+		h.interactions[0].Handler = &mockinteract.InteractHandler{
+			QueryVal: &api.ConsentResult{
+				SubjectData: map[string]string{
+					"sub":   "user-123",
+					"email": "user@example.com",
+				},
+			},
+		}
+
+		s, err := h.sessionStore.GetOrCreateByKey(clientKey(t))
+		require.NoError(t, err)
+
+		s.ContinueToken = &api.ExpiringToken{AccessToken: gnap.AccessToken{
+			Value: "foo",
+		}}
+		s.SubjectRequest = &gnap.RequestSubject{SubIDFormats: []string{"email", "opaque"}}
+		s.NeedsConsent = &api.AccessMetadata{SubjectKeys: []string{"email", "sub"}}
+
+		require.NoError(t, h.sessionStore.Save(s))
+
+		resp, err := h.HandleContinueRequest(&gnap.ContinueRequest{InteractRef: "interact-ref"}, "foo",
+			&mockverifier.MockVerifier{}, "")
+		require.NoError(t, err)
+		require.Equal(t, []gnap.SubjectID{
+			{Format: "email", Email: "user@example.com"},
+			{Format: "opaque", ID: "user-123"},
+		}, resp.Subject.SubIDs)
+
+		s, err = h.sessionStore.GetByID(s.ClientID)
+		require.NoError(t, err)
+		require.Equal(t, []string{"email", "sub"}, s.ConsentedSubjectKeys)
+	})
+
+	t.Run("subject request with denied data", func(t *testing.T) {
+		h, err := New(config(t))
+		require.NoError(t, err)
+
+		h.interactions[0].Handler = &mockinteract.InteractHandler{
+			QueryVal: &api.ConsentResult{
+				SubjectData: map[string]string{
+					"sub":   "user-123",
+					"email": "user@example.com",
+				},
+				DeniedSubjectKeys: []string{"email"},
+			},
+		}
+
+		s, err := h.sessionStore.GetOrCreateByKey(clientKey(t))
+		require.NoError(t, err)
+
+		s.ContinueToken = &api.ExpiringToken{AccessToken: gnap.AccessToken{Value: "foo"}}
+		s.SubjectRequest = &gnap.RequestSubject{SubIDFormats: []string{"email", "opaque"}}
+		s.NeedsConsent = &api.AccessMetadata{SubjectKeys: []string{"email", "sub"}}
+		s.ConsentedSubjectKeys = []string{"email"}
+
+		require.NoError(t, h.sessionStore.Save(s))
+
+		resp, err := h.HandleContinueRequest(&gnap.ContinueRequest{InteractRef: "interact-ref"}, "foo",
+			&mockverifier.MockVerifier{}, "")
+		require.NoError(t, err)
+		require.Equal(t, []gnap.SubjectID{{Format: "opaque", ID: "user-123"}}, resp.Subject.SubIDs)
+
+		s, err = h.sessionStore.GetByID(s.ClientID)
+		require.NoError(t, err)
+		require.Equal(t, []string{"sub"}, s.ConsentedSubjectKeys)
+	})
+
+	t.Run("partial consent", func(t *testing.T) {
+		h, err := New(config(t))
+		require.NoError(t, err)
+
+		denied := gnap.TokenRequest{Label: "bar", Access: []gnap.TokenAccess{*gnap.NewTokenAccessRef("other-access")}}
+
+		h.interactions[0].Handler = &mockinteract.InteractHandler{
+			QueryVal: &api.ConsentResult{
+				Tokens: []*api.ExpiringTokenRequest{{
+					TokenRequest: gnap.TokenRequest{Label: "foo", Access: []gnap.TokenAccess{*gnap.NewTokenAccessRef("client-id")}},
+				}},
+				DeniedTokens:      []*api.ExpiringTokenRequest{{TokenRequest: denied}},
+				DeniedSubjectKeys: []string{"sub"},
+				SubjectData:       map[string]string{"sub": "user-123"},
+			},
+		}
+
+		s, err := h.sessionStore.GetOrCreateByKey(clientKey(t))
+		require.NoError(t, err)
+
+		s.ContinueToken = &api.ExpiringToken{AccessToken: gnap.AccessToken{Value: "foo"}}
+		s.NeedsConsent = &api.AccessMetadata{SubjectKeys: []string{"sub"}}
+
+		require.NoError(t, h.sessionStore.Save(s))
+
+		resp, err := h.HandleContinueRequest(&gnap.ContinueRequest{InteractRef: "interact-ref"}, "foo",
+			&mockverifier.MockVerifier{}, "")
+		require.NoError(t, err)
+		require.Len(t, resp.AccessToken, 1)
+		require.Equal(t, "foo", resp.AccessToken[0].Label)
+		require.Nil(t, resp.Subject)
+		require.Equal(t, &gnap.DeniedAccess{
+			AccessToken: []gnap.TokenRequest{denied},
+			SubjectKeys: []string{"sub"},
+		}, resp.Denied)
+
+		s, err = h.sessionStore.GetByID(s.ClientID)
+		require.NoError(t, err)
+		require.Equal(t, []string{"sub"}, s.DeniedSubjectKeys)
+	})
+
+	t.Run("polling client", func(t *testing.T) {
+		h, err := New(config(t))
+		require.NoError(t, err)
+
+		interact := &mockinteract.InteractHandler{
+			QueryVal: &api.ConsentResult{
+				Tokens: []*api.ExpiringTokenRequest{{
+					TokenRequest: gnap.TokenRequest{Label: "foo", Access: []gnap.TokenAccess{*gnap.NewTokenAccessRef("client-id")}},
+				}},
+				SubjectData: map[string]string{"sub": "user-123"},
+			},
+		}
+
+		h.interactions[0].Handler = interact
+
+		s, err := h.sessionStore.GetOrCreateByKey(clientKey(t))
+		require.NoError(t, err)
+
+		s.ContinueToken = &api.ExpiringToken{AccessToken: gnap.AccessToken{Value: "foo"}}
+		s.InteractFlowID = "flow-id"
+
+		require.NoError(t, h.sessionStore.Save(s))
+
+		resp, err := h.HandleContinueRequest(&gnap.ContinueRequest{}, "foo", &mockverifier.MockVerifier{},
+			"https://as.example.com")
+		require.NoError(t, err)
+		require.Empty(t, resp.AccessToken)
+		require.Equal(t, &gnap.ResponseContinue{
+			URI:         "https://as.example.com/continue",
+			AccessToken: gnap.AccessToken{Value: "foo"},
+			Wait:        5,
+		}, resp.Continue)
+
+		require.Error(t, h.HandleInteractionCompleted("other-flow", "interact-ref"))
+		require.NoError(t, h.HandleInteractionCompleted("flow-id", "interact-ref"))
+
+		// the client has to wait before it polls again
+		_, err = h.HandleContinueRequest(&gnap.ContinueRequest{}, "foo", &mockverifier.MockVerifier{}, "")
+		require.ErrorIs(t, err, ErrTooFast)
+
+		h.now = func() time.Time { return time.Now().Add(DefaultPollInterval) }
+
+		resp, err = h.HandleContinueRequest(&gnap.ContinueRequest{}, "foo", &mockverifier.MockVerifier{}, "")
+		require.NoError(t, err)
+		require.Len(t, resp.AccessToken, 1)
+		require.Equal(t, "foo", resp.AccessToken[0].Label)
+
+		s, err = h.sessionStore.GetByID(s.ClientID)
+		require.NoError(t, err)
+		require.Empty(t, s.InteractFlowID)
+		require.Empty(t, s.InteractRef)
+		require.True(t, s.NextPoll.IsZero())
+	})
+
+	t.Run("polling client wait", func(t *testing.T) {
+		conf := config(t)
+		conf.PollInterval = 1500 * time.Millisecond
+
+		h, err := New(conf)
+		require.NoError(t, err)
+
+		h.interactions[0].Handler = &mockinteract.InteractHandler{
+			PrepareVal:    &gnap.ResponseInteract{Redirect: "foo.com"},
+			PrepareFlowID: "flow-id",
+			DetailsVal:    &api.InteractionDetails{},
+		}
+
+		resp, err := h.HandleAccessRequest(&gnap.AuthRequest{
+			Interact: &gnap.RequestInteract{Start: []string{"redirect"}},
+			Client:   &gnap.RequestClient{Key: clientKey(t)},
+		}, &mockverifier.MockVerifier{}, "", "")
+		require.NoError(t, err)
+		require.Equal(t, 2, resp.Continue.Wait)
+
+		continueToken := resp.Continue.AccessToken.Value
+
+		_, err = h.HandleContinueRequest(&gnap.ContinueRequest{}, continueToken, &mockverifier.MockVerifier{}, "")
+		require.ErrorIs(t, err, ErrTooFast)
+
+		h.now = func() time.Time { return time.Now().Add(2 * time.Second) }
+
+		resp, err = h.HandleContinueRequest(&gnap.ContinueRequest{}, continueToken, &mockverifier.MockVerifier{}, "")
+		require.NoError(t, err)
+		require.Empty(t, resp.AccessToken)
+		require.Equal(t, 2, resp.Continue.Wait)
+
+		_, err = h.HandleContinueRequest(&gnap.ContinueRequest{}, continueToken, &mockverifier.MockVerifier{}, "")
+		require.ErrorIs(t, err, ErrTooFast)
+	})
+
+	t.Run("client that requested a finish method isn't told to wait", func(t *testing.T) {
+		h, err := New(config(t))
+		require.NoError(t, err)
+
+		h.interactions[0].Handler = &mockinteract.InteractHandler{PrepareVal: &gnap.ResponseInteract{Redirect: "foo.com"}}
+
+		resp, err := h.HandleAccessRequest(&gnap.AuthRequest{
+			Interact: &gnap.RequestInteract{
+				Start:  []string{"redirect"},
+				Finish: &gnap.RequestFinish{Method: "redirect"},
+			},
+			Client: &gnap.RequestClient{Key: clientKey(t)},
+		}, &mockverifier.MockVerifier{}, "", "")
+		require.NoError(t, err)
+		require.Zero(t, resp.Continue.Wait)
+	})
+
+	t.Run("no pending grant", func(t *testing.T) {
+		h, err := New(config(t))
+		require.NoError(t, err)
+
+		s, err := h.sessionStore.GetOrCreateByKey(clientKey(t))
+		require.NoError(t, err)
+
+		s.ContinueToken = &api.ExpiringToken{AccessToken: gnap.AccessToken{Value: "foo"}}
+
+		require.NoError(t, h.sessionStore.Save(s))
+
+		_, err = h.HandleContinueRequest(&gnap.ContinueRequest{}, "foo", &mockverifier.MockVerifier{}, "")
+		require.ErrorIs(t, err, ErrInvalidContinuation)
+	})
+
+	t.Run("polling client of expired interaction", func(t *testing.T) {
+		h, err := New(config(t))
+		require.NoError(t, err)
+
+		expectErr := errors.New("interaction expired")
+
+		h.interactions[0].Handler = &mockinteract.InteractHandler{DetailsErr: expectErr}
+
+		s, err := h.sessionStore.GetOrCreateByKey(clientKey(t))
+		require.NoError(t, err)
+
+		s.ContinueToken = &api.ExpiringToken{AccessToken: gnap.AccessToken{Value: "foo"}}
+		s.InteractFlowID = "flow-id"
+
+		require.NoError(t, h.sessionStore.Save(s))
+
+		_, err = h.HandleContinueRequest(&gnap.ContinueRequest{}, "foo", &mockverifier.MockVerifier{}, "")
+		require.ErrorIs(t, err, expectErr)
+	})
+}
+
+func TestDeniedSubjectKeys(t *testing.T) {
+	tests := []struct {
+		name         string
+		denied       []string
+		needsConsent []string
+		consent      []string
+		expect       []string
+	}{
+		{name: "nothing denied"},
+		{name: "denied in consent", needsConsent: []string{"email"}, consent: []string{"email"}, expect: []string{"email"}},
+		{name: "approved after denial", denied: []string{"email"}, needsConsent: []string{"email"}},
+		{name: "denied before", denied: []string{"email"}, needsConsent: []string{"name"}, expect: []string{"email"}},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			s := &session.Session{
+				DeniedSubjectKeys: tc.denied,
+				NeedsConsent:      &api.AccessMetadata{SubjectKeys: tc.needsConsent},
+			}
+
+			require.Equal(t, tc.expect, deniedSubjectKeys(s, &api.ConsentResult{DeniedSubjectKeys: tc.consent}))
+		})
+	}
 }
 
 func TestAuthHandler_HandleIntrospection(t *testing.T) {
@@ -653,6 +1615,7 @@ func TestAuthHandler_HandleIntrospection(t *testing.T) {
 			SubjectData: map[string]string{
 				"sub": clientIDVal,
 			},
+			InstanceID: clientSession.ClientID,
 		}
 		require.Equal(t, expectedResp, resp)
 	})
@@ -691,8 +1654,13 @@ func config(t *testing.T) *Config {
 	return &Config{
 		StoreProvider:      mem.NewProvider(),
 		AccessPolicyConfig: apConfig,
-		ContinuePath:       "example.com",
-		InteractionHandler: &mockinteract.InteractHandler{},
+		ContinuePath:       "/continue",
+		InteractionHandlers: []*InteractionHandlerConfig{{
+			Name:          "redirect",
+			Handler:       &mockinteract.InteractHandler{},
+			StartModes:    []string{"redirect"},
+			FinishMethods: []string{"redirect", "push"},
+		}},
 	}
 }
 
@@ -713,6 +1681,62 @@ func clientKey(t *testing.T) *gnap.ClientKey {
 	return &ck
 }
 
+// testCert creates a certificate signed by the given parent, or a self-signed certificate if parent is nil.
+func testCert(
+	t *testing.T,
+	parent *x509.Certificate,
+	parentKey crypto.Signer,
+	isCA bool,
+) (*x509.Certificate, crypto.Signer) {
+	t.Helper()
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: serial.String()},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	if parent == nil {
+		parent, parentKey = template, priv
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &priv.PublicKey, parentKey)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return cert, priv
+}
+
+func registeredClients(t *testing.T, clientID string, keyIDs ...string) *clientregistry.Config {
+	t.Helper()
+
+	jwks := &clientregistry.JWKS{}
+
+	for _, keyID := range keyIDs {
+		key := clientKey(t).JWK
+		key.KeyID = keyID
+
+		jwks.Keys = append(jwks.Keys, key)
+	}
+
+	return &clientregistry.Config{
+		Clients: []clientregistry.ClientConfig{{ID: clientID, JWKS: jwks}},
+	}
+}
+
 const (
 	accessPolicyConf = `{
 	"access-types": [{
@@ -23,3 +23,13 @@ func CreateToken(req *api.ExpiringTokenRequest) *gnap.AccessToken {
 		Flags:   req.Flags,
 	}
 }
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,260 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package authhandler
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/trustbloc/auth/pkg/gnap/api"
+	"github.com/trustbloc/auth/pkg/gnap/session"
+	"github.com/trustbloc/auth/spi/gnap"
+)
+
+// Subject identifier formats that the AS can return, see https://www.rfc-editor.org/rfc/rfc9493.html#section-3.
+const (
+	SubIDFormatOpaque = "opaque"
+	SubIDFormatIssSub = "iss_sub"
+	SubIDFormatEmail  = "email"
+	SubIDFormatDID    = "did"
+)
+
+// Subject assertion formats that the AS can return, if it has an id_token issuer.
+const (
+	// AssertionFormatIDToken is the format of subject assertions that are id_tokens signed by the AS.
+	AssertionFormatIDToken = "id_token"
+	// AssertionFormatVC is the format of subject assertions that are JWT-encoded login credentials, W3C Verifiable
+	// Credentials signed by the AS.
+	AssertionFormatVC = "vc"
+)
+
+// ErrUnsupportedSubjectFormat is returned when a client requests subject information in a format that the AS
+// doesn't support.
+var ErrUnsupportedSubjectFormat = errors.New("unsupported subject format")
+
+// validateSubjectRequest checks that the AS supports all subject information formats in the given request.
+func (h *AuthHandler) validateSubjectRequest(req *gnap.RequestSubject) error {
+	if req == nil {
+		return nil
+	}
+
+	for _, format := range req.SubIDFormats {
+		switch format {
+		case SubIDFormatOpaque, SubIDFormatIssSub, SubIDFormatEmail, SubIDFormatDID:
+		default:
+			return fmt.Errorf("%w: sub_id_format %s", ErrUnsupportedSubjectFormat, format)
+		}
+	}
+
+	for _, format := range req.AssertionFormats {
+		if (format != AssertionFormatIDToken && format != AssertionFormatVC) || h.idTokens == nil {
+			return fmt.Errorf("%w: assertion_format %s", ErrUnsupportedSubjectFormat, format)
+		}
+	}
+
+	return nil
+}
+
+// requestsSubject returns true iff the given subject request asks for the user's subject identifiers or assertions.
+func requestsSubject(req *gnap.RequestSubject) bool {
+	return req != nil && (len(req.SubIDFormats) > 0 || len(req.AssertionFormats) > 0)
+}
+
+/*
+subject returns the subject information to return to the client of the given session, which may be empty.
+
+If the client requested subject information, identifiers are returned in each of the requested formats that the
+user's released subject data can be expressed in, along with the requested assertions. Subject data is released if
+the access policy allows the granted tokens to see it, or if the user approved releasing it to the client in an
+interaction. Otherwise, an opaque identifier is returned if the access policy allows the granted tokens to see the
+user's sub.
+
+allowedData is the user's subject data that the access policy allows the granted tokens to see. Only released data is
+added as claims to assertions.
+*/
+func (h *AuthHandler) subject(s *session.Session, allowedData map[string]string) (*gnap.Subject, error) {
+	data := allowedData
+
+	if requestsSubject(s.SubjectRequest) {
+		data = releasedSubjectData(s, allowedData)
+	}
+
+	out := &gnap.Subject{
+		SubIDs: subjectIDs(s.SubjectRequest, data),
+	}
+
+	sub := data[api.SubjectDataSub]
+
+	// validateSubjectRequest only accepts assertion formats if the AS issues them.
+	if s.SubjectRequest != nil && sub != "" && h.idTokens != nil {
+		for _, format := range s.SubjectRequest.AssertionFormats {
+			value, err := h.assertion(format, sub, s, data)
+			if err != nil {
+				return nil, fmt.Errorf("issuing %s assertion: %w", format, err)
+			}
+
+			out.Assertions = append(out.Assertions, gnap.SubjectAssertion{Format: format, Value: value})
+		}
+	}
+
+	return out, nil
+}
+
+// releasedSubjectData returns allowedData, along with the user's subject data that the user approved releasing to
+// the client of the given session.
+func releasedSubjectData(s *session.Session, allowedData map[string]string) map[string]string {
+	data := map[string]string{}
+
+	for k, v := range allowedData {
+		data[k] = v
+	}
+
+	for _, k := range s.ConsentedSubjectKeys {
+		if v, ok := s.SubjectData[k]; ok && !contains(s.DeniedSubjectKeys, k) {
+			data[k] = v
+		}
+	}
+
+	return data
+}
+
+// subjectKeys returns the keys of the user's subject data that the given subject request asks to release: the data
+// that each requested identifier format needs, and the user's sub if assertions are requested.
+func subjectKeys(req *gnap.RequestSubject) []string {
+	var keys []string
+
+	add := func(names ...string) {
+		for _, k := range names {
+			if !contains(keys, k) {
+				keys = append(keys, k)
+			}
+		}
+	}
+
+	if req == nil {
+		return nil
+	}
+
+	for _, format := range req.SubIDFormats {
+		switch format {
+		case SubIDFormatOpaque:
+			add(api.SubjectDataSub)
+		case SubIDFormatIssSub:
+			add(api.SubjectDataSub, api.SubjectDataIssuer)
+		case SubIDFormatEmail:
+			add(api.SubjectDataEmail)
+		case SubIDFormatDID:
+			add(api.SubjectDataDID)
+		}
+	}
+
+	if len(req.AssertionFormats) > 0 {
+		add(api.SubjectDataSub)
+	}
+
+	return keys
+}
+
+// unconsentedSubjectKeys returns the keys of the subject data that the given subject request asks to release, which
+// neither the access policy allows nor the user approved releasing to the client of the given session.
+func unconsentedSubjectKeys(req *gnap.RequestSubject, allowedKeys []string, s *session.Session) []string {
+	var keys []string
+
+	for _, k := range subjectKeys(req) {
+		if contains(allowedKeys, k) || (contains(s.ConsentedSubjectKeys, k) && !contains(s.DeniedSubjectKeys, k)) {
+			continue
+		}
+
+		keys = append(keys, k)
+	}
+
+	return keys
+}
+
+// assertion mints a subject assertion of the given format, about the user with the given sub, for the client of
+// the given session. data is the user's subject data released to the client.
+func (h *AuthHandler) assertion(format, sub string, s *session.Session, data map[string]string) (string, error) {
+	switch format {
+	case AssertionFormatIDToken:
+		return h.idTokens.Issue(sub, s.ClientID, assertionClaims(data))
+	case AssertionFormatVC:
+		return h.idTokens.IssueCredential(sub, s.ClientID, credentialClaims(data))
+	}
+
+	return "", fmt.Errorf("%w: assertion_format %s", ErrUnsupportedSubjectFormat, format)
+}
+
+// subjectIDs returns the user's subject identifiers in the formats that the client requested, or an opaque
+// identifier if the client didn't request identifiers. data is the user's subject data released to the client.
+func subjectIDs(req *gnap.RequestSubject, data map[string]string) []gnap.SubjectID {
+	formats := []string{SubIDFormatOpaque}
+
+	if requestsSubject(req) {
+		formats = req.SubIDFormats
+	}
+
+	var subIDs []gnap.SubjectID
+
+	for _, format := range formats {
+		if subID, ok := subjectID(format, data); ok {
+			subIDs = append(subIDs, subID)
+		}
+	}
+
+	return subIDs
+}
+
+// assertionClaims returns the claims about the user to add to an assertion. The user's sub is the assertion's
+// subject, and the identity provider that authenticated the user isn't added as a claim, since the AS is the
+// assertion's issuer.
+func assertionClaims(data map[string]string) map[string]string {
+	claims := map[string]string{}
+
+	for k, v := range data {
+		switch k {
+		case api.SubjectDataSub, api.SubjectDataIssuer, api.SubjectDataProvider:
+		default:
+			claims[k] = v
+		}
+	}
+
+	return claims
+}
+
+// credentialClaims returns the claims about the user to add to a login credential: the assertion claims, and the
+// identity provider that authenticated the user if it's released, since the credential is presented to other parties.
+func credentialClaims(data map[string]string) map[string]string {
+	claims := assertionClaims(data)
+
+	if provider, ok := data[api.SubjectDataProvider]; ok {
+		claims[api.SubjectDataProvider] = provider
+	}
+
+	return claims
+}
+
+// subjectID expresses the user's subject data as a subject identifier of the given format, if it has the data the
+// format needs.
+func subjectID(format string, data map[string]string) (gnap.SubjectID, bool) {
+	sub := data[api.SubjectDataSub]
+	iss := data[api.SubjectDataIssuer]
+	email := data[api.SubjectDataEmail]
+	did := data[api.SubjectDataDID]
+
+	switch {
+	case format == SubIDFormatOpaque && sub != "":
+		return gnap.SubjectID{Format: format, ID: sub}, true
+	case format == SubIDFormatIssSub && sub != "" && iss != "":
+		return gnap.SubjectID{Format: format, Iss: iss, Sub: sub}, true
+	case format == SubIDFormatEmail && email != "":
+		return gnap.SubjectID{Format: format, Email: email}, true
+	case format == SubIDFormatDID && did != "":
+		return gnap.SubjectID{Format: format, URL: did}, true
+	}
+
+	return gnap.SubjectID{}, false
+}
@@ -0,0 +1,278 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package authhandler
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	"github.com/square/go-jose/v3/jwt"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/auth/pkg/gnap/idtoken"
+	"github.com/trustbloc/auth/pkg/gnap/session"
+	"github.com/trustbloc/auth/spi/gnap"
+)
+
+func TestAuthHandler_validateSubjectRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *gnap.RequestSubject
+		err  string
+	}{
+		{
+			name: "no subject request",
+		},
+		{
+			name: "supported formats",
+			req:  &gnap.RequestSubject{SubIDFormats: []string{"opaque", "iss_sub", "email", "did"}},
+		},
+		{
+			name: "unsupported sub_id format",
+			req:  &gnap.RequestSubject{SubIDFormats: []string{"opaque", "phone_number"}},
+			err:  "sub_id_format phone_number",
+		},
+		{
+			name: "unsupported assertion format",
+			req:  &gnap.RequestSubject{AssertionFormats: []string{"saml2"}},
+			err:  "assertion_format saml2",
+		},
+		{
+			name: "id_token assertion without id_token issuer",
+			req:  &gnap.RequestSubject{AssertionFormats: []string{"id_token"}},
+			err:  "assertion_format id_token",
+		},
+		{
+			name: "vc assertion without id_token issuer",
+			req:  &gnap.RequestSubject{AssertionFormats: []string{"vc"}},
+			err:  "assertion_format vc",
+		},
+	}
+
+	h := &AuthHandler{}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			err := h.validateSubjectRequest(tc.req)
+			if tc.err == "" {
+				require.NoError(t, err)
+
+				return
+			}
+
+			require.ErrorIs(t, err, ErrUnsupportedSubjectFormat)
+			require.Contains(t, err.Error(), tc.err)
+		})
+	}
+}
+
+func TestSubjectIDs(t *testing.T) {
+	userData := map[string]string{
+		"sub":   "user-123",
+		"iss":   "https://idp.example.com",
+		"email": "user@example.com",
+		"did":   "did:example:123",
+	}
+
+	t.Run("opaque identifier allowed by access policy", func(t *testing.T) {
+		require.Equal(t, []gnap.SubjectID{{Format: "opaque", ID: "user-123"}},
+			subjectIDs(nil, map[string]string{"sub": "user-123"}))
+	})
+
+	t.Run("no identifier allowed by access policy", func(t *testing.T) {
+		require.Empty(t, subjectIDs(nil, map[string]string{}))
+	})
+
+	t.Run("requested formats", func(t *testing.T) {
+		req := &gnap.RequestSubject{SubIDFormats: []string{"iss_sub", "email", "did", "opaque"}}
+
+		require.Equal(t, []gnap.SubjectID{
+			{Format: "iss_sub", Iss: "https://idp.example.com", Sub: "user-123"},
+			{Format: "email", Email: "user@example.com"},
+			{Format: "did", URL: "did:example:123"},
+			{Format: "opaque", ID: "user-123"},
+		}, subjectIDs(req, userData))
+	})
+
+	t.Run("requested formats missing released data", func(t *testing.T) {
+		req := &gnap.RequestSubject{SubIDFormats: []string{"iss_sub", "email", "did"}}
+
+		require.Empty(t, subjectIDs(req, map[string]string{"sub": "user-123"}))
+	})
+}
+
+func TestUnconsentedSubjectKeys(t *testing.T) {
+	req := &gnap.RequestSubject{
+		SubIDFormats:     []string{"iss_sub", "email", "opaque", "did"},
+		AssertionFormats: []string{"id_token"},
+	}
+
+	require.Equal(t, []string{"sub", "iss", "email", "did"}, subjectKeys(req))
+	require.Empty(t, subjectKeys(nil))
+
+	t.Run("nothing released", func(t *testing.T) {
+		require.Equal(t, []string{"sub", "iss", "email", "did"}, unconsentedSubjectKeys(req, nil, &session.Session{}))
+	})
+
+	t.Run("allowed by access policy or approved by the user", func(t *testing.T) {
+		s := &session.Session{ConsentedSubjectKeys: []string{"email", "did"}, DeniedSubjectKeys: []string{"did"}}
+
+		require.Equal(t, []string{"iss", "did"}, unconsentedSubjectKeys(req, []string{"sub"}, s))
+	})
+}
+
+func TestReleasedSubjectData(t *testing.T) {
+	s := &session.Session{
+		SubjectData: map[string]string{
+			"sub":   "user-123",
+			"email": "user@example.com",
+			"did":   "did:example:123",
+		},
+		ConsentedSubjectKeys: []string{"email", "did", "iss"},
+		DeniedSubjectKeys:    []string{"did"},
+	}
+
+	require.Equal(t, map[string]string{
+		"sub":   "user-123",
+		"email": "user@example.com",
+	}, releasedSubjectData(s, map[string]string{"sub": "user-123"}))
+}
+
+func TestAuthHandler_subject(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	issuer, err := idtoken.New(&idtoken.Config{Issuer: "https://as.example.com", SigningKey: priv})
+	require.NoError(t, err)
+
+	h := &AuthHandler{idTokens: issuer}
+
+	t.Run("id_token assertion", func(t *testing.T) {
+		s := &session.Session{
+			ClientID: "client-instance",
+			SubjectRequest: &gnap.RequestSubject{
+				SubIDFormats:     []string{"opaque"},
+				AssertionFormats: []string{"id_token"},
+			},
+			SubjectData: map[string]string{
+				"sub":    "user-123",
+				"iss":    "https://idp.example.com",
+				"email":  "user@example.com",
+				"secret": "not allowed",
+			},
+		}
+
+		subject, err := h.subject(s, map[string]string{
+			"sub":   "user-123",
+			"iss":   "https://idp.example.com",
+			"email": "user@example.com",
+		})
+		require.NoError(t, err)
+		require.Equal(t, []gnap.SubjectID{{Format: "opaque", ID: "user-123"}}, subject.SubIDs)
+		require.Len(t, subject.Assertions, 1)
+		require.Equal(t, "id_token", subject.Assertions[0].Format)
+
+		token, err := jwt.ParseSigned(subject.Assertions[0].Value)
+		require.NoError(t, err)
+
+		claims := map[string]interface{}{}
+
+		require.NoError(t, token.Claims(&priv.PublicKey, &claims))
+		require.Equal(t, "https://as.example.com", claims["iss"])
+		require.Equal(t, "user-123", claims["sub"])
+		require.Equal(t, "client-instance", claims["aud"])
+		require.Equal(t, "user@example.com", claims["email"])
+		require.NotContains(t, claims, "secret")
+	})
+
+	t.Run("vc assertion", func(t *testing.T) {
+		s := &session.Session{
+			ClientID:       "client-instance",
+			SubjectRequest: &gnap.RequestSubject{AssertionFormats: []string{"vc"}},
+			SubjectData: map[string]string{
+				"sub":      "user-123",
+				"provider": "google",
+			},
+			ConsentedSubjectKeys: []string{"sub"},
+		}
+
+		subject, err := h.subject(s, map[string]string{"email": "user@example.com"})
+		require.NoError(t, err)
+		require.Len(t, subject.Assertions, 1)
+		require.Equal(t, "vc", subject.Assertions[0].Format)
+
+		token, err := jwt.ParseSigned(subject.Assertions[0].Value)
+		require.NoError(t, err)
+
+		claims := struct {
+			VC map[string]interface{} `json:"vc"`
+		}{}
+
+		require.NoError(t, token.Claims(&priv.PublicKey, &claims))
+		require.Equal(t, map[string]interface{}{
+			"id":    "urn:trustbloc:auth:sub:user-123",
+			"email": "user@example.com",
+		}, claims.VC["credentialSubject"])
+	})
+
+	t.Run("vc assertion with consented provider", func(t *testing.T) {
+		s := &session.Session{
+			ClientID:       "client-instance",
+			SubjectRequest: &gnap.RequestSubject{AssertionFormats: []string{"vc"}},
+			SubjectData: map[string]string{
+				"sub":      "user-123",
+				"provider": "google",
+			},
+			ConsentedSubjectKeys: []string{"sub", "provider"},
+		}
+
+		subject, err := h.subject(s, nil)
+		require.NoError(t, err)
+		require.Len(t, subject.Assertions, 1)
+
+		token, err := jwt.ParseSigned(subject.Assertions[0].Value)
+		require.NoError(t, err)
+
+		claims := struct {
+			VC map[string]interface{} `json:"vc"`
+		}{}
+
+		require.NoError(t, token.Claims(&priv.PublicKey, &claims))
+		require.Equal(t, map[string]interface{}{
+			"id":       "urn:trustbloc:auth:sub:user-123",
+			"provider": "google",
+		}, claims.VC["credentialSubject"])
+	})
+
+	t.Run("no assertion without released sub", func(t *testing.T) {
+		s := &session.Session{
+			ClientID:       "client-instance",
+			SubjectRequest: &gnap.RequestSubject{AssertionFormats: []string{"id_token"}},
+			SubjectData:    map[string]string{"sub": "user-123"},
+		}
+
+		subject, err := h.subject(s, map[string]string{})
+		require.NoError(t, err)
+		require.Empty(t, subject.Assertions)
+	})
+
+	t.Run("no assertion for unknown user", func(t *testing.T) {
+		s := &session.Session{
+			ClientID:       "client-instance",
+			SubjectRequest: &gnap.RequestSubject{AssertionFormats: []string{"id_token"}},
+		}
+
+		subject, err := h.subject(s, map[string]string{})
+		require.NoError(t, err)
+		require.Empty(t, subject.Assertions)
+		require.Empty(t, subject.SubIDs)
+	})
+}
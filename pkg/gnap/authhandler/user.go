@@ -0,0 +1,155 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package authhandler
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/trustbloc/auth/pkg/gnap/api"
+	"github.com/trustbloc/auth/pkg/gnap/idtoken"
+	"github.com/trustbloc/auth/pkg/gnap/session"
+	"github.com/trustbloc/auth/spi/gnap"
+)
+
+// ErrUnknownUser is returned when a client identifies the user with information that the AS can't verify.
+var ErrUnknownUser = errors.New("unknown user")
+
+/*
+userHint reads the user information that the client of the given session sent in its grant request, returning a
+hint that identifies the user to the interaction, or nil if the client didn't identify the user.
+
+Assertions must be id_tokens or login credentials that the AS issued to the client, and must all identify the same
+user. Subject identifiers aren't verified, so they only pre-fill the hint where the assertions don't say.
+
+The returned subject data is read from the assertions only, and is nil unless an assertion is still valid, which
+proves that the user logged in recently.
+*/
+func (h *AuthHandler) userHint(req *gnap.RequestUser, s *session.Session) (*api.UserHint, map[string]string, error) {
+	if req == nil {
+		return nil, nil, nil
+	}
+
+	if req.IsReference {
+		return nil, nil, fmt.Errorf("%w: user references aren't supported", ErrUnknownUser)
+	}
+
+	asserted := api.UserHint{}
+	valid := false
+
+	for _, assertion := range req.Assertions {
+		a, err := h.verifyAssertion(assertion, s.ClientID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if asserted.Sub != "" && asserted.Sub != a.Sub {
+			return nil, nil, fmt.Errorf("%w: assertions identify different users", ErrUnknownUser)
+		}
+
+		asserted.Sub = a.Sub
+		asserted.Email = firstNonEmpty(asserted.Email, a.Email)
+		asserted.Provider = firstNonEmpty(asserted.Provider, a.Provider)
+		valid = valid || !a.Expired
+	}
+
+	hint := asserted
+
+	for _, subID := range req.SubIDs {
+		switch subID.Format {
+		case SubIDFormatOpaque:
+			hint.Sub = firstNonEmpty(hint.Sub, subID.ID)
+		case SubIDFormatIssSub:
+			hint.Sub = firstNonEmpty(hint.Sub, subID.Sub)
+		case SubIDFormatEmail:
+			hint.Email = firstNonEmpty(hint.Email, subID.Email)
+		}
+	}
+
+	if hint == (api.UserHint{}) {
+		return nil, nil, nil
+	}
+
+	// pre-select the identity provider that the user logged in with in the client's earlier interaction.
+	if isSessionUser(&hint, s) {
+		hint.Provider = firstNonEmpty(hint.Provider, s.SubjectData[api.SubjectDataProvider])
+	}
+
+	var data map[string]string
+
+	if valid {
+		data = assertedSubjectData(&asserted)
+	}
+
+	return &hint, data, nil
+}
+
+// verifyAssertion verifies that the given subject assertion was issued by the AS to the client with the given
+// instance identifier.
+func (h *AuthHandler) verifyAssertion(assertion gnap.SubjectAssertion, clientID string) (*idtoken.Assertion, error) {
+	var (
+		a   *idtoken.Assertion
+		err error
+	)
+
+	switch {
+	case h.idTokens == nil:
+		return nil, fmt.Errorf("%w: assertion format %s", ErrUnsupportedSubjectFormat, assertion.Format)
+	case assertion.Format == AssertionFormatIDToken:
+		a, err = h.idTokens.VerifyIDToken(assertion.Value, clientID)
+	case assertion.Format == AssertionFormatVC:
+		a, err = h.idTokens.VerifyCredential(assertion.Value, clientID)
+	default:
+		return nil, fmt.Errorf("%w: assertion format %s", ErrUnsupportedSubjectFormat, assertion.Format)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownUser, err.Error())
+	}
+
+	return a, nil
+}
+
+// isSessionUser returns true iff the given hint doesn't identify a different user than the one who completed the
+// earlier interactions of the given session's client.
+func isSessionUser(hint *api.UserHint, s *session.Session) bool {
+	switch {
+	case hint == nil:
+		return true
+	case hint.Sub != "":
+		return hint.Sub == s.SubjectData[api.SubjectDataSub]
+	case hint.Email != "":
+		return hint.Email == s.SubjectData[api.SubjectDataEmail]
+	}
+
+	return true
+}
+
+// assertedSubjectData returns the subject data of the user that verified assertions identify.
+func assertedSubjectData(asserted *api.UserHint) map[string]string {
+	data := map[string]string{api.SubjectDataSub: asserted.Sub}
+
+	if asserted.Email != "" {
+		data[api.SubjectDataEmail] = asserted.Email
+	}
+
+	if asserted.Provider != "" {
+		data[api.SubjectDataProvider] = asserted.Provider
+	}
+
+	return data
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
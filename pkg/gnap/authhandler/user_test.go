@@ -0,0 +1,154 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package authhandler
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/auth/pkg/gnap/api"
+	"github.com/trustbloc/auth/pkg/gnap/idtoken"
+	"github.com/trustbloc/auth/pkg/gnap/session"
+	"github.com/trustbloc/auth/spi/gnap"
+)
+
+func TestAuthHandler_userHint(t *testing.T) {
+	issuer := idTokenIssuer(t)
+
+	idToken, err := issuer.Issue("user-123", "client-instance", map[string]string{"email": "user@example.com"})
+	require.NoError(t, err)
+
+	vc, err := issuer.IssueCredential("user-123", "client-instance", map[string]string{"provider": "google"})
+	require.NoError(t, err)
+
+	otherUser, err := issuer.Issue("user-456", "client-instance", nil)
+	require.NoError(t, err)
+
+	h := &AuthHandler{idTokens: issuer}
+
+	tests := []struct {
+		name    string
+		req     *gnap.RequestUser
+		session map[string]string
+		hint    *api.UserHint
+		data    map[string]string
+		err     error
+	}{
+		{
+			name: "no user",
+		},
+		{
+			name: "empty user",
+			req:  &gnap.RequestUser{},
+		},
+		{
+			name: "assertions",
+			req: &gnap.RequestUser{
+				Assertions: []gnap.SubjectAssertion{{Format: "id_token", Value: idToken}, {Format: "vc", Value: vc}},
+			},
+			hint: &api.UserHint{Sub: "user-123", Email: "user@example.com", Provider: "google"},
+			data: map[string]string{"sub": "user-123", "email": "user@example.com", "provider": "google"},
+		},
+		{
+			name: "assertion and sub_ids",
+			req: &gnap.RequestUser{
+				Assertions: []gnap.SubjectAssertion{{Format: "id_token", Value: idToken}},
+				SubIDs:     []gnap.SubjectID{{Format: "opaque", ID: "user-456"}, {Format: "email", Email: "other@example.com"}},
+			},
+			hint: &api.UserHint{Sub: "user-123", Email: "user@example.com"},
+			data: map[string]string{"sub": "user-123", "email": "user@example.com"},
+		},
+		{
+			name: "sub_ids only pre-fill what assertions don't say",
+			req: &gnap.RequestUser{
+				Assertions: []gnap.SubjectAssertion{{Format: "id_token", Value: otherUser}},
+				SubIDs:     []gnap.SubjectID{{Format: "email", Email: "other@example.com"}},
+			},
+			hint: &api.UserHint{Sub: "user-456", Email: "other@example.com"},
+			data: map[string]string{"sub": "user-456"},
+		},
+		{
+			name: "sub_ids of the session's user",
+			req: &gnap.RequestUser{
+				SubIDs: []gnap.SubjectID{{Format: "iss_sub", Iss: "https://idp.example.com", Sub: "user-123"}},
+			},
+			session: map[string]string{"sub": "user-123", "provider": "google"},
+			hint:    &api.UserHint{Sub: "user-123", Provider: "google"},
+		},
+		{
+			name: "sub_ids of another user",
+			req: &gnap.RequestUser{
+				SubIDs: []gnap.SubjectID{{Format: "email", Email: "other@example.com"}},
+			},
+			session: map[string]string{"sub": "user-123", "email": "user@example.com", "provider": "google"},
+			hint:    &api.UserHint{Email: "other@example.com"},
+		},
+		{
+			name: "user reference",
+			req:  &gnap.RequestUser{IsReference: true, Ref: "foo"},
+			err:  ErrUnknownUser,
+		},
+		{
+			name: "assertions of different users",
+			req: &gnap.RequestUser{
+				Assertions: []gnap.SubjectAssertion{{Format: "id_token", Value: idToken}, {Format: "id_token", Value: otherUser}},
+			},
+			err: ErrUnknownUser,
+		},
+		{
+			name: "invalid assertion",
+			req:  &gnap.RequestUser{Assertions: []gnap.SubjectAssertion{{Format: "id_token", Value: "foo"}}},
+			err:  ErrUnknownUser,
+		},
+		{
+			name: "unsupported assertion format",
+			req:  &gnap.RequestUser{Assertions: []gnap.SubjectAssertion{{Format: "saml2", Value: "foo"}}},
+			err:  ErrUnsupportedSubjectFormat,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			s := &session.Session{ClientID: "client-instance", SubjectData: tc.session}
+
+			hint, data, err := h.userHint(tc.req, s)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.hint, hint)
+			require.Equal(t, tc.data, data)
+		})
+	}
+
+	t.Run("no id_token issuer", func(t *testing.T) {
+		_, _, err := (&AuthHandler{}).userHint(&gnap.RequestUser{
+			Assertions: []gnap.SubjectAssertion{{Format: "id_token", Value: idToken}},
+		}, &session.Session{ClientID: "client-instance"})
+		require.ErrorIs(t, err, ErrUnsupportedSubjectFormat)
+	})
+}
+
+func idTokenIssuer(t *testing.T) *idtoken.Issuer {
+	t.Helper()
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	issuer, err := idtoken.New(&idtoken.Config{Issuer: "https://as.example.com", SigningKey: priv})
+	require.NoError(t, err)
+
+	return issuer
+}
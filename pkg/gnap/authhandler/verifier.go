@@ -0,0 +1,109 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package authhandler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/trustbloc/auth/pkg/gnap/api"
+	"github.com/trustbloc/auth/spi/gnap"
+	"github.com/trustbloc/auth/spi/gnap/proof/httpsig"
+	"github.com/trustbloc/auth/spi/gnap/proof/jws"
+	"github.com/trustbloc/auth/spi/gnap/proof/jwsd"
+	"github.com/trustbloc/auth/spi/gnap/proof/mtls"
+)
+
+const (
+	// ProofHTTPSig is the gnap.ClientKey proof value for http-signature proofs.
+	ProofHTTPSig = "httpsig"
+	// ProofJWSD is the gnap.ClientKey proof value for detached-JWS proofs.
+	ProofJWSD = "jwsd"
+	// ProofJWS is the gnap.ClientKey proof value for attached-JWS proofs.
+	ProofJWS = "jws"
+	// ProofMTLS is the gnap.ClientKey proof value for mutual-TLS proofs.
+	ProofMTLS = "mtls"
+)
+
+// VerifierConfig holds optional configuration for request verifiers.
+type VerifierConfig struct {
+	// ReplayCache, if set, is used to reject http-signature requests whose signature was already seen.
+	ReplayCache *httpsig.ReplayCache
+	// SignatureLabel, if set, is the label of the http-signature to verify. Otherwise, any valid signature is accepted.
+	SignatureLabel string
+	// RequiredComponents are covered components that http-signatures must include, in addition to the defaults.
+	RequiredComponents []string
+	// ClockSkew is the largest allowed difference between the current time and the created time of http-signature
+	// and JWS proofs. Defaults to httpsig.DefaultClockSkew.
+	ClockSkew time.Duration
+}
+
+// requestVerifier verifies a client request using the proof method named by the client key.
+type requestVerifier struct {
+	req    *http.Request
+	config *VerifierConfig
+}
+
+// NewRequestVerifier initializes an api.Verifier on the given client request, that
+// verifies the request using the proof method declared by the client key. The config may be nil.
+func NewRequestVerifier(req *http.Request, config *VerifierConfig) api.Verifier {
+	if config == nil {
+		config = &VerifierConfig{}
+	}
+
+	return &requestVerifier{req: req, config: config}
+}
+
+// Verify verifies that the client request is signed by the given key, using the key's proof method.
+func (v *requestVerifier) Verify(key *gnap.ClientKey) error {
+	var verifier api.Verifier
+
+	switch key.Proof {
+	case ProofHTTPSig:
+		verifier = httpsig.NewVerifier(v.req).
+			WithClockSkew(v.clockSkew()).
+			WithReplayCache(v.config.ReplayCache).
+			WithLabel(v.config.SignatureLabel).
+			WithRequiredComponents(v.config.RequiredComponents...)
+	case ProofJWSD:
+		verifier = jwsd.NewVerifier(v.req).WithClockSkew(v.clockSkew())
+	case ProofJWS:
+		verifier = jws.NewVerifier(v.req).WithClockSkew(v.clockSkew())
+	case ProofMTLS:
+		verifier = mtls.NewVerifier(v.req)
+	default:
+		return fmt.Errorf("unsupported proof method '%s'", key.Proof)
+	}
+
+	return verifier.Verify(key)
+}
+
+func (v *requestVerifier) clockSkew() time.Duration {
+	if v.config.ClockSkew == 0 {
+		return httpsig.DefaultClockSkew
+	}
+
+	return v.config.ClockSkew
+}
+
+// KeyID returns the id of the key that the client request claims to be signed with, using the given proof method.
+// Requests with mtls proofs don't identify their key, so the key id is empty.
+func (v *requestVerifier) KeyID(proof string) (string, error) {
+	switch proof {
+	case ProofHTTPSig:
+		return httpsig.NewVerifier(v.req).WithLabel(v.config.SignatureLabel).KeyID()
+	case ProofJWSD:
+		return jwsd.NewVerifier(v.req).KeyID()
+	case ProofJWS:
+		return jws.NewVerifier(v.req).KeyID()
+	case ProofMTLS:
+		return "", nil
+	default:
+		return "", fmt.Errorf("unsupported proof method '%s'", proof)
+	}
+}
@@ -0,0 +1,275 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package authhandler
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk"
+	"github.com/square/go-jose/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/auth/spi/gnap"
+	"github.com/trustbloc/auth/spi/gnap/proof/httpsig"
+	"github.com/trustbloc/auth/spi/gnap/proof/jws"
+	"github.com/trustbloc/auth/spi/gnap/proof/jwsd"
+)
+
+func TestRequestVerifier(t *testing.T) {
+	body := []byte("foo bar baz")
+
+	t.Run("httpsig", func(t *testing.T) {
+		priv, pub := signingKeyPair(t)
+
+		req := httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", bytes.NewReader(body))
+
+		req, err := (&httpsig.Signer{SigningKey: priv}).Sign(req, body)
+		require.NoError(t, err)
+
+		require.NoError(t, NewRequestVerifier(req, nil).Verify(&gnap.ClientKey{
+			Proof: ProofHTTPSig,
+			JWK:   *pub,
+		}))
+	})
+
+	t.Run("httpsig with replay cache", func(t *testing.T) {
+		priv, pub := signingKeyPair(t)
+
+		cache, err := httpsig.NewReplayCache(mem.NewProvider())
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", bytes.NewReader(body))
+
+		req, err = (&httpsig.Signer{SigningKey: priv}).Sign(req, body)
+		require.NoError(t, err)
+
+		key := &gnap.ClientKey{
+			Proof: ProofHTTPSig,
+			JWK:   *pub,
+		}
+
+		conf := &VerifierConfig{ReplayCache: cache}
+
+		require.NoError(t, NewRequestVerifier(req, conf).Verify(key))
+		require.ErrorIs(t, NewRequestVerifier(req, conf).Verify(key), httpsig.ErrReplayedSignature)
+	})
+
+	t.Run("httpsig with signature policy", func(t *testing.T) {
+		priv, pub := signingKeyPair(t)
+
+		key := &gnap.ClientKey{
+			Proof: ProofHTTPSig,
+			JWK:   *pub,
+		}
+
+		conf := &VerifierConfig{
+			SignatureLabel:     "gnap",
+			RequiredComponents: []string{"@authority"},
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", bytes.NewReader(body))
+
+		req, err := (&httpsig.Signer{SigningKey: priv, Label: "gnap", Components: []string{"@authority"}}).Sign(req, body)
+		require.NoError(t, err)
+
+		require.NoError(t, NewRequestVerifier(req, conf).Verify(key))
+
+		req = httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", bytes.NewReader(body))
+
+		req, err = (&httpsig.Signer{SigningKey: priv, Label: "gnap"}).Sign(req, body)
+		require.NoError(t, err)
+
+		require.ErrorIs(t, NewRequestVerifier(req, conf).Verify(key), httpsig.ErrInvalidSignature)
+	})
+
+	t.Run("configured clock skew", func(t *testing.T) {
+		priv, pub := signingKeyPair(t)
+
+		// signatures are created with a time in seconds, so they're never within a nanosecond of the current time.
+		conf := &VerifierConfig{ClockSkew: time.Nanosecond}
+
+		req := httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", bytes.NewReader(body))
+
+		req, err := (&httpsig.Signer{SigningKey: priv}).Sign(req, body)
+		require.NoError(t, err)
+
+		err = NewRequestVerifier(req, conf).Verify(&gnap.ClientKey{Proof: ProofHTTPSig, JWK: *pub})
+		require.ErrorIs(t, err, httpsig.ErrStaleSignature)
+
+		req = httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", bytes.NewReader(body))
+
+		req, err = (&jwsd.Signer{SigningKey: priv}).Sign(req, body)
+		require.NoError(t, err)
+
+		err = NewRequestVerifier(req, conf).Verify(&gnap.ClientKey{Proof: ProofJWSD, JWK: *pub})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "outside of allowed window")
+
+		req = httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", bytes.NewReader(body))
+
+		req, err = (&jws.Signer{SigningKey: priv}).Sign(req, body)
+		require.NoError(t, err)
+
+		err = NewRequestVerifier(req, conf).Verify(&gnap.ClientKey{Proof: ProofJWS, JWK: *pub})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "outside of allowed window")
+	})
+
+	t.Run("jwsd", func(t *testing.T) {
+		priv, pub := signingKeyPair(t)
+
+		req := httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", bytes.NewReader(body))
+
+		req, err := (&jwsd.Signer{SigningKey: priv}).Sign(req, body)
+		require.NoError(t, err)
+
+		require.NoError(t, NewRequestVerifier(req, nil).Verify(&gnap.ClientKey{
+			Proof: ProofJWSD,
+			JWK:   *pub,
+		}))
+	})
+
+	t.Run("jws", func(t *testing.T) {
+		priv, pub := signingKeyPair(t)
+
+		req := httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", bytes.NewReader(body))
+
+		req, err := (&jws.Signer{SigningKey: priv}).Sign(req, body)
+		require.NoError(t, err)
+
+		require.NoError(t, NewRequestVerifier(req, nil).Verify(&gnap.ClientKey{
+			Proof: ProofJWS,
+			JWK:   *pub,
+		}))
+	})
+
+	t.Run("mtls", func(t *testing.T) {
+		priv, pub := signingKeyPair(t)
+
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(1),
+			NotBefore:    time.Now(),
+			NotAfter:     time.Now().Add(time.Hour),
+		}
+
+		der, err := x509.CreateCertificate(rand.Reader, template, template, pub.Key, priv.Key)
+		require.NoError(t, err)
+
+		cert, err := x509.ParseCertificate(der)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodPost, "https://foo.bar/baz", bytes.NewReader(body))
+		req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+
+		require.NoError(t, NewRequestVerifier(req, nil).Verify(&gnap.ClientKey{
+			Proof: ProofMTLS,
+			Cert:  base64.StdEncoding.EncodeToString(der),
+		}))
+	})
+
+	t.Run("request signed with a different proof method than the key declares", func(t *testing.T) {
+		priv, pub := signingKeyPair(t)
+
+		req := httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", bytes.NewReader(body))
+
+		req, err := (&jwsd.Signer{SigningKey: priv}).Sign(req, body)
+		require.NoError(t, err)
+
+		require.Error(t, NewRequestVerifier(req, nil).Verify(&gnap.ClientKey{
+			Proof: ProofHTTPSig,
+			JWK:   *pub,
+		}))
+	})
+
+	t.Run("unsupported proof method", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", nil)
+
+		err := NewRequestVerifier(req, nil).Verify(&gnap.ClientKey{
+			Proof: "foo",
+		})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unsupported proof method")
+	})
+}
+
+func TestRequestVerifierKeyID(t *testing.T) {
+	body := []byte("foo bar baz")
+
+	priv, _ := signingKeyPair(t)
+
+	t.Run("signed requests", func(t *testing.T) {
+		signers := map[string]gnap.Signer{
+			ProofHTTPSig: &httpsig.Signer{SigningKey: priv},
+			ProofJWSD:    &jwsd.Signer{SigningKey: priv},
+			ProofJWS:     &jws.Signer{SigningKey: priv},
+		}
+
+		for proof, signer := range signers {
+			req := httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", bytes.NewReader(body))
+
+			req, err := signer.Sign(req, body)
+			require.NoError(t, err, proof)
+
+			keyID, err := NewRequestVerifier(req, nil).(*requestVerifier).KeyID(proof)
+			require.NoError(t, err, proof)
+			require.Equal(t, "key1", keyID, proof)
+		}
+	})
+
+	t.Run("mtls", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "https://foo.bar/baz", nil)
+
+		keyID, err := NewRequestVerifier(req, nil).(*requestVerifier).KeyID(ProofMTLS)
+		require.NoError(t, err)
+		require.Empty(t, keyID)
+	})
+
+	t.Run("unsupported proof method", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", nil)
+
+		_, err := NewRequestVerifier(req, nil).(*requestVerifier).KeyID("foo")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unsupported proof method")
+	})
+}
+
+func signingKeyPair(t *testing.T) (*jwk.JWK, *jwk.JWK) {
+	t.Helper()
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	privJWK := &jwk.JWK{
+		JSONWebKey: jose.JSONWebKey{
+			Key:       priv,
+			KeyID:     "key1",
+			Algorithm: "ES256",
+		},
+		Kty: "EC",
+		Crv: "P-256",
+	}
+
+	pubJWK := &jwk.JWK{
+		JSONWebKey: privJWK.Public(),
+		Kty:        "EC",
+		Crv:        "P-256",
+	}
+
+	return privJWK, pubJWK
+}
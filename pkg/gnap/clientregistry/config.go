@@ -0,0 +1,57 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package clientregistry
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk"
+
+	"github.com/trustbloc/auth/spi/gnap"
+)
+
+// Config holds the configuration details for the client registry.
+type Config struct {
+	Clients []ClientConfig `json:"clients"`
+	// CacheTTL is how long keys fetched from a client's jwks_uri are used before they are fetched again.
+	// Defaults to DefaultCacheTTL.
+	CacheTTL time.Duration `json:"-"`
+	// MinRefreshInterval is the shortest time between two fetches of a client's jwks_uri, which limits
+	// the fetches caused by requests signed with unknown key ids. Defaults to DefaultMinRefreshInterval.
+	MinRefreshInterval time.Duration `json:"-"`
+	// FetchTimeout bounds each fetch of a client's jwks_uri, including reading the key set. Defaults to
+	// DefaultFetchTimeout.
+	FetchTimeout time.Duration `json:"-"`
+	// HTTPClient is used to fetch the clients' jwks_uri. Defaults to http.DefaultClient.
+	HTTPClient *http.Client `json:"-"`
+}
+
+// ClientConfig holds the parameters of a pre-registered client. The client's keys are either fetched from
+// JWKSURI or given statically in JWKS.
+type ClientConfig struct {
+	// ID is the client instance identifier, which the client sends by reference in place of its key.
+	ID string `json:"id"`
+	// Proof is the proof method of the client's keys. Defaults to "httpsig".
+	Proof   string `json:"proof,omitempty"`
+	JWKSURI string `json:"jwks_uri,omitempty"`
+	JWKS    *JWKS  `json:"jwks,omitempty"`
+	// Display is the verified display information of the client, which is shown to users instead of the display
+	// information that the client sends.
+	Display *gnap.ClientDisplay `json:"display,omitempty"`
+	// AppURITemplate is the deep-link template of the client's companion authenticator app, which lets the client
+	// start interactions with the app start mode. See the app interaction handler for its placeholders.
+	AppURITemplate string `json:"app_uri_template,omitempty"`
+	// AppClientID is the instance identifier that the client's companion app is registered with. Only access tokens
+	// issued to it can complete the client's app interactions. Defaults to the client's own identifier.
+	AppClientID string `json:"app_client_id,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set.
+type JWKS struct {
+	Keys []jwk.JWK `json:"keys"`
+}
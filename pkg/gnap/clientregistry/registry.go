@@ -0,0 +1,308 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package clientregistry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/log"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk"
+
+	"github.com/trustbloc/auth/spi/gnap"
+)
+
+var logger = log.New("gnap/client-registry") // nolint:gochecknoglobals
+
+const (
+	// DefaultCacheTTL is the default time that keys fetched from a jwks_uri are cached for.
+	DefaultCacheTTL = time.Hour
+	// DefaultMinRefreshInterval is the default shortest time between two fetches of a jwks_uri.
+	DefaultMinRefreshInterval = 30 * time.Second
+	// DefaultFetchTimeout is the default time that fetching a jwks_uri may take.
+	DefaultFetchTimeout = 10 * time.Second
+
+	defaultProof = "httpsig"
+	// maxJWKSSize limits the size of fetched key sets.
+	maxJWKSSize = 1 << 20
+)
+
+var (
+	// ErrUnknownClient is returned when a client isn't registered.
+	ErrUnknownClient = errors.New("unknown client")
+	// ErrKeyNotFound is returned when a registered client has no key with the requested key id.
+	ErrKeyNotFound = errors.New("client key not found")
+)
+
+/*
+Registry holds pre-registered GNAP clients, which send their instance identifier by reference instead of sending
+their key, and dereferences their keys.
+
+A client's keys are either configured statically, or fetched from the client's jwks_uri. Fetched keys are cached,
+and fetched again when the cache expires, or when a request is signed with a key id that isn't in the cache, so
+a client can rotate its keys without losing its instance identity. Fetches are limited to one at a time per client,
+and to one per minimum refresh interval whether they succeed or not, so an unavailable jwks_uri isn't hammered with
+a fetch for every request.
+*/
+type Registry struct {
+	clients            map[string]*client
+	cacheTTL           time.Duration
+	minRefreshInterval time.Duration
+	fetchTimeout       time.Duration
+	httpClient         *http.Client
+	now                func() time.Time
+}
+
+type client struct {
+	proof   string
+	jwksURI string
+	display *gnap.ClientDisplay
+
+	mu      sync.Mutex
+	keys    []jwk.JWK
+	fetched time.Time
+	// attempted is when the last fetch started, and fetchErr is its error.
+	attempted time.Time
+	fetchErr  error
+	// fetching is true while a fetch is in flight, which is done without holding mu. fetchingDone signals its end.
+	fetching     bool
+	fetchingDone *sync.Cond
+}
+
+// New creates a Registry of the clients in the given Config. The config may be nil.
+func New(config *Config) (*Registry, error) {
+	if config == nil {
+		config = &Config{}
+	}
+
+	r := &Registry{
+		clients:            map[string]*client{},
+		cacheTTL:           config.CacheTTL,
+		minRefreshInterval: config.MinRefreshInterval,
+		fetchTimeout:       config.FetchTimeout,
+		httpClient:         config.HTTPClient,
+		now:                time.Now,
+	}
+
+	if r.cacheTTL == 0 {
+		r.cacheTTL = DefaultCacheTTL
+	}
+
+	if r.minRefreshInterval == 0 {
+		r.minRefreshInterval = DefaultMinRefreshInterval
+	}
+
+	if r.fetchTimeout == 0 {
+		r.fetchTimeout = DefaultFetchTimeout
+	}
+
+	if r.httpClient == nil {
+		r.httpClient = http.DefaultClient
+	}
+
+	for _, clientConfig := range config.Clients {
+		if clientConfig.ID == "" {
+			return nil, errors.New("registered client is missing id")
+		}
+
+		if _, ok := r.clients[clientConfig.ID]; ok {
+			return nil, fmt.Errorf("client %s is registered more than once", clientConfig.ID)
+		}
+
+		if (clientConfig.JWKSURI == "") == (clientConfig.JWKS == nil) {
+			return nil, fmt.Errorf("client %s must have exactly one of jwks_uri and jwks", clientConfig.ID)
+		}
+
+		c := &client{
+			proof:   clientConfig.Proof,
+			jwksURI: clientConfig.JWKSURI,
+			display: clientConfig.Display,
+		}
+
+		c.fetchingDone = sync.NewCond(&c.mu)
+
+		if c.proof == "" {
+			c.proof = defaultProof
+		}
+
+		if clientConfig.JWKS != nil {
+			c.keys = clientConfig.JWKS.Keys
+		}
+
+		r.clients[clientConfig.ID] = c
+	}
+
+	return r, nil
+}
+
+// IsRegistered returns true iff the given client instance identifier belongs to a registered client.
+func (r *Registry) IsRegistered(clientID string) bool {
+	if r == nil {
+		return false
+	}
+
+	_, ok := r.clients[clientID]
+
+	return ok
+}
+
+// Proof returns the proof method of the given registered client's keys.
+func (r *Registry) Proof(clientID string) string {
+	if !r.IsRegistered(clientID) {
+		return ""
+	}
+
+	return r.clients[clientID].proof
+}
+
+// Display returns the verified display information of the given registered client, or nil if it has none.
+func (r *Registry) Display(clientID string) *gnap.ClientDisplay {
+	if !r.IsRegistered(clientID) {
+		return nil
+	}
+
+	return r.clients[clientID].display
+}
+
+// ClientKey returns the key of the given client with the given key id. If the key id is empty, the client must
+// have exactly one key.
+func (r *Registry) ClientKey(clientID, keyID string) (*gnap.ClientKey, error) {
+	if !r.IsRegistered(clientID) {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownClient, clientID)
+	}
+
+	c := r.clients[clientID]
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.jwksURI == "" {
+		return c.clientKey(keyID)
+	}
+
+	key, err := c.clientKey(keyID)
+
+	for c.fetching {
+		if err == nil {
+			// expired keys are used while they're being refreshed.
+			return key, nil
+		}
+
+		c.fetchingDone.Wait()
+
+		key, err = c.clientKey(keyID)
+	}
+
+	if err == nil && r.now().Sub(c.fetched) < r.cacheTTL {
+		return key, nil
+	}
+
+	// an unknown key id is likely a rotated key, but limit how often a client can cause its keys to be fetched.
+	if r.now().Sub(c.attempted) < r.minRefreshInterval {
+		if err != nil && c.fetchErr != nil {
+			return nil, fmt.Errorf("fetching keys of client %s: %w", clientID, c.fetchErr)
+		}
+
+		return key, err
+	}
+
+	c.fetch(r)
+
+	if c.fetchErr != nil {
+		if err == nil {
+			logger.Warnf("using expired keys of client %s: %s", clientID, c.fetchErr.Error())
+
+			return key, nil
+		}
+
+		return nil, fmt.Errorf("fetching keys of client %s: %w", clientID, c.fetchErr)
+	}
+
+	return c.clientKey(keyID)
+}
+
+// fetch fetches the client's keys from its jwks_uri. It's called with mu held, which it releases during the fetch.
+func (c *client) fetch(r *Registry) {
+	c.attempted = r.now()
+	c.fetching = true
+
+	c.mu.Unlock()
+
+	keys, err := r.fetchKeys(c.jwksURI)
+
+	c.mu.Lock()
+
+	c.fetching = false
+	c.fetchErr = err
+
+	if err == nil {
+		c.keys = keys
+		c.fetched = r.now()
+	}
+
+	c.fetchingDone.Broadcast()
+}
+
+func (c *client) clientKey(keyID string) (*gnap.ClientKey, error) {
+	if keyID == "" {
+		if len(c.keys) != 1 {
+			return nil, fmt.Errorf("%w: key id is required for a client with %d keys", ErrKeyNotFound, len(c.keys))
+		}
+
+		return &gnap.ClientKey{Proof: c.proof, JWK: c.keys[0]}, nil
+	}
+
+	for _, key := range c.keys {
+		if key.KeyID == keyID && key.Use != "enc" {
+			return &gnap.ClientKey{Proof: c.proof, JWK: key}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, keyID)
+}
+
+func (r *Registry) fetchKeys(jwksURI string) ([]jwk.JWK, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.fetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, jwksURI, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating jwks request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching jwks: %w", err)
+	}
+
+	defer func() {
+		if e := resp.Body.Close(); e != nil {
+			logger.Warnf("failed to close jwks response body: %s", e.Error())
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching jwks: unexpected status %d", resp.StatusCode)
+	}
+
+	jwks := &JWKS{}
+
+	err = json.NewDecoder(io.LimitReader(resp.Body, maxJWKSSize)).Decode(jwks)
+	if err != nil {
+		return nil, fmt.Errorf("parsing jwks: %w", err)
+	}
+
+	return jwks.Keys, nil
+}
@@ -0,0 +1,418 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package clientregistry
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk/jwksupport"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/auth/spi/gnap"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		r, err := New(&Config{
+			Clients: []ClientConfig{
+				{ID: "foo", JWKSURI: "https://foo.example.com/jwks"},
+				{ID: "bar", Proof: "jwsd", JWKS: jwks(t, "key1"), Display: &gnap.ClientDisplay{Name: "Bar"}},
+			},
+		})
+		require.NoError(t, err)
+
+		require.True(t, r.IsRegistered("foo"))
+		require.True(t, r.IsRegistered("bar"))
+		require.False(t, r.IsRegistered("baz"))
+
+		require.Equal(t, "httpsig", r.Proof("foo"))
+		require.Equal(t, "jwsd", r.Proof("bar"))
+		require.Empty(t, r.Proof("baz"))
+
+		require.Nil(t, r.Display("foo"))
+		require.Equal(t, &gnap.ClientDisplay{Name: "Bar"}, r.Display("bar"))
+		require.Nil(t, r.Display("baz"))
+	})
+
+	t.Run("nil config", func(t *testing.T) {
+		r, err := New(nil)
+		require.NoError(t, err)
+		require.False(t, r.IsRegistered("foo"))
+	})
+
+	t.Run("nil registry", func(t *testing.T) {
+		var r *Registry
+
+		require.False(t, r.IsRegistered("foo"))
+
+		_, err := r.ClientKey("foo", "")
+		require.ErrorIs(t, err, ErrUnknownClient)
+	})
+
+	t.Run("invalid client config", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			clients []ClientConfig
+			err     string
+		}{
+			{
+				name:    "missing id",
+				clients: []ClientConfig{{JWKSURI: "https://foo.example.com/jwks"}},
+				err:     "missing id",
+			},
+			{
+				name: "duplicate id",
+				clients: []ClientConfig{
+					{ID: "foo", JWKSURI: "https://foo.example.com/jwks"},
+					{ID: "foo", JWKSURI: "https://bar.example.com/jwks"},
+				},
+				err: "registered more than once",
+			},
+			{
+				name:    "no keys",
+				clients: []ClientConfig{{ID: "foo"}},
+				err:     "exactly one of jwks_uri and jwks",
+			},
+			{
+				name:    "both jwks_uri and jwks",
+				clients: []ClientConfig{{ID: "foo", JWKSURI: "https://foo.example.com/jwks", JWKS: &JWKS{}}},
+				err:     "exactly one of jwks_uri and jwks",
+			},
+		}
+
+		for _, tt := range tests {
+			tc := tt
+
+			t.Run(tc.name, func(t *testing.T) {
+				_, err := New(&Config{Clients: tc.clients})
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.err)
+			})
+		}
+	})
+}
+
+func TestRegistry_ClientKey(t *testing.T) {
+	t.Run("static jwks", func(t *testing.T) {
+		keys := jwks(t, "key1", "key2")
+		keys.Keys[1].Use = "enc"
+
+		r, err := New(&Config{Clients: []ClientConfig{{ID: "foo", JWKS: keys}}})
+		require.NoError(t, err)
+
+		key, err := r.ClientKey("foo", "key1")
+		require.NoError(t, err)
+		require.Equal(t, "httpsig", key.Proof)
+		require.Equal(t, "key1", key.JWK.KeyID)
+
+		_, err = r.ClientKey("foo", "key2")
+		require.ErrorIs(t, err, ErrKeyNotFound)
+
+		_, err = r.ClientKey("foo", "")
+		require.ErrorIs(t, err, ErrKeyNotFound)
+		require.Contains(t, err.Error(), "key id is required")
+
+		_, err = r.ClientKey("bar", "key1")
+		require.ErrorIs(t, err, ErrUnknownClient)
+	})
+
+	t.Run("single key without key id", func(t *testing.T) {
+		r, err := New(&Config{Clients: []ClientConfig{{ID: "foo", JWKS: jwks(t, "")}}})
+		require.NoError(t, err)
+
+		_, err = r.ClientKey("foo", "")
+		require.NoError(t, err)
+	})
+
+	t.Run("jwks uri", func(t *testing.T) {
+		srv := newJWKSServer(t, jwks(t, "key1"))
+
+		r, err := New(&Config{
+			Clients:            []ClientConfig{{ID: "foo", JWKSURI: srv.URL}},
+			MinRefreshInterval: time.Minute,
+			CacheTTL:           time.Hour,
+		})
+		require.NoError(t, err)
+
+		now := time.Now()
+		r.now = func() time.Time { return now }
+
+		key, err := r.ClientKey("foo", "key1")
+		require.NoError(t, err)
+		require.Equal(t, "key1", key.JWK.KeyID)
+		require.Equal(t, 1, srv.fetches())
+
+		// cached
+		_, err = r.ClientKey("foo", "key1")
+		require.NoError(t, err)
+		require.Equal(t, 1, srv.fetches())
+
+		// client rotates its key
+		srv.setKeys(jwks(t, "key2"))
+
+		// unknown key ids don't cause fetches more often than the min refresh interval
+		_, err = r.ClientKey("foo", "key2")
+		require.ErrorIs(t, err, ErrKeyNotFound)
+		require.Equal(t, 1, srv.fetches())
+
+		now = now.Add(2 * time.Minute)
+
+		key, err = r.ClientKey("foo", "key2")
+		require.NoError(t, err)
+		require.Equal(t, "key2", key.JWK.KeyID)
+		require.Equal(t, 2, srv.fetches())
+
+		// the rotated key is no longer valid
+		now = now.Add(2 * time.Minute)
+
+		_, err = r.ClientKey("foo", "key1")
+		require.ErrorIs(t, err, ErrKeyNotFound)
+		require.Equal(t, 3, srv.fetches())
+
+		// cache expires
+		now = now.Add(2 * time.Hour)
+
+		_, err = r.ClientKey("foo", "key2")
+		require.NoError(t, err)
+		require.Equal(t, 4, srv.fetches())
+	})
+
+	t.Run("expired keys are used if jwks uri is unavailable", func(t *testing.T) {
+		srv := newJWKSServer(t, jwks(t, "key1"))
+
+		r, err := New(&Config{Clients: []ClientConfig{{ID: "foo", JWKSURI: srv.URL}}})
+		require.NoError(t, err)
+
+		now := time.Now()
+		r.now = func() time.Time { return now }
+
+		_, err = r.ClientKey("foo", "key1")
+		require.NoError(t, err)
+
+		srv.setStatus(http.StatusInternalServerError)
+
+		now = now.Add(2 * DefaultCacheTTL)
+
+		_, err = r.ClientKey("foo", "key1")
+		require.NoError(t, err)
+		require.Equal(t, 2, srv.fetches())
+
+		_, err = r.ClientKey("foo", "key2")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unexpected status 500")
+	})
+
+	t.Run("failing jwks uri isn't fetched more often than the min refresh interval", func(t *testing.T) {
+		srv := newJWKSServer(t, jwks(t, "key1"))
+		srv.setStatus(http.StatusServiceUnavailable)
+
+		r, err := New(&Config{Clients: []ClientConfig{{ID: "foo", JWKSURI: srv.URL}}})
+		require.NoError(t, err)
+
+		now := time.Now()
+		r.now = func() time.Time { return now }
+
+		for i := 0; i < 3; i++ {
+			_, err = r.ClientKey("foo", "key1")
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "unexpected status 503")
+		}
+
+		require.Equal(t, 1, srv.fetches())
+
+		srv.setStatus(http.StatusOK)
+
+		now = now.Add(DefaultMinRefreshInterval)
+
+		key, err := r.ClientKey("foo", "key1")
+		require.NoError(t, err)
+		require.Equal(t, "key1", key.JWK.KeyID)
+		require.Equal(t, 2, srv.fetches())
+	})
+
+	t.Run("hanging jwks uri times out without blocking other requests", func(t *testing.T) {
+		var hang int32
+
+		keys := jwks(t, "key1")
+		release := make(chan struct{})
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if atomic.LoadInt32(&hang) == 1 {
+				select {
+				case <-release:
+				case <-req.Context().Done():
+				}
+
+				return
+			}
+
+			require.NoError(t, json.NewEncoder(w).Encode(keys))
+		}))
+		t.Cleanup(srv.Close)
+		t.Cleanup(func() { close(release) })
+
+		r, err := New(&Config{
+			Clients:      []ClientConfig{{ID: "foo", JWKSURI: srv.URL}},
+			FetchTimeout: 200 * time.Millisecond,
+		})
+		require.NoError(t, err)
+
+		_, err = r.ClientKey("foo", "key1")
+		require.NoError(t, err)
+
+		// the cached keys expire, and the jwks uri hangs.
+		atomic.StoreInt32(&hang, 1)
+
+		expired := time.Now().Add(2 * DefaultCacheTTL)
+		r.now = func() time.Time { return expired }
+
+		refreshed := make(chan error, 1)
+
+		go func() {
+			_, e := r.ClientKey("foo", "key1")
+			refreshed <- e
+		}()
+
+		c := r.clients["foo"]
+
+		require.Eventually(t, func() bool {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+
+			return c.fetching
+		}, time.Second, time.Millisecond)
+
+		// other requests use the expired keys while the fetch hangs.
+		key, err := r.ClientKey("foo", "key1")
+		require.NoError(t, err)
+		require.Equal(t, "key1", key.JWK.KeyID)
+
+		select {
+		case err = <-refreshed:
+			require.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "fetch didn't time out")
+		}
+
+		_, err = r.ClientKey("foo", "key2")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "context deadline exceeded")
+	})
+
+	t.Run("fetch errors", func(t *testing.T) {
+		badJSON := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte("foo")) // nolint:errcheck
+		}))
+		t.Cleanup(badJSON.Close)
+
+		tests := []struct {
+			name    string
+			jwksURI string
+			err     string
+		}{
+			{name: "invalid url", jwksURI: "\u007f", err: "creating jwks request"},
+			{name: "unreachable", jwksURI: "http://127.0.0.1:0/jwks", err: "fetching jwks"},
+			{name: "malformed jwks", jwksURI: badJSON.URL, err: "parsing jwks"},
+		}
+
+		for _, tt := range tests {
+			tc := tt
+
+			t.Run(tc.name, func(t *testing.T) {
+				r, err := New(&Config{Clients: []ClientConfig{{ID: "foo", JWKSURI: tc.jwksURI}}})
+				require.NoError(t, err)
+
+				_, err = r.ClientKey("foo", "key1")
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.err)
+			})
+		}
+	})
+}
+
+type jwksServer struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	keys   *JWKS
+	status int
+	count  int
+}
+
+func newJWKSServer(t *testing.T, keys *JWKS) *jwksServer {
+	t.Helper()
+
+	s := &jwksServer{keys: keys, status: http.StatusOK}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.count++
+
+		if s.status != http.StatusOK {
+			w.WriteHeader(s.status)
+
+			return
+		}
+
+		require.NoError(t, json.NewEncoder(w).Encode(s.keys))
+	}))
+
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *jwksServer) setKeys(keys *JWKS) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys = keys
+}
+
+func (s *jwksServer) setStatus(status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.status = status
+}
+
+func (s *jwksServer) fetches() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.count
+}
+
+func jwks(t *testing.T, keyIDs ...string) *JWKS {
+	t.Helper()
+
+	keys := &JWKS{}
+
+	for _, keyID := range keyIDs {
+		pub, _, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		k, err := jwksupport.JWKFromKey(pub)
+		require.NoError(t, err)
+
+		k.KeyID = keyID
+
+		keys.Keys = append(keys.Keys, *k)
+	}
+
+	return keys
+}
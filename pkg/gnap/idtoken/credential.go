@@ -0,0 +1,88 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package idtoken
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/util"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/square/go-jose/v3/jwt"
+)
+
+const (
+	// CredentialType is the type of the login credentials that Issuer issues, in addition to VerifiableCredential.
+	CredentialType = "LoginCredential"
+
+	credentialsContext = "https://www.w3.org/2018/credentials/v1"
+	// subjectURNPrefix prefixes the credentialSubject id of login credentials, which must be a URI. It's followed by
+	// the user's escaped subject identifier at the AS.
+	subjectURNPrefix = "urn:trustbloc:auth:sub:"
+)
+
+/*
+IssueCredential issues a JWT-encoded W3C Verifiable Credential that proves that the user with the given subject
+identifier logged in to the AS. Unlike an id_token, the credential is meant to be held by a wallet and presented to
+other parties, so it's valid for the credential lifetime.
+
+The credential is issued to the client instance with the given identifier. Its subject is identified by a URN that
+holds the user's sub, and holds the given claims about the user.
+*/
+func (i *Issuer) IssueCredential(sub, audience string, claims map[string]string) (string, error) {
+	now := i.now()
+
+	// a single subject map is marshaled as an object, while a verifiable.Subject or map that only has an id would be
+	// marshaled as the id string.
+	subject := map[string]interface{}{}
+
+	for k, v := range claims {
+		subject[k] = v
+	}
+
+	subject["id"] = subjectURNPrefix + url.PathEscape(sub)
+
+	vc := &verifiable.Credential{
+		Context: []string{credentialsContext},
+		ID:      "urn:uuid:" + uuid.New().String(),
+		Types:   []string{"VerifiableCredential", CredentialType},
+		Subject: []map[string]interface{}{subject},
+		Issuer:  verifiable.Issuer{ID: i.issuer},
+		Issued:  util.NewTime(now),
+		Expired: util.NewTime(now.Add(i.credentialLifetime)),
+	}
+
+	vcClaims, err := vc.JWTClaims(false)
+	if err != nil {
+		return "", fmt.Errorf("creating credential claims: %w", err)
+	}
+
+	vcClaims.Audience = jwt.Audience{audience}
+
+	token, err := jwt.Signed(i.signer).Claims(vcClaims).CompactSerialize()
+	if err != nil {
+		return "", fmt.Errorf("signing credential: %w", err)
+	}
+
+	return token, nil
+}
+
+// credentialSubjectSub returns the user's subject identifier held by the given credentialSubject id.
+func credentialSubjectSub(id string) (string, error) {
+	if !strings.HasPrefix(id, subjectURNPrefix) {
+		return "", fmt.Errorf("%w: unexpected credential subject '%s'", ErrInvalidAssertion, id)
+	}
+
+	sub, err := url.PathUnescape(strings.TrimPrefix(id, subjectURNPrefix))
+	if err != nil || sub == "" {
+		return "", fmt.Errorf("%w: malformed credential subject '%s'", ErrInvalidAssertion, id)
+	}
+
+	return sub, nil
+}
@@ -0,0 +1,60 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package idtoken
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/square/go-jose/v3/jwt"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIssuer_IssueCredential(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	issuer, err := New(&Config{Issuer: "https://as.example.com", SigningKey: priv})
+	require.NoError(t, err)
+
+	now := time.Now()
+	issuer.now = func() time.Time { return now }
+
+	vc, err := issuer.IssueCredential("user-123", "client-instance", map[string]string{
+		"email":    "user@example.com",
+		"provider": "google",
+	})
+	require.NoError(t, err)
+
+	parsed, err := jwt.ParseSigned(vc)
+	require.NoError(t, err)
+	require.Equal(t, issuer.JWKS().Keys[0].KeyID, parsed.Headers[0].KeyID)
+
+	claims := jwt.Claims{}
+	vcClaims := struct {
+		VC map[string]interface{} `json:"vc"`
+	}{}
+
+	require.NoError(t, parsed.Claims(&priv.PublicKey, &claims, &vcClaims))
+	require.NoError(t, claims.Validate(jwt.Expected{
+		Issuer:   "https://as.example.com",
+		Subject:  "urn:trustbloc:auth:sub:user-123",
+		Audience: jwt.Audience{"client-instance"},
+		Time:     now.Add(time.Hour),
+	}))
+	require.Equal(t, now.Add(DefaultCredentialLifetime).Unix(), claims.Expiry.Time().Unix())
+
+	require.Equal(t, []interface{}{"VerifiableCredential", CredentialType}, vcClaims.VC["type"])
+	require.Equal(t, map[string]interface{}{
+		"id":       "urn:trustbloc:auth:sub:user-123",
+		"provider": "google",
+		"email":    "user@example.com",
+	}, vcClaims.VC["credentialSubject"])
+}
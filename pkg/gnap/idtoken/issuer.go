@@ -0,0 +1,161 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package idtoken
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/square/go-jose/v3"
+	"github.com/square/go-jose/v3/cryptosigner"
+	"github.com/square/go-jose/v3/jwt"
+)
+
+const (
+	// DefaultLifetime is the default time that an issued id_token is valid for.
+	DefaultLifetime = 5 * time.Minute
+	// DefaultCredentialLifetime is the default time that an issued login credential is valid for.
+	DefaultCredentialLifetime = 24 * time.Hour
+)
+
+/*
+Issuer mints id_tokens and login credentials, signed by the AS, that assert the identity of the user who completed
+a GNAP interaction. Clients request them as subject assertions, and verify them using the keys published by
+Issuer.JWKS.
+*/
+type Issuer struct {
+	issuer             string
+	signer             jose.Signer
+	publicKey          jose.JSONWebKey
+	lifetime           time.Duration
+	credentialLifetime time.Duration
+	now                func() time.Time
+}
+
+// Config holds Issuer constructor configuration.
+type Config struct {
+	// Issuer is the iss claim of issued id_tokens, the public url of the AS.
+	Issuer string
+	// SigningKey is the private key that signs id_tokens. It may be held in memory or in a KMS.
+	SigningKey crypto.Signer
+	// Lifetime is the time that an issued id_token is valid for. Defaults to DefaultLifetime.
+	Lifetime time.Duration
+	// CredentialLifetime is the time that an issued login credential is valid for. Defaults to
+	// DefaultCredentialLifetime.
+	CredentialLifetime time.Duration
+}
+
+// New creates an Issuer.
+func New(config *Config) (*Issuer, error) {
+	if config.SigningKey == nil {
+		return nil, errors.New("missing id_token signing key")
+	}
+
+	alg, err := algorithm(config.SigningKey.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	opaque := cryptosigner.Opaque(config.SigningKey)
+
+	publicKey := *opaque.Public()
+
+	thumbprint, err := publicKey.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("computing id_token signing key thumbprint: %w", err)
+	}
+
+	publicKey.KeyID = base64.RawURLEncoding.EncodeToString(thumbprint)
+	publicKey.Algorithm = string(alg)
+	publicKey.Use = "sig"
+
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: alg, Key: opaque},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader(jose.HeaderKey("kid"), publicKey.KeyID))
+	if err != nil {
+		return nil, fmt.Errorf("creating id_token signer: %w", err)
+	}
+
+	lifetime := config.Lifetime
+	if lifetime == 0 {
+		lifetime = DefaultLifetime
+	}
+
+	credentialLifetime := config.CredentialLifetime
+	if credentialLifetime == 0 {
+		credentialLifetime = DefaultCredentialLifetime
+	}
+
+	return &Issuer{
+		issuer:             config.Issuer,
+		signer:             signer,
+		publicKey:          publicKey,
+		lifetime:           lifetime,
+		credentialLifetime: credentialLifetime,
+		now:                time.Now,
+	}, nil
+}
+
+// Issue mints an id_token about the user with the given subject identifier, for the client instance with the given
+// identifier. The given claims are added to the id_token, but can't override its registered claims.
+func (i *Issuer) Issue(sub, audience string, claims map[string]string) (string, error) {
+	now := i.now()
+
+	extra := map[string]interface{}{}
+
+	for k, v := range claims {
+		extra[k] = v
+	}
+
+	token, err := jwt.Signed(i.signer).
+		Claims(extra).
+		Claims(&jwt.Claims{
+			Issuer:   i.issuer,
+			Subject:  sub,
+			Audience: jwt.Audience{audience},
+			IssuedAt: jwt.NewNumericDate(now),
+			Expiry:   jwt.NewNumericDate(now.Add(i.lifetime)),
+		}).
+		CompactSerialize()
+	if err != nil {
+		return "", fmt.Errorf("signing id_token: %w", err)
+	}
+
+	return token, nil
+}
+
+// JWKS returns the key set that clients verify issued id_tokens and login credentials with.
+func (i *Issuer) JWKS() *jose.JSONWebKeySet {
+	return &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{i.publicKey}}
+}
+
+// algorithm returns the JWS algorithm that id_tokens are signed with using the given public key's private key.
+func algorithm(publicKey crypto.PublicKey) (jose.SignatureAlgorithm, error) {
+	switch key := publicKey.(type) {
+	case ed25519.PublicKey:
+		return jose.EdDSA, nil
+	case *rsa.PublicKey:
+		return jose.RS256, nil
+	case *ecdsa.PublicKey:
+		switch key.Curve {
+		case elliptic.P256():
+			return jose.ES256, nil
+		case elliptic.P384():
+			return jose.ES384, nil
+		case elliptic.P521():
+			return jose.ES512, nil
+		}
+	}
+
+	return "", fmt.Errorf("unsupported id_token signing key type %T", publicKey)
+}
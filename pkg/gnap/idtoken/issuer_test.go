@@ -0,0 +1,123 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package idtoken
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/square/go-jose/v3"
+	"github.com/square/go-jose/v3/jwt"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("missing signing key", func(t *testing.T) {
+		_, err := New(&Config{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "missing id_token signing key")
+	})
+
+	t.Run("unsupported signing key", func(t *testing.T) {
+		_, err := New(&Config{SigningKey: &unsupportedSigner{}})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unsupported id_token signing key type")
+	})
+}
+
+func TestIssuer_Issue(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		key  crypto.Signer
+		alg  string
+	}{
+		{name: "ecdsa", key: ecKey, alg: "ES384"},
+		{name: "ed25519", key: edKey, alg: "EdDSA"},
+		{name: "rsa", key: rsaKey, alg: "RS256"},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			issuer, err := New(&Config{
+				Issuer:     "https://as.example.com",
+				SigningKey: tc.key,
+				Lifetime:   time.Hour,
+			})
+			require.NoError(t, err)
+
+			now := time.Now()
+			issuer.now = func() time.Time { return now }
+
+			token, err := issuer.Issue("user-123", "client-instance", map[string]string{
+				"email": "user@example.com",
+				"iss":   "https://evil.example.com",
+			})
+			require.NoError(t, err)
+
+			jwks := issuer.JWKS()
+			require.Len(t, jwks.Keys, 1)
+			require.Equal(t, tc.alg, jwks.Keys[0].Algorithm)
+			require.True(t, jwks.Keys[0].IsPublic())
+
+			// the key set is published as json
+			jwksBytes, err := json.Marshal(jwks)
+			require.NoError(t, err)
+
+			published := &jose.JSONWebKeySet{}
+			require.NoError(t, json.Unmarshal(jwksBytes, published))
+
+			parsed, err := jwt.ParseSigned(token)
+			require.NoError(t, err)
+			require.Equal(t, jwks.Keys[0].KeyID, parsed.Headers[0].KeyID)
+
+			claims := jwt.Claims{}
+			custom := map[string]interface{}{}
+
+			keys := published.Key(parsed.Headers[0].KeyID)
+			require.Len(t, keys, 1)
+
+			require.NoError(t, parsed.Claims(keys[0].Key, &claims, &custom))
+			require.NoError(t, claims.Validate(jwt.Expected{
+				Issuer:   "https://as.example.com",
+				Subject:  "user-123",
+				Audience: jwt.Audience{"client-instance"},
+				Time:     now.Add(time.Minute),
+			}))
+			require.Equal(t, "user@example.com", custom["email"])
+		})
+	}
+}
+
+type unsupportedSigner struct{}
+
+func (s *unsupportedSigner) Public() crypto.PublicKey {
+	return "foo"
+}
+
+func (s *unsupportedSigner) Sign(io.Reader, []byte, crypto.SignerOpts) ([]byte, error) {
+	return nil, errors.New("not supported")
+}
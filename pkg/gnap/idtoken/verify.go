@@ -0,0 +1,129 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package idtoken
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/square/go-jose/v3/jwt"
+)
+
+// ErrInvalidAssertion is returned when an id_token or login credential wasn't issued by the Issuer to the expected
+// client, or can't be parsed.
+var ErrInvalidAssertion = errors.New("invalid subject assertion")
+
+// Assertion is the identity of a user, as asserted by an id_token or login credential that the Issuer issued.
+type Assertion struct {
+	// Sub is the user's subject identifier.
+	Sub string
+	// Email is the user's email address, if the assertion holds it.
+	Email string
+	// Provider is the id of the identity provider that the user logged in with, if the assertion holds it.
+	Provider string
+	// Expired is true if the assertion's lifetime is over. An expired assertion still identifies the user, but no
+	// longer proves that the user logged in recently.
+	Expired bool
+}
+
+type idTokenClaims struct {
+	Email string `json:"email,omitempty"`
+}
+
+type credentialClaims struct {
+	VC *struct {
+		Types   []string `json:"type"`
+		Subject struct {
+			Email    string `json:"email,omitempty"`
+			Provider string `json:"provider,omitempty"`
+		} `json:"credentialSubject"`
+	} `json:"vc"`
+}
+
+// VerifyIDToken verifies that the given id_token was issued by the Issuer to the client instance with the given
+// identifier, and returns the identity that it asserts.
+func (i *Issuer) VerifyIDToken(token, audience string) (*Assertion, error) {
+	extra := &idTokenClaims{}
+
+	a, err := i.verify(token, audience, extra)
+	if err != nil {
+		return nil, err
+	}
+
+	a.Email = extra.Email
+
+	return a, nil
+}
+
+// VerifyCredential verifies that the given JWT-encoded login credential was issued by the Issuer to the client
+// instance with the given identifier, and returns the identity that it asserts.
+func (i *Issuer) VerifyCredential(token, audience string) (*Assertion, error) {
+	extra := &credentialClaims{}
+
+	a, err := i.verify(token, audience, extra)
+	if err != nil {
+		return nil, err
+	}
+
+	if extra.VC == nil || !contains(extra.VC.Types, CredentialType) {
+		return nil, fmt.Errorf("%w: not a %s", ErrInvalidAssertion, CredentialType)
+	}
+
+	a.Sub, err = credentialSubjectSub(a.Sub)
+	if err != nil {
+		return nil, err
+	}
+
+	a.Email = extra.VC.Subject.Email
+	a.Provider = extra.VC.Subject.Provider
+
+	return a, nil
+}
+
+// verify checks the signature and registered claims of the given JWT, parses its other claims into extra, and
+// returns the asserted identity.
+func (i *Issuer) verify(token, audience string, extra interface{}) (*Assertion, error) {
+	parsed, err := jwt.ParseSigned(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidAssertion, err.Error())
+	}
+
+	claims := jwt.Claims{}
+
+	err = parsed.Claims(i.publicKey.Key, &claims, extra)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidAssertion, err.Error())
+	}
+
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: missing sub", ErrInvalidAssertion)
+	}
+
+	err = claims.ValidateWithLeeway(jwt.Expected{
+		Issuer:   i.issuer,
+		Audience: jwt.Audience{audience},
+		Time:     i.now(),
+	}, jwt.DefaultLeeway)
+	if err != nil && !errors.Is(err, jwt.ErrExpired) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidAssertion, err.Error())
+	}
+
+	return &Assertion{
+		Sub:     claims.Subject,
+		Expired: err != nil,
+	}, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
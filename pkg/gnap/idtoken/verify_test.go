@@ -0,0 +1,149 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package idtoken
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/square/go-jose/v3/jwt"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIssuer_VerifyIDToken(t *testing.T) {
+	issuer := newIssuer(t)
+
+	token, err := issuer.Issue("user-123", "client-instance", map[string]string{"email": "user@example.com"})
+	require.NoError(t, err)
+
+	t.Run("success", func(t *testing.T) {
+		a, err := issuer.VerifyIDToken(token, "client-instance")
+		require.NoError(t, err)
+		require.Equal(t, &Assertion{Sub: "user-123", Email: "user@example.com"}, a)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		now := time.Now()
+
+		expiredIssuer := *issuer
+		expiredIssuer.now = func() time.Time { return now.Add(time.Hour) }
+
+		a, err := expiredIssuer.VerifyIDToken(token, "client-instance")
+		require.NoError(t, err)
+		require.True(t, a.Expired)
+		require.Equal(t, "user-123", a.Sub)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		other := newIssuer(t)
+
+		otherToken, err := other.Issue("user-123", "client-instance", nil)
+		require.NoError(t, err)
+
+		noSub, err := issuer.Issue("", "client-instance", nil)
+		require.NoError(t, err)
+
+		otherIssuer := *issuer
+		otherIssuer.issuer = "https://other.example.com"
+
+		tests := []struct {
+			name     string
+			issuer   *Issuer
+			token    string
+			audience string
+			err      string
+		}{
+			{name: "not a jwt", issuer: issuer, token: "foo", audience: "client-instance", err: "compact JWS format"},
+			{name: "wrong audience", issuer: issuer, token: token, audience: "other-client", err: "audience"},
+			{name: "other key", issuer: issuer, token: otherToken, audience: "client-instance", err: "cryptographic"},
+			{name: "other issuer", issuer: &otherIssuer, token: token, audience: "client-instance", err: "issuer"},
+			{name: "missing sub", issuer: issuer, token: noSub, audience: "client-instance", err: "missing sub"},
+		}
+
+		for _, tt := range tests {
+			tc := tt
+
+			t.Run(tc.name, func(t *testing.T) {
+				_, err := tc.issuer.VerifyIDToken(tc.token, tc.audience)
+				require.ErrorIs(t, err, ErrInvalidAssertion)
+				require.Contains(t, err.Error(), tc.err)
+			})
+		}
+	})
+}
+
+func TestIssuer_VerifyCredential(t *testing.T) {
+	issuer := newIssuer(t)
+
+	t.Run("success", func(t *testing.T) {
+		vc, err := issuer.IssueCredential("user-123", "client-instance", map[string]string{
+			"email":    "user@example.com",
+			"provider": "google",
+		})
+		require.NoError(t, err)
+
+		a, err := issuer.VerifyCredential(vc, "client-instance")
+		require.NoError(t, err)
+		require.Equal(t, &Assertion{Sub: "user-123", Email: "user@example.com", Provider: "google"}, a)
+	})
+
+	t.Run("sub that isn't a URI", func(t *testing.T) {
+		vc, err := issuer.IssueCredential("google|user 123/a", "client-instance", nil)
+		require.NoError(t, err)
+
+		a, err := issuer.VerifyCredential(vc, "client-instance")
+		require.NoError(t, err)
+		require.Equal(t, "google|user 123/a", a.Sub)
+	})
+
+	t.Run("unexpected credential subject", func(t *testing.T) {
+		token, err := jwt.Signed(issuer.signer).
+			Claims(&jwt.Claims{
+				Issuer:   "https://as.example.com",
+				Subject:  "user-123",
+				Audience: jwt.Audience{"client-instance"},
+			}).
+			Claims(map[string]interface{}{"vc": map[string]interface{}{"type": []string{CredentialType}}}).
+			CompactSerialize()
+		require.NoError(t, err)
+
+		_, err = issuer.VerifyCredential(token, "client-instance")
+		require.ErrorIs(t, err, ErrInvalidAssertion)
+		require.Contains(t, err.Error(), "unexpected credential subject")
+	})
+
+	t.Run("not a login credential", func(t *testing.T) {
+		token, err := issuer.Issue("user-123", "client-instance", nil)
+		require.NoError(t, err)
+
+		_, err = issuer.VerifyCredential(token, "client-instance")
+		require.ErrorIs(t, err, ErrInvalidAssertion)
+		require.Contains(t, err.Error(), "not a LoginCredential")
+	})
+
+	t.Run("wrong audience", func(t *testing.T) {
+		vc, err := issuer.IssueCredential("user-123", "client-instance", nil)
+		require.NoError(t, err)
+
+		_, err = issuer.VerifyCredential(vc, "other-client")
+		require.ErrorIs(t, err, ErrInvalidAssertion)
+	})
+}
+
+func newIssuer(t *testing.T) *Issuer {
+	t.Helper()
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	issuer, err := New(&Config{Issuer: "https://as.example.com", SigningKey: edKey})
+	require.NoError(t, err)
+
+	return issuer
+}
@@ -0,0 +1,125 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package app
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/trustbloc/auth/pkg/gnap/api"
+	"github.com/trustbloc/auth/spi/gnap"
+)
+
+// Placeholders of the deep-link templates, which are replaced with query-escaped values.
+const (
+	// InteractionPlaceholder is replaced with the flow ID of the interaction, which the app completes.
+	InteractionPlaceholder = "{interaction}"
+	// ServerPlaceholder is replaced with the public base url of the AS, which the app calls to complete the
+	// interaction.
+	ServerPlaceholder = "{as}"
+)
+
+/*
+InteractHandler handles the GNAP app interaction start mode, for native wallets that launch a companion
+authenticator app instead of a browser.
+
+The app url is a deep link, expanded from a template that is configured per client, which passes the interaction's
+flow ID to the app. The user logs in to the app, which completes the interaction of the wrapped InteractionHandler
+through an authenticated API of the AS. The interaction details record the app start mode and the companion app's
+client instance identifier, so that only the access tokens of the client's companion app can complete it.
+
+Interactions that don't use the app start mode are handled by the wrapped InteractionHandler alone.
+*/
+type InteractHandler struct {
+	api.InteractionHandler
+	templates  map[string]string
+	appClients map[string]string
+}
+
+// Config startup configuration for InteractHandler.
+type Config struct {
+	// InteractionHandler prepares and completes the interactions that the app runs.
+	InteractionHandler api.InteractionHandler
+	// Templates maps client instance identifiers to the deep-link templates of their companion apps. A template
+	// must hold InteractionPlaceholder, and may hold ServerPlaceholder. Clients without a template can't use the
+	// app start mode.
+	Templates map[string]string
+	// AppClients maps client instance identifiers to the instance identifiers that their companion apps are
+	// registered with. A client without an entry is its own companion app.
+	AppClients map[string]string
+}
+
+// New creates a GNAP app interaction handler.
+func New(config *Config) (*InteractHandler, error) {
+	for clientID, template := range config.Templates {
+		if !strings.Contains(template, InteractionPlaceholder) {
+			return nil, fmt.Errorf("app template of client %s is missing %s", clientID, InteractionPlaceholder)
+		}
+
+		_, err := url.Parse(template)
+		if err != nil {
+			return nil, fmt.Errorf("parsing app template of client %s: %w", clientID, err)
+		}
+	}
+
+	return &InteractHandler{
+		InteractionHandler: config.InteractionHandler,
+		templates:          config.Templates,
+		appClients:         config.AppClients,
+	}, nil
+}
+
+// PrepareInteraction prepares the interaction with the wrapped InteractionHandler, and if the client starts it with
+// the app start mode, returns the deep link that launches the client's companion app.
+func (h *InteractHandler) PrepareInteraction(
+	clientInteract *gnap.RequestInteract,
+	requestURI, baseURL string,
+	requestedTokens []*api.ExpiringTokenRequest,
+	details *api.InteractionDetails,
+) (*gnap.ResponseInteract, string, error) {
+	if !api.HasStartMode(clientInteract, api.StartModeApp) {
+		return h.InteractionHandler.PrepareInteraction(clientInteract, requestURI, baseURL, requestedTokens, details)
+	}
+
+	appDetails := api.InteractionDetails{}
+
+	if details != nil {
+		appDetails = *details
+	}
+
+	template, ok := h.templates[appDetails.ClientID]
+	if !ok {
+		return nil, "", fmt.Errorf("%w: client %s has no app", api.ErrInvalidInteraction, appDetails.ClientID)
+	}
+
+	appDetails.App = true
+	appDetails.AppClientID = appDetails.ClientID
+
+	if appClientID, ok := h.appClients[appDetails.ClientID]; ok {
+		appDetails.AppClientID = appClientID
+	}
+
+	res, flowID, err := h.InteractionHandler.PrepareInteraction(clientInteract, requestURI, baseURL, requestedTokens,
+		&appDetails)
+	if err != nil {
+		return nil, "", err
+	}
+
+	out := *res
+
+	out.App = strings.NewReplacer(
+		InteractionPlaceholder, url.QueryEscape(flowID),
+		ServerPlaceholder, url.QueryEscape(baseURL),
+	).Replace(template)
+
+	if !api.HasStartMode(clientInteract, api.StartModeRedirect) {
+		out.Redirect = ""
+	}
+
+	return &out, flowID, nil
+}
@@ -0,0 +1,142 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package app
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/auth/pkg/gnap/api"
+	"github.com/trustbloc/auth/pkg/internal/common/mockinteract"
+	"github.com/trustbloc/auth/spi/gnap"
+)
+
+const template = "wallet-authenticator://interact?as={as}&interaction={interaction}"
+
+func TestNew(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		h, err := New(config())
+		require.NoError(t, err)
+		require.NotNil(t, h)
+	})
+
+	t.Run("template without interaction", func(t *testing.T) {
+		_, err := New(&Config{Templates: map[string]string{"client1": "wallet-authenticator://interact"}})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "missing {interaction}")
+	})
+
+	t.Run("invalid template", func(t *testing.T) {
+		_, err := New(&Config{Templates: map[string]string{"client1": "\u007f{interaction}"}})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "parsing app template of client client1")
+	})
+}
+
+func TestInteractHandler_PrepareInteraction(t *testing.T) {
+	details := &api.InteractionDetails{ClientID: "client1"}
+
+	t.Run("app", func(t *testing.T) {
+		conf := config()
+
+		h, err := New(conf)
+		require.NoError(t, err)
+
+		res, flowID, err := h.PrepareInteraction(&gnap.RequestInteract{Start: []string{"app"}}, "",
+			"https://as.example.com/auth", nil, details)
+		require.NoError(t, err)
+		require.Equal(t, "flow/id", flowID)
+		require.Equal(t, &gnap.ResponseInteract{
+			App:    "wallet-authenticator://interact?as=https%3A%2F%2Fas.example.com%2Fauth&interaction=flow%2Fid",
+			Finish: "nonce",
+		}, res)
+
+		prepared := conf.InteractionHandler.(*mockinteract.InteractHandler).PrepareArgs
+		require.Equal(t, &api.InteractionDetails{ClientID: "client1", App: true, AppClientID: "client1"}, prepared)
+		require.False(t, details.App)
+	})
+
+	t.Run("companion app client", func(t *testing.T) {
+		conf := config()
+		conf.AppClients = map[string]string{"client1": "authenticator"}
+
+		h, err := New(conf)
+		require.NoError(t, err)
+
+		_, _, err = h.PrepareInteraction(&gnap.RequestInteract{Start: []string{"app"}}, "", "", nil, details)
+		require.NoError(t, err)
+
+		prepared := conf.InteractionHandler.(*mockinteract.InteractHandler).PrepareArgs
+		require.True(t, prepared.App)
+		require.Equal(t, "authenticator", prepared.AppClientID)
+	})
+
+	t.Run("app and redirect", func(t *testing.T) {
+		h, err := New(config())
+		require.NoError(t, err)
+
+		res, _, err := h.PrepareInteraction(&gnap.RequestInteract{Start: []string{"redirect", "app"}}, "",
+			"https://as.example.com", nil, details)
+		require.NoError(t, err)
+		require.Equal(t, "https://as.example.com/interact?txnID=foo", res.Redirect)
+		require.NotEmpty(t, res.App)
+	})
+
+	t.Run("redirect only", func(t *testing.T) {
+		conf := config()
+
+		h, err := New(conf)
+		require.NoError(t, err)
+
+		res, _, err := h.PrepareInteraction(&gnap.RequestInteract{Start: []string{"redirect"}}, "",
+			"https://as.example.com", nil, details)
+		require.NoError(t, err)
+		require.Equal(t, "https://as.example.com/interact?txnID=foo", res.Redirect)
+		require.Empty(t, res.App)
+
+		prepared := conf.InteractionHandler.(*mockinteract.InteractHandler).PrepareArgs
+		require.False(t, prepared.App)
+		require.Empty(t, prepared.AppClientID)
+	})
+
+	t.Run("client without app", func(t *testing.T) {
+		h, err := New(config())
+		require.NoError(t, err)
+
+		_, _, err = h.PrepareInteraction(&gnap.RequestInteract{Start: []string{"app"}}, "", "", nil,
+			&api.InteractionDetails{ClientID: "client2"})
+		require.ErrorIs(t, err, api.ErrInvalidInteraction)
+
+		_, _, err = h.PrepareInteraction(&gnap.RequestInteract{Start: []string{"app"}}, "", "", nil, nil)
+		require.ErrorIs(t, err, api.ErrInvalidInteraction)
+	})
+
+	t.Run("wrapped handler error", func(t *testing.T) {
+		expectErr := errors.New("expected error")
+
+		conf := config()
+		conf.InteractionHandler = &mockinteract.InteractHandler{PrepareErr: expectErr}
+
+		h, err := New(conf)
+		require.NoError(t, err)
+
+		_, _, err = h.PrepareInteraction(&gnap.RequestInteract{Start: []string{"app"}}, "", "", nil, details)
+		require.ErrorIs(t, err, expectErr)
+	})
+}
+
+func config() *Config {
+	return &Config{
+		InteractionHandler: &mockinteract.InteractHandler{
+			PrepareVal:    &gnap.ResponseInteract{Redirect: "https://as.example.com/interact?txnID=foo", Finish: "nonce"},
+			PrepareFlowID: "flow/id",
+		},
+		Templates: map[string]string{"client1": template},
+	}
+}
@@ -0,0 +1,201 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package push
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff"
+	"github.com/hyperledger/aries-framework-go/pkg/common/log"
+)
+
+var logger = log.New("gnap/interact-push") // nolint:gochecknoglobals
+
+const (
+	// DefaultMaxRetries is the default number of times that a failed push is retried.
+	DefaultMaxRetries = 5
+	// DefaultRetryInterval is the default time before the first retry of a failed push.
+	DefaultRetryInterval = time.Second
+	// DefaultTimeout is the default time that a push request may take.
+	DefaultTimeout = 10 * time.Second
+)
+
+// Config holds the configuration of a Pusher.
+type Config struct {
+	// AllowedURIs are the client URIs that interaction results may be pushed to. A client URI is allowed if it has
+	// the scheme and host of an allowed URI, and its cleaned path is the allowed URI's path or below it.
+	AllowedURIs []string
+	// HTTPClient sends the push requests. Defaults to http.DefaultClient. Redirects are never followed, since they
+	// could send the interaction result to a URI that isn't allowed.
+	HTTPClient *http.Client
+	// Timeout bounds each push request, including reading the client's response, so that a hung client doesn't
+	// hold the push forever. Defaults to DefaultTimeout.
+	Timeout time.Duration
+	// MaxRetries is the number of times that a failed push is retried. Defaults to DefaultMaxRetries.
+	MaxRetries uint64
+	// RetryInterval is the time before the first retry, which doubles on every retry. Defaults to
+	// DefaultRetryInterval.
+	RetryInterval time.Duration
+}
+
+// Pusher implements the GNAP push interaction finish method, sending the interaction reference and hash to the
+// client when the user completes an interaction.
+type Pusher struct {
+	allowed       []*url.URL
+	httpClient    *http.Client
+	timeout       time.Duration
+	maxRetries    uint64
+	retryInterval time.Duration
+}
+
+// pushRequest https://www.rfc-editor.org/rfc/rfc9635.html#section-4.2.2
+type pushRequest struct {
+	Hash        string `json:"hash"`
+	InteractRef string `json:"interact_ref"`
+}
+
+// New creates a Pusher.
+func New(config *Config) (*Pusher, error) {
+	p := &Pusher{
+		timeout:       config.Timeout,
+		maxRetries:    config.MaxRetries,
+		retryInterval: config.RetryInterval,
+	}
+
+	for _, uri := range config.AllowedURIs {
+		u, err := url.Parse(uri)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("invalid allowed push uri '%s'", uri)
+		}
+
+		p.allowed = append(p.allowed, u)
+	}
+
+	httpClient := http.DefaultClient
+	if config.HTTPClient != nil {
+		httpClient = config.HTTPClient
+	}
+
+	// copy the client, so that refusing redirects doesn't change the client of the caller.
+	noRedirects := *httpClient
+	noRedirects.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	p.httpClient = &noRedirects
+
+	if p.timeout == 0 {
+		p.timeout = DefaultTimeout
+	}
+
+	if p.maxRetries == 0 {
+		p.maxRetries = DefaultMaxRetries
+	}
+
+	if p.retryInterval == 0 {
+		p.retryInterval = DefaultRetryInterval
+	}
+
+	return p, nil
+}
+
+// Allowed returns true iff interaction results may be pushed to the given client URI.
+func (p *Pusher) Allowed(uri string) bool {
+	u, err := url.Parse(uri)
+	if err != nil || u.User != nil {
+		return false
+	}
+
+	for _, allowed := range p.allowed {
+		if u.Scheme == allowed.Scheme && u.Host == allowed.Host && isPathWithin(u.Path, allowed.Path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isPathWithin returns true iff the cleaned path p is the cleaned path dir, or a path below it.
+func isPathWithin(p, dir string) bool {
+	p = path.Clean("/" + p)
+	dir = path.Clean("/" + dir)
+
+	return dir == "/" || p == dir || strings.HasPrefix(p, dir+"/")
+}
+
+// Push sends the interaction reference and hash to the given client URI, retrying with an exponential backoff if
+// the client can't be reached or fails with a server error.
+func (p *Pusher) Push(uri, interactRef, hash string) error {
+	if !p.Allowed(uri) {
+		return fmt.Errorf("push uri '%s' isn't allowed", uri)
+	}
+
+	body, err := json.Marshal(&pushRequest{Hash: hash, InteractRef: interactRef})
+	if err != nil {
+		return fmt.Errorf("marshaling push request: %w", err)
+	}
+
+	retry := backoff.NewExponentialBackOff()
+	retry.InitialInterval = p.retryInterval
+	retry.MaxElapsedTime = 0
+
+	err = backoff.RetryNotify(
+		func() error {
+			return p.send(uri, body)
+		},
+		backoff.WithMaxRetries(retry, p.maxRetries),
+		func(retryErr error, t time.Duration) {
+			logger.Warnf("failed to push interaction result to %s, will retry in %s: %s", uri, t, retryErr)
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("pushing interaction result to %s: %w", uri, err)
+	}
+
+	return nil
+}
+
+func (p *Pusher) send(uri string, body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uri, bytes.NewReader(body))
+	if err != nil {
+		return backoff.Permanent(fmt.Errorf("creating push request: %w", err))
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending push request: %w", err)
+	}
+
+	defer func() {
+		if e := resp.Body.Close(); e != nil {
+			logger.Warnf("failed to close push response body: %s", e.Error())
+		}
+	}()
+
+	switch {
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return fmt.Errorf("client responded with status %d", resp.StatusCode)
+	case resp.StatusCode >= http.StatusMultipleChoices:
+		// redirects aren't followed, so they fail like client errors.
+		return backoff.Permanent(fmt.Errorf("client responded with status %d", resp.StatusCode))
+	}
+
+	return nil
+}
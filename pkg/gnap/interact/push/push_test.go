@@ -0,0 +1,197 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package push
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		p, err := New(&Config{})
+		require.NoError(t, err)
+		require.Equal(t, http.DefaultClient.Transport, p.httpClient.Transport)
+		require.NotNil(t, p.httpClient.CheckRedirect)
+		require.Nil(t, http.DefaultClient.CheckRedirect)
+		require.Equal(t, DefaultTimeout, p.timeout)
+		require.Equal(t, uint64(DefaultMaxRetries), p.maxRetries)
+		require.Equal(t, DefaultRetryInterval, p.retryInterval)
+	})
+
+	t.Run("invalid allowed uri", func(t *testing.T) {
+		_, err := New(&Config{AllowedURIs: []string{"client.example.com/push"}})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid allowed push uri")
+	})
+}
+
+func TestPusher_Allowed(t *testing.T) {
+	p, err := New(&Config{AllowedURIs: []string{"https://client.example.com/push", "https://other.example.com"}})
+	require.NoError(t, err)
+
+	tests := []struct {
+		uri     string
+		allowed bool
+	}{
+		{uri: "https://client.example.com/push", allowed: true},
+		{uri: "https://client.example.com/push/txn-123?foo=bar", allowed: true},
+		{uri: "https://other.example.com/anything", allowed: true},
+		{uri: "https://client.example.com/push/../push/txn-123", allowed: true},
+		{uri: "https://client.example.com/other", allowed: false},
+		{uri: "https://client.example.com/push-evil", allowed: false},
+		{uri: "https://client.example.com/push/../admin", allowed: false},
+		{uri: "https://client.example.com/push/%2e%2e/admin", allowed: false},
+		{uri: "http://client.example.com/push", allowed: false},
+		{uri: "https://client.example.com.evil.com/push", allowed: false},
+		{uri: "https://user@client.example.com/push", allowed: false},
+		{uri: "://", allowed: false},
+	}
+
+	for _, tc := range tests {
+		require.Equal(t, tc.allowed, p.Allowed(tc.uri), tc.uri)
+	}
+}
+
+func TestPusher_Push(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		received := make(chan *pushRequest, 1)
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, http.MethodPost, r.Method)
+			require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+			req := &pushRequest{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(req))
+
+			received <- req
+		}))
+		defer srv.Close()
+
+		p, err := New(&Config{AllowedURIs: []string{srv.URL}})
+		require.NoError(t, err)
+
+		require.NoError(t, p.Push(srv.URL+"/push", "interact-ref", "hash"))
+		require.Equal(t, &pushRequest{InteractRef: "interact-ref", Hash: "hash"}, <-received)
+	})
+
+	t.Run("retries server errors", func(t *testing.T) {
+		var calls int32
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}))
+		defer srv.Close()
+
+		p, err := New(&Config{AllowedURIs: []string{srv.URL}, RetryInterval: time.Millisecond})
+		require.NoError(t, err)
+
+		require.NoError(t, p.Push(srv.URL, "interact-ref", "hash"))
+		require.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("gives up after max retries", func(t *testing.T) {
+		var calls int32
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer srv.Close()
+
+		p, err := New(&Config{AllowedURIs: []string{srv.URL}, MaxRetries: 2, RetryInterval: time.Millisecond})
+		require.NoError(t, err)
+
+		err = p.Push(srv.URL, "interact-ref", "hash")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "status 500")
+		require.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("doesn't retry client errors", func(t *testing.T) {
+		var calls int32
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer srv.Close()
+
+		p, err := New(&Config{AllowedURIs: []string{srv.URL}, RetryInterval: time.Millisecond})
+		require.NoError(t, err)
+
+		err = p.Push(srv.URL, "interact-ref", "hash")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "status 400")
+		require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("times out hung client", func(t *testing.T) {
+		release := make(chan struct{})
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}))
+		defer srv.Close()
+		defer close(release)
+
+		p, err := New(&Config{
+			AllowedURIs:   []string{srv.URL},
+			Timeout:       10 * time.Millisecond,
+			MaxRetries:    1,
+			RetryInterval: time.Millisecond,
+		})
+		require.NoError(t, err)
+
+		err = p.Push(srv.URL, "interact-ref", "hash")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "context deadline exceeded")
+	})
+
+	t.Run("doesn't follow redirects", func(t *testing.T) {
+		var redirected int32
+
+		other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&redirected, 1)
+		}))
+		defer other.Close()
+
+		var calls int32
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			http.Redirect(w, r, other.URL+"/steal", http.StatusTemporaryRedirect)
+		}))
+		defer srv.Close()
+
+		p, err := New(&Config{AllowedURIs: []string{srv.URL}, RetryInterval: time.Millisecond})
+		require.NoError(t, err)
+
+		err = p.Push(srv.URL+"/push", "interact-ref", "hash")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "status 307")
+		require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+		require.Equal(t, int32(0), atomic.LoadInt32(&redirected))
+	})
+
+	t.Run("uri not allowed", func(t *testing.T) {
+		p, err := New(&Config{AllowedURIs: []string{"https://client.example.com/push"}})
+		require.NoError(t, err)
+
+		err = p.Push("https://other.example.com/push", "interact-ref", "hash")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "isn't allowed")
+	})
+}
@@ -9,29 +9,54 @@ package redirect
 import (
 	"crypto"
 	"crypto/rand"
+	_ "crypto/sha256" // init sha-256 hash.
+	_ "crypto/sha512" // init sha-384 and sha-512 hashes.
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 
+	"github.com/hyperledger/aries-framework-go/pkg/common/log"
 	"github.com/hyperledger/aries-framework-go/spi/storage"
 	_ "golang.org/x/crypto/sha3" // nolint:gci // init sha3 hash.
 
 	"github.com/trustbloc/auth/pkg/gnap/api"
+	"github.com/trustbloc/auth/pkg/gnap/interact/push"
 	"github.com/trustbloc/auth/spi/gnap"
 )
 
 // InteractHandler handles GNAP redirect-based user login and consent.
 type InteractHandler struct {
-	interactBasePath string
-	txnStore         storage.Store
+	interactPath string
+	txnStore     storage.Store
+	lifetime     time.Duration
+	now          func() time.Time
+	pusher       *push.Pusher
 }
 
 // Config startup configuration for InteractHandler.
 type Config struct {
-	StoreProvider    storage.Provider
-	InteractBasePath string
+	StoreProvider storage.Provider
+	// InteractPath is the path of the interaction endpoint, relative to the server's public base url.
+	InteractPath string
+	// Lifetime is the time that the user has to complete an interaction. Defaults to DefaultLifetime.
+	Lifetime time.Duration
+	// Pusher sends interaction results to clients that request the push finish method. If nil, the push finish
+	// method isn't supported.
+	Pusher *push.Pusher
 }
 
+// DefaultLifetime is the default time that the user has to complete an interaction.
+const DefaultLifetime = 15 * time.Minute
+
+var logger = log.New("gnap/interact-redirect") // nolint:gochecknoglobals
+
+var (
+	errTxnExpired       = errors.New("interaction expired")
+	errInvalidSelection = errors.New("invalid consent selection")
+)
+
 const (
 	txnDBName         = "gnap_interact_redirect_store"
 	txnIDPrefix       = "t."
@@ -42,9 +67,15 @@ const (
 
 type txnData struct {
 	api.ConsentResult
-	Interact    *gnap.RequestInteract `json:"interact,omitempty"`
-	RequestURL  string                `json:"req-url,omitempty"`
-	ServerNonce string                `json:"server-nonce,omitempty"`
+	Interact    *gnap.RequestInteract   `json:"interact,omitempty"`
+	RequestURL  string                  `json:"req-url,omitempty"`
+	ServerNonce string                  `json:"server-nonce,omitempty"`
+	Details     *api.InteractionDetails `json:"details,omitempty"`
+	Expires     time.Time               `json:"expires"`
+	// ClientID, App and AppClientID persist the interaction details that aren't shown to the user.
+	ClientID    string `json:"client-id,omitempty"`
+	App         bool   `json:"app,omitempty"`
+	AppClientID string `json:"app-client-id,omitempty"`
 }
 
 // New creates a GNAP redirect-based user login&consent interaction handler.
@@ -54,29 +85,45 @@ func New(config *Config) (*InteractHandler, error) {
 		return nil, err
 	}
 
+	lifetime := config.Lifetime
+	if lifetime == 0 {
+		lifetime = DefaultLifetime
+	}
+
 	return &InteractHandler{
-		txnStore:         store,
-		interactBasePath: config.InteractBasePath,
+		txnStore:     store,
+		interactPath: config.InteractPath,
+		lifetime:     lifetime,
+		now:          time.Now,
+		pusher:       config.Pusher,
 	}, nil
 }
 
 // TODO consider: split out the interaction hash stuff into a general handler for both redirect & push finish methods.
 
 // PrepareInteraction initializes a redirect-based login&consent interaction,
-// returning the redirect parameters to be sent to the client.
+// returning the redirect parameters to be sent to the client, and the txnID of the interaction.
 func (h InteractHandler) PrepareInteraction(
 	clientInteract *gnap.RequestInteract,
-	requestURI string,
+	requestURI, baseURL string,
 	requestedTokens []*api.ExpiringTokenRequest,
-) (*gnap.ResponseInteract, error) {
+	details *api.InteractionDetails,
+) (*gnap.ResponseInteract, string, error) {
+	if clientInteract != nil && clientInteract.Finish != nil {
+		err := h.validateFinish(clientInteract.Finish)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
 	txnID, err := nonce()
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	serverNonce, err := nonce()
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	txn := &txnData{
@@ -86,22 +133,77 @@ func (h InteractHandler) PrepareInteraction(
 		Interact:    clientInteract,
 		ServerNonce: serverNonce,
 		RequestURL:  requestURI,
+		Details:     details,
+		Expires:     h.now().Add(h.lifetime),
+	}
+
+	if details != nil {
+		txn.ClientID = details.ClientID
+		txn.App = details.App
+		txn.AppClientID = details.AppClientID
 	}
 
 	txnBytes, err := json.Marshal(txn)
 	if err != nil {
-		return nil, fmt.Errorf("marshaling txn data: %w", err)
+		return nil, "", fmt.Errorf("marshaling txn data: %w", err)
 	}
 
 	err = h.txnStore.Put(txnIDPrefix+txnID, txnBytes)
 	if err != nil {
-		return nil, fmt.Errorf("saving txn data: %w", err)
+		return nil, "", fmt.Errorf("saving txn data: %w", err)
 	}
 
 	return &gnap.ResponseInteract{
-		Redirect: h.interactBasePath + txnIDURLQueryPrefix + txnID,
+		Redirect: baseURL + h.interactPath + txnIDURLQueryPrefix + txnID,
 		Finish:   serverNonce,
-	}, nil
+	}, txnID, nil
+}
+
+// validateFinish checks that the handler supports the interaction finish that the client requests.
+func (h InteractHandler) validateFinish(finish *gnap.RequestFinish) error {
+	if _, ok := hashMethods[finish.HashMethod]; !ok {
+		return fmt.Errorf("unsupported hash method %s", finish.HashMethod)
+	}
+
+	switch finish.Method {
+	case api.FinishMethodRedirect:
+		return nil
+	case api.FinishMethodPush:
+		if h.pusher == nil || !h.pusher.Allowed(finish.URI) {
+			return fmt.Errorf("%w: push to '%s' isn't allowed", api.ErrInvalidInteraction, finish.URI)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("%w: unsupported finish method %s", api.ErrInvalidInteraction, finish.Method)
+}
+
+// QueryDetails returns the details of the login&consent interaction with the given txnID.
+func (h InteractHandler) QueryDetails(txnID string) (*api.InteractionDetails, error) {
+	txn, err := h.loadTxn(txnID)
+	if err != nil {
+		return nil, err
+	}
+
+	details := &api.InteractionDetails{}
+
+	if txn.Details != nil {
+		details = txn.Details
+	}
+
+	details.Tokens = nil
+
+	for _, tok := range txn.Tokens {
+		details.Tokens = append(details.Tokens, tok.TokenRequest)
+	}
+
+	details.Expires = txn.Expires
+	details.ClientID = txn.ClientID
+	details.App = txn.App
+	details.AppClientID = txn.AppClientID
+
+	return details, nil
 }
 
 // CompleteInteraction saves an interaction with the given consent data for
@@ -110,31 +212,35 @@ func (h InteractHandler) CompleteInteraction(
 	txnID string,
 	consentSet *api.ConsentResult,
 ) (string, string, *gnap.RequestInteract, error) {
-	txnBytes, err := h.txnStore.Get(txnIDPrefix + txnID)
+	txn, err := h.loadTxn(txnID)
 	if err != nil {
-		return "", "", nil, fmt.Errorf("loading txn data: %w", err)
+		return "", "", nil, err
 	}
 
-	txn := &txnData{}
+	txn.ConsentResult.SubjectData = consentSet.SubjectData
 
-	err = json.Unmarshal(txnBytes, txn)
-	if err != nil {
-		return "", "", nil, fmt.Errorf("parsing txn data: %w", err)
+	if consentSet.Selection != nil {
+		err = txn.applySelection(consentSet.Selection)
+		if err != nil {
+			return "", "", nil, err
+		}
 	}
 
-	txn.ConsentResult.SubjectData = consentSet.SubjectData
-
 	interactRef, err := nonce()
 	if err != nil {
 		return "", "", nil, err
 	}
 
-	hashValue, err := responseHash(txn.Interact.Finish.Nonce, txn.ServerNonce, interactRef, txn.RequestURL)
-	if err != nil {
-		return "", "", nil, fmt.Errorf("creating response hash: %w", err)
+	var hashValue string
+
+	if txn.Interact != nil && txn.Interact.Finish != nil {
+		hashValue, err = responseHash(txn.Interact.Finish, txn.ServerNonce, interactRef, txn.RequestURL)
+		if err != nil {
+			return "", "", nil, fmt.Errorf("creating response hash: %w", err)
+		}
 	}
 
-	txnBytes, err = json.Marshal(txn.ConsentResult)
+	txnBytes, err := json.Marshal(txn.ConsentResult)
 	if err != nil {
 		return "", "", nil, fmt.Errorf("marshaling txn data: %w", err)
 	}
@@ -149,13 +255,112 @@ func (h InteractHandler) CompleteInteraction(
 		return "", "", nil, fmt.Errorf("deleting old txn data: %w", err)
 	}
 
+	if txn.Interact != nil && txn.Interact.Finish != nil && txn.Interact.Finish.Method == api.FinishMethodPush {
+		go h.push(txn.Interact.Finish.URI, interactRef, hashValue)
+	}
+
 	return interactRef, hashValue, txn.Interact, nil
 }
 
-func responseHash(clientNonce, serverNonce, interactRef, requestURI string) (string, error) {
-	hashBase := clientNonce + "\n" + serverNonce + "\n" + interactRef + "\n" + requestURI
+// push sends the interaction result to the client in the background, so that the user doesn't wait for the retries.
+// The pusher's request timeout and retry limit bound how long it runs, even if the client hangs.
+func (h InteractHandler) push(uri, interactRef, hash string) {
+	if h.pusher == nil {
+		logger.Errorf("failed to push interaction result to %s: push isn't supported", uri)
+
+		return
+	}
+
+	err := h.pusher.Push(uri, interactRef, hash)
+	if err != nil {
+		logger.Errorf("failed to push interaction result: %s", err.Error())
+	}
+}
+
+// applySelection keeps the token requests that the user approved in the txn's consent result, and records the
+// token requests and subject keys that the user denied.
+func (txn *txnData) applySelection(selection *api.ConsentSelection) error {
+	approved := make([]bool, len(txn.Tokens))
+
+	for _, i := range selection.Tokens {
+		if i < 0 || i >= len(txn.Tokens) {
+			return fmt.Errorf("%w: no token request %d", errInvalidSelection, i)
+		}
+
+		approved[i] = true
+	}
+
+	var tokens []*api.ExpiringTokenRequest
+
+	for i, tok := range txn.Tokens {
+		if approved[i] {
+			tokens = append(tokens, tok)
+		} else {
+			txn.DeniedTokens = append(txn.DeniedTokens, tok)
+		}
+	}
+
+	txn.Tokens = tokens
+
+	if txn.Details == nil {
+		return nil
+	}
+
+	approvedKeys := map[string]bool{}
+
+	for _, key := range selection.SubjectKeys {
+		approvedKeys[key] = true
+	}
+
+	for _, key := range txn.Details.SubjectKeys {
+		if !approvedKeys[key] {
+			txn.DeniedSubjectKeys = append(txn.DeniedSubjectKeys, key)
+		}
+	}
+
+	return nil
+}
+
+func (h InteractHandler) loadTxn(txnID string) (*txnData, error) {
+	txnBytes, err := h.txnStore.Get(txnIDPrefix + txnID)
+	if err != nil {
+		return nil, fmt.Errorf("loading txn data: %w", err)
+	}
+
+	txn := &txnData{}
+
+	err = json.Unmarshal(txnBytes, txn)
+	if err != nil {
+		return nil, fmt.Errorf("parsing txn data: %w", err)
+	}
+
+	// txns saved by earlier versions of the handler have no expiry.
+	if !txn.Expires.IsZero() && h.now().After(txn.Expires) {
+		return nil, errTxnExpired
+	}
+
+	return txn, nil
+}
+
+// hashMethods are the supported interaction finish hash methods. A client that doesn't send a hash method gets the
+// RFC 9635 default of sha-256. Draft-09 requests are parsed with their default of sha3-512 set explicitly.
+var hashMethods = map[string]crypto.Hash{ // nolint:gochecknoglobals
+	"":         crypto.SHA256,
+	"sha3-512": crypto.SHA3_512,
+	"sha-256":  crypto.SHA256,
+	"sha-384":  crypto.SHA384,
+	"sha-512":  crypto.SHA512,
+}
+
+func responseHash(finish *gnap.RequestFinish, serverNonce, interactRef, requestURI string) (string, error) {
+	hashMethod, ok := hashMethods[finish.HashMethod]
+	if !ok {
+		return "", fmt.Errorf("unsupported hash method %s", finish.HashMethod)
+	}
+
+	hashBase := finish.Nonce + "\n" + serverNonce + "\n" + interactRef + "\n" + requestURI
 
-	hasher := crypto.SHA3_512.New()
+	hasher := hashMethod.New()
 
 	_, err := hasher.Write([]byte(hashBase))
 	if err != nil {
@@ -7,15 +7,23 @@ SPDX-License-Identifier: Apache-2.0
 package redirect
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
 	"github.com/stretchr/testify/require"
 
 	"github.com/trustbloc/auth/pkg/gnap/api"
+	"github.com/trustbloc/auth/pkg/gnap/interact/push"
 	"github.com/trustbloc/auth/pkg/internal/common/mockstorage"
+	"github.com/trustbloc/auth/spi/gnap"
 )
 
 func TestNew(t *testing.T) {
@@ -48,19 +56,66 @@ func TestInteractHandler_PrepareInteraction(t *testing.T) {
 			ErrPut: expectErr,
 		}
 
-		res, err := h.PrepareInteraction(nil, "foo", nil)
+		res, _, err := h.PrepareInteraction(nil, "foo", "https://example.com", nil, nil)
 		require.ErrorIs(t, err, expectErr)
 		require.Nil(t, res)
 	})
 
+	t.Run("unsupported hash method", func(t *testing.T) {
+		h, err := New(config())
+		require.NoError(t, err)
+
+		_, _, err = h.PrepareInteraction(&gnap.RequestInteract{
+			Finish: &gnap.RequestFinish{Method: "redirect", HashMethod: "md5"},
+		}, "foo", "https://example.com", nil, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unsupported hash method md5")
+	})
+
+	t.Run("unsupported finish method", func(t *testing.T) {
+		h, err := New(config())
+		require.NoError(t, err)
+
+		_, _, err = h.PrepareInteraction(&gnap.RequestInteract{
+			Finish: &gnap.RequestFinish{Method: "carrier-pigeon"},
+		}, "foo", "https://example.com", nil, nil)
+		require.ErrorIs(t, err, api.ErrInvalidInteraction)
+	})
+
+	t.Run("push finish", func(t *testing.T) {
+		pusher, err := push.New(&push.Config{AllowedURIs: []string{"https://client.example.com/push"}})
+		require.NoError(t, err)
+
+		noPush, err := New(config())
+		require.NoError(t, err)
+
+		conf := config()
+		conf.Pusher = pusher
+
+		h, err := New(conf)
+		require.NoError(t, err)
+
+		allowed := &gnap.RequestInteract{Finish: &gnap.RequestFinish{Method: "push", URI: "https://client.example.com/push"}}
+		other := &gnap.RequestInteract{Finish: &gnap.RequestFinish{Method: "push", URI: "https://other.example.com/push"}}
+
+		_, _, err = h.PrepareInteraction(allowed, "foo", "https://example.com", nil, nil)
+		require.NoError(t, err)
+
+		_, _, err = h.PrepareInteraction(other, "foo", "https://example.com", nil, nil)
+		require.ErrorIs(t, err, api.ErrInvalidInteraction)
+
+		_, _, err = noPush.PrepareInteraction(allowed, "foo", "https://example.com", nil, nil)
+		require.ErrorIs(t, err, api.ErrInvalidInteraction)
+	})
+
 	t.Run("success", func(t *testing.T) {
 		h, err := New(config())
 		require.NoError(t, err)
 
-		res, err := h.PrepareInteraction(nil, "foo", nil)
+		res, txnID, err := h.PrepareInteraction(nil, "foo", "https://example.com", nil, nil)
 		require.NoError(t, err)
 
-		require.True(t, strings.HasPrefix(res.Redirect, h.interactBasePath))
+		require.Equal(t, "https://example.com/interact-path?txnID="+txnID, res.Redirect)
 		require.NotEmpty(t, res.Finish)
 	})
 }
@@ -145,6 +200,245 @@ func TestInteractHandler_CompleteInteraction(t *testing.T) {
 		_, _, _, err = h.CompleteInteraction("", &api.ConsentResult{})
 		require.NoError(t, err)
 	})
+
+	t.Run("finish hash method", func(t *testing.T) {
+		h, err := New(config())
+		require.NoError(t, err)
+
+		hashes := map[string]int{}
+
+		for method, size := range map[string]int{"": 32, "sha-256": 32, "sha-512": 64, "sha3-512": 64} {
+			res, _, err := h.PrepareInteraction(&gnap.RequestInteract{
+				Finish: &gnap.RequestFinish{Method: "redirect", Nonce: "foo", HashMethod: method},
+			}, "https://example.com/gnap", "https://example.com", nil, nil)
+			require.NoError(t, err)
+
+			txnID := strings.TrimPrefix(res.Redirect, "https://example.com/interact-path?txnID=")
+
+			_, hash, _, err := h.CompleteInteraction(txnID, &api.ConsentResult{})
+			require.NoError(t, err)
+
+			hashBytes, err := base64.RawURLEncoding.DecodeString(hash)
+			require.NoError(t, err)
+			require.Len(t, hashBytes, size)
+
+			hashes[hash]++
+		}
+
+		require.Len(t, hashes, 4)
+	})
+
+	t.Run("RFC 9635 default hash method", func(t *testing.T) {
+		h, err := New(config())
+		require.NoError(t, err)
+
+		res, _, err := h.PrepareInteraction(&gnap.RequestInteract{
+			Finish: &gnap.RequestFinish{Method: "redirect", Nonce: "client-nonce"},
+		}, "https://example.com/gnap", "https://example.com", nil, nil)
+		require.NoError(t, err)
+
+		txnID := strings.TrimPrefix(res.Redirect, "https://example.com/interact-path?txnID=")
+
+		interactRef, hash, _, err := h.CompleteInteraction(txnID, &api.ConsentResult{})
+		require.NoError(t, err)
+
+		expected := sha256.Sum256([]byte("client-nonce\n" + res.Finish + "\n" + interactRef + "\nhttps://example.com/gnap"))
+		require.Equal(t, base64.RawURLEncoding.EncodeToString(expected[:]), hash)
+	})
+
+	t.Run("no finish method", func(t *testing.T) {
+		h, err := New(config())
+		require.NoError(t, err)
+
+		res, _, err := h.PrepareInteraction(&gnap.RequestInteract{
+			Start: []string{"redirect"},
+		}, "foo", "https://example.com", nil, nil)
+		require.NoError(t, err)
+
+		txnID := strings.TrimPrefix(res.Redirect, "https://example.com/interact-path?txnID=")
+
+		_, hash, _, err := h.CompleteInteraction(txnID, &api.ConsentResult{})
+		require.NoError(t, err)
+		require.Empty(t, hash)
+	})
+
+	t.Run("push finish", func(t *testing.T) {
+		received := make(chan map[string]string, 1)
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body := map[string]string{}
+
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+
+				return
+			}
+
+			received <- body
+		}))
+		defer srv.Close()
+
+		pusher, err := push.New(&push.Config{AllowedURIs: []string{srv.URL}})
+		require.NoError(t, err)
+
+		conf := config()
+		conf.Pusher = pusher
+
+		h, err := New(conf)
+		require.NoError(t, err)
+
+		res, _, err := h.PrepareInteraction(&gnap.RequestInteract{
+			Finish: &gnap.RequestFinish{Method: "push", URI: srv.URL + "/push", Nonce: "foo"},
+		}, "https://example.com/gnap", "https://example.com", nil, nil)
+		require.NoError(t, err)
+
+		txnID := strings.TrimPrefix(res.Redirect, "https://example.com/interact-path?txnID=")
+
+		interactRef, hash, _, err := h.CompleteInteraction(txnID, &api.ConsentResult{})
+		require.NoError(t, err)
+
+		select {
+		case body := <-received:
+			require.Equal(t, map[string]string{"interact_ref": interactRef, "hash": hash}, body)
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "interaction result wasn't pushed")
+		}
+	})
+
+	t.Run("partial consent", func(t *testing.T) {
+		h, err := New(config())
+		require.NoError(t, err)
+
+		tokens := []*api.ExpiringTokenRequest{
+			{TokenRequest: gnap.TokenRequest{Label: "foo"}},
+			{TokenRequest: gnap.TokenRequest{Label: "bar"}},
+			{TokenRequest: gnap.TokenRequest{Label: "baz"}},
+		}
+
+		res, _, err := h.PrepareInteraction(nil, "foo", "https://example.com", tokens,
+			&api.InteractionDetails{SubjectKeys: []string{"email", "name"}})
+		require.NoError(t, err)
+
+		txnID := strings.TrimPrefix(res.Redirect, "https://example.com/interact-path?txnID=")
+
+		interactRef, _, _, err := h.CompleteInteraction(txnID, &api.ConsentResult{
+			SubjectData: map[string]string{"sub": "user-123"},
+			Selection:   &api.ConsentSelection{Tokens: []int{0, 2}, SubjectKeys: []string{"name"}},
+		})
+		require.NoError(t, err)
+
+		consent, err := h.QueryInteraction(interactRef)
+		require.NoError(t, err)
+		require.Len(t, consent.Tokens, 2)
+		require.Equal(t, "foo", consent.Tokens[0].Label)
+		require.Equal(t, "baz", consent.Tokens[1].Label)
+		require.Len(t, consent.DeniedTokens, 1)
+		require.Equal(t, "bar", consent.DeniedTokens[0].Label)
+		require.Equal(t, []string{"email"}, consent.DeniedSubjectKeys)
+		require.Equal(t, map[string]string{"sub": "user-123"}, consent.SubjectData)
+	})
+
+	t.Run("invalid consent selection", func(t *testing.T) {
+		h, err := New(config())
+		require.NoError(t, err)
+
+		res, _, err := h.PrepareInteraction(nil, "foo", "https://example.com",
+			[]*api.ExpiringTokenRequest{{TokenRequest: gnap.TokenRequest{Label: "foo"}}}, nil)
+		require.NoError(t, err)
+
+		txnID := strings.TrimPrefix(res.Redirect, "https://example.com/interact-path?txnID=")
+
+		_, _, _, err = h.CompleteInteraction(txnID, &api.ConsentResult{
+			Selection: &api.ConsentSelection{Tokens: []int{1}},
+		})
+		require.ErrorIs(t, err, errInvalidSelection)
+	})
+}
+
+func TestInteractHandler_QueryDetails(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		h, err := New(config())
+		require.NoError(t, err)
+
+		now := time.Date(2021, time.October, 1, 12, 0, 0, 0, time.UTC)
+		h.now = func() time.Time { return now }
+
+		tokens := []*api.ExpiringTokenRequest{{
+			TokenRequest: gnap.TokenRequest{
+				Label:  "foo",
+				Access: []gnap.TokenAccess{*gnap.NewTokenAccess("example-token-type").WithActions("read")},
+			},
+		}}
+
+		details := &api.InteractionDetails{
+			SubjectKeys:    []string{"email"},
+			Client:         &gnap.ClientDisplay{Name: "Wallet", URI: "https://wallet.example.com"},
+			ClientID:       "client1",
+			App:            true,
+			AppClientID:    "authenticator",
+			ClientVerified: true,
+			User:           &api.UserHint{Sub: "user-123", Provider: "google"},
+		}
+
+		res, _, err := h.PrepareInteraction(nil, "foo", "https://example.com", tokens, details)
+		require.NoError(t, err)
+
+		txnID := strings.TrimPrefix(res.Redirect, "https://example.com/interact-path?txnID=")
+
+		got, err := h.QueryDetails(txnID)
+		require.NoError(t, err)
+		require.Len(t, got.Tokens, 1)
+		require.Equal(t, "foo", got.Tokens[0].Label)
+		require.Len(t, got.Tokens[0].Access, 1)
+		require.True(t, got.Tokens[0].Access[0].IsSubsetOf(&tokens[0].Access[0]))
+		require.Equal(t, []string{"email"}, got.SubjectKeys)
+		require.Equal(t, details.Client, got.Client)
+		require.True(t, got.ClientVerified)
+		require.Equal(t, details.User, got.User)
+		require.Equal(t, "client1", got.ClientID)
+		require.True(t, got.App)
+		require.Equal(t, "authenticator", got.AppClientID)
+		require.True(t, now.Add(DefaultLifetime).Equal(got.Expires))
+	})
+
+	t.Run("no details", func(t *testing.T) {
+		h, err := New(config())
+		require.NoError(t, err)
+
+		res, _, err := h.PrepareInteraction(nil, "foo", "https://example.com", nil, nil)
+		require.NoError(t, err)
+
+		got, err := h.QueryDetails(strings.TrimPrefix(res.Redirect, "https://example.com/interact-path?txnID="))
+		require.NoError(t, err)
+		require.Nil(t, got.Tokens)
+		require.Nil(t, got.Client)
+		require.False(t, got.Expires.IsZero())
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		conf := config()
+		conf.Lifetime = time.Minute
+
+		h, err := New(conf)
+		require.NoError(t, err)
+
+		res, _, err := h.PrepareInteraction(nil, "foo", "https://example.com", nil, nil)
+		require.NoError(t, err)
+
+		h.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+
+		_, err = h.QueryDetails(strings.TrimPrefix(res.Redirect, "https://example.com/interact-path?txnID="))
+		require.ErrorIs(t, err, errTxnExpired)
+	})
+
+	t.Run("unknown txn", func(t *testing.T) {
+		h, err := New(config())
+		require.NoError(t, err)
+
+		_, err = h.QueryDetails("foo")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "loading txn data")
+	})
 }
 
 func TestInteractHandler_QueryInteraction(t *testing.T) {
@@ -231,7 +525,7 @@ func TestInteractHandler_DeleteInteraction(t *testing.T) {
 
 func config() *Config {
 	return &Config{
-		InteractBasePath: "https://example.com/interact-base-path",
-		StoreProvider:    mem.NewProvider(),
+		InteractPath:  "/interact-path",
+		StoreProvider: mem.NewProvider(),
 	}
 }
@@ -0,0 +1,466 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package usercode
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+
+	"github.com/trustbloc/auth/pkg/gnap/api"
+	"github.com/trustbloc/auth/spi/gnap"
+)
+
+/*
+InteractHandler handles the GNAP user_code and user_code_uri interaction start modes, for clients on devices that
+can't open a browser, like kiosks and TVs.
+
+The client shows a short code to the user, who types it in at a page of the AS on another device. The code resolves
+to the redirect url of the wrapped InteractionHandler, which runs the login & consent interaction in the user's
+browser. Since the client isn't redirected when the user finishes, it polls the continue endpoint for the result.
+
+Interactions that don't use a user code start mode are handled by the wrapped InteractionHandler alone.
+*/
+type InteractHandler struct {
+	api.InteractionHandler
+	codeStore    storage.Store
+	userCodePath string
+	lifetime     time.Duration
+	attempts     *limiter
+	now          func() time.Time
+}
+
+// Config startup configuration for InteractHandler.
+type Config struct {
+	StoreProvider storage.Provider
+	// InteractionHandler runs the interaction in the user's browser once the user enters the code. It must return
+	// a redirect url from PrepareInteraction.
+	InteractionHandler api.InteractionHandler
+	// UserCodePath is the path of the page where the user enters the code, relative to the server's public base url.
+	UserCodePath string
+	// Lifetime is the time that the user has to enter a code. Defaults to DefaultLifetime.
+	Lifetime time.Duration
+	// MaxAttempts is the number of invalid codes that a requester can enter within AttemptWindow, before its
+	// attempts are refused until the earliest of them is older than the window. The counts are kept in
+	// StoreProvider. Defaults to DefaultMaxAttempts.
+	MaxAttempts int
+	// AttemptWindow is the time that invalid codes are counted in. Defaults to DefaultAttemptWindow.
+	AttemptWindow time.Duration
+}
+
+// Defaults of the InteractHandler Config.
+const (
+	DefaultLifetime      = 10 * time.Minute
+	DefaultMaxAttempts   = 5
+	DefaultAttemptWindow = 15 * time.Minute
+)
+
+var (
+	// ErrInvalidCode is returned when a user code is unknown, expired or was already entered.
+	ErrInvalidCode = errors.New("invalid user code")
+	// ErrTooManyAttempts is returned when a requester entered too many invalid codes.
+	ErrTooManyAttempts = errors.New("too many invalid user codes")
+)
+
+const (
+	codeDBName    = "gnap_interact_usercode_store"
+	attemptDBName = "gnap_interact_usercode_attempts"
+
+	// tags of the failed attempt entries.
+	requesterTag = "requester"
+	expiresTag   = "expires"
+
+	// codeAlphabet has no vowels, so that codes don't spell words, and no digits, so that no characters look alike.
+	codeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+	codeLength   = 8
+	codeGroup    = 4
+)
+
+type codeData struct {
+	Redirect string    `json:"redirect"`
+	Expires  time.Time `json:"expires"`
+}
+
+// New creates a GNAP user code interaction handler.
+func New(config *Config) (*InteractHandler, error) {
+	store, err := config.StoreProvider.OpenStore(codeDBName)
+	if err != nil {
+		return nil, err
+	}
+
+	attemptStore, err := config.StoreProvider.OpenStore(attemptDBName)
+	if err != nil {
+		return nil, err
+	}
+
+	err = config.StoreProvider.SetStoreConfig(attemptDBName,
+		storage.StoreConfiguration{TagNames: []string{requesterTag, expiresTag}})
+	if err != nil {
+		return nil, fmt.Errorf("configuring user code attempt store: %w", err)
+	}
+
+	h := &InteractHandler{
+		InteractionHandler: config.InteractionHandler,
+		codeStore:          store,
+		userCodePath:       config.UserCodePath,
+		lifetime:           config.Lifetime,
+		now:                time.Now,
+	}
+
+	if h.lifetime == 0 {
+		h.lifetime = DefaultLifetime
+	}
+
+	maxAttempts := config.MaxAttempts
+	if maxAttempts == 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+
+	window := config.AttemptWindow
+	if window == 0 {
+		window = DefaultAttemptWindow
+	}
+
+	h.attempts = &limiter{
+		store:  attemptStore,
+		max:    maxAttempts,
+		window: window,
+		now:    func() time.Time { return h.now() },
+	}
+
+	return h, nil
+}
+
+// PrepareInteraction prepares the interaction with the wrapped InteractionHandler, and if the client can start it
+// with a user code, issues a code that resolves to the interaction's redirect url.
+func (h *InteractHandler) PrepareInteraction(
+	clientInteract *gnap.RequestInteract,
+	requestURI, baseURL string,
+	requestedTokens []*api.ExpiringTokenRequest,
+	details *api.InteractionDetails,
+) (*gnap.ResponseInteract, string, error) {
+	res, flowID, err := h.InteractionHandler.PrepareInteraction(clientInteract, requestURI, baseURL, requestedTokens,
+		details)
+	if err != nil {
+		return nil, "", err
+	}
+
+	userCode := api.HasStartMode(clientInteract, api.StartModeUserCode)
+	userCodeURI := api.HasStartMode(clientInteract, api.StartModeUserCodeURI)
+
+	if !userCode && !userCodeURI {
+		return res, flowID, nil
+	}
+
+	code, err := newCode()
+	if err != nil {
+		return nil, "", err
+	}
+
+	dataBytes, err := json.Marshal(&codeData{
+		Redirect: res.Redirect,
+		Expires:  h.now().Add(h.lifetime),
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("marshaling user code data: %w", err)
+	}
+
+	err = h.codeStore.Put(code, dataBytes)
+	if err != nil {
+		return nil, "", fmt.Errorf("saving user code data: %w", err)
+	}
+
+	out := &gnap.ResponseInteract{
+		Finish:    res.Finish,
+		ExpiresIn: int64(h.lifetime / time.Second),
+	}
+
+	if api.HasStartMode(clientInteract, api.StartModeRedirect) {
+		out.Redirect = res.Redirect
+	}
+
+	if userCode {
+		out.UserCode = formatCode(code)
+	}
+
+	if userCodeURI {
+		out.UserCodeURI = &gnap.UserCodeURI{
+			Code: formatCode(code),
+			URI:  baseURL + h.userCodePath,
+		}
+	}
+
+	return out, flowID, nil
+}
+
+/*
+Resolve returns the redirect url of the interaction that the given user code was issued for. The code is matched
+regardless of case, spaces and dashes.
+
+Codes can only be entered once, so that a code that someone else sees can't be used after the user entered it.
+requester identifies who enters the code, usually by network address: a requester that enters too many invalid codes
+gets ErrTooManyAttempts, so that codes can't be guessed.
+*/
+func (h *InteractHandler) Resolve(code, requester string) (string, error) {
+	allowed, err := h.attempts.allow(requester)
+	if err != nil {
+		return "", fmt.Errorf("counting failed attempts: %w", err)
+	}
+
+	if !allowed {
+		return "", ErrTooManyAttempts
+	}
+
+	redirect, err := h.resolve(normalizeCode(code))
+	if errors.Is(err, ErrInvalidCode) {
+		if failErr := h.attempts.fail(requester); failErr != nil {
+			return "", fmt.Errorf("counting failed attempt: %w", failErr)
+		}
+	}
+
+	return redirect, err
+}
+
+func (h *InteractHandler) resolve(code string) (string, error) {
+	if len(code) != codeLength || strings.Trim(code, codeAlphabet) != "" {
+		return "", fmt.Errorf("%w: malformed code", ErrInvalidCode)
+	}
+
+	dataBytes, err := h.codeStore.Get(code)
+	if errors.Is(err, storage.ErrDataNotFound) {
+		return "", fmt.Errorf("%w: unknown code", ErrInvalidCode)
+	} else if err != nil {
+		return "", fmt.Errorf("loading user code data: %w", err)
+	}
+
+	err = h.codeStore.Delete(code)
+	if err != nil {
+		return "", fmt.Errorf("deleting user code data: %w", err)
+	}
+
+	data := &codeData{}
+
+	err = json.Unmarshal(dataBytes, data)
+	if err != nil {
+		return "", fmt.Errorf("parsing user code data: %w", err)
+	}
+
+	if h.now().After(data.Expires) {
+		return "", fmt.Errorf("%w: code expired", ErrInvalidCode)
+	}
+
+	return data.Redirect, nil
+}
+
+func newCode() (string, error) {
+	code := make([]byte, codeLength)
+	max := big.NewInt(int64(len(codeAlphabet)))
+
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("creating user code: %w", err)
+		}
+
+		code[i] = codeAlphabet[n.Int64()]
+	}
+
+	return string(code), nil
+}
+
+// formatCode splits a code into dash-separated groups, which are easier for the user to read and type.
+func formatCode(code string) string {
+	var groups []string
+
+	for i := 0; i < len(code); i += codeGroup {
+		groups = append(groups, code[i:i+codeGroup])
+	}
+
+	return strings.Join(groups, "-")
+}
+
+func normalizeCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+
+		return r
+	}, strings.ToUpper(strings.TrimSpace(code)))
+}
+
+/*
+limiter counts the failed attempts of requesters within a sliding time window.
+
+Each failed attempt is saved as its own entry in the storage provider, tagged with the requester and the time it
+expires, so that replicas sharing a database share the counts without overwriting each other's, and the counts
+survive restarts. Expired entries are deleted.
+*/
+type limiter struct {
+	store     storage.Store
+	max       int
+	window    time.Duration
+	now       func() time.Time
+	mu        sync.Mutex
+	nextPurge time.Time
+}
+
+type failure struct {
+	Expires time.Time `json:"expires"`
+}
+
+// allow returns true iff the requester has attempts left in the window that ends now.
+func (l *limiter) allow(requester string) (bool, error) {
+	now := l.now()
+	count := 0
+
+	err := l.each(requesterTag+":"+requesterKey(requester), func(_ string, expires time.Time) error {
+		if !now.After(expires) {
+			count++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return count < l.max, nil
+}
+
+// fail counts a failed attempt of the requester, which expires when the window that starts now ends.
+func (l *limiter) fail(requester string) error {
+	now := l.now()
+
+	err := l.purgeExpired(now)
+	if err != nil {
+		return err
+	}
+
+	expires := now.Add(l.window)
+
+	data, err := json.Marshal(&failure{Expires: expires})
+	if err != nil {
+		return fmt.Errorf("marshaling failed attempt: %w", err)
+	}
+
+	err = l.store.Put(uuid.New().String(), data,
+		storage.Tag{Name: requesterTag, Value: requesterKey(requester)},
+		storage.Tag{Name: expiresTag, Value: strconv.FormatInt(expires.Unix(), 10)},
+	)
+	if err != nil {
+		return fmt.Errorf("saving failed attempt: %w", err)
+	}
+
+	return nil
+}
+
+// purgeExpired deletes the failed attempts that expired, at most once per window.
+func (l *limiter) purgeExpired(now time.Time) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Before(l.nextPurge) {
+		return nil
+	}
+
+	l.nextPurge = now.Add(l.window)
+
+	var expired []string
+
+	err := l.each(expiresTag, func(key string, expires time.Time) error {
+		if now.After(expires) {
+			expired = append(expired, key)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, key := range expired {
+		err = l.store.Delete(key)
+		if err != nil {
+			return fmt.Errorf("deleting expired failed attempt: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// each calls f with the key and expiry of each failed attempt that matches the given query expression.
+func (l *limiter) each(query string, f func(key string, expires time.Time) error) error {
+	iter, err := l.store.Query(query)
+	if err != nil {
+		return fmt.Errorf("querying failed attempts: %w", err)
+	}
+
+	defer iter.Close() // nolint:errcheck // nothing to do on failure
+
+	for {
+		more, err := iter.Next()
+		if err != nil {
+			return fmt.Errorf("iterating failed attempts: %w", err)
+		}
+
+		if !more {
+			return nil
+		}
+
+		key, err := iter.Key()
+		if err != nil {
+			return fmt.Errorf("reading failed attempt key: %w", err)
+		}
+
+		tags, err := iter.Tags()
+		if err != nil {
+			return fmt.Errorf("reading failed attempt tags: %w", err)
+		}
+
+		err = f(key, tagExpiry(tags))
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// tagExpiry returns the expiry in the given tags of a failed attempt, or the zero time if it has none.
+func tagExpiry(tags []storage.Tag) time.Time {
+	for _, tag := range tags {
+		if tag.Name != expiresTag {
+			continue
+		}
+
+		expires, err := strconv.ParseInt(tag.Value, 10, 64)
+		if err != nil {
+			return time.Time{}
+		}
+
+		return time.Unix(expires, 0)
+	}
+
+	return time.Time{}
+}
+
+// requesterKey hashes the requester, so that it can be used in tag queries whatever characters it has.
+func requesterKey(requester string) string {
+	h := sha256.Sum256([]byte(requester))
+
+	return base64.RawURLEncoding.EncodeToString(h[:])
+}
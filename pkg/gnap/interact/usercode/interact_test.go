@@ -0,0 +1,314 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package usercode
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	mockstore "github.com/hyperledger/aries-framework-go/pkg/mock/storage"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/auth/pkg/internal/common/mockinteract"
+	"github.com/trustbloc/auth/pkg/internal/common/mockstorage"
+	"github.com/trustbloc/auth/spi/gnap"
+)
+
+const redirectURL = "https://as.example.com/interact?txnID=foo"
+
+func TestNew(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		h, err := New(config())
+		require.NoError(t, err)
+		require.Equal(t, DefaultLifetime, h.lifetime)
+		require.Equal(t, DefaultMaxAttempts, h.attempts.max)
+	})
+
+	t.Run("fail to configure attempt store", func(t *testing.T) {
+		_, err := New(&Config{
+			StoreProvider: &mockstore.MockStoreProvider{
+				Store:             &mockstore.MockStore{Store: map[string]mockstore.DBEntry{}},
+				ErrSetStoreConfig: errors.New("expected error"),
+			},
+		})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "configuring user code attempt store")
+	})
+
+	t.Run("error", func(t *testing.T) {
+		expectErr := errors.New("expected error")
+
+		h, err := New(&Config{
+			StoreProvider: &mockstorage.Provider{ErrOpenStoreHandle: expectErr},
+		})
+		require.ErrorIs(t, err, expectErr)
+		require.Nil(t, h)
+	})
+}
+
+func TestInteractHandler_PrepareInteraction(t *testing.T) {
+	t.Run("redirect start only", func(t *testing.T) {
+		h, err := New(config())
+		require.NoError(t, err)
+
+		res, flowID, err := h.PrepareInteraction(&gnap.RequestInteract{Start: []string{"redirect"}},
+			"", "https://as.example.com", nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, "flow-id", flowID)
+		require.Equal(t, &gnap.ResponseInteract{Redirect: redirectURL, Finish: "nonce"}, res)
+	})
+
+	t.Run("user code", func(t *testing.T) {
+		h, err := New(config())
+		require.NoError(t, err)
+
+		res, flowID, err := h.PrepareInteraction(&gnap.RequestInteract{Start: []string{"user_code"}},
+			"", "https://as.example.com", nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, "flow-id", flowID)
+		require.Empty(t, res.Redirect)
+		require.Nil(t, res.UserCodeURI)
+		require.Equal(t, "nonce", res.Finish)
+		require.Equal(t, int64(600), res.ExpiresIn)
+		require.Regexp(t, "^[B-Z]{4}-[B-Z]{4}$", res.UserCode)
+
+		redirect, err := h.Resolve(strings.ToLower(res.UserCode), "requester")
+		require.NoError(t, err)
+		require.Equal(t, redirectURL, redirect)
+	})
+
+	t.Run("user code uri and redirect", func(t *testing.T) {
+		h, err := New(config())
+		require.NoError(t, err)
+
+		res, _, err := h.PrepareInteraction(&gnap.RequestInteract{Start: []string{"redirect", "user_code_uri"}},
+			"", "https://as.example.com", nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, redirectURL, res.Redirect)
+		require.Empty(t, res.UserCode)
+		require.Equal(t, "https://as.example.com/device", res.UserCodeURI.URI)
+
+		redirect, err := h.Resolve(strings.ReplaceAll(res.UserCodeURI.Code, "-", " "), "requester")
+		require.NoError(t, err)
+		require.Equal(t, redirectURL, redirect)
+	})
+
+	t.Run("wrapped handler error", func(t *testing.T) {
+		expectErr := errors.New("expected error")
+
+		conf := config()
+		conf.InteractionHandler = &mockinteract.InteractHandler{PrepareErr: expectErr}
+
+		h, err := New(conf)
+		require.NoError(t, err)
+
+		_, _, err = h.PrepareInteraction(&gnap.RequestInteract{Start: []string{"user_code"}}, "", "", nil, nil)
+		require.ErrorIs(t, err, expectErr)
+	})
+
+	t.Run("fail to save code", func(t *testing.T) {
+		expectErr := errors.New("expected error")
+
+		h, err := New(config())
+		require.NoError(t, err)
+
+		h.codeStore = &mockstorage.MockStore{Store: map[string][]byte{}, ErrPut: expectErr}
+
+		_, _, err = h.PrepareInteraction(&gnap.RequestInteract{Start: []string{"user_code"}}, "", "", nil, nil)
+		require.ErrorIs(t, err, expectErr)
+	})
+}
+
+func TestInteractHandler_Resolve(t *testing.T) {
+	prepare := func(t *testing.T, h *InteractHandler) string {
+		t.Helper()
+
+		res, _, err := h.PrepareInteraction(&gnap.RequestInteract{Start: []string{"user_code"}}, "", "", nil, nil)
+		require.NoError(t, err)
+
+		return res.UserCode
+	}
+
+	t.Run("single use", func(t *testing.T) {
+		h, err := New(config())
+		require.NoError(t, err)
+
+		code := prepare(t, h)
+
+		_, err = h.Resolve(code, "requester")
+		require.NoError(t, err)
+
+		_, err = h.Resolve(code, "requester")
+		require.ErrorIs(t, err, ErrInvalidCode)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		h, err := New(config())
+		require.NoError(t, err)
+
+		code := prepare(t, h)
+
+		h.now = func() time.Time { return time.Now().Add(DefaultLifetime + time.Minute) }
+
+		_, err = h.Resolve(code, "requester")
+		require.ErrorIs(t, err, ErrInvalidCode)
+		require.Contains(t, err.Error(), "expired")
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		h, err := New(config())
+		require.NoError(t, err)
+
+		for _, code := range []string{"", "BCDF", "BCDF-GHJ1", "BCDF-GHJKL"} {
+			_, err = h.Resolve(code, "requester")
+			require.ErrorIs(t, err, ErrInvalidCode)
+		}
+	})
+
+	t.Run("too many attempts", func(t *testing.T) {
+		conf := config()
+		conf.MaxAttempts = 2
+
+		h, err := New(conf)
+		require.NoError(t, err)
+
+		code := prepare(t, h)
+
+		for i := 0; i < 2; i++ {
+			_, err = h.Resolve("BCDF-GHJK", "requester")
+			require.ErrorIs(t, err, ErrInvalidCode)
+		}
+
+		_, err = h.Resolve(code, "requester")
+		require.ErrorIs(t, err, ErrTooManyAttempts)
+
+		// other requesters aren't limited
+		redirect, err := h.Resolve(code, "other-requester")
+		require.NoError(t, err)
+		require.Equal(t, redirectURL, redirect)
+
+		// the attempts are allowed again after the window ends
+		h.now = func() time.Time { return time.Now().Add(DefaultAttemptWindow + time.Minute) }
+
+		_, err = h.Resolve("BCDF-GHJK", "requester")
+		require.ErrorIs(t, err, ErrInvalidCode)
+	})
+
+	t.Run("fail to load code", func(t *testing.T) {
+		expectErr := errors.New("expected error")
+
+		h, err := New(config())
+		require.NoError(t, err)
+
+		h.codeStore = &mockstorage.MockStore{Store: map[string][]byte{"BCDFGHJK": nil}, ErrGet: expectErr}
+
+		_, err = h.Resolve("BCDF-GHJK", "requester")
+		require.ErrorIs(t, err, expectErr)
+
+		allowed, err := h.attempts.allow("requester")
+		require.NoError(t, err)
+		require.True(t, allowed)
+	})
+
+	t.Run("attempts are shared through the store", func(t *testing.T) {
+		conf := config()
+		conf.MaxAttempts = 2
+
+		replica1, err := New(conf)
+		require.NoError(t, err)
+
+		replica2, err := New(conf)
+		require.NoError(t, err)
+
+		_, err = replica1.Resolve("BCDF-GHJK", "requester")
+		require.ErrorIs(t, err, ErrInvalidCode)
+
+		_, err = replica2.Resolve("BCDF-GHJK", "requester")
+		require.ErrorIs(t, err, ErrInvalidCode)
+
+		_, err = replica1.Resolve("BCDF-GHJK", "requester")
+		require.ErrorIs(t, err, ErrTooManyAttempts)
+	})
+
+	t.Run("expired attempts are deleted", func(t *testing.T) {
+		store := &mockstore.MockStore{Store: map[string]mockstore.DBEntry{}}
+
+		h, err := New(config())
+		require.NoError(t, err)
+
+		h.attempts.store = store
+
+		_, err = h.Resolve("BCDF-GHJK", "requester")
+		require.ErrorIs(t, err, ErrInvalidCode)
+		require.Len(t, store.Store, 1)
+
+		h.now = func() time.Time { return time.Now().Add(DefaultAttemptWindow + time.Minute) }
+
+		_, err = h.Resolve("BCDF-GHJK", "other-requester")
+		require.ErrorIs(t, err, ErrInvalidCode)
+		require.Len(t, store.Store, 1)
+	})
+
+	t.Run("attempt store errors", func(t *testing.T) {
+		store := &mockstore.MockStore{Store: map[string]mockstore.DBEntry{}}
+
+		h, err := New(config())
+		require.NoError(t, err)
+
+		h.attempts.store = store
+
+		tests := []struct {
+			setup  func()
+			expect string
+		}{
+			{setup: func() { store.ErrQuery = errors.New("query error") }, expect: "querying failed attempts"},
+			{setup: func() { store.ErrNext = errors.New("next error") }, expect: "iterating failed attempts"},
+			{setup: func() { store.ErrPut = errors.New("put error") }, expect: "saving failed attempt"},
+		}
+
+		for _, tc := range tests {
+			store.ErrQuery, store.ErrNext, store.ErrPut = nil, nil, nil
+			tc.setup()
+
+			_, err = h.Resolve("BCDF-GHJK", "requester")
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.expect)
+		}
+
+		store.ErrPut = nil
+		store.ErrKey = errors.New("key error")
+		store.Store["foo"] = mockstore.DBEntry{Tags: []storage.Tag{{Name: requesterTag, Value: requesterKey("requester")}}}
+
+		_, err = h.Resolve("BCDF-GHJK", "requester")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "reading failed attempt key")
+
+		store.ErrKey = nil
+		store.ErrDelete = errors.New("delete error")
+		store.Store["foo"] = mockstore.DBEntry{Tags: []storage.Tag{{Name: expiresTag, Value: "foo"}}}
+		h.attempts.nextPurge = time.Time{}
+
+		_, err = h.Resolve("BCDF-GHJK", "requester")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "deleting expired failed attempt")
+	})
+}
+
+func config() *Config {
+	return &Config{
+		StoreProvider: mem.NewProvider(),
+		InteractionHandler: &mockinteract.InteractHandler{
+			PrepareVal:    &gnap.ResponseInteract{Redirect: redirectURL, Finish: "nonce"},
+			PrepareFlowID: "flow-id",
+		},
+		UserCodePath: "/device",
+	}
+}
@@ -91,9 +91,22 @@ type Session struct {
 	NeedsConsent   *api.AccessMetadata
 	AllowedRequest *api.AccessMetadata
 	SubjectData    map[string]string
-	Expires        time.Time
-	InteractRef    string
-	InteractFlowID string
+	// DeniedSubjectKeys are the keys of the subject data that the user didn't approve releasing to the client.
+	DeniedSubjectKeys []string
+	// ConsentedSubjectKeys are the keys of the subject data that the user approved releasing to the client, which
+	// the client can get as subject identifiers and assertions without another interaction.
+	ConsentedSubjectKeys []string
+	SubjectRequest       *gnap.RequestSubject
+	ClientDisplay        *gnap.ClientDisplay
+	// DisplayVerified is true iff ClientDisplay was registered with the AS, instead of being sent by the client.
+	DisplayVerified bool
+	Expires         time.Time
+	InteractRef     string
+	InteractFlowID  string
+	// InteractHandler is the name of the interaction handler that runs the interaction of InteractFlowID.
+	InteractHandler string
+	// NextPoll is the earliest time that the client may poll for the result of the interaction of InteractFlowID.
+	NextPoll time.Time
 }
 
 var errNotFound = errors.New("session not found")
@@ -131,14 +144,14 @@ func (s *Manager) Save(session *Session) error { // nolint:funlen,gocyclo
 	tags := []storage.Tag{}
 
 	if session.ClientKey != nil {
-		keyFingerprint, e := session.ClientKey.JWK.Thumbprint(crypto.SHA3_512)
+		keyFP, e := keyFingerprint(session.ClientKey)
 		if e != nil {
-			return fmt.Errorf("creating jwk thumbprint: %w", e)
+			return e
 		}
 
 		tags = append(tags, storage.Tag{
 			Name:  keyFingerprintTag,
-			Value: base64.RawURLEncoding.EncodeToString(keyFingerprint),
+			Value: keyFP,
 		})
 	}
 
@@ -237,16 +250,31 @@ func (s *Manager) getByTag(tag storage.Tag) (*Session, error) {
 	return session, nil
 }
 
+// keyFingerprint returns the fingerprint that identifies the session of the given client key: the thumbprint
+// of the key's jwk, or of its certificate's public key if the key is bound to a certificate.
+func keyFingerprint(clientKey *gnap.ClientKey) (string, error) {
+	// sessions are bound to the public key itself, whichever representation the client sends it in.
+	key, err := clientKey.VerificationKey()
+	if err != nil {
+		return "", err
+	}
+
+	fp, err := key.Thumbprint(crypto.SHA3_512)
+	if err != nil {
+		return "", fmt.Errorf("creating jwk thumbprint: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(fp), nil
+}
+
 // GetOrCreateByKey gets the client session with the given key, or creates a
 // fresh session with the given key if one doesn't exist.
 func (s *Manager) GetOrCreateByKey(clientKey *gnap.ClientKey) (*Session, error) {
-	keyFingerprint, err := clientKey.JWK.Thumbprint(crypto.SHA3_512)
+	keyFP, err := keyFingerprint(clientKey)
 	if err != nil {
-		return nil, fmt.Errorf("creating jwk thumbprint: %w", err)
+		return nil, err
 	}
 
-	keyFP := base64.RawURLEncoding.EncodeToString(keyFingerprint)
-
 	session, err := s.getByTag(storage.Tag{
 		Name:  keyFingerprintTag,
 		Value: keyFP,
@@ -272,6 +300,29 @@ func (s *Manager) GetOrCreateByKey(clientKey *gnap.ClientKey) (*Session, error)
 	return session, nil
 }
 
+// GetOrCreateByID gets the client session with the given client ID, or creates a
+// fresh session with the given client ID if one doesn't exist. This is used for
+// pre-registered clients, whose instance identifier is assigned by configuration.
+func (s *Manager) GetOrCreateByID(clientID string) (*Session, error) {
+	session, err := s.GetByID(clientID)
+	if err == nil {
+		return session, nil
+	} else if !errors.Is(err, errNotFound) {
+		return nil, err
+	}
+
+	session = &Session{
+		ClientID: clientID,
+	}
+
+	err = s.Save(session)
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
 // GetByID gets the Session under the given client ID.
 func (s *Manager) GetByID(clientID string) (*Session, error) {
 	data, err := s.store.Get(clientID)
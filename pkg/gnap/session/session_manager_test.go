@@ -9,7 +9,12 @@ package session
 import (
 	"crypto/ed25519"
 	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
 	"errors"
+	"math/big"
 	"testing"
 	"time"
 
@@ -17,6 +22,7 @@ import (
 	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk/jwksupport"
+	"github.com/square/go-jose/v3"
 	"github.com/stretchr/testify/require"
 
 	"github.com/trustbloc/auth/pkg/gnap/api"
@@ -71,6 +77,104 @@ func TestManager(t *testing.T) {
 		require.Equal(t, s.ClientID, s2.ClientID)
 	})
 
+	t.Run("create / get by rsa key", func(t *testing.T) {
+		sm, err := New(config(t))
+		require.NoError(t, err)
+
+		priv, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+
+		ck := &gnap.ClientKey{
+			Proof: "httpsig",
+			JWK: jwk.JWK{
+				JSONWebKey: jose.JSONWebKey{Key: &priv.PublicKey, Algorithm: "PS512"},
+				Kty:        "RSA",
+			},
+		}
+
+		s, err := sm.GetOrCreateByKey(ck)
+		require.NoError(t, err)
+
+		s2, err := sm.GetOrCreateByKey(ck)
+		require.NoError(t, err)
+
+		require.Equal(t, s.ClientID, s2.ClientID)
+	})
+
+	t.Run("create / get by certificate-bound key", func(t *testing.T) {
+		sm, err := New(config(t))
+		require.NoError(t, err)
+
+		_, err = sm.GetOrCreateByKey(&gnap.ClientKey{Proof: "mtls", Cert: "foo"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "client certificate")
+
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(1),
+			Subject:      pkix.Name{CommonName: "client"},
+			NotBefore:    time.Now(),
+			NotAfter:     time.Now().Add(time.Hour),
+		}
+
+		der, err := x509.CreateCertificate(rand.Reader, template, template, pub, priv)
+		require.NoError(t, err)
+
+		ck := &gnap.ClientKey{
+			Proof: "mtls",
+			Cert:  base64.StdEncoding.EncodeToString(der),
+		}
+
+		s, err := sm.GetOrCreateByKey(ck)
+		require.NoError(t, err)
+
+		s2, err := sm.GetOrCreateByKey(&gnap.ClientKey{
+			Proof: "mtls",
+			Cert:  base64.StdEncoding.EncodeToString(der),
+		})
+		require.NoError(t, err)
+
+		require.Equal(t, s.ClientID, s2.ClientID)
+	})
+
+	t.Run("same key in different representations shares a session", func(t *testing.T) {
+		sm, err := New(config(t))
+		require.NoError(t, err)
+
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		pubJWK, err := jwksupport.JWKFromKey(pub)
+		require.NoError(t, err)
+
+		jwkBytes, err := pubJWK.MarshalJSON()
+		require.NoError(t, err)
+
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(1),
+			Subject:      pkix.Name{CommonName: "client"},
+			NotBefore:    time.Now(),
+			NotAfter:     time.Now().Add(time.Hour),
+		}
+
+		der, err := x509.CreateCertificate(rand.Reader, template, template, pub, priv)
+		require.NoError(t, err)
+
+		s, err := sm.GetOrCreateByKey(&gnap.ClientKey{Proof: "httpsig", JWK: *pubJWK})
+		require.NoError(t, err)
+
+		for _, ck := range []*gnap.ClientKey{
+			{Proof: "httpsig", X5C: []string{base64.StdEncoding.EncodeToString(der)}},
+			{Proof: "httpsig", DID: "did:jwk:" + base64.RawURLEncoding.EncodeToString(jwkBytes)},
+		} {
+			s2, err := sm.GetOrCreateByKey(ck)
+			require.NoError(t, err)
+			require.Equal(t, s.ClientID, s2.ClientID)
+		}
+	})
+
 	t.Run("get by ID", func(t *testing.T) {
 		sm, err := New(config(t))
 		require.NoError(t, err)
@@ -87,6 +191,23 @@ func TestManager(t *testing.T) {
 		require.Equal(t, s, s2)
 	})
 
+	t.Run("get or create by ID", func(t *testing.T) {
+		sm, err := New(config(t))
+		require.NoError(t, err)
+
+		s, err := sm.GetOrCreateByID("foo")
+		require.NoError(t, err)
+		require.Equal(t, "foo", s.ClientID)
+
+		s.ClientKey = clientKey(t)
+
+		require.NoError(t, sm.Save(s))
+
+		s2, err := sm.GetOrCreateByID("foo")
+		require.NoError(t, err)
+		require.Equal(t, s, s2)
+	})
+
 	t.Run("add&get token", func(t *testing.T) {
 		sm, err := New(config(t))
 		require.NoError(t, err)
@@ -13,22 +13,36 @@ import (
 
 // InteractHandler mock.
 type InteractHandler struct {
-	PrepareVal  *gnap.ResponseInteract
-	PrepareErr  error
-	CompleteVal string
-	CompleteErr error
-	QueryVal    *api.ConsentResult
-	QueryErr    error
-	DeleteErr   error
+	PrepareVal       *gnap.ResponseInteract
+	PrepareFlowID    string
+	PrepareErr       error
+	PrepareArgs      *api.InteractionDetails
+	CompleteVal      string
+	CompleteErr      error
+	CompleteArgs     *api.ConsentResult
+	CompleteInteract *gnap.RequestInteract
+	QueryVal         *api.ConsentResult
+	QueryErr         error
+	DeleteErr        error
+	DetailsVal       *api.InteractionDetails
+	DetailsErr       error
 }
 
 // PrepareInteraction mock.
 func (l *InteractHandler) PrepareInteraction(
 	clientInteract *gnap.RequestInteract,
-	requestURI string,
+	requestURI, baseURL string,
 	requestedTokens []*api.ExpiringTokenRequest,
-) (*gnap.ResponseInteract, error) {
-	return l.PrepareVal, l.PrepareErr
+	details *api.InteractionDetails,
+) (*gnap.ResponseInteract, string, error) {
+	l.PrepareArgs = details
+
+	return l.PrepareVal, l.PrepareFlowID, l.PrepareErr
+}
+
+// QueryDetails mock.
+func (l *InteractHandler) QueryDetails(flowID string) (*api.InteractionDetails, error) {
+	return l.DetailsVal, l.DetailsErr
 }
 
 // CompleteInteraction mock.
@@ -36,7 +50,9 @@ func (l *InteractHandler) CompleteInteraction(
 	flowID string,
 	consentSet *api.ConsentResult,
 ) (string, string, *gnap.RequestInteract, error) {
-	return l.CompleteVal, "", nil, l.CompleteErr
+	l.CompleteArgs = consentSet
+
+	return l.CompleteVal, "", l.CompleteInteract, l.CompleteErr
 }
 
 // QueryInteraction mock.
@@ -14,11 +14,21 @@ import (
 // MockVerifier mocks api.Verifier.
 type MockVerifier struct {
 	ErrVerify error
+	KeyIDVal  string
+	ErrKeyID  error
 }
 
-var _ api.Verifier = &MockVerifier{}
+var (
+	_ api.Verifier      = &MockVerifier{}
+	_ api.KeyIdentifier = &MockVerifier{}
+)
 
 // Verify mock api.Verifier.Verify.
 func (m *MockVerifier) Verify(*gnap.ClientKey) error {
 	return m.ErrVerify
 }
+
+// KeyID mock api.KeyIdentifier.KeyID.
+func (m *MockVerifier) KeyID(string) (string, error) {
+	return m.KeyIDVal, m.ErrKeyID
+}
@@ -0,0 +1,281 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Config holds Resolver configuration.
+type Config struct {
+	// BaseURL is the server's public url, used for requests that aren't matched by a forwarding header or a prefix.
+	BaseURL string
+	// TrustedProxies are IP addresses or CIDR ranges of reverse proxies whose Forwarded and X-Forwarded-* headers
+	// are trusted.
+	TrustedProxies []string
+	// PublicPrefixes maps request path prefixes to the public url prefix that they are served under. A request
+	// whose path starts with a prefix has that prefix replaced with the public url prefix.
+	PublicPrefixes map[string]string
+}
+
+// Resolver reconstructs the public url of a request, as sent by the client, for requests that may
+// have been received through a reverse proxy. A nil Resolver resolves requests to their path only.
+type Resolver struct {
+	baseURL        *url.URL
+	trustedProxies []*net.IPNet
+	prefixes       []prefixMapping
+}
+
+type prefixMapping struct {
+	prefix    string
+	publicURL *url.URL
+}
+
+// matches returns true iff the given request path is within the prefix.
+func (m *prefixMapping) matches(path string) bool {
+	return path == m.prefix || strings.HasPrefix(path, m.prefix+"/")
+}
+
+// New creates a Resolver.
+func New(config *Config) (*Resolver, error) {
+	baseURL, err := url.Parse(strings.TrimSuffix(config.BaseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("parsing base url: %w", err)
+	}
+
+	r := &Resolver{baseURL: baseURL}
+
+	for _, p := range config.TrustedProxies {
+		ipNet, e := parseIPNet(p)
+		if e != nil {
+			return nil, e
+		}
+
+		r.trustedProxies = append(r.trustedProxies, ipNet)
+	}
+
+	for prefix, public := range config.PublicPrefixes {
+		publicURL, e := url.Parse(strings.TrimSuffix(public, "/"))
+		if e != nil {
+			return nil, fmt.Errorf("parsing public url for prefix '%s': %w", prefix, e)
+		}
+
+		r.prefixes = append(r.prefixes, prefixMapping{prefix: strings.TrimSuffix(prefix, "/"), publicURL: publicURL})
+	}
+
+	// match the longest prefix first
+	sort.Slice(r.prefixes, func(i, j int) bool {
+		return len(r.prefixes[i].prefix) > len(r.prefixes[j].prefix)
+	})
+
+	return r, nil
+}
+
+// TargetURI returns the public url of the given request, including its query.
+func (r *Resolver) TargetURI(req *http.Request) *url.URL {
+	target := r.resolve(req)
+	target.RawQuery = req.URL.RawQuery
+
+	return target
+}
+
+// BaseURL returns the public url that the server is reached at by the client of the given request, so that server
+// endpoints can be addressed by appending their paths.
+//
+// A public prefix whose url path ends with its request path prefix serves the server's paths under the part of the
+// url that sits outside the prefix, which is the base url. A public prefix that renames the paths it serves has no
+// such base url, so the base url is taken from the longest public prefix of the request path that keeps its path,
+// or else is the configured base url.
+func (r *Resolver) BaseURL(req *http.Request) string {
+	if r == nil {
+		return ""
+	}
+
+	if r.isTrustedProxy(req.RemoteAddr) {
+		if scheme, host, prefix, ok := forwarded(req); ok {
+			base := &url.URL{Scheme: scheme, Host: host, Path: strings.TrimSuffix(prefix, "/")}
+
+			return base.String()
+		}
+	}
+
+	base := r.baseURL
+
+	for _, m := range r.prefixes {
+		if m.matches(req.URL.Path) && strings.HasSuffix(m.publicURL.Path, m.prefix) {
+			base = withPath(m.publicURL, strings.TrimSuffix(m.publicURL.Path, m.prefix))
+
+			break
+		}
+	}
+
+	return strings.TrimSuffix(base.String(), "/")
+}
+
+// ClientAddr returns the IP address of the client of the given request. For requests received through a trusted
+// proxy, this is the last address of the X-Forwarded-For header, which the proxy appended.
+func (r *Resolver) ClientAddr(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+
+	if r == nil || !r.isTrustedProxy(req.RemoteAddr) {
+		return host
+	}
+
+	forwardedFor := strings.Split(req.Header.Get("X-Forwarded-For"), ",")
+
+	if client := strings.TrimSpace(forwardedFor[len(forwardedFor)-1]); client != "" {
+		return client
+	}
+
+	return host
+}
+
+func (r *Resolver) resolve(req *http.Request) *url.URL {
+	path := req.URL.Path
+
+	if r == nil {
+		return &url.URL{Path: path}
+	}
+
+	if r.isTrustedProxy(req.RemoteAddr) {
+		if scheme, host, prefix, ok := forwarded(req); ok {
+			return &url.URL{Scheme: scheme, Host: host, Path: strings.TrimSuffix(prefix, "/") + path}
+		}
+	}
+
+	for _, m := range r.prefixes {
+		if m.matches(path) {
+			return withPath(m.publicURL, m.publicURL.Path+strings.TrimPrefix(path, m.prefix))
+		}
+	}
+
+	return withPath(r.baseURL, r.baseURL.Path+path)
+}
+
+func (r *Resolver) isTrustedProxy(remoteAddr string) bool {
+	if len(r.trustedProxies) == 0 {
+		return false
+	}
+
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	for _, ipNet := range r.trustedProxies {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// forwarded returns the scheme, host and path prefix of the original client request, from the Forwarded header
+// if present, and the X-Forwarded-* headers otherwise.
+func forwarded(req *http.Request) (string, string, string, bool) {
+	scheme, host := forwardedHeader(req.Header.Get("Forwarded"))
+
+	if scheme == "" && host == "" {
+		scheme = firstValue(req.Header.Get("X-Forwarded-Proto"))
+		host = firstValue(req.Header.Get("X-Forwarded-Host"))
+	}
+
+	prefix := firstValue(req.Header.Get("X-Forwarded-Prefix"))
+
+	if scheme == "" && host == "" && prefix == "" {
+		return "", "", "", false
+	}
+
+	if scheme == "" {
+		scheme = "http"
+		if req.TLS != nil {
+			scheme = "https"
+		}
+	}
+
+	if host == "" {
+		host = req.Host
+	}
+
+	return strings.ToLower(scheme), host, prefix, true
+}
+
+// forwardedHeader parses the proto and host parameters of the first element of an RFC 7239 Forwarded header,
+// which describes the request as received by the proxy closest to the client.
+func forwardedHeader(header string) (string, string) {
+	var proto, host string
+
+	element := firstValue(header)
+
+	for _, pair := range strings.Split(element, ";") {
+		kv := strings.SplitN(strings.TrimSpace(pair), "=", 2) // nolint:gomnd
+
+		if len(kv) != 2 { // nolint:gomnd
+			continue
+		}
+
+		value := strings.Trim(kv[1], `"`)
+
+		switch strings.ToLower(kv[0]) {
+		case "proto":
+			proto = value
+		case "host":
+			host = value
+		}
+	}
+
+	return proto, host
+}
+
+func firstValue(header string) string {
+	return strings.TrimSpace(strings.Split(header, ",")[0])
+}
+
+func withPath(u *url.URL, path string) *url.URL {
+	out := *u
+	out.Path = path
+	out.RawPath = ""
+
+	return &out
+}
+
+func parseIPNet(s string) (*net.IPNet, error) {
+	if strings.Contains(s, "/") {
+		_, ipNet, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, fmt.Errorf("parsing trusted proxy range '%s': %w", s, err)
+		}
+
+		return ipNet, nil
+	}
+
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid trusted proxy address '%s'", s)
+	}
+
+	bits := 8 * net.IPv4len
+	if ip.To4() == nil {
+		bits = 8 * net.IPv6len
+	}
+
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
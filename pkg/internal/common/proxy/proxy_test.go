@@ -0,0 +1,278 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package proxy
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		r, err := New(&Config{
+			BaseURL:        "https://auth.example.com/",
+			TrustedProxies: []string{"10.0.0.0/8", "192.168.1.1", "::1"},
+			PublicPrefixes: map[string]string{"/gnap": "https://example.com/auth/gnap"},
+		})
+		require.NoError(t, err)
+		require.Len(t, r.trustedProxies, 3)
+		require.Len(t, r.prefixes, 1)
+	})
+
+	t.Run("invalid base url", func(t *testing.T) {
+		_, err := New(&Config{BaseURL: "\u007f"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "parsing base url")
+	})
+
+	t.Run("invalid trusted proxy", func(t *testing.T) {
+		_, err := New(&Config{TrustedProxies: []string{"foo"}})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid trusted proxy address")
+
+		_, err = New(&Config{TrustedProxies: []string{"10.0.0.0/foo"}})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "parsing trusted proxy range")
+	})
+
+	t.Run("invalid public prefix url", func(t *testing.T) {
+		_, err := New(&Config{PublicPrefixes: map[string]string{"/foo": "\u007f"}})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "parsing public url for prefix")
+	})
+}
+
+func TestResolver(t *testing.T) {
+	r, err := New(&Config{
+		BaseURL:        "https://auth.example.com",
+		TrustedProxies: []string{"10.0.0.0/8"},
+		PublicPrefixes: map[string]string{
+			"/gnap":          "https://example.com/auth/gnap",
+			"/gnap/continue": "https://continue.example.com",
+		},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		target     string
+		remoteAddr string
+		headers    map[string]string
+		targetURI  string
+		baseURL    string
+	}{
+		{
+			name:       "base url",
+			target:     "/oidc/login?provider=foo",
+			remoteAddr: "1.2.3.4:1234",
+			targetURI:  "https://auth.example.com/oidc/login?provider=foo",
+			baseURL:    "https://auth.example.com",
+		},
+		{
+			name:       "public prefix",
+			target:     "/gnap/auth",
+			remoteAddr: "1.2.3.4:1234",
+			targetURI:  "https://example.com/auth/gnap/auth",
+			baseURL:    "https://example.com/auth",
+		},
+		{
+			name:       "longest public prefix",
+			target:     "/gnap/continue",
+			remoteAddr: "1.2.3.4:1234",
+			targetURI:  "https://continue.example.com",
+			baseURL:    "https://example.com/auth",
+		},
+		{
+			name:       "forwarded header",
+			target:     "/gnap/auth",
+			remoteAddr: "10.1.2.3:1234",
+			headers: map[string]string{
+				"Forwarded":          `for=1.2.3.4;proto=https;host="public.example.com", for=10.0.0.2;proto=http`,
+				"X-Forwarded-Prefix": "/auth/",
+			},
+			targetURI: "https://public.example.com/auth/gnap/auth",
+			baseURL:   "https://public.example.com/auth",
+		},
+		{
+			name:       "x-forwarded headers",
+			target:     "/gnap/auth?foo=bar",
+			remoteAddr: "10.1.2.3:1234",
+			headers: map[string]string{
+				"X-Forwarded-Proto": "HTTPS, http",
+				"X-Forwarded-Host":  "public.example.com",
+			},
+			targetURI: "https://public.example.com/gnap/auth?foo=bar",
+			baseURL:   "https://public.example.com",
+		},
+		{
+			name:       "x-forwarded prefix only",
+			target:     "/gnap/auth",
+			remoteAddr: "10.1.2.3",
+			headers: map[string]string{
+				"X-Forwarded-Prefix": "/auth",
+			},
+			targetURI: "http://example.com/auth/gnap/auth",
+			baseURL:   "http://example.com/auth",
+		},
+		{
+			name:       "forwarded headers from untrusted client are ignored",
+			target:     "/oidc/login",
+			remoteAddr: "1.2.3.4:1234",
+			headers: map[string]string{
+				"X-Forwarded-Proto": "http",
+				"X-Forwarded-Host":  "evil.example.com",
+			},
+			targetURI: "https://auth.example.com/oidc/login",
+			baseURL:   "https://auth.example.com",
+		},
+		{
+			name:       "trusted proxy without forwarding headers",
+			target:     "/oidc/login",
+			remoteAddr: "10.1.2.3:1234",
+			targetURI:  "https://auth.example.com/oidc/login",
+			baseURL:    "https://auth.example.com",
+		},
+		{
+			name:       "invalid remote address",
+			target:     "/oidc/login",
+			remoteAddr: "foo",
+			headers: map[string]string{
+				"X-Forwarded-Host": "evil.example.com",
+			},
+			targetURI: "https://auth.example.com/oidc/login",
+			baseURL:   "https://auth.example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			req := newRequest(tc.target, tc.remoteAddr, tc.headers)
+
+			require.Equal(t, tc.targetURI, r.TargetURI(req).String())
+			require.Equal(t, tc.baseURL, r.BaseURL(req))
+		})
+	}
+
+	t.Run("tls request forwarded without proto", func(t *testing.T) {
+		req := newRequest("/gnap/auth", "10.1.2.3:1234", map[string]string{"X-Forwarded-Host": "public.example.com"})
+		req.TLS = &tls.ConnectionState{}
+
+		require.Equal(t, "https://public.example.com/gnap/auth", r.TargetURI(req).String())
+	})
+
+	t.Run("nil resolver", func(t *testing.T) {
+		var nilResolver *Resolver
+
+		req := newRequest("/gnap/auth?foo=bar", "10.1.2.3:1234", nil)
+
+		require.Equal(t, "/gnap/auth?foo=bar", nilResolver.TargetURI(req).String())
+		require.Equal(t, "", nilResolver.BaseURL(req))
+	})
+
+	t.Run("renaming public prefix", func(t *testing.T) {
+		// the path of the GNAP continue endpoint, which clients reach at the base url.
+		const continuePath = "/gnap/continue"
+
+		for _, tc := range []struct {
+			prefixes    map[string]string
+			continueURL string
+		}{
+			{
+				prefixes:    map[string]string{"/gnap": "https://x.example.com/api"},
+				continueURL: "https://auth.example.com/gnap/continue",
+			},
+			{
+				prefixes:    map[string]string{"/gnap/continue": "https://continue.example.com"},
+				continueURL: "https://auth.example.com/gnap/continue",
+			},
+			{
+				prefixes:    map[string]string{"/gnap": "https://x.example.com/api/gnap"},
+				continueURL: "https://x.example.com/api/gnap/continue",
+			},
+			{
+				prefixes:    map[string]string{"/": "https://x.example.com/api"},
+				continueURL: "https://x.example.com/api/gnap/continue",
+			},
+		} {
+			r, err := New(&Config{BaseURL: "https://auth.example.com", PublicPrefixes: tc.prefixes})
+			require.NoError(t, err)
+
+			for _, target := range []string{"/gnap/auth", continuePath} {
+				req := newRequest(target, "1.2.3.4:1234", nil)
+
+				require.Equal(t, tc.continueURL, r.BaseURL(req)+continuePath)
+			}
+		}
+	})
+
+	t.Run("no trusted proxies", func(t *testing.T) {
+		r, err := New(&Config{BaseURL: "https://auth.example.com"})
+		require.NoError(t, err)
+
+		req := newRequest("/gnap/auth", "10.1.2.3:1234", map[string]string{"X-Forwarded-Host": "evil.example.com"})
+
+		require.Equal(t, "https://auth.example.com/gnap/auth", r.TargetURI(req).String())
+	})
+}
+
+func TestResolver_ClientAddr(t *testing.T) {
+	r, err := New(&Config{TrustedProxies: []string{"10.0.0.0/8"}})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		expect     string
+	}{
+		{name: "direct", remoteAddr: "192.0.2.1:1234", expect: "192.0.2.1"},
+		{
+			name:       "untrusted proxy",
+			remoteAddr: "192.0.2.1:1234",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1"},
+			expect:     "192.0.2.1",
+		},
+		{
+			name:       "trusted proxy",
+			remoteAddr: "10.1.2.3:1234",
+			headers:    map[string]string{"X-Forwarded-For": "203.0.113.1, 198.51.100.1"},
+			expect:     "198.51.100.1",
+		},
+		{name: "trusted proxy without header", remoteAddr: "10.1.2.3:1234", expect: "10.1.2.3"},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expect, r.ClientAddr(newRequest("/gnap/device", tc.remoteAddr, tc.headers)))
+		})
+	}
+
+	t.Run("nil resolver", func(t *testing.T) {
+		var nilResolver *Resolver
+
+		require.Equal(t, "10.1.2.3", nilResolver.ClientAddr(newRequest("/", "10.1.2.3:1234", nil)))
+	})
+}
+
+func newRequest(target, remoteAddr string, headers map[string]string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, target, nil)
+	req.RemoteAddr = remoteAddr
+
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	return req
+}
@@ -7,7 +7,10 @@ SPDX-License-Identifier: Apache-2.0
 package gnap
 
 type oidcClaims struct {
-	Sub string `json:"sub"`
+	Sub           string `json:"sub"`
+	Iss           string `json:"iss"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
 }
 
 type authProviders struct {
@@ -9,10 +9,12 @@ package gnap
 import (
 	"bytes"
 	"context"
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -27,6 +29,7 @@ import (
 	"github.com/cenkalti/backoff"
 	"github.com/coreos/go-oidc/v3/oidc"
 	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 	"github.com/hyperledger/aries-framework-go/pkg/common/log"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk"
 	"github.com/hyperledger/aries-framework-go/spi/storage"
@@ -37,15 +40,24 @@ import (
 	"github.com/trustbloc/auth/pkg/gnap/accesspolicy"
 	"github.com/trustbloc/auth/pkg/gnap/api"
 	"github.com/trustbloc/auth/pkg/gnap/authhandler"
+	"github.com/trustbloc/auth/pkg/gnap/clientregistry"
+	"github.com/trustbloc/auth/pkg/gnap/idtoken"
+	"github.com/trustbloc/auth/pkg/gnap/interact/usercode"
+	"github.com/trustbloc/auth/pkg/internal/common/proxy"
 	"github.com/trustbloc/auth/pkg/internal/common/support"
 	"github.com/trustbloc/auth/pkg/restapi/common"
 	oidcmodel "github.com/trustbloc/auth/pkg/restapi/common/oidc"
+	"github.com/trustbloc/auth/pkg/restapi/common/store/cookie"
 	"github.com/trustbloc/auth/spi/gnap"
 	"github.com/trustbloc/auth/spi/gnap/proof/httpsig"
+	"github.com/trustbloc/auth/spi/gnap/proof/jws"
 )
 
 var logger = log.New("auth-restapi") //nolint:gochecknoglobals
 
+// errInteractionBound is returned when binding an interaction that is already bound to a browser.
+var errInteractionBound = errors.New("interaction is already bound")
+
 const (
 	gnapBasePath = "/gnap"
 	// AuthRequestPath endpoint for GNAP authorization request.
@@ -56,6 +68,15 @@ const (
 	AuthIntrospectPath = gnapBasePath + "/introspect"
 	// InteractPath endpoint for GNAP interact.
 	InteractPath = gnapBasePath + "/interact"
+	// InteractDetailsPath endpoint for the details of an interaction, which the UI renders on its consent page.
+	InteractDetailsPath = InteractPath + "/{" + txnPathVar + "}"
+	// JWKSPath endpoint for the keys that verify id_tokens issued by the AS.
+	JWKSPath = gnapBasePath + "/jwks"
+	// UserCodePath endpoint where the user enters the user code of an interaction started on another device.
+	UserCodePath = gnapBasePath + "/device"
+	// InteractAppPath endpoint where a companion authenticator app reads and completes the interaction of an app
+	// deep link, on behalf of the user that is logged in to the app.
+	InteractAppPath = InteractPath + "/app/{" + txnPathVar + "}"
 
 	bootstrapPath = gnapBasePath + "/bootstrap"
 
@@ -65,16 +86,33 @@ const (
 	oidcCallbackPath  = "/oidc/callback"
 
 	// GNAP error response codes.
-	errInvalidRequest = "invalid_request"
-	errRequestDenied  = "request_denied"
+	errInvalidRequest  = "invalid_request"
+	errRequestDenied   = "request_denied"
+	errInvalidClient   = "invalid_client"
+	errUnknownUser     = "unknown_user"
+	errInvalidInteract = "invalid_interaction"
+	errInvalidContinue = "invalid_continuation"
+	errTooFast         = "too_fast"
 
 	// api path params.
 	providerQueryParam = "provider"
 	txnQueryParam      = "txnID"
+	txnPathVar         = "txnID"
+
+	// interaction cookies.
+	interactTxnCookie     = "gnap_interact_txn"
+	interactConsentCookie = "gnap_interact_consent"
+	cookieKeySize         = 32
+
+	// upstream oidc login request params.
+	loginHintParam = "login_hint"
 
 	transientStoreName = "gnap_transient"
 	bootstrapStoreName = "bootstrapdata"
 
+	// interactBindingPrefix prefixes the transient store keys that record which interactions are bound to a browser.
+	interactBindingPrefix = "interact_bound_"
+
 	// client redirect query params.
 	interactRefQueryParam  = "interact_ref"
 	responseHashQueryParam = "hash"
@@ -97,6 +135,28 @@ type UpdateBootstrapDataRequest struct {
 	Data map[string]string `json:"data"`
 }
 
+// UserCodeRequest is a user code that the user entered to start an interaction.
+type UserCodeRequest struct {
+	UserCode string `json:"user_code"`
+}
+
+// UserCodeResponse holds the url that the user's browser is sent to, to continue the interaction of a user code.
+type UserCodeResponse struct {
+	Redirect string `json:"redirect"`
+}
+
+// AppConsentRequest completes an interaction from a companion authenticator app. If Selection is nil, all of the
+// requested access is approved.
+type AppConsentRequest struct {
+	Selection *api.ConsentSelection `json:"selection,omitempty"`
+}
+
+// AppConsentResponse holds the url that the app sends the user to, if the client asked to be redirected when the
+// interaction finishes.
+type AppConsentResponse struct {
+	Redirect string `json:"redirect,omitempty"`
+}
+
 // Operation defines Auth Server GNAP handlers.
 type Operation struct {
 	authHandler         *authhandler.AuthHandler
@@ -110,28 +170,80 @@ type Operation struct {
 	cachedOIDCProvLock  sync.RWMutex
 	tlsConfig           *tls.Config
 	callbackURL         string
-	baseURL             string
+	publicURL           *proxy.Resolver
 	timeout             uint64
 	transientStore      storage.Store
+	bindLock            sync.Mutex
 	bootstrapStore      storage.Store
 	bootstrapConfig     *BootstrapConfig
 	gnapRSClient        *gnap.RequestClient
+	verifierConfig      *authhandler.VerifierConfig
+	idTokenIssuer       *idtoken.Issuer
+	cookies             cookie.Store
+	userCodes           *usercode.InteractHandler
 }
 
 // Config defines configuration for GNAP operations.
 type Config struct {
-	StoreProvider          storage.Provider
-	AccessPolicyConfig     *accesspolicy.Config
-	BaseURL                string
-	ClosePopupHTML         string
-	InteractionHandler     api.InteractionHandler
+	StoreProvider      storage.Provider
+	AccessPolicyConfig *accesspolicy.Config
+	// ClientRegistryConfig holds pre-registered clients, which send their instance identifier by reference. Their
+	// jwks_uri is fetched using TLSConfig, unless the config has an http client.
+	ClientRegistryConfig *clientregistry.Config
+	// ClientCAs are the trust anchors of certificates bound to client keys. If nil, certificates aren't validated.
+	ClientCAs      *x509.CertPool
+	BaseURL        string
+	ClosePopupHTML string
+	// InteractionHandler runs the login & consent interactions in the user's browser, which the interaction and
+	// oidc callback endpoints complete.
+	InteractionHandler api.InteractionHandler
+	// InteractionHandlers are the handlers that grant requests can start interactions with, in order of preference.
+	// Each of them must share the interactions of InteractionHandler. If empty, InteractionHandler is registered for
+	// the redirect start mode.
+	InteractionHandlers    []*authhandler.InteractionHandlerConfig
 	UIEndpoint             string
 	OIDC                   *oidcmodel.Config
 	StartupTimeout         uint64
 	TransientStoreProvider storage.Provider
 	TLSConfig              *tls.Config
 	DisableHTTPSigVerify   bool
-	BootstrapConfig        *BootstrapConfig
+	ReplayProtection       bool
+	HTTPSigConfig          *HTTPSigConfig
+	// TrustedProxies are addresses or CIDR ranges of reverse proxies, whose Forwarded and X-Forwarded-* headers
+	// are used to reconstruct the public url of client requests.
+	TrustedProxies []string
+	// PublicPrefixes maps request path prefixes to the public url prefix they are served under.
+	PublicPrefixes  map[string]string
+	BootstrapConfig *BootstrapConfig
+	// IDTokenSigningKey signs the id_tokens and login credentials that clients can request as subject assertions.
+	// If nil, the AS doesn't issue subject assertions.
+	IDTokenSigningKey crypto.Signer
+	// Cookies holds the keys of the cookies that bind interactions to the user's browser. If nil, random keys are
+	// generated, so that the cookies are only valid until the server restarts.
+	Cookies *CookieConfig
+	// UserCodes resolves the codes that users enter at the user code endpoint. If nil, user codes aren't supported.
+	UserCodes *usercode.InteractHandler
+	// PollInterval is the time that clients wait between continue requests, while they poll for the result of an
+	// interaction. Defaults to authhandler.DefaultPollInterval.
+	PollInterval time.Duration
+}
+
+// CookieConfig holds cookie configuration.
+type CookieConfig struct {
+	AuthKey []byte
+	EncKey  []byte
+}
+
+// HTTPSigConfig holds the policy for verifying client http-signatures.
+type HTTPSigConfig struct {
+	// Label, if set, is the label of the client signature. Otherwise, any valid signature is accepted.
+	Label string
+	// RequiredComponents are covered components that client signatures must include, in addition to the method,
+	// target uri, authorization and content-digest.
+	RequiredComponents []string
+	// ClockSkew is the largest allowed difference between the current time and the created time of client
+	// signatures, for both http-signature and JWS proofs. Defaults to httpsig.DefaultClockSkew.
+	ClockSkew time.Duration
 }
 
 // BootstrapConfig holds user bootstrap-related config.
@@ -154,12 +266,21 @@ func New(config *Config) (*Operation, error) {
 		authProviders = append(authProviders, prov)
 	}
 
+	idTokenIssuer, err := createIDTokenIssuer(config)
+	if err != nil {
+		return nil, err
+	}
+
 	auth, err := authhandler.New(&authhandler.Config{
-		StoreProvider:      config.StoreProvider,
-		AccessPolicyConfig: config.AccessPolicyConfig,
-		ContinuePath:       config.BaseURL + AuthContinuePath,
-		InteractionHandler: config.InteractionHandler,
-		DisableHTTPSig:     config.DisableHTTPSigVerify,
+		StoreProvider:        config.StoreProvider,
+		AccessPolicyConfig:   config.AccessPolicyConfig,
+		ClientRegistryConfig: createClientRegistryConfig(config),
+		ClientCAs:            config.ClientCAs,
+		ContinuePath:         AuthContinuePath,
+		InteractionHandlers:  createInteractionHandlers(config),
+		DisableHTTPSig:       config.DisableHTTPSigVerify,
+		IDTokenIssuer:        idTokenIssuer,
+		PollInterval:         config.PollInterval,
 	})
 	if err != nil {
 		return nil, err
@@ -184,6 +305,25 @@ func New(config *Config) (*Operation, error) {
 		return nil, err
 	}
 
+	verifierConfig, err := createVerifierConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	cookies, err := createCookieStore(config.Cookies)
+	if err != nil {
+		return nil, err
+	}
+
+	publicURL, err := proxy.New(&proxy.Config{
+		BaseURL:        config.BaseURL,
+		TrustedProxies: config.TrustedProxies,
+		PublicPrefixes: config.PublicPrefixes,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("initializing public url resolver: %w", err)
+	}
+
 	return &Operation{
 		authHandler:         auth,
 		uiEndpoint:          config.UIEndpoint,
@@ -200,7 +340,11 @@ func New(config *Config) (*Operation, error) {
 		bootstrapConfig:     config.BootstrapConfig,
 		introspectHandler:   introspectHandler,
 		gnapRSClient:        gnapRSClient,
-		baseURL:             config.BaseURL,
+		publicURL:           publicURL,
+		verifierConfig:      verifierConfig,
+		idTokenIssuer:       idTokenIssuer,
+		cookies:             cookies,
+		userCodes:           config.UserCodes,
 	}, nil
 }
 
@@ -210,8 +354,15 @@ func (o *Operation) GetRESTHandlers() []common.Handler {
 		support.NewHTTPHandler(AuthRequestPath, http.MethodPost, o.authRequestHandler),
 		// TODO add txn_id to url path
 		support.NewHTTPHandler(InteractPath, http.MethodGet, o.interactHandler),
+		support.NewHTTPHandler(InteractDetailsPath, http.MethodGet, o.interactDetailsHandler),
+		support.NewHTTPHandler(InteractDetailsPath, http.MethodPost, o.interactConsentHandler),
 		support.NewHTTPHandler(AuthContinuePath, http.MethodPost, o.authContinueHandler),
 		support.NewHTTPHandler(AuthIntrospectPath, http.MethodPost, o.authIntrospectHandler),
+		support.NewHTTPHandler(JWKSPath, http.MethodGet, o.jwksHandler),
+		support.NewHTTPHandler(UserCodePath, http.MethodGet, o.userCodePageHandler),
+		support.NewHTTPHandler(UserCodePath, http.MethodPost, o.userCodeHandler),
+		support.NewHTTPHandler(InteractAppPath, http.MethodGet, o.interactAppDetailsHandler),
+		support.NewHTTPHandler(InteractAppPath, http.MethodPost, o.interactAppConsentHandler),
 
 		support.NewHTTPHandler(authProvidersPath, http.MethodGet, o.authProvidersHandler),
 		support.NewHTTPHandler(oidcLoginPath, http.MethodGet, o.oidcLoginHandler),
@@ -230,14 +381,8 @@ func (o *Operation) SetIntrospectHandler(i common.Introspecter) {
 func (o *Operation) authRequestHandler(w http.ResponseWriter, req *http.Request) {
 	logger.Debugf("handling auth request to URL: %s", req.URL.String())
 
-	prevURL := req.URL
-
-	var err error
-
-	req.URL, err = url.Parse(o.baseURL + req.URL.Path)
-	if err != nil {
-		req.URL = prevURL
-	}
+	baseURL := o.publicURL.BaseURL(req)
+	req.URL = o.publicURL.TargetURI(req)
 
 	authRequest := &gnap.AuthRequest{}
 
@@ -254,7 +399,7 @@ func (o *Operation) authRequestHandler(w http.ResponseWriter, req *http.Request)
 
 	req.Body = ioutil.NopCloser(bytes.NewReader(bodyBytes))
 
-	if err = json.Unmarshal(bodyBytes, authRequest); err != nil {
+	if err = unmarshalRequest(req, bodyBytes, authRequest); err != nil {
 		logger.Errorf("failed to parse gnap auth request: %s", err.Error())
 		w.WriteHeader(http.StatusBadRequest)
 		o.writeResponse(w, &gnap.ErrorResponse{
@@ -264,14 +409,17 @@ func (o *Operation) authRequestHandler(w http.ResponseWriter, req *http.Request)
 		return
 	}
 
-	v := httpsig.NewVerifier(req)
+	v := authhandler.NewRequestVerifier(req, o.verifierConfig)
 
-	resp, err := o.authHandler.HandleAccessRequest(authRequest, v, "")
+	resp, err := o.authHandler.HandleAccessRequest(authRequest, v, req.URL.String(), baseURL)
 	if err != nil {
 		logger.Errorf("access policy failed to handle access request: %s", err.Error())
-		w.WriteHeader(http.StatusUnauthorized)
+
+		status, code := gnapError(err)
+
+		w.WriteHeader(status)
 		o.writeResponse(w, &gnap.ErrorResponse{
-			Error: errRequestDenied,
+			Error: code,
 		})
 
 		return
@@ -281,9 +429,61 @@ func (o *Operation) authRequestHandler(w http.ResponseWriter, req *http.Request)
 }
 
 func (o *Operation) interactHandler(w http.ResponseWriter, req *http.Request) {
-	// TODO validate txnID
 	txnID := req.URL.Query().Get(txnQueryParam)
 
+	details, err := o.interactionHandler.QueryDetails(txnID)
+	if err != nil {
+		o.writeErrorResponse(w, http.StatusNotFound, "failed to get interaction details: %s", err.Error())
+
+		return
+	}
+
+	jar, err := o.cookies.Open(req)
+	if err != nil {
+		o.writeErrorResponse(w, http.StatusInternalServerError, "failed to open session cookies: %s", err.Error())
+
+		return
+	}
+
+	// bind the interaction to the first browser that opens it, so that only the user who started it can read its
+	// details.
+	if boundTxn, ok := jar.Get(interactTxnCookie); !ok || boundTxn != txnID {
+		err = o.bindInteraction(txnID)
+		if errors.Is(err, errInteractionBound) {
+			o.writeErrorResponse(w, http.StatusForbidden, "interaction is bound to another browser")
+
+			return
+		}
+
+		if err != nil {
+			o.writeErrorResponse(w, http.StatusInternalServerError, "failed to bind interaction: %s", err.Error())
+
+			return
+		}
+
+		jar.Set(interactTxnCookie, txnID)
+		jar.Delete(interactConsentCookie)
+
+		err = jar.Save(req, w)
+		if err != nil {
+			o.writeErrorResponse(w, http.StatusInternalServerError, "failed to persist session cookies: %s", err.Error())
+
+			return
+		}
+	}
+
+	// skip the provider selection if the client identified a user who logged in with a known provider before.
+	if hint := details.User; hint != nil && o.oidcProvidersConfig[hint.Provider] != nil {
+		loginURL := o.publicURL.BaseURL(req) + oidcLoginPath + "?" + url.Values{
+			providerQueryParam: {hint.Provider},
+			txnQueryParam:      {txnID},
+		}.Encode()
+
+		http.Redirect(w, req, loginURL, http.StatusFound)
+
+		return
+	}
+
 	redirURL, err := url.Parse(o.uiEndpoint + "/sign-up")
 	if err != nil {
 		o.writeErrorResponse(w, http.StatusInternalServerError, "failed to construct redirect url")
@@ -301,6 +501,279 @@ func (o *Operation) interactHandler(w http.ResponseWriter, req *http.Request) {
 	http.Redirect(w, req, redirURL.String(), http.StatusFound)
 }
 
+// interactDetailsHandler returns the access, subject information and client that the user is asked to consent to
+// in an interaction, and the time the interaction expires. Only the browser that started the interaction can read
+// its details.
+func (o *Operation) interactDetailsHandler(w http.ResponseWriter, req *http.Request) {
+	txnID := mux.Vars(req)[txnPathVar]
+
+	if _, ok := o.boundInteraction(w, req, txnID); !ok {
+		return
+	}
+
+	details, err := o.interactionHandler.QueryDetails(txnID)
+	if err != nil {
+		o.writeErrorResponse(w, http.StatusNotFound, "failed to get interaction details: %s", err.Error())
+
+		return
+	}
+
+	o.writeResponse(w, details)
+}
+
+// interactConsentHandler saves the part of an interaction's requested access that the user approved, which is
+// granted when the user completes the interaction. If the user doesn't select the access to approve, all of it is
+// granted.
+func (o *Operation) interactConsentHandler(w http.ResponseWriter, req *http.Request) {
+	txnID := mux.Vars(req)[txnPathVar]
+
+	selection := &api.ConsentSelection{}
+
+	err := json.NewDecoder(req.Body).Decode(selection)
+	if err != nil {
+		o.writeErrorResponse(w, http.StatusBadRequest, "failed to parse consent selection: %s", err.Error())
+
+		return
+	}
+
+	jar, ok := o.boundInteraction(w, req, txnID)
+	if !ok {
+		return
+	}
+
+	selectionBytes, err := json.Marshal(selection)
+	if err != nil {
+		o.writeErrorResponse(w, http.StatusInternalServerError, "failed to marshal consent selection: %s", err.Error())
+
+		return
+	}
+
+	jar.Set(interactConsentCookie, string(selectionBytes))
+
+	err = jar.Save(req, w)
+	if err != nil {
+		o.writeErrorResponse(w, http.StatusInternalServerError, "failed to persist session cookies: %s", err.Error())
+
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// bindInteraction records that the interaction with the given txn ID is bound to a browser. It returns
+// errInteractionBound if the interaction was bound before, so that an interaction is never bound to another browser.
+func (o *Operation) bindInteraction(txnID string) error {
+	key := interactBindingPrefix + txnID
+
+	o.bindLock.Lock()
+	defer o.bindLock.Unlock()
+
+	_, err := o.transientStore.Get(key)
+
+	switch {
+	case err == nil:
+		return errInteractionBound
+	case !errors.Is(err, storage.ErrDataNotFound):
+		return fmt.Errorf("reading interaction binding: %w", err)
+	}
+
+	err = o.transientStore.Batch([]storage.Operation{{
+		Key:        key,
+		Value:      []byte(txnID),
+		PutOptions: &storage.PutOptions{IsNewKey: true},
+	}})
+	if errors.Is(err, storage.ErrDuplicateKey) {
+		return errInteractionBound
+	}
+
+	if err != nil {
+		return fmt.Errorf("saving interaction binding: %w", err)
+	}
+
+	return nil
+}
+
+// boundInteraction opens the session cookies of the request, and checks that the interaction with the given txn ID
+// was started by the same browser. If not, it writes an error response and returns false.
+func (o *Operation) boundInteraction(w http.ResponseWriter, req *http.Request, txnID string) (cookie.Jar, bool) {
+	jar, err := o.cookies.Open(req)
+	if err != nil {
+		o.writeErrorResponse(w, http.StatusInternalServerError, "failed to open session cookies: %s", err.Error())
+
+		return nil, false
+	}
+
+	if boundTxn, ok := jar.Get(interactTxnCookie); !ok || boundTxn != txnID {
+		o.writeErrorResponse(w, http.StatusForbidden, "interaction isn't bound to this browser")
+
+		return nil, false
+	}
+
+	return jar, true
+}
+
+// consentSelection returns the part of the interaction's requested access that the user approved in this browser,
+// or nil if the user didn't select the access to approve.
+func (o *Operation) consentSelection(req *http.Request, txnID string) (*api.ConsentSelection, error) {
+	jar, err := o.cookies.Open(req)
+	if err != nil {
+		return nil, fmt.Errorf("opening session cookies: %w", err)
+	}
+
+	if boundTxn, ok := jar.Get(interactTxnCookie); !ok || boundTxn != txnID {
+		return nil, nil
+	}
+
+	raw, ok := jar.Get(interactConsentCookie)
+	if !ok {
+		return nil, nil
+	}
+
+	selectionJSON, ok := raw.(string)
+	if !ok {
+		return nil, errors.New("invalid consent selection cookie")
+	}
+
+	selection := &api.ConsentSelection{}
+
+	err = json.Unmarshal([]byte(selectionJSON), selection)
+	if err != nil {
+		return nil, fmt.Errorf("parsing consent selection: %w", err)
+	}
+
+	return selection, nil
+}
+
+// userCodePageHandler redirects to the UI page where the user enters a user code, which is the url that clients
+// show for the user_code_uri start mode.
+func (o *Operation) userCodePageHandler(w http.ResponseWriter, req *http.Request) {
+	http.Redirect(w, req, o.uiEndpoint+"/device", http.StatusFound)
+}
+
+// userCodeHandler resolves a user code that the user entered, returning the url that continues the interaction in
+// the user's browser. Requesters that enter too many invalid codes are refused for a while.
+func (o *Operation) userCodeHandler(w http.ResponseWriter, req *http.Request) {
+	if o.userCodes == nil {
+		o.writeErrorResponse(w, http.StatusNotFound, "user codes aren't supported")
+
+		return
+	}
+
+	codeReq := &UserCodeRequest{}
+
+	err := json.NewDecoder(req.Body).Decode(codeReq)
+	if err != nil {
+		o.writeErrorResponse(w, http.StatusBadRequest, "failed to parse user code request: %s", err.Error())
+
+		return
+	}
+
+	redirect, err := o.userCodes.Resolve(codeReq.UserCode, o.publicURL.ClientAddr(req))
+
+	switch {
+	case errors.Is(err, usercode.ErrTooManyAttempts):
+		o.writeErrorResponse(w, http.StatusTooManyRequests, "%s", err.Error())
+	case errors.Is(err, usercode.ErrInvalidCode):
+		o.writeErrorResponse(w, http.StatusNotFound, "%s", err.Error())
+	case err != nil:
+		o.writeErrorResponse(w, http.StatusInternalServerError, "failed to resolve user code: %s", err.Error())
+	default:
+		o.writeResponse(w, &UserCodeResponse{Redirect: redirect})
+	}
+}
+
+// interactAppDetailsHandler returns the details of an interaction to the companion authenticator app that the
+// interaction's app deep link launched, which asks the user logged in to the app for consent.
+func (o *Operation) interactAppDetailsHandler(w http.ResponseWriter, req *http.Request) {
+	_, details, ok := o.appInteraction(w, req, mux.Vars(req)[txnPathVar])
+	if !ok {
+		return
+	}
+
+	o.writeResponse(w, details)
+}
+
+// interactAppConsentHandler completes an interaction from a companion authenticator app, granting the approved access
+// to the client for the user that is logged in to the app.
+func (o *Operation) interactAppConsentHandler(w http.ResponseWriter, req *http.Request) {
+	txnID := mux.Vars(req)[txnPathVar]
+
+	consentReq := &AppConsentRequest{}
+
+	err := json.NewDecoder(req.Body).Decode(consentReq)
+	if err != nil {
+		o.writeErrorResponse(w, http.StatusBadRequest, "failed to parse app consent request: %s", err.Error())
+
+		return
+	}
+
+	subject, _, ok := o.appInteraction(w, req, txnID)
+	if !ok {
+		return
+	}
+
+	interactRef, responseHash, clientInteract, err := o.interactionHandler.CompleteInteraction(
+		txnID,
+		&api.ConsentResult{
+			SubjectData: subject,
+			Selection:   consentReq.Selection,
+		},
+	)
+	if err != nil {
+		o.writeErrorResponse(w, http.StatusInternalServerError,
+			fmt.Sprintf("failed to complete GNAP interaction : %s", err))
+
+		return
+	}
+
+	redirect, ok := o.finishInteraction(w, txnID, interactRef, responseHash, clientInteract)
+	if !ok {
+		return
+	}
+
+	o.writeResponse(w, &AppConsentResponse{Redirect: redirect})
+}
+
+// appInteraction returns the subject data of the user logged in to the companion app that authorizes the request,
+// and the details of the interaction with the given txnID. If the interaction wasn't started with the app start mode,
+// or the request isn't authorized by an access token of the companion app of the interaction's client, it writes an
+// error response and returns false.
+func (o *Operation) appInteraction(
+	w http.ResponseWriter, req *http.Request, txnID string,
+) (map[string]string, *api.InteractionDetails, bool) {
+	introspection, ok := o.authenticatedToken(w, req)
+	if !ok {
+		return nil, nil, false
+	}
+
+	details, err := o.interactionHandler.QueryDetails(txnID)
+	if err != nil {
+		o.writeErrorResponse(w, http.StatusNotFound, "failed to get interaction details: %s", err.Error())
+
+		return nil, nil, false
+	}
+
+	if !details.App || details.AppClientID == "" || introspection.InstanceID != details.AppClientID {
+		o.writeErrorResponse(w, http.StatusForbidden, "interaction can't be completed by this app")
+
+		return nil, nil, false
+	}
+
+	return introspection.SubjectData, details, true
+}
+
+// jwksHandler publishes the keys that verify id_tokens issued by the AS. The key set is empty if the AS doesn't
+// issue id_tokens.
+func (o *Operation) jwksHandler(w http.ResponseWriter, _ *http.Request) {
+	jwks := &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{}}
+
+	if o.idTokenIssuer != nil {
+		jwks = o.idTokenIssuer.JWKS()
+	}
+
+	o.writeResponse(w, jwks)
+}
+
 func (o *Operation) authProvidersHandler(w http.ResponseWriter, _ *http.Request) {
 	o.writeResponse(w, &authProviders{Providers: o.authProviders})
 }
@@ -371,16 +844,40 @@ func (o *Operation) oidcLoginHandler(w http.ResponseWriter, r *http.Request) { /
 		return
 	}
 
-	authOption := oauth2.SetAuthURLParam(providerQueryParam, providerID)
+	authOptions := []oauth2.AuthCodeOption{
+		oauth2.AccessTypeOnline,
+		oauth2.SetAuthURLParam(providerQueryParam, providerID),
+	}
+
+	// pre-fill the login of the user that the client identified, unless the user chose a different provider.
+	hint := o.userHint(interactTxnID)
+
+	if loginHint := hint.LoginHint(); loginHint != "" && (hint.Provider == "" || hint.Provider == providerID) {
+		authOptions = append(authOptions, oauth2.SetAuthURLParam(loginHintParam, loginHint))
+	}
+
 	redirectURL := provider.OAuth2Config(
 		scopes...,
-	).AuthCodeURL(state, oauth2.AccessTypeOnline, authOption)
+	).AuthCodeURL(state, authOptions...)
 
 	http.Redirect(w, r, redirectURL, http.StatusFound)
 
 	logger.Debugf("redirected to: %s", redirectURL)
 }
 
+// userHint returns the user that the client of the given interaction identified, or nil if the client didn't
+// identify the user or the interaction can't be found.
+func (o *Operation) userHint(txnID string) *api.UserHint {
+	details, err := o.interactionHandler.QueryDetails(txnID)
+	if err != nil {
+		logger.Debugf("no user hint for interaction %s: %s", txnID, err.Error())
+
+		return nil
+	}
+
+	return details.User
+}
+
 func (o *Operation) oidcCallbackHandler(w http.ResponseWriter, r *http.Request) { // nolint:funlen,gocyclo
 	state := r.URL.Query().Get("state")
 	if state == "" {
@@ -456,9 +953,9 @@ func (o *Operation) oidcCallbackHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	_, err = user.NewStore(o.bootstrapStore).Get(claims.Sub)
+	profile, err := user.NewStore(o.bootstrapStore).Get(claims.Sub)
 	if errors.Is(err, storage.ErrDataNotFound) {
-		_, err = o.onboardUser(claims.Sub)
+		profile, err = o.onboardUser(claims.Sub)
 		if err != nil {
 			o.writeErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("failed to onboard new user : %s", err))
 
@@ -466,12 +963,22 @@ func (o *Operation) oidcCallbackHandler(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
+	if err != nil {
+		profile = nil
+	}
+
+	selection, err := o.consentSelection(r, data.TxnID)
+	if err != nil {
+		o.writeErrorResponse(w, http.StatusInternalServerError, "failed to read consent selection : %s", err.Error())
+
+		return
+	}
+
 	interactRef, responseHash, clientInteract, err := o.interactionHandler.CompleteInteraction(
 		data.TxnID,
 		&api.ConsentResult{
-			SubjectData: map[string]string{
-				"sub": claims.Sub,
-			},
+			SubjectData: subjectData(providerID, claims, profile),
+			Selection:   selection,
 		},
 	)
 	if err != nil {
@@ -481,23 +988,15 @@ func (o *Operation) oidcCallbackHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	clientURI, err := url.Parse(clientInteract.Finish.URI)
-	if err != nil {
-		o.writeErrorResponse(w, http.StatusBadRequest, "client provided invalid redirect URI : %s", err.Error())
-
+	redirect, ok := o.finishInteraction(w, data.TxnID, interactRef, responseHash, clientInteract)
+	if !ok {
 		return
 	}
 
-	// TODO: validate clientURI for security
-
-	q := clientURI.Query()
-
-	q.Add(interactRefQueryParam, interactRef)
-	q.Add(responseHashQueryParam, responseHash)
-
-	clientURI.RawQuery = q.Encode()
-
-	redirect := clientURI.String()
+	if redirect == "" {
+		// the client isn't redirected, so the user is told to go back to the device that started the interaction.
+		redirect = o.uiEndpoint + "/done"
+	}
 
 	t, err := template.ParseFiles(o.closePopupHTML)
 	if err != nil {
@@ -513,18 +1012,59 @@ func (o *Operation) oidcCallbackHandler(w http.ResponseWriter, r *http.Request)
 	}
 }
 
-func (o *Operation) authContinueHandler(w http.ResponseWriter, req *http.Request) { // nolint: funlen
-	logger.Debugf("handling continue request to URL: %s", req.URL.String())
+// finishInteraction hands the result of a completed interaction to the client, as the client's finish method asks.
+// It returns the url that the user is redirected to, which is empty if the client isn't redirected. If the result
+// can't be handed over, it writes an error response and returns false.
+func (o *Operation) finishInteraction(
+	w http.ResponseWriter,
+	txnID, interactRef, responseHash string,
+	clientInteract *gnap.RequestInteract,
+) (string, bool) {
+	if clientInteract == nil || clientInteract.Finish == nil {
+		// the client didn't request to be notified when the interaction finishes, so it polls the continue endpoint.
+		err := o.authHandler.HandleInteractionCompleted(txnID, interactRef)
+		if err != nil {
+			o.writeErrorResponse(w, http.StatusInternalServerError,
+				fmt.Sprintf("failed to save GNAP interaction result : %s", err))
 
-	prevURL := req.URL
+			return "", false
+		}
 
-	var err error
+		return "", true
+	}
 
-	req.URL, err = url.Parse(o.baseURL + req.URL.Path)
+	if clientInteract.Finish.Method == api.FinishMethodPush {
+		// the interaction handler notifies the client's server, so the user isn't redirected.
+		return "", true
+	}
+
+	clientURI, err := url.Parse(clientInteract.Finish.URI)
 	if err != nil {
-		req.URL = prevURL
+		o.writeErrorResponse(w, http.StatusBadRequest, "client provided invalid redirect URI : %s", err.Error())
+
+		return "", false
 	}
 
+	// TODO: validate clientURI for security
+
+	q := clientURI.Query()
+
+	q.Add(interactRefQueryParam, interactRef)
+	q.Add(responseHashQueryParam, responseHash)
+
+	clientURI.RawQuery = q.Encode()
+
+	return clientURI.String(), true
+}
+
+func (o *Operation) authContinueHandler(w http.ResponseWriter, req *http.Request) { // nolint: funlen
+	logger.Debugf("handling continue request to URL: %s", req.URL.String())
+
+	baseURL := o.publicURL.BaseURL(req)
+	req.URL = o.publicURL.TargetURI(req)
+
+	var err error
+
 	tokHeader := strings.Split(strings.Trim(req.Header.Get("Authorization"), " "), " ")
 
 	if len(tokHeader) < 2 || tokHeader[0] != "GNAP" {
@@ -554,7 +1094,7 @@ func (o *Operation) authContinueHandler(w http.ResponseWriter, req *http.Request
 
 	req.Body = ioutil.NopCloser(bytes.NewReader(bodyBytes))
 
-	if err = json.Unmarshal(bodyBytes, continueRequest); err != nil {
+	if err = unmarshalRequest(req, bodyBytes, continueRequest); err != nil {
 		logger.Errorf("failed to parse gnap continue request: %s", err.Error())
 		w.WriteHeader(http.StatusBadRequest)
 		o.writeResponse(w, &gnap.ErrorResponse{
@@ -564,14 +1104,17 @@ func (o *Operation) authContinueHandler(w http.ResponseWriter, req *http.Request
 		return
 	}
 
-	v := httpsig.NewVerifier(req)
+	v := authhandler.NewRequestVerifier(req, o.verifierConfig)
 
-	resp, err := o.authHandler.HandleContinueRequest(continueRequest, token, v)
+	resp, err := o.authHandler.HandleContinueRequest(continueRequest, token, v, baseURL)
 	if err != nil {
 		logger.Errorf("access policy failed to handle continue request: %s", err.Error())
-		w.WriteHeader(http.StatusUnauthorized)
+
+		status, code := gnapError(err)
+
+		w.WriteHeader(status)
 		o.writeResponse(w, &gnap.ErrorResponse{
-			Error: errRequestDenied,
+			Error: code,
 		})
 
 		return
@@ -680,15 +1223,10 @@ func (o *Operation) InternalIntrospectHandler() common.Introspecter {
 func (o *Operation) authIntrospectHandler(w http.ResponseWriter, req *http.Request) {
 	logger.Debugf("handling introspect request to URL: %s", req.URL.String())
 
-	prevURL := req.URL
+	req.URL = o.publicURL.TargetURI(req)
 
 	var err error
 
-	req.URL, err = url.Parse(o.baseURL + req.URL.Path)
-	if err != nil {
-		req.URL = prevURL
-	}
-
 	introspectRequest := &gnap.IntrospectRequest{}
 
 	bodyBytes, err := ioutil.ReadAll(req.Body)
@@ -704,7 +1242,7 @@ func (o *Operation) authIntrospectHandler(w http.ResponseWriter, req *http.Reque
 
 	req.Body = ioutil.NopCloser(bytes.NewReader(bodyBytes))
 
-	if err = json.Unmarshal(bodyBytes, introspectRequest); err != nil {
+	if err = unmarshalRequest(req, bodyBytes, introspectRequest); err != nil {
 		logger.Errorf("failed to parse gnap introspection request: %s", err.Error())
 		w.WriteHeader(http.StatusBadRequest)
 		o.writeResponse(w, &gnap.ErrorResponse{
@@ -714,14 +1252,17 @@ func (o *Operation) authIntrospectHandler(w http.ResponseWriter, req *http.Reque
 		return
 	}
 
-	v := httpsig.NewVerifier(req)
+	v := authhandler.NewRequestVerifier(req, o.verifierConfig)
 
 	resp, err := o.authHandler.HandleIntrospection(introspectRequest, v)
 	if err != nil {
 		logger.Errorf("failed to handle gnap introspection request: %s", err.Error())
-		w.WriteHeader(http.StatusUnauthorized)
+
+		status, code := gnapError(err)
+
+		w.WriteHeader(status)
 		o.writeResponse(w, &gnap.ErrorResponse{
-			Error: errRequestDenied,
+			Error: code,
 		})
 
 		return
@@ -730,6 +1271,45 @@ func (o *Operation) authIntrospectHandler(w http.ResponseWriter, req *http.Reque
 	o.writeResponse(w, resp)
 }
 
+// gnapError maps an error from the auth handler to the http status and GNAP error code to respond with.
+func gnapError(err error) (int, string) {
+	switch {
+	case errors.Is(err, httpsig.ErrInvalidContentDigest),
+		errors.Is(err, authhandler.ErrUnsupportedSubjectFormat):
+		return http.StatusBadRequest, errInvalidRequest
+	case errors.Is(err, authhandler.ErrUnknownUser):
+		return http.StatusBadRequest, errUnknownUser
+	case errors.Is(err, api.ErrInvalidInteraction):
+		return http.StatusBadRequest, errInvalidInteract
+	case errors.Is(err, authhandler.ErrInvalidContinuation):
+		return http.StatusBadRequest, errInvalidContinue
+	case errors.Is(err, authhandler.ErrTooFast):
+		return http.StatusBadRequest, errTooFast
+	case errors.Is(err, httpsig.ErrInvalidSignature),
+		errors.Is(err, httpsig.ErrStaleSignature),
+		errors.Is(err, httpsig.ErrFutureSignature),
+		errors.Is(err, httpsig.ErrReplayedSignature):
+		return http.StatusUnauthorized, errInvalidClient
+	default:
+		return http.StatusUnauthorized, errRequestDenied
+	}
+}
+
+// unmarshalRequest parses a GNAP request body, unwrapping it first if the client sent it as an attached JWS.
+// The JWS signature itself is verified later, by the proof method of the client key.
+func unmarshalRequest(req *http.Request, body []byte, v interface{}) error {
+	if strings.HasPrefix(req.Header.Get("Content-Type"), jws.ContentType) {
+		payload, err := jws.UnverifiedPayload(body)
+		if err != nil {
+			return err
+		}
+
+		body = payload
+	}
+
+	return json.Unmarshal(body, v)
+}
+
 // WriteResponse writes interface value to response.
 func (o *Operation) writeResponse(rw http.ResponseWriter, v interface{}) {
 	rw.Header().Set("Content-Type", "application/json")
@@ -836,6 +1416,89 @@ func createStore(p storage.Provider, name string) (storage.Store, error) {
 	return s, nil
 }
 
+func createVerifierConfig(config *Config) (*authhandler.VerifierConfig, error) {
+	verifierConfig := &authhandler.VerifierConfig{}
+
+	if config.ReplayProtection {
+		cache, err := httpsig.NewReplayCache(config.StoreProvider)
+		if err != nil {
+			return nil, err
+		}
+
+		verifierConfig.ReplayCache = cache
+	}
+
+	if config.HTTPSigConfig != nil {
+		verifierConfig.SignatureLabel = config.HTTPSigConfig.Label
+		verifierConfig.RequiredComponents = config.HTTPSigConfig.RequiredComponents
+		verifierConfig.ClockSkew = config.HTTPSigConfig.ClockSkew
+	}
+
+	return verifierConfig, nil
+}
+
+func createClientRegistryConfig(config *Config) *clientregistry.Config {
+	if config.ClientRegistryConfig == nil {
+		return nil
+	}
+
+	registryConfig := *config.ClientRegistryConfig
+
+	if registryConfig.HTTPClient == nil {
+		registryConfig.HTTPClient = &http.Client{Transport: &http.Transport{TLSClientConfig: config.TLSConfig}}
+	}
+
+	return &registryConfig
+}
+
+func createInteractionHandlers(config *Config) []*authhandler.InteractionHandlerConfig {
+	if len(config.InteractionHandlers) > 0 || config.InteractionHandler == nil {
+		return config.InteractionHandlers
+	}
+
+	return []*authhandler.InteractionHandlerConfig{{
+		Name:          api.StartModeRedirect,
+		Handler:       config.InteractionHandler,
+		StartModes:    []string{api.StartModeRedirect},
+		FinishMethods: []string{api.FinishMethodRedirect, api.FinishMethodPush},
+	}}
+}
+
+func createIDTokenIssuer(config *Config) (*idtoken.Issuer, error) {
+	if config.IDTokenSigningKey == nil {
+		return nil, nil
+	}
+
+	issuer, err := idtoken.New(&idtoken.Config{
+		Issuer:     config.BaseURL,
+		SigningKey: config.IDTokenSigningKey,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("initializing id_token issuer: %w", err)
+	}
+
+	return issuer, nil
+}
+
+func createCookieStore(config *CookieConfig) (cookie.Store, error) {
+	if config != nil {
+		return cookie.NewStore(config.AuthKey, config.EncKey), nil
+	}
+
+	authKey := make([]byte, cookieKeySize)
+	encKey := make([]byte, cookieKeySize)
+
+	if _, err := rand.Read(authKey); err != nil {
+		return nil, fmt.Errorf("generating cookie auth key: %w", err)
+	}
+
+	if _, err := rand.Read(encKey); err != nil {
+		return nil, fmt.Errorf("generating cookie enc key: %w", err)
+	}
+
+	return cookie.NewStore(authKey, encKey), nil
+}
+
 func createGNAPClient() (*gnap.RequestClient, error) {
 	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 	if err != nil {
@@ -845,7 +1508,7 @@ func createGNAPClient() (*gnap.RequestClient, error) {
 	return &gnap.RequestClient{
 		IsReference: false,
 		Key: &gnap.ClientKey{
-			Proof: "httpsig",
+			Proof: authhandler.ProofHTTPSig,
 			JWK: jwk.JWK{
 				JSONWebKey: jose.JSONWebKey{
 					Key:       &priv.PublicKey,
@@ -873,25 +1536,72 @@ func (o *Operation) onboardUser(sub string) (*user.Profile, error) {
 	return userProfile, nil
 }
 
+// subjectData returns the identifiers of the user that logged in to the given provider with the given id_token
+// claims, which the AS can return to clients that request subject information.
+func subjectData(providerID string, claims *oidcClaims, profile *user.Profile) map[string]string {
+	data := map[string]string{
+		api.SubjectDataSub:      claims.Sub,
+		api.SubjectDataProvider: providerID,
+	}
+
+	if claims.Iss != "" {
+		data[api.SubjectDataIssuer] = claims.Iss
+	}
+
+	if claims.Email != "" && claims.EmailVerified {
+		data[api.SubjectDataEmail] = claims.Email
+	}
+
+	if profile != nil && profile.Data[api.SubjectDataDID] != "" {
+		data[api.SubjectDataDID] = profile.Data[api.SubjectDataDID]
+	}
+
+	return data
+}
+
 func (o *Operation) subject(w http.ResponseWriter, r *http.Request) (string, bool) {
+	data, ok := o.authenticatedSubject(w, r)
+	if !ok {
+		return "", false
+	}
+
+	return data[api.SubjectDataSub], true
+}
+
+// authenticatedSubject returns the subject data of the user whose access token authorizes the request. If the request
+// isn't authorized, it writes an error response and returns false.
+func (o *Operation) authenticatedSubject(w http.ResponseWriter, r *http.Request) (map[string]string, bool) {
+	introspection, ok := o.authenticatedToken(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	return introspection.SubjectData, true
+}
+
+// authenticatedToken returns the introspection of the access token that authorizes the request, which grants access
+// to the subject id of a user. If the request isn't authorized, it writes an error response and returns false.
+func (o *Operation) authenticatedToken(w http.ResponseWriter, r *http.Request) (*gnap.IntrospectResponse, bool) {
 	authHeader := strings.TrimSpace(r.Header.Get("authorization"))
 	if authHeader == "" {
 		o.writeErrorResponse(w, http.StatusForbidden, "no credentials")
 
-		return "", false
+		return nil, false
 	}
 
 	switch {
 	case strings.HasPrefix(authHeader, gnapScheme):
-		return o.gnapSub(w, r, authHeader)
+		return o.gnapIntrospection(w, r, authHeader)
 	default:
 		o.writeErrorResponse(w, http.StatusBadRequest, "invalid authorization scheme")
 
-		return "", false
+		return nil, false
 	}
 }
 
-func (o *Operation) gnapSub(w http.ResponseWriter, _ *http.Request, authHeader string) (string, bool) {
+func (o *Operation) gnapIntrospection(
+	w http.ResponseWriter, _ *http.Request, authHeader string,
+) (*gnap.IntrospectResponse, bool) {
 	token := authHeader[len(gnapScheme):]
 
 	introspection, err := o.introspectHandler(&gnap.IntrospectRequest{
@@ -901,16 +1611,16 @@ func (o *Operation) gnapSub(w http.ResponseWriter, _ *http.Request, authHeader s
 	if err != nil {
 		o.writeErrorResponse(w, http.StatusUnauthorized, "failed to introspect token: %s", err.Error())
 
-		return "", false
+		return nil, false
 	}
 
-	if sub, ok := introspection.SubjectData["sub"]; ok {
-		return sub, true
+	if _, ok := introspection.SubjectData[api.SubjectDataSub]; ok {
+		return introspection, true
 	}
 
 	o.writeErrorResponse(w, http.StatusUnauthorized, "token does not grant access to subject id")
 
-	return "", false
+	return nil, false
 }
 
 func merge(existing *user.Profile, update *UpdateBootstrapDataRequest) *user.Profile {
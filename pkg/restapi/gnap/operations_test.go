@@ -10,8 +10,10 @@ import (
 	"bytes"
 	"context"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/rsa"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -22,8 +24,10 @@ import (
 	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk"
 	mockstore "github.com/hyperledger/aries-framework-go/pkg/mock/storage"
@@ -35,13 +39,19 @@ import (
 	"github.com/trustbloc/auth/pkg/bootstrap/user"
 	"github.com/trustbloc/auth/pkg/gnap/accesspolicy"
 	"github.com/trustbloc/auth/pkg/gnap/api"
+	"github.com/trustbloc/auth/pkg/gnap/authhandler"
+	"github.com/trustbloc/auth/pkg/gnap/clientregistry"
 	"github.com/trustbloc/auth/pkg/gnap/interact/redirect"
+	"github.com/trustbloc/auth/pkg/gnap/interact/usercode"
 	"github.com/trustbloc/auth/pkg/internal/common/mockinteract"
 	"github.com/trustbloc/auth/pkg/internal/common/mockoidc"
 	"github.com/trustbloc/auth/pkg/internal/common/mockstorage"
 	oidcmodel "github.com/trustbloc/auth/pkg/restapi/common/oidc"
+	"github.com/trustbloc/auth/pkg/restapi/common/store/cookie"
 	"github.com/trustbloc/auth/spi/gnap"
 	"github.com/trustbloc/auth/spi/gnap/proof/httpsig"
+	"github.com/trustbloc/auth/spi/gnap/proof/jws"
+	"github.com/trustbloc/auth/spi/gnap/proof/jwsd"
 )
 
 const (
@@ -68,6 +78,87 @@ func TestNew(t *testing.T) {
 		require.Nil(t, o)
 	})
 
+	t.Run("success with replay protection", func(t *testing.T) {
+		conf := config(t)
+		conf.ReplayProtection = true
+
+		o, err := New(conf)
+		require.NoError(t, err)
+		require.NotNil(t, o.verifierConfig.ReplayCache)
+	})
+
+	t.Run("invalid trusted proxy", func(t *testing.T) {
+		conf := config(t)
+		conf.TrustedProxies = []string{"foo"}
+
+		_, err := New(conf)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "initializing public url resolver")
+	})
+
+	t.Run("success with http-signature policy", func(t *testing.T) {
+		conf := config(t)
+		conf.HTTPSigConfig = &HTTPSigConfig{
+			Label:              "gnap",
+			RequiredComponents: []string{"@authority"},
+		}
+
+		o, err := New(conf)
+		require.NoError(t, err)
+		require.Equal(t, "gnap", o.verifierConfig.SignatureLabel)
+		require.Equal(t, []string{"@authority"}, o.verifierConfig.RequiredComponents)
+	})
+
+	t.Run("invalid client registry", func(t *testing.T) {
+		conf := config(t)
+		conf.ClientRegistryConfig = &clientregistry.Config{
+			Clients: []clientregistry.ClientConfig{{ID: "foo"}},
+		}
+
+		_, err := New(conf)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "initializing client registry")
+	})
+
+	t.Run("invalid id_token signing key", func(t *testing.T) {
+		priv, err := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+		require.NoError(t, err)
+
+		conf := config(t)
+		conf.IDTokenSigningKey = priv
+
+		_, err = New(conf)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "initializing id_token issuer")
+	})
+
+	t.Run("invalid interaction handlers", func(t *testing.T) {
+		conf := config(t)
+		conf.InteractionHandlers = []*authhandler.InteractionHandlerConfig{{Name: "app"}}
+
+		_, err := New(conf)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "interaction handlers need a name and a handler")
+	})
+
+	t.Run("interaction handlers", func(t *testing.T) {
+		interact := &mockinteract.InteractHandler{}
+
+		conf := config(t)
+		conf.InteractionHandler = interact
+
+		require.Equal(t, []*authhandler.InteractionHandlerConfig{{
+			Name:          "redirect",
+			Handler:       interact,
+			StartModes:    []string{"redirect"},
+			FinishMethods: []string{"redirect", "push"},
+		}}, createInteractionHandlers(conf))
+
+		conf.InteractionHandlers = []*authhandler.InteractionHandlerConfig{{Name: "app", Handler: interact}}
+
+		require.Equal(t, conf.InteractionHandlers, createInteractionHandlers(conf))
+	})
+
 	t.Run("error if unable to open transient store", func(t *testing.T) {
 		config := config(t)
 		config.TransientStoreProvider = &mockstore.MockStoreProvider{
@@ -82,7 +173,136 @@ func TestOperation_GetRESTHandlers(t *testing.T) {
 	o := &Operation{}
 
 	h := o.GetRESTHandlers()
-	require.Len(t, h, 9)
+	require.Len(t, h, 16)
+}
+
+func TestOperation_jwksHandler(t *testing.T) {
+	t.Run("id_tokens not issued", func(t *testing.T) {
+		o, err := New(config(t))
+		require.NoError(t, err)
+
+		rw := httptest.NewRecorder()
+
+		o.jwksHandler(rw, httptest.NewRequest(http.MethodGet, JWKSPath, nil))
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.JSONEq(t, `{"keys":[]}`, rw.Body.String())
+	})
+
+	t.Run("success", func(t *testing.T) {
+		priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		conf := config(t)
+		conf.IDTokenSigningKey = priv
+
+		o, err := New(conf)
+		require.NoError(t, err)
+
+		rw := httptest.NewRecorder()
+
+		o.jwksHandler(rw, httptest.NewRequest(http.MethodGet, JWKSPath, nil))
+		require.Equal(t, http.StatusOK, rw.Code)
+
+		jwks := &jose.JSONWebKeySet{}
+
+		require.NoError(t, json.Unmarshal(rw.Body.Bytes(), jwks))
+		require.Len(t, jwks.Keys, 1)
+		require.True(t, jwks.Keys[0].IsPublic())
+		require.Equal(t, "ES256", jwks.Keys[0].Algorithm)
+	})
+}
+
+func TestOperation_userCodeHandlers(t *testing.T) {
+	newUserCodes := func(t *testing.T, conf *Config) *usercode.InteractHandler {
+		t.Helper()
+
+		h, err := usercode.New(&usercode.Config{
+			StoreProvider:      mem.NewProvider(),
+			InteractionHandler: conf.InteractionHandler,
+			UserCodePath:       UserCodePath,
+			MaxAttempts:        1,
+		})
+		require.NoError(t, err)
+
+		return h
+	}
+
+	postCode := func(o *Operation, body string) *httptest.ResponseRecorder {
+		rw := httptest.NewRecorder()
+
+		o.userCodeHandler(rw, httptest.NewRequest(http.MethodPost, UserCodePath, strings.NewReader(body)))
+
+		return rw
+	}
+
+	t.Run("page", func(t *testing.T) {
+		conf := config(t)
+		conf.UIEndpoint = "https://ui.example.com"
+
+		o, err := New(conf)
+		require.NoError(t, err)
+
+		rw := httptest.NewRecorder()
+
+		o.userCodePageHandler(rw, httptest.NewRequest(http.MethodGet, UserCodePath, nil))
+		require.Equal(t, http.StatusFound, rw.Code)
+		require.Equal(t, "https://ui.example.com/device", rw.Header().Get("Location"))
+	})
+
+	t.Run("success", func(t *testing.T) {
+		conf := config(t)
+		userCodes := newUserCodes(t, conf)
+		conf.UserCodes = userCodes
+
+		o, err := New(conf)
+		require.NoError(t, err)
+
+		res, _, err := userCodes.PrepareInteraction(&gnap.RequestInteract{Start: []string{"user_code"}},
+			"", baseURL, nil, nil)
+		require.NoError(t, err)
+
+		rw := postCode(o, `{"user_code":"`+res.UserCode+`"}`)
+		require.Equal(t, http.StatusOK, rw.Code)
+
+		resp := &UserCodeResponse{}
+
+		require.NoError(t, json.Unmarshal(rw.Body.Bytes(), resp))
+		require.True(t, strings.HasPrefix(resp.Redirect, baseURL+InteractPath+"?txnID="))
+	})
+
+	t.Run("invalid code", func(t *testing.T) {
+		conf := config(t)
+		conf.UserCodes = newUserCodes(t, conf)
+
+		o, err := New(conf)
+		require.NoError(t, err)
+
+		rw := postCode(o, `{"user_code":"BCDF-GHJK"}`)
+		require.Equal(t, http.StatusNotFound, rw.Code)
+
+		rw = postCode(o, `{"user_code":"BCDF-GHJK"}`)
+		require.Equal(t, http.StatusTooManyRequests, rw.Code)
+	})
+
+	t.Run("invalid request", func(t *testing.T) {
+		conf := config(t)
+		conf.UserCodes = newUserCodes(t, conf)
+
+		o, err := New(conf)
+		require.NoError(t, err)
+
+		rw := postCode(o, `{`)
+		require.Equal(t, http.StatusBadRequest, rw.Code)
+	})
+
+	t.Run("user codes not supported", func(t *testing.T) {
+		o, err := New(config(t))
+		require.NoError(t, err)
+
+		rw := postCode(o, `{"user_code":"BCDF-GHJK"}`)
+		require.Equal(t, http.StatusNotFound, rw.Code)
+		require.Contains(t, rw.Body.String(), "user codes aren't supported")
+	})
 }
 
 func TestOperation_AuthProvidersHandler(t *testing.T) {
@@ -113,75 +333,1166 @@ func TestOperation_authRequestHandler(t *testing.T) {
 
 		req := httptest.NewRequest(http.MethodPost, AuthRequestPath, &errorReader{err: expectErr})
 
-		o.authRequestHandler(rw, req)
+		o.authRequestHandler(rw, req)
+
+		require.Equal(t, http.StatusInternalServerError, rw.Code)
+	})
+
+	t.Run("fail to parse empty request body", func(t *testing.T) {
+		o := &Operation{}
+
+		rw := httptest.NewRecorder()
+
+		req := httptest.NewRequest(http.MethodPost, AuthRequestPath, nil)
+
+		o.authRequestHandler(rw, req)
+
+		require.Equal(t, http.StatusBadRequest, rw.Code)
+	})
+
+	t.Run("auth handler error", func(t *testing.T) {
+		o := &Operation{}
+
+		rw := httptest.NewRecorder()
+
+		req := httptest.NewRequest(http.MethodPost, AuthRequestPath, bytes.NewReader([]byte("{}")))
+
+		o.authRequestHandler(rw, req)
+
+		require.Equal(t, http.StatusUnauthorized, rw.Code)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		o, err := New(config(t))
+		require.NoError(t, err)
+
+		priv, client := clientKey(t)
+
+		authReq := &gnap.AuthRequest{
+			Interact: &gnap.RequestInteract{Start: []string{"redirect"}},
+			Client: &gnap.RequestClient{
+				IsReference: false,
+				Key:         client,
+			},
+		}
+
+		authReqBytes, err := json.Marshal(authReq)
+		require.NoError(t, err)
+
+		rw := httptest.NewRecorder()
+
+		req := httptest.NewRequest(http.MethodPost, baseURL+AuthRequestPath, bytes.NewReader(authReqBytes))
+
+		req, err = httpsig.Sign(req, authReqBytes, priv, "sha-256")
+		require.NoError(t, err)
+
+		o.authRequestHandler(rw, req)
+
+		require.Equal(t, http.StatusOK, rw.Code)
+	})
+
+	t.Run("success behind trusted proxy", func(t *testing.T) {
+		conf := config(t)
+		conf.TrustedProxies = []string{"10.0.0.0/8"}
+
+		o, err := New(conf)
+		require.NoError(t, err)
+
+		priv, client := clientKey(t)
+
+		authReq := &gnap.AuthRequest{
+			Client: &gnap.RequestClient{
+				Key: client,
+			},
+			AccessToken: []*gnap.TokenRequest{
+				{
+					Access: []gnap.TokenAccess{
+						{
+							IsReference: true,
+							Ref:         "client-id",
+						},
+					},
+				},
+			},
+			Interact: &gnap.RequestInteract{
+				Start: []string{"redirect"},
+				Finish: &gnap.RequestFinish{
+					Method: "redirect",
+					URI:    "example.com/client-ui",
+				},
+			},
+		}
+
+		authReqBytes, err := json.Marshal(authReq)
+		require.NoError(t, err)
+
+		// the client signs the public url, which the proxy forwards under a path prefix.
+		req := httptest.NewRequest(http.MethodPost, "https://public.example.com/auth"+AuthRequestPath,
+			bytes.NewReader(authReqBytes))
+
+		req, err = httpsig.Sign(req, authReqBytes, priv, "sha-256")
+		require.NoError(t, err)
+
+		req.URL, err = url.Parse(AuthRequestPath)
+		require.NoError(t, err)
+
+		req.Host = "auth.internal"
+		req.RemoteAddr = "10.0.0.2:1234"
+		req.Header.Set("X-Forwarded-Proto", "https")
+		req.Header.Set("X-Forwarded-Host", "public.example.com")
+		req.Header.Set("X-Forwarded-Prefix", "/auth")
+
+		rw := httptest.NewRecorder()
+
+		o.authRequestHandler(rw, req)
+
+		require.Equal(t, http.StatusOK, rw.Code, rw.Body.String())
+
+		authResp := &gnap.AuthResponse{}
+		require.NoError(t, json.Unmarshal(rw.Body.Bytes(), authResp))
+
+		require.Equal(t, "https://public.example.com/auth"+AuthContinuePath, authResp.Continue.URI)
+		require.True(t, strings.HasPrefix(authResp.Interact.Redirect, "https://public.example.com/auth"+InteractPath))
+	})
+
+	t.Run("success with registered client", func(t *testing.T) {
+		priv, client := clientKey(t)
+
+		jwksServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			require.NoError(t, json.NewEncoder(w).Encode(&clientregistry.JWKS{Keys: []jwk.JWK{client.JWK}}))
+		}))
+		t.Cleanup(jwksServer.Close)
+
+		conf := config(t)
+		conf.ClientRegistryConfig = &clientregistry.Config{
+			Clients: []clientregistry.ClientConfig{{ID: "client1", JWKSURI: jwksServer.URL}},
+		}
+
+		o, err := New(conf)
+		require.NoError(t, err)
+
+		authReq := &gnap.AuthRequest{
+			Client: &gnap.RequestClient{
+				IsReference: true,
+				Ref:         "client1",
+			},
+			AccessToken: []*gnap.TokenRequest{
+				{
+					Access: []gnap.TokenAccess{
+						{
+							IsReference: true,
+							Ref:         "client-id",
+						},
+					},
+				},
+			},
+			Interact: &gnap.RequestInteract{
+				Start: []string{"redirect"},
+				Finish: &gnap.RequestFinish{
+					Method: "redirect",
+					URI:    "example.com/client-ui",
+				},
+			},
+		}
+
+		authReqBytes, err := json.Marshal(authReq)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodPost, baseURL+AuthRequestPath, bytes.NewReader(authReqBytes))
+
+		req, err = httpsig.Sign(req, authReqBytes, priv, "sha-256")
+		require.NoError(t, err)
+
+		rw := httptest.NewRecorder()
+
+		o.authRequestHandler(rw, req)
+
+		require.Equal(t, http.StatusOK, rw.Code, rw.Body.String())
+
+		authResp := &gnap.AuthResponse{}
+		require.NoError(t, json.Unmarshal(rw.Body.Bytes(), authResp))
+		require.Equal(t, "client1", authResp.InstanceID)
+	})
+
+	t.Run("forwarding headers from untrusted client", func(t *testing.T) {
+		conf := config(t)
+		conf.TrustedProxies = []string{"10.0.0.0/8"}
+
+		o, err := New(conf)
+		require.NoError(t, err)
+
+		priv, client := clientKey(t)
+
+		authReq := &gnap.AuthRequest{
+			Interact: &gnap.RequestInteract{Start: []string{"redirect"}},
+			Client: &gnap.RequestClient{
+				Key: client,
+			},
+		}
+
+		authReqBytes, err := json.Marshal(authReq)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodPost, "https://public.example.com"+AuthRequestPath,
+			bytes.NewReader(authReqBytes))
+
+		req, err = httpsig.Sign(req, authReqBytes, priv, "sha-256")
+		require.NoError(t, err)
+
+		req.RemoteAddr = "1.2.3.4:1234"
+		req.Header.Set("X-Forwarded-Proto", "https")
+		req.Header.Set("X-Forwarded-Host", "public.example.com")
+
+		rw := httptest.NewRecorder()
+
+		o.authRequestHandler(rw, req)
+
+		require.Equal(t, http.StatusUnauthorized, rw.Code)
+	})
+
+	t.Run("content-digest mismatch", func(t *testing.T) {
+		o, err := New(config(t))
+		require.NoError(t, err)
+
+		priv, client := clientKey(t)
+
+		authReq := &gnap.AuthRequest{
+			Interact: &gnap.RequestInteract{Start: []string{"redirect"}},
+			Client: &gnap.RequestClient{
+				Key: client,
+			},
+		}
+
+		authReqBytes, err := json.Marshal(authReq)
+		require.NoError(t, err)
+
+		rw := httptest.NewRecorder()
+
+		req := httptest.NewRequest(http.MethodPost, baseURL+AuthRequestPath, bytes.NewReader(authReqBytes))
+
+		req, err = httpsig.Sign(req, []byte("foo"), priv, "sha-256")
+		require.NoError(t, err)
+
+		o.authRequestHandler(rw, req)
+
+		require.Equal(t, http.StatusBadRequest, rw.Code)
+		require.Contains(t, rw.Body.String(), errInvalidRequest)
+	})
+
+	t.Run("unsupported subject format", func(t *testing.T) {
+		o, err := New(config(t))
+		require.NoError(t, err)
+
+		priv, client := clientKey(t)
+
+		authReq := &gnap.AuthRequest{
+			Interact: &gnap.RequestInteract{Start: []string{"redirect"}},
+			Client: &gnap.RequestClient{
+				Key: client,
+			},
+			Subject: &gnap.RequestSubject{SubIDFormats: []string{"phone_number"}},
+		}
+
+		authReqBytes, err := json.Marshal(authReq)
+		require.NoError(t, err)
+
+		rw := httptest.NewRecorder()
+
+		req := httptest.NewRequest(http.MethodPost, baseURL+AuthRequestPath, bytes.NewReader(authReqBytes))
+
+		req, err = httpsig.Sign(req, authReqBytes, priv, "sha-256")
+		require.NoError(t, err)
+
+		o.authRequestHandler(rw, req)
+
+		require.Equal(t, http.StatusBadRequest, rw.Code)
+		require.Contains(t, rw.Body.String(), errInvalidRequest)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		o, err := New(config(t))
+		require.NoError(t, err)
+
+		priv, client := clientKey(t)
+
+		authReq := &gnap.AuthRequest{
+			Interact: &gnap.RequestInteract{Start: []string{"redirect"}},
+			Client: &gnap.RequestClient{
+				Key: client,
+			},
+			User: &gnap.RequestUser{IsReference: true, Ref: "foo"},
+		}
+
+		authReqBytes, err := json.Marshal(authReq)
+		require.NoError(t, err)
+
+		rw := httptest.NewRecorder()
+
+		req := httptest.NewRequest(http.MethodPost, baseURL+AuthRequestPath, bytes.NewReader(authReqBytes))
+
+		req, err = httpsig.Sign(req, authReqBytes, priv, "sha-256")
+		require.NoError(t, err)
+
+		o.authRequestHandler(rw, req)
+
+		require.Equal(t, http.StatusBadRequest, rw.Code)
+		require.Contains(t, rw.Body.String(), errUnknownUser)
+	})
+
+	t.Run("unsupported interaction finish", func(t *testing.T) {
+		o, err := New(config(t))
+		require.NoError(t, err)
+
+		priv, client := clientKey(t)
+
+		authReq := &gnap.AuthRequest{
+			Client:      &gnap.RequestClient{Key: client},
+			AccessToken: []*gnap.TokenRequest{{Access: []gnap.TokenAccess{*gnap.NewTokenAccessRef("client-id")}}},
+			Interact: &gnap.RequestInteract{
+				Start:  []string{"redirect"},
+				Finish: &gnap.RequestFinish{Method: "push", URI: "https://client.example.com/push"},
+			},
+		}
+
+		authReqBytes, err := json.Marshal(authReq)
+		require.NoError(t, err)
+
+		rw := httptest.NewRecorder()
+
+		req := httptest.NewRequest(http.MethodPost, baseURL+AuthRequestPath, bytes.NewReader(authReqBytes))
+
+		req, err = httpsig.Sign(req, authReqBytes, priv, "sha-256")
+		require.NoError(t, err)
+
+		o.authRequestHandler(rw, req)
+
+		require.Equal(t, http.StatusBadRequest, rw.Code)
+		require.Contains(t, rw.Body.String(), errInvalidInteract)
+	})
+
+	t.Run("signed by a different key", func(t *testing.T) {
+		o, err := New(config(t))
+		require.NoError(t, err)
+
+		_, client := clientKey(t)
+		priv, _ := clientKey(t)
+
+		authReq := &gnap.AuthRequest{
+			Interact: &gnap.RequestInteract{Start: []string{"redirect"}},
+			Client: &gnap.RequestClient{
+				Key: client,
+			},
+		}
+
+		authReqBytes, err := json.Marshal(authReq)
+		require.NoError(t, err)
+
+		rw := httptest.NewRecorder()
+
+		req := httptest.NewRequest(http.MethodPost, baseURL+AuthRequestPath, bytes.NewReader(authReqBytes))
+
+		req, err = httpsig.Sign(req, authReqBytes, priv, "sha-256")
+		require.NoError(t, err)
+
+		o.authRequestHandler(rw, req)
+
+		require.Equal(t, http.StatusUnauthorized, rw.Code)
+		require.Contains(t, rw.Body.String(), errInvalidClient)
+	})
+
+	t.Run("replayed request", func(t *testing.T) {
+		conf := config(t)
+		conf.ReplayProtection = true
+
+		o, err := New(conf)
+		require.NoError(t, err)
+
+		priv, client := clientKey(t)
+
+		authReq := &gnap.AuthRequest{
+			Interact: &gnap.RequestInteract{Start: []string{"redirect"}},
+			Client: &gnap.RequestClient{
+				Key: client,
+			},
+		}
+
+		authReqBytes, err := json.Marshal(authReq)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodPost, baseURL+AuthRequestPath, bytes.NewReader(authReqBytes))
+
+		req, err = httpsig.Sign(req, authReqBytes, priv, "sha-256")
+		require.NoError(t, err)
+
+		rw := httptest.NewRecorder()
+
+		o.authRequestHandler(rw, req)
+
+		require.Equal(t, http.StatusOK, rw.Code)
+
+		replay := httptest.NewRequest(http.MethodPost, baseURL+AuthRequestPath, bytes.NewReader(authReqBytes))
+		replay.Header = req.Header.Clone()
+
+		rw = httptest.NewRecorder()
+
+		o.authRequestHandler(rw, replay)
+
+		require.Equal(t, http.StatusUnauthorized, rw.Code)
+		require.Contains(t, rw.Body.String(), errInvalidClient)
+	})
+
+	t.Run("success with eddsa and rsa-pss client keys", func(t *testing.T) {
+		_, edPriv, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		rsaPriv, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+
+		keys := []*jwk.JWK{
+			{JSONWebKey: jose.JSONWebKey{Key: edPriv, KeyID: "key1", Algorithm: "EdDSA"}, Kty: "OKP", Crv: "Ed25519"},
+			{JSONWebKey: jose.JSONWebKey{Key: rsaPriv, KeyID: "key1", Algorithm: "PS256"}, Kty: "RSA"},
+			{JSONWebKey: jose.JSONWebKey{Key: rsaPriv, KeyID: "key1", Algorithm: "PS512"}, Kty: "RSA"},
+		}
+
+		for _, priv := range keys {
+			o, err := New(config(t))
+			require.NoError(t, err)
+
+			authReq := &gnap.AuthRequest{
+				Interact: &gnap.RequestInteract{Start: []string{"redirect"}},
+				Client: &gnap.RequestClient{
+					Key: &gnap.ClientKey{
+						Proof: "httpsig",
+						JWK:   jwk.JWK{JSONWebKey: priv.Public(), Kty: priv.Kty, Crv: priv.Crv},
+					},
+				},
+			}
+
+			authReqBytes, err := json.Marshal(authReq)
+			require.NoError(t, err)
+
+			rw := httptest.NewRecorder()
+
+			req := httptest.NewRequest(http.MethodPost, baseURL+AuthRequestPath, bytes.NewReader(authReqBytes))
+
+			req, err = httpsig.Sign(req, authReqBytes, priv, "sha-512")
+			require.NoError(t, err)
+
+			o.authRequestHandler(rw, req)
+
+			require.Equal(t, http.StatusOK, rw.Code, priv.Algorithm)
+		}
+	})
+
+	t.Run("success with detached-jws proof", func(t *testing.T) {
+		o, err := New(config(t))
+		require.NoError(t, err)
+
+		priv, client := clientKey(t)
+		client.Proof = "jwsd"
+
+		authReq := &gnap.AuthRequest{
+			Interact: &gnap.RequestInteract{Start: []string{"redirect"}},
+			Client: &gnap.RequestClient{
+				IsReference: false,
+				Key:         client,
+			},
+		}
+
+		authReqBytes, err := json.Marshal(authReq)
+		require.NoError(t, err)
+
+		rw := httptest.NewRecorder()
+
+		req := httptest.NewRequest(http.MethodPost, baseURL+AuthRequestPath, bytes.NewReader(authReqBytes))
+
+		req, err = jwsd.Sign(req, authReqBytes, priv)
+		require.NoError(t, err)
+
+		o.authRequestHandler(rw, req)
+
+		require.Equal(t, http.StatusOK, rw.Code)
+	})
+
+	t.Run("success with attached-jws proof", func(t *testing.T) {
+		o, err := New(config(t))
+		require.NoError(t, err)
+
+		priv, client := clientKey(t)
+		client.Proof = "jws"
+
+		authReq := &gnap.AuthRequest{
+			Interact: &gnap.RequestInteract{Start: []string{"redirect"}},
+			Client: &gnap.RequestClient{
+				IsReference: false,
+				Key:         client,
+			},
+		}
+
+		authReqBytes, err := json.Marshal(authReq)
+		require.NoError(t, err)
+
+		rw := httptest.NewRecorder()
+
+		req := httptest.NewRequest(http.MethodPost, baseURL+AuthRequestPath, bytes.NewReader(authReqBytes))
+
+		req, err = jws.Sign(req, authReqBytes, priv)
+		require.NoError(t, err)
+
+		o.authRequestHandler(rw, req)
+
+		require.Equal(t, http.StatusOK, rw.Code)
+	})
+
+	t.Run("malformed attached-jws body", func(t *testing.T) {
+		o, err := New(config(t))
+		require.NoError(t, err)
+
+		rw := httptest.NewRecorder()
+
+		req := httptest.NewRequest(http.MethodPost, baseURL+AuthRequestPath, bytes.NewReader([]byte("foo")))
+		req.Header.Set("Content-Type", jws.ContentType)
+
+		o.authRequestHandler(rw, req)
+
+		require.Equal(t, http.StatusBadRequest, rw.Code)
+	})
+}
+
+func TestOperation_interactHandler(t *testing.T) {
+	detailsConfig := func(t *testing.T) *Config {
+		t.Helper()
+
+		conf := config(t)
+		conf.InteractionHandler = &mockinteract.InteractHandler{DetailsVal: &api.InteractionDetails{}}
+
+		return conf
+	}
+
+	t.Run("success", func(t *testing.T) {
+		o, err := New(detailsConfig(t))
+		require.NoError(t, err)
+
+		rw := httptest.NewRecorder()
+
+		req := httptest.NewRequest(http.MethodGet, InteractPath+"?txnID=foo", nil)
+
+		o.interactHandler(rw, req)
+
+		require.Equal(t, http.StatusFound, rw.Code)
+		require.Contains(t, rw.Header().Get("location"), "/sign-up")
+		require.NotEmpty(t, rw.Result().Cookies())
+	})
+
+	t.Run("unknown interaction", func(t *testing.T) {
+		o, err := New(config(t))
+		require.NoError(t, err)
+
+		rw := httptest.NewRecorder()
+
+		o.interactHandler(rw, httptest.NewRequest(http.MethodGet, InteractPath+"?txnID=foo", nil))
+
+		require.Equal(t, http.StatusNotFound, rw.Code)
+		require.Contains(t, rw.Body.String(), "failed to get interaction details")
+	})
+
+	t.Run("interaction is bound to the first browser", func(t *testing.T) {
+		o, err := New(detailsConfig(t))
+		require.NoError(t, err)
+
+		first := &cookie.MockJar{}
+		o.cookies = &cookie.MockStore{Jar: first}
+
+		rw := httptest.NewRecorder()
+
+		o.interactHandler(rw, httptest.NewRequest(http.MethodGet, InteractPath+"?txnID=foo", nil))
+
+		require.Equal(t, http.StatusFound, rw.Code)
+		require.Equal(t, "foo", first.Cookies[interactTxnCookie])
+
+		// the same browser can open the interaction again.
+		rw = httptest.NewRecorder()
+
+		o.interactHandler(rw, httptest.NewRequest(http.MethodGet, InteractPath+"?txnID=foo", nil))
+
+		require.Equal(t, http.StatusFound, rw.Code)
+
+		// another browser can't rebind it.
+		o.cookies = &cookie.MockStore{Jar: &cookie.MockJar{Cookies: map[interface{}]interface{}{interactTxnCookie: "bar"}}}
+
+		rw = httptest.NewRecorder()
+
+		o.interactHandler(rw, httptest.NewRequest(http.MethodGet, InteractPath+"?txnID=foo", nil))
+
+		require.Equal(t, http.StatusForbidden, rw.Code)
+		require.Contains(t, rw.Body.String(), "interaction is bound to another browser")
+	})
+
+	t.Run("fail to bind interaction", func(t *testing.T) {
+		o, err := New(detailsConfig(t))
+		require.NoError(t, err)
+
+		store := &mockstore.MockStore{Store: map[string]mockstore.DBEntry{}}
+		o.transientStore = store
+
+		store.ErrGet = errors.New("get error")
+
+		rw := httptest.NewRecorder()
+
+		o.interactHandler(rw, httptest.NewRequest(http.MethodGet, InteractPath+"?txnID=foo", nil))
+
+		require.Equal(t, http.StatusInternalServerError, rw.Code)
+		require.Contains(t, rw.Body.String(), "reading interaction binding")
+
+		store.ErrGet = nil
+		store.ErrBatch = errors.New("batch error")
+
+		rw = httptest.NewRecorder()
+
+		o.interactHandler(rw, httptest.NewRequest(http.MethodGet, InteractPath+"?txnID=foo", nil))
+
+		require.Equal(t, http.StatusInternalServerError, rw.Code)
+		require.Contains(t, rw.Body.String(), "saving interaction binding")
+
+		store.ErrBatch = fmt.Errorf("batch: %w", storage.ErrDuplicateKey)
+
+		rw = httptest.NewRecorder()
+
+		o.interactHandler(rw, httptest.NewRequest(http.MethodGet, InteractPath+"?txnID=foo", nil))
+
+		require.Equal(t, http.StatusForbidden, rw.Code)
+	})
+
+	t.Run("fail to open cookies", func(t *testing.T) {
+		o, err := New(detailsConfig(t))
+		require.NoError(t, err)
+
+		o.cookies = &cookie.MockStore{OpenErr: errors.New("expected error")}
+
+		rw := httptest.NewRecorder()
+
+		o.interactHandler(rw, httptest.NewRequest(http.MethodGet, InteractPath+"?txnID=foo", nil))
+
+		require.Equal(t, http.StatusInternalServerError, rw.Code)
+		require.Contains(t, rw.Body.String(), "failed to open session cookies")
+	})
+
+	t.Run("fail to save cookies", func(t *testing.T) {
+		o, err := New(detailsConfig(t))
+		require.NoError(t, err)
+
+		o.cookies = &cookie.MockStore{Jar: &cookie.MockJar{SaveErr: errors.New("expected error")}}
+
+		rw := httptest.NewRecorder()
+
+		o.interactHandler(rw, httptest.NewRequest(http.MethodGet, InteractPath+"?txnID=foo", nil))
+
+		require.Equal(t, http.StatusInternalServerError, rw.Code)
+		require.Contains(t, rw.Body.String(), "failed to persist session cookies")
+	})
+
+	t.Run("user hint pre-selects provider", func(t *testing.T) {
+		conf := config(t)
+		conf.InteractionHandler = &mockinteract.InteractHandler{
+			DetailsVal: &api.InteractionDetails{User: &api.UserHint{Sub: "user-123", Provider: "mock1"}},
+		}
+
+		o, err := New(conf)
+		require.NoError(t, err)
+
+		rw := httptest.NewRecorder()
+
+		req := httptest.NewRequest(http.MethodGet, baseURL+InteractPath+"?txnID=foo", nil)
+
+		o.interactHandler(rw, req)
+
+		require.Equal(t, http.StatusFound, rw.Code)
+
+		loginURL, err := url.Parse(rw.Header().Get("location"))
+		require.NoError(t, err)
+		require.Equal(t, oidcLoginPath, loginURL.Path)
+		require.Equal(t, "mock1", loginURL.Query().Get(providerQueryParam))
+		require.Equal(t, "foo", loginURL.Query().Get(txnQueryParam))
+	})
+
+	t.Run("user hint with unknown provider", func(t *testing.T) {
+		conf := config(t)
+		conf.InteractionHandler = &mockinteract.InteractHandler{
+			DetailsVal: &api.InteractionDetails{User: &api.UserHint{Sub: "user-123", Provider: "unknown"}},
+		}
+
+		o, err := New(conf)
+		require.NoError(t, err)
+
+		rw := httptest.NewRecorder()
+
+		o.interactHandler(rw, httptest.NewRequest(http.MethodGet, InteractPath+"?txnID=foo", nil))
+
+		require.Equal(t, http.StatusFound, rw.Code)
+		require.Contains(t, rw.Header().Get("location"), "/sign-up")
+	})
+}
+
+func TestOperation_interactDetailsHandler(t *testing.T) {
+	details := &api.InteractionDetails{
+		Tokens:      []gnap.TokenRequest{{Label: "foo", Access: []gnap.TokenAccess{{Type: "example-token-type"}}}},
+		SubjectKeys: []string{"email"},
+		Client:      &gnap.ClientDisplay{Name: "Wallet", URI: "https://wallet.example.com"},
+	}
+
+	detailsRequest := func(txnID string) *http.Request {
+		return mux.SetURLVars(httptest.NewRequest(http.MethodGet, InteractPath+"/"+txnID, nil),
+			map[string]string{txnPathVar: txnID})
+	}
+
+	t.Run("success", func(t *testing.T) {
+		conf := config(t)
+		conf.InteractionHandler = &mockinteract.InteractHandler{DetailsVal: details}
+
+		o, err := New(conf)
+		require.NoError(t, err)
+
+		rw := httptest.NewRecorder()
+
+		o.interactHandler(rw, httptest.NewRequest(http.MethodGet, InteractPath+"?txnID=foo", nil))
+		require.Equal(t, http.StatusFound, rw.Code)
+
+		req := detailsRequest("foo")
+
+		for _, c := range rw.Result().Cookies() {
+			req.AddCookie(c)
+		}
+
+		rw = httptest.NewRecorder()
+
+		o.interactDetailsHandler(rw, req)
+
+		require.Equal(t, http.StatusOK, rw.Code)
+
+		got := &api.InteractionDetails{}
+		require.NoError(t, json.Unmarshal(rw.Body.Bytes(), got))
+		require.Equal(t, details.SubjectKeys, got.SubjectKeys)
+		require.Equal(t, details.Client, got.Client)
+		require.Len(t, got.Tokens, 1)
+		require.Equal(t, "foo", got.Tokens[0].Label)
+	})
+
+	t.Run("interaction bound to another browser", func(t *testing.T) {
+		o, err := New(config(t))
+		require.NoError(t, err)
+
+		o.cookies = &cookie.MockStore{Jar: &cookie.MockJar{
+			Cookies: map[interface{}]interface{}{interactTxnCookie: "bar"},
+		}}
+
+		rw := httptest.NewRecorder()
+
+		o.interactDetailsHandler(rw, detailsRequest("foo"))
+
+		require.Equal(t, http.StatusForbidden, rw.Code)
+	})
+
+	t.Run("no bound interaction", func(t *testing.T) {
+		o, err := New(config(t))
+		require.NoError(t, err)
+
+		rw := httptest.NewRecorder()
+
+		o.interactDetailsHandler(rw, detailsRequest("foo"))
+
+		require.Equal(t, http.StatusForbidden, rw.Code)
+	})
+
+	t.Run("unknown interaction", func(t *testing.T) {
+		conf := config(t)
+		conf.InteractionHandler = &mockinteract.InteractHandler{DetailsErr: errors.New("expected error")}
+
+		o, err := New(conf)
+		require.NoError(t, err)
+
+		o.cookies = &cookie.MockStore{Jar: &cookie.MockJar{
+			Cookies: map[interface{}]interface{}{interactTxnCookie: "foo"},
+		}}
+
+		rw := httptest.NewRecorder()
+
+		o.interactDetailsHandler(rw, detailsRequest("foo"))
+
+		require.Equal(t, http.StatusNotFound, rw.Code)
+	})
+
+	t.Run("fail to open cookies", func(t *testing.T) {
+		o, err := New(config(t))
+		require.NoError(t, err)
+
+		o.cookies = &cookie.MockStore{OpenErr: errors.New("expected error")}
+
+		rw := httptest.NewRecorder()
+
+		o.interactDetailsHandler(rw, detailsRequest("foo"))
+
+		require.Equal(t, http.StatusInternalServerError, rw.Code)
+	})
+}
+
+func TestOperation_interactAppHandlers(t *testing.T) {
+	introspect := func(req *gnap.IntrospectRequest) (*gnap.IntrospectResponse, error) {
+		return &gnap.IntrospectResponse{
+			SubjectData: map[string]string{"sub": "user1", "email": "user1@example.com"},
+			InstanceID:  "authenticator",
+		}, nil
+	}
+
+	appDetails := func() *api.InteractionDetails {
+		return &api.InteractionDetails{ClientID: "wallet", App: true, AppClientID: "authenticator"}
+	}
+
+	appRequest := func(method, txnID string, body []byte) *http.Request {
+		req := httptest.NewRequest(method, InteractPath+"/app/"+txnID, bytes.NewReader(body))
+		req.Header.Set("authorization", "GNAP 123")
+
+		return mux.SetURLVars(req, map[string]string{txnPathVar: txnID})
+	}
+
+	t.Run("details", func(t *testing.T) {
+		details := appDetails()
+		details.SubjectKeys = []string{"email"}
+
+		conf := config(t)
+		conf.InteractionHandler = &mockinteract.InteractHandler{DetailsVal: details}
+
+		o, err := New(conf)
+		require.NoError(t, err)
+
+		o.SetIntrospectHandler(introspect)
+
+		rw := httptest.NewRecorder()
+
+		o.interactAppDetailsHandler(rw, appRequest(http.MethodGet, "foo", nil))
+
+		require.Equal(t, http.StatusOK, rw.Code)
+
+		got := &api.InteractionDetails{}
+		require.NoError(t, json.Unmarshal(rw.Body.Bytes(), got))
+		require.Equal(t, []string{"email"}, got.SubjectKeys)
+	})
+
+	t.Run("details of unknown interaction", func(t *testing.T) {
+		conf := config(t)
+		conf.InteractionHandler = &mockinteract.InteractHandler{DetailsErr: errors.New("expected error")}
+
+		o, err := New(conf)
+		require.NoError(t, err)
+
+		o.SetIntrospectHandler(introspect)
+
+		rw := httptest.NewRecorder()
+
+		o.interactAppDetailsHandler(rw, appRequest(http.MethodGet, "foo", nil))
+
+		require.Equal(t, http.StatusNotFound, rw.Code)
+	})
+
+	t.Run("details of interaction without app", func(t *testing.T) {
+		conf := config(t)
+		conf.InteractionHandler = &mockinteract.InteractHandler{
+			DetailsVal: &api.InteractionDetails{ClientID: "wallet"},
+		}
+
+		o, err := New(conf)
+		require.NoError(t, err)
+
+		o.SetIntrospectHandler(introspect)
+
+		rw := httptest.NewRecorder()
+
+		o.interactAppDetailsHandler(rw, appRequest(http.MethodGet, "foo", nil))
+
+		require.Equal(t, http.StatusForbidden, rw.Code)
+		require.Contains(t, rw.Body.String(), "interaction can't be completed by this app")
+	})
+
+	t.Run("details without credentials", func(t *testing.T) {
+		o, err := New(config(t))
+		require.NoError(t, err)
+
+		rw := httptest.NewRecorder()
+
+		o.interactAppDetailsHandler(rw, httptest.NewRequest(http.MethodGet, InteractPath+"/app/foo", nil))
+
+		require.Equal(t, http.StatusForbidden, rw.Code)
+	})
+
+	t.Run("consent with redirect finish", func(t *testing.T) {
+		interact := &mockinteract.InteractHandler{
+			DetailsVal:  appDetails(),
+			CompleteVal: "interact-ref",
+			CompleteInteract: &gnap.RequestInteract{Finish: &gnap.RequestFinish{
+				Method: "redirect",
+				URI:    "https://client.example.com/finish",
+			}},
+		}
+
+		conf := config(t)
+		conf.InteractionHandler = interact
+
+		o, err := New(conf)
+		require.NoError(t, err)
+
+		o.SetIntrospectHandler(introspect)
+
+		selection := &api.ConsentSelection{SubjectKeys: []string{}}
+
+		rw := httptest.NewRecorder()
+
+		o.interactAppConsentHandler(rw, appRequest(http.MethodPost, "foo",
+			marshal(t, &AppConsentRequest{Selection: selection})))
+
+		require.Equal(t, http.StatusOK, rw.Code)
+
+		res := &AppConsentResponse{}
+		require.NoError(t, json.Unmarshal(rw.Body.Bytes(), res))
+		require.Contains(t, res.Redirect, "https://client.example.com/finish?")
+		require.Contains(t, res.Redirect, "interact_ref=interact-ref")
+
+		require.Equal(t, "user1", interact.CompleteArgs.SubjectData["sub"])
+		require.Equal(t, "user1@example.com", interact.CompleteArgs.SubjectData["email"])
+		require.Equal(t, selection, interact.CompleteArgs.Selection)
+	})
+
+	t.Run("consent with push finish", func(t *testing.T) {
+		conf := config(t)
+		conf.InteractionHandler = &mockinteract.InteractHandler{
+			DetailsVal:       appDetails(),
+			CompleteInteract: &gnap.RequestInteract{Finish: &gnap.RequestFinish{Method: "push"}},
+		}
+
+		o, err := New(conf)
+		require.NoError(t, err)
+
+		o.SetIntrospectHandler(introspect)
+
+		rw := httptest.NewRecorder()
+
+		o.interactAppConsentHandler(rw, appRequest(http.MethodPost, "foo", []byte("{}")))
+
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.JSONEq(t, "{}", rw.Body.String())
+	})
+
+	t.Run("consent of polling client without session", func(t *testing.T) {
+		conf := config(t)
+		conf.InteractionHandler = &mockinteract.InteractHandler{DetailsVal: appDetails()}
+
+		o, err := New(conf)
+		require.NoError(t, err)
+
+		o.SetIntrospectHandler(introspect)
+
+		rw := httptest.NewRecorder()
+
+		o.interactAppConsentHandler(rw, appRequest(http.MethodPost, "foo", []byte("{}")))
+
+		require.Equal(t, http.StatusInternalServerError, rw.Code)
+		require.Contains(t, rw.Body.String(), "failed to save GNAP interaction result")
+	})
+
+	t.Run("consent of interaction without app", func(t *testing.T) {
+		interact := &mockinteract.InteractHandler{DetailsVal: &api.InteractionDetails{ClientID: "wallet"}}
+
+		conf := config(t)
+		conf.InteractionHandler = interact
+
+		o, err := New(conf)
+		require.NoError(t, err)
+
+		o.SetIntrospectHandler(introspect)
+
+		rw := httptest.NewRecorder()
+
+		o.interactAppConsentHandler(rw, appRequest(http.MethodPost, "foo", []byte("{}")))
+
+		require.Equal(t, http.StatusForbidden, rw.Code)
+		require.Nil(t, interact.CompleteArgs)
+	})
+
+	t.Run("consent with token of another client", func(t *testing.T) {
+		interact := &mockinteract.InteractHandler{DetailsVal: appDetails()}
+
+		conf := config(t)
+		conf.InteractionHandler = interact
+
+		o, err := New(conf)
+		require.NoError(t, err)
+
+		o.SetIntrospectHandler(func(req *gnap.IntrospectRequest) (*gnap.IntrospectResponse, error) {
+			return &gnap.IntrospectResponse{SubjectData: map[string]string{"sub": "user1"}, InstanceID: "wallet"}, nil
+		})
+
+		rw := httptest.NewRecorder()
+
+		o.interactAppConsentHandler(rw, appRequest(http.MethodPost, "foo", []byte("{}")))
+
+		require.Equal(t, http.StatusForbidden, rw.Code)
+		require.Contains(t, rw.Body.String(), "interaction can't be completed by this app")
+		require.Nil(t, interact.CompleteArgs)
+	})
+
+	t.Run("consent of unknown interaction", func(t *testing.T) {
+		conf := config(t)
+		conf.InteractionHandler = &mockinteract.InteractHandler{DetailsErr: errors.New("expected error")}
+
+		o, err := New(conf)
+		require.NoError(t, err)
+
+		o.SetIntrospectHandler(introspect)
+
+		rw := httptest.NewRecorder()
+
+		o.interactAppConsentHandler(rw, appRequest(http.MethodPost, "foo", []byte("{}")))
+
+		require.Equal(t, http.StatusNotFound, rw.Code)
+	})
+
+	t.Run("invalid consent request", func(t *testing.T) {
+		o, err := New(config(t))
+		require.NoError(t, err)
+
+		rw := httptest.NewRecorder()
+
+		o.interactAppConsentHandler(rw, appRequest(http.MethodPost, "foo", []byte("}")))
+
+		require.Equal(t, http.StatusBadRequest, rw.Code)
+	})
+
+	t.Run("token without subject", func(t *testing.T) {
+		o, err := New(config(t))
+		require.NoError(t, err)
+
+		o.SetIntrospectHandler(func(req *gnap.IntrospectRequest) (*gnap.IntrospectResponse, error) {
+			return &gnap.IntrospectResponse{SubjectData: map[string]string{}}, nil
+		})
+
+		rw := httptest.NewRecorder()
+
+		o.interactAppConsentHandler(rw, appRequest(http.MethodPost, "foo", []byte("{}")))
+
+		require.Equal(t, http.StatusUnauthorized, rw.Code)
+	})
+
+	t.Run("fail to complete interaction", func(t *testing.T) {
+		conf := config(t)
+		conf.InteractionHandler = &mockinteract.InteractHandler{
+			DetailsVal:  appDetails(),
+			CompleteErr: errors.New("expected error"),
+		}
+
+		o, err := New(conf)
+		require.NoError(t, err)
+
+		o.SetIntrospectHandler(introspect)
+
+		rw := httptest.NewRecorder()
+
+		o.interactAppConsentHandler(rw, appRequest(http.MethodPost, "foo", []byte("{}")))
 
 		require.Equal(t, http.StatusInternalServerError, rw.Code)
+		require.Contains(t, rw.Body.String(), "failed to complete GNAP interaction")
 	})
+}
 
-	t.Run("fail to parse empty request body", func(t *testing.T) {
-		o := &Operation{}
+func TestOperation_interactConsentHandler(t *testing.T) {
+	consentRequest := func(txnID, body string) *http.Request {
+		return mux.SetURLVars(httptest.NewRequest(http.MethodPost, InteractPath+"/"+txnID, strings.NewReader(body)),
+			map[string]string{txnPathVar: txnID})
+	}
+
+	t.Run("success", func(t *testing.T) {
+		o, err := New(config(t))
+		require.NoError(t, err)
+
+		jar := &cookie.MockJar{Cookies: map[interface{}]interface{}{interactTxnCookie: "foo"}}
+		o.cookies = &cookie.MockStore{Jar: jar}
 
 		rw := httptest.NewRecorder()
 
-		req := httptest.NewRequest(http.MethodPost, AuthRequestPath, nil)
+		o.interactConsentHandler(rw, consentRequest("foo", `{"access_token":[0],"subject_keys":["email"]}`))
 
-		o.authRequestHandler(rw, req)
+		require.Equal(t, http.StatusOK, rw.Code)
 
-		require.Equal(t, http.StatusBadRequest, rw.Code)
+		selection, err := o.consentSelection(httptest.NewRequest(http.MethodGet, "/", nil), "foo")
+		require.NoError(t, err)
+		require.Equal(t, &api.ConsentSelection{Tokens: []int{0}, SubjectKeys: []string{"email"}}, selection)
 	})
 
-	t.Run("auth handler error", func(t *testing.T) {
-		o := &Operation{}
+	t.Run("invalid selection", func(t *testing.T) {
+		o, err := New(config(t))
+		require.NoError(t, err)
 
 		rw := httptest.NewRecorder()
 
-		req := httptest.NewRequest(http.MethodPost, AuthRequestPath, bytes.NewReader([]byte("{}")))
-
-		o.authRequestHandler(rw, req)
+		o.interactConsentHandler(rw, consentRequest("foo", `{"access_token":"foo"}`))
 
-		require.Equal(t, http.StatusUnauthorized, rw.Code)
+		require.Equal(t, http.StatusBadRequest, rw.Code)
 	})
 
-	t.Run("success", func(t *testing.T) {
+	t.Run("interaction bound to another browser", func(t *testing.T) {
 		o, err := New(config(t))
 		require.NoError(t, err)
 
-		priv, client := clientKey(t)
+		o.cookies = &cookie.MockStore{Jar: &cookie.MockJar{
+			Cookies: map[interface{}]interface{}{interactTxnCookie: "bar"},
+		}}
 
-		authReq := &gnap.AuthRequest{
-			Client: &gnap.RequestClient{
-				IsReference: false,
-				Key:         client,
-			},
-		}
+		rw := httptest.NewRecorder()
 
-		authReqBytes, err := json.Marshal(authReq)
+		o.interactConsentHandler(rw, consentRequest("foo", `{}`))
+
+		require.Equal(t, http.StatusForbidden, rw.Code)
+	})
+
+	t.Run("fail to save cookies", func(t *testing.T) {
+		o, err := New(config(t))
 		require.NoError(t, err)
 
+		o.cookies = &cookie.MockStore{Jar: &cookie.MockJar{
+			Cookies: map[interface{}]interface{}{interactTxnCookie: "foo"},
+			SaveErr: errors.New("expected error"),
+		}}
+
 		rw := httptest.NewRecorder()
 
-		req := httptest.NewRequest(http.MethodPost, baseURL+AuthRequestPath, bytes.NewReader(authReqBytes))
+		o.interactConsentHandler(rw, consentRequest("foo", `{}`))
 
-		req, err = httpsig.Sign(req, authReqBytes, priv, "sha-256")
+		require.Equal(t, http.StatusInternalServerError, rw.Code)
+	})
+
+	t.Run("selection of another interaction", func(t *testing.T) {
+		o, err := New(config(t))
 		require.NoError(t, err)
 
-		o.authRequestHandler(rw, req)
+		o.cookies = &cookie.MockStore{Jar: &cookie.MockJar{Cookies: map[interface{}]interface{}{
+			interactTxnCookie:     "bar",
+			interactConsentCookie: `{"access_token":[]}`,
+		}}}
 
-		require.Equal(t, http.StatusOK, rw.Code)
+		selection, err := o.consentSelection(httptest.NewRequest(http.MethodGet, "/", nil), "foo")
+		require.NoError(t, err)
+		require.Nil(t, selection)
 	})
-}
-
-func TestOperation_interactHandler(t *testing.T) {
-	t.Run("success", func(t *testing.T) {
-		o := &Operation{}
-
-		rw := httptest.NewRecorder()
 
-		req := httptest.NewRequest(http.MethodGet, InteractPath, nil)
+	t.Run("invalid selection cookie", func(t *testing.T) {
+		o, err := New(config(t))
+		require.NoError(t, err)
 
-		o.interactHandler(rw, req)
+		o.cookies = &cookie.MockStore{Jar: &cookie.MockJar{Cookies: map[interface{}]interface{}{
+			interactTxnCookie:     "foo",
+			interactConsentCookie: `{`,
+		}}}
 
-		require.Equal(t, http.StatusFound, rw.Code)
+		_, err = o.consentSelection(httptest.NewRequest(http.MethodGet, "/", nil), "foo")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "parsing consent selection")
 	})
 }
 
@@ -272,6 +1583,37 @@ func TestOperation_authContinueHandler(t *testing.T) {
 		require.NoError(t, json.Unmarshal(rw.Body.Bytes(), resp))
 		require.Equal(t, errRequestDenied, resp.Error)
 	})
+
+	t.Run("polling client", func(t *testing.T) {
+		conf := config(t)
+		conf.DisableHTTPSigVerify = true
+
+		o, err := New(conf)
+		require.NoError(t, err)
+
+		_, client := clientKey(t)
+
+		authResp, err := o.authHandler.HandleAccessRequest(&gnap.AuthRequest{
+			Client:      &gnap.RequestClient{Key: client},
+			AccessToken: []*gnap.TokenRequest{{Access: []gnap.TokenAccess{*gnap.NewTokenAccessRef("client-id")}}},
+			Interact:    &gnap.RequestInteract{Start: []string{"redirect"}},
+		}, nil, "", baseURL)
+		require.NoError(t, err)
+		require.Equal(t, 5, authResp.Continue.Wait)
+
+		rw := httptest.NewRecorder()
+
+		req := httptest.NewRequest(http.MethodPost, AuthContinuePath, bytes.NewReader([]byte("{}")))
+		req.Header.Add("Authorization", "GNAP "+authResp.Continue.AccessToken.Value)
+
+		o.authContinueHandler(rw, req)
+
+		require.Equal(t, http.StatusBadRequest, rw.Code)
+
+		resp := &gnap.ErrorResponse{}
+		require.NoError(t, json.Unmarshal(rw.Body.Bytes(), resp))
+		require.Equal(t, errTooFast, resp.Error)
+	})
 }
 
 func TestOperation_authIntrospectHandler(t *testing.T) {
@@ -366,6 +1708,52 @@ func TestOIDCLoginHandler(t *testing.T) {
 		require.NotEmpty(t, w.Header().Get("location"))
 	})
 
+	t.Run("passes login hint", func(t *testing.T) {
+		provider := uuid.New().String()
+
+		tests := []struct {
+			name      string
+			hint      *api.UserHint
+			loginHint string
+		}{
+			{name: "email", hint: &api.UserHint{Sub: "user-123", Email: "user@example.com"}, loginHint: "user@example.com"},
+			{name: "sub", hint: &api.UserHint{Sub: "user-123", Provider: provider}, loginHint: "user-123"},
+			{name: "other provider", hint: &api.UserHint{Sub: "user-123", Provider: "other"}},
+			{name: "no hint"},
+		}
+
+		for _, tt := range tests {
+			tc := tt
+
+			t.Run(tc.name, func(t *testing.T) {
+				conf := config(t)
+				conf.InteractionHandler = &mockinteract.InteractHandler{
+					DetailsVal: &api.InteractionDetails{User: tc.hint},
+				}
+
+				svc, err := New(conf)
+				require.NoError(t, err)
+
+				svc.cachedOIDCProviders = map[string]oidcProvider{provider: &mockOIDCProvider{
+					oauth2Config: &mockOAuth2Config{
+						authCodeFunc: func(state string, opts ...oauth2.AuthCodeOption) string {
+							return (&oauth2.Config{}).AuthCodeURL(state, opts...)
+						},
+					},
+				}}
+				svc.oidcProvidersConfig = map[string]*oidcmodel.ProviderConfig{provider: {}}
+
+				w := httptest.NewRecorder()
+				svc.oidcLoginHandler(w, newOIDCLoginRequest(provider, "foo"))
+				require.Equal(t, http.StatusFound, w.Code)
+
+				redirectURL, err := url.Parse(w.Header().Get("location"))
+				require.NoError(t, err)
+				require.Equal(t, tc.loginHint, redirectURL.Query().Get(loginHintParam))
+			})
+		}
+	})
+
 	t.Run("provider not supported", func(t *testing.T) {
 		provider := uuid.New().String()
 		config := config(t)
@@ -444,6 +1832,31 @@ func TestOIDCLoginHandler(t *testing.T) {
 	})
 }
 
+func TestSubjectData(t *testing.T) {
+	t.Run("all identifiers", func(t *testing.T) {
+		data := subjectData("google", &oidcClaims{
+			Sub:           "user-123",
+			Iss:           "https://idp.example.com",
+			Email:         "user@example.com",
+			EmailVerified: true,
+		}, &user.Profile{Data: map[string]string{"did": "did:example:123"}})
+
+		require.Equal(t, map[string]string{
+			"sub":      "user-123",
+			"provider": "google",
+			"iss":      "https://idp.example.com",
+			"email":    "user@example.com",
+			"did":      "did:example:123",
+		}, data)
+	})
+
+	t.Run("unverified email", func(t *testing.T) {
+		data := subjectData("google", &oidcClaims{Sub: "user-123", Email: "user@example.com"}, nil)
+
+		require.Equal(t, map[string]string{"sub": "user-123", "provider": "google"}, data)
+	})
+}
+
 func TestOIDCCallbackHandler(t *testing.T) {
 	t.Run("setup user", func(t *testing.T) {
 		provider := uuid.New().String()
@@ -479,13 +1892,13 @@ func TestOIDCCallbackHandler(t *testing.T) {
 			},
 		}
 
-		respInteract, err := o.interactionHandler.PrepareInteraction(&gnap.RequestInteract{
+		respInteract, _, err := o.interactionHandler.PrepareInteraction(&gnap.RequestInteract{
 			Start: []string{"redirect"},
-			Finish: gnap.RequestFinish{
+			Finish: &gnap.RequestFinish{
 				Method: "redirect",
 				URI:    "example.foo/client-redirect",
 			},
-		}, "", []*api.ExpiringTokenRequest{
+		}, "", baseURL, []*api.ExpiringTokenRequest{
 			{
 				TokenRequest: gnap.TokenRequest{
 					Access: []gnap.TokenAccess{
@@ -496,7 +1909,7 @@ func TestOIDCCallbackHandler(t *testing.T) {
 					},
 				},
 			},
-		})
+		}, nil)
 		require.NoError(t, err)
 
 		redirURL, err := url.Parse(respInteract.Redirect)
@@ -843,6 +2256,133 @@ func TestOIDCCallbackHandler(t *testing.T) {
 		require.Contains(t, result.Body.String(), "failed to complete GNAP interaction")
 	})
 
+	t.Run("consent selection", func(t *testing.T) {
+		provider := uuid.New().String()
+		state := uuid.New().String()
+		config := config(t)
+
+		templatePath, deleteTmp := tmpStaticHTML(t)
+		defer deleteTmp()
+
+		config.ClosePopupHTML = templatePath
+
+		interact := &mockinteract.InteractHandler{
+			CompleteVal:      "interact-ref",
+			CompleteInteract: &gnap.RequestInteract{Finish: &gnap.RequestFinish{Method: "push"}},
+		}
+		config.InteractionHandler = interact
+
+		o, err := New(config)
+		require.NoError(t, err)
+
+		o.cachedOIDCProviders = map[string]oidcProvider{
+			provider: &mockOIDCProvider{
+				name:         provider,
+				oauth2Config: &mockOAuth2Config{exchangeVal: &mockToken{oauth2Claim: uuid.New().String()}},
+				verifyVal: &mockToken{
+					oidcClaimsFunc: func(v interface{}) error {
+						v.(*oidcClaims).Sub = uuid.New().String()
+
+						return nil
+					},
+				},
+			},
+		}
+
+		o.cookies = &cookie.MockStore{Jar: &cookie.MockJar{Cookies: map[interface{}]interface{}{
+			interactTxnCookie:     "foo",
+			interactConsentCookie: `{"access_token":[1],"subject_keys":["email"]}`,
+		}}}
+
+		dataBytes, err := json.Marshal(&oidcTransientData{Provider: provider, TxnID: "foo"})
+		require.NoError(t, err)
+		require.NoError(t, o.transientStore.Put(state, dataBytes))
+
+		result := httptest.NewRecorder()
+		o.oidcCallbackHandler(result, newOIDCCallback(state, uuid.New().String()))
+		require.Equal(t, http.StatusOK, result.Code)
+		require.Equal(t, &api.ConsentSelection{Tokens: []int{1}, SubjectKeys: []string{"email"}},
+			interact.CompleteArgs.Selection)
+	})
+
+	t.Run("push finish", func(t *testing.T) {
+		provider := uuid.New().String()
+		state := uuid.New().String()
+		config := config(t)
+		config.UIEndpoint = "https://ui.example.com"
+
+		templatePath, deleteTmp := tmpStaticHTML(t)
+		defer deleteTmp()
+
+		config.ClosePopupHTML = templatePath
+
+		config.InteractionHandler = &mockinteract.InteractHandler{
+			CompleteVal: "interact-ref",
+			CompleteInteract: &gnap.RequestInteract{
+				Finish: &gnap.RequestFinish{Method: "push", URI: "https://client.example.com/push"},
+			},
+		}
+
+		o, err := New(config)
+		require.NoError(t, err)
+
+		o.cachedOIDCProviders = map[string]oidcProvider{
+			provider: &mockOIDCProvider{
+				name:         provider,
+				oauth2Config: &mockOAuth2Config{exchangeVal: &mockToken{oauth2Claim: uuid.New().String()}},
+				verifyVal: &mockToken{
+					oidcClaimsFunc: func(v interface{}) error {
+						v.(*oidcClaims).Sub = uuid.New().String()
+
+						return nil
+					},
+				},
+			},
+		}
+
+		dataBytes, err := json.Marshal(&oidcTransientData{Provider: provider, TxnID: "foo"})
+		require.NoError(t, err)
+		require.NoError(t, o.transientStore.Put(state, dataBytes))
+
+		result := httptest.NewRecorder()
+		o.oidcCallbackHandler(result, newOIDCCallback(state, uuid.New().String()))
+		require.Equal(t, http.StatusOK, result.Code)
+		require.Contains(t, result.Body.String(), `window.opener.location.href = 'https:\/\/ui.example.com\/done';`)
+	})
+
+	t.Run("fail to read consent selection", func(t *testing.T) {
+		provider := uuid.New().String()
+		state := uuid.New().String()
+
+		o, err := New(config(t))
+		require.NoError(t, err)
+
+		o.cachedOIDCProviders = map[string]oidcProvider{
+			provider: &mockOIDCProvider{
+				name:         provider,
+				oauth2Config: &mockOAuth2Config{exchangeVal: &mockToken{oauth2Claim: uuid.New().String()}},
+				verifyVal: &mockToken{
+					oidcClaimsFunc: func(v interface{}) error {
+						v.(*oidcClaims).Sub = uuid.New().String()
+
+						return nil
+					},
+				},
+			},
+		}
+
+		o.cookies = &cookie.MockStore{OpenErr: errors.New("expected error")}
+
+		dataBytes, err := json.Marshal(&oidcTransientData{Provider: provider, TxnID: "foo"})
+		require.NoError(t, err)
+		require.NoError(t, o.transientStore.Put(state, dataBytes))
+
+		result := httptest.NewRecorder()
+		o.oidcCallbackHandler(result, newOIDCCallback(state, uuid.New().String()))
+		require.Equal(t, http.StatusInternalServerError, result.Code)
+		require.Contains(t, result.Body.String(), "failed to read consent selection")
+	})
+
 	t.Run("bad client redirect URI", func(t *testing.T) {
 		provider := uuid.New().String()
 		state := uuid.New().String()
@@ -872,13 +2412,13 @@ func TestOIDCCallbackHandler(t *testing.T) {
 			},
 		}
 
-		respInteract, err := o.interactionHandler.PrepareInteraction(&gnap.RequestInteract{
+		respInteract, _, err := o.interactionHandler.PrepareInteraction(&gnap.RequestInteract{
 			Start: []string{"redirect"},
-			Finish: gnap.RequestFinish{
+			Finish: &gnap.RequestFinish{
 				Method: "redirect",
 				URI:    "^$#^*#%$^&#$%#T^ UTTER GIBBERISH",
 			},
-		}, "", []*api.ExpiringTokenRequest{
+		}, "", baseURL, []*api.ExpiringTokenRequest{
 			{
 				TokenRequest: gnap.TokenRequest{
 					Access: []gnap.TokenAccess{
@@ -889,7 +2429,7 @@ func TestOIDCCallbackHandler(t *testing.T) {
 					},
 				},
 			},
-		})
+		}, nil)
 		require.NoError(t, err)
 
 		redirURL, err := url.Parse(respInteract.Redirect)
@@ -914,6 +2454,88 @@ func TestOIDCCallbackHandler(t *testing.T) {
 		require.Contains(t, result.Body.String(), "client provided invalid redirect URI")
 	})
 
+	t.Run("client without finish method", func(t *testing.T) {
+		provider := uuid.New().String()
+		state := uuid.New().String()
+		code := uuid.New().String()
+		config := config(t)
+		config.DisableHTTPSigVerify = true
+		config.PollInterval = time.Nanosecond
+		config.UIEndpoint = "https://ui.example.com"
+
+		templatePath, deleteTmp := tmpStaticHTML(t)
+		defer deleteTmp()
+
+		config.ClosePopupHTML = templatePath
+
+		o, err := New(config)
+		require.NoError(t, err)
+
+		o.cachedOIDCProviders = map[string]oidcProvider{
+			provider: &mockOIDCProvider{
+				name: provider,
+				oauth2Config: &mockOAuth2Config{
+					exchangeVal: &mockToken{
+						oauth2Claim: uuid.New().String(),
+					},
+				},
+				verifyVal: &mockToken{
+					oidcClaimsFunc: func(v interface{}) error {
+						c, ok := v.(*oidcClaims)
+						require.True(t, ok)
+						c.Sub = uuid.New().String()
+
+						return nil
+					},
+				},
+			},
+		}
+
+		_, client := clientKey(t)
+
+		authResp, err := o.authHandler.HandleAccessRequest(&gnap.AuthRequest{
+			Client:      &gnap.RequestClient{Key: client},
+			AccessToken: []*gnap.TokenRequest{{Access: []gnap.TokenAccess{*gnap.NewTokenAccessRef("client-id")}}},
+			Interact:    &gnap.RequestInteract{Start: []string{"redirect"}},
+		}, nil, "", baseURL)
+		require.NoError(t, err)
+
+		continueReq := func() *gnap.AuthResponse {
+			resp, e := o.authHandler.HandleContinueRequest(&gnap.ContinueRequest{},
+				authResp.Continue.AccessToken.Value, nil, baseURL)
+			require.NoError(t, e)
+
+			return resp
+		}
+
+		// the client polls until the user completes the interaction.
+		require.Empty(t, continueReq().AccessToken)
+
+		redirURL, err := url.Parse(authResp.Interact.Redirect)
+		require.NoError(t, err)
+
+		txnID := redirURL.Query().Get("txnID")
+
+		data := &oidcTransientData{
+			Provider: provider,
+			TxnID:    txnID,
+		}
+
+		dataBytes, err := json.Marshal(data)
+		require.NoError(t, err)
+
+		err = o.transientStore.Put(state, dataBytes)
+		require.NoError(t, err)
+
+		result := httptest.NewRecorder()
+		o.oidcCallbackHandler(result, newOIDCCallback(state, code))
+		require.Equal(t, http.StatusOK, result.Code)
+		// the user is told to go back to the device that polls for the token.
+		require.Contains(t, result.Body.String(), `window.opener.location.href = 'https:\/\/ui.example.com\/done';`)
+
+		require.Len(t, continueReq().AccessToken, 1)
+	})
+
 	t.Run("generic bootstrap store PUT error while onboarding user", func(t *testing.T) {
 		provider := uuid.New().String()
 		id := uuid.New().String()
@@ -1270,7 +2892,7 @@ func Test_Full_Flow(t *testing.T) {
 			},
 			Interact: &gnap.RequestInteract{
 				Start: []string{"redirect"},
-				Finish: gnap.RequestFinish{
+				Finish: &gnap.RequestFinish{
 					Method: "redirect",
 					URI:    "example.com/client-ui",
 				},
@@ -1541,8 +3163,8 @@ func config(t *testing.T) *Config {
 	storeProv := mem.NewProvider()
 
 	interact, err := redirect.New(&redirect.Config{
-		StoreProvider:    storeProv,
-		InteractBasePath: InteractPath,
+		StoreProvider: storeProv,
+		InteractPath:  InteractPath,
 	})
 	require.NoError(t, err)
 
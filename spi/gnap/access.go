@@ -0,0 +1,170 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gnap
+
+// NewTokenAccess creates an access descriptor object of the given type, to be completed with the With* builders.
+func NewTokenAccess(accessType string) *TokenAccess {
+	return &TokenAccess{Type: accessType}
+}
+
+// NewTokenAccessRef creates an access descriptor that references access by the given string.
+func NewTokenAccessRef(ref string) *TokenAccess {
+	return &TokenAccess{IsReference: true, Ref: ref}
+}
+
+// WithActions adds the actions that the access descriptor allows at the resource server.
+func (t *TokenAccess) WithActions(actions ...string) *TokenAccess {
+	t.Actions = append(t.Actions, actions...)
+
+	return t
+}
+
+// WithLocations adds the locations of the resource servers that the access descriptor is for.
+func (t *TokenAccess) WithLocations(locations ...string) *TokenAccess {
+	t.Locations = append(t.Locations, locations...)
+
+	return t
+}
+
+// WithDatatypes adds the kinds of data that the access descriptor gives access to.
+func (t *TokenAccess) WithDatatypes(datatypes ...string) *TokenAccess {
+	t.Datatypes = append(t.Datatypes, datatypes...)
+
+	return t
+}
+
+// WithIdentifier sets the identifier of the specific resource that the access descriptor is for.
+func (t *TokenAccess) WithIdentifier(identifier string) *TokenAccess {
+	t.Identifier = identifier
+
+	return t
+}
+
+// WithPrivileges adds the privileges that the access descriptor gives at the resource server.
+func (t *TokenAccess) WithPrivileges(privileges ...string) *TokenAccess {
+	t.Privileges = append(t.Privileges, privileges...)
+
+	return t
+}
+
+// WithExtension sets an API-specific member of the access descriptor. The value must be marshalable to JSON.
+func (t *TokenAccess) WithExtension(name string, value interface{}) *TokenAccess {
+	if t.Extensions == nil {
+		t.Extensions = map[string]interface{}{}
+	}
+
+	t.Extensions[name] = value
+
+	return t
+}
+
+/*
+IsSubsetOf returns true iff the access described by t is within the access described by super, so that a grant of
+super also grants t.
+
+Every member of t must be present in super: string members must be equal, and array members of t must only hold
+values that super's array holds, in any order. Extension members are compared the same way, and extension members
+of other types are never within super. The descriptors' types aren't compared, since an access policy may let a
+descriptor of one type cover the access of another type.
+
+Access references can't be compared without resolving them, so a reference is only a subset of the same reference.
+*/
+func (t *TokenAccess) IsSubsetOf(super *TokenAccess) bool {
+	if t.IsReference || super.IsReference {
+		return t.IsReference && super.IsReference && t.Ref == super.Ref
+	}
+
+	if !isStringSubset(super.Actions, t.Actions) ||
+		!isStringSubset(super.Locations, t.Locations) ||
+		!isStringSubset(super.Datatypes, t.Datatypes) ||
+		!isStringSubset(super.Privileges, t.Privileges) {
+		return false
+	}
+
+	if t.Identifier != "" && t.Identifier != super.Identifier {
+		return false
+	}
+
+	for name, subValue := range t.Extensions {
+		superValue, ok := super.Extensions[name]
+		if !ok || !isExtensionSubset(superValue, subValue) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// IsSupersetOf returns true iff the access described by t includes the access described by sub.
+func (t *TokenAccess) IsSupersetOf(sub *TokenAccess) bool {
+	return sub.IsSubsetOf(t)
+}
+
+// isStringSubset returns true iff sub is absent, or super is present and holds every value of sub.
+func isStringSubset(super, sub []string) bool {
+	if sub == nil {
+		return true
+	}
+
+	if super == nil {
+		return false
+	}
+
+	superSet := map[string]struct{}{}
+
+	for _, s := range super {
+		superSet[s] = struct{}{}
+	}
+
+	for _, s := range sub {
+		if _, ok := superSet[s]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isExtensionSubset(super, sub interface{}) bool {
+	if subValue, ok := sub.(string); ok {
+		superValue, ok := super.(string)
+
+		return ok && superValue == subValue
+	}
+
+	subStrings, ok := toStrings(sub)
+	if !ok {
+		return false
+	}
+
+	superStrings, ok := toStrings(super)
+
+	return ok && isStringSubset(superStrings, subStrings)
+}
+
+// toStrings converts an array of strings, as parsed by encoding/json or set by WithExtension, to a []string.
+func toStrings(value interface{}) ([]string, bool) {
+	switch values := value.(type) {
+	case []string:
+		return values, true
+	case []interface{}:
+		out := []string{}
+
+		for _, v := range values {
+			s, ok := v.(string)
+			if !ok {
+				return nil, false
+			}
+
+			out = append(out, s)
+		}
+
+		return out, true
+	}
+
+	return nil, false
+}
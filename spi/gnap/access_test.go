@@ -0,0 +1,331 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gnap
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTokenAccess(t *testing.T) {
+	t.Run("object", func(t *testing.T) {
+		access := NewTokenAccess("photo-api").
+			WithActions("read", "write").
+			WithLocations("https://server.example.net/").
+			WithDatatypes("metadata", "images").
+			WithIdentifier("album-123").
+			WithPrivileges("admin").
+			WithExtension("subject-keys", []string{"name"})
+
+		data, err := json.Marshal(access)
+		require.NoError(t, err)
+
+		require.JSONEq(t, `{
+	"type": "photo-api",
+	"actions": ["read", "write"],
+	"locations": ["https://server.example.net/"],
+	"datatypes": ["metadata", "images"],
+	"identifier": "album-123",
+	"privileges": ["admin"],
+	"subject-keys": ["name"]
+}`, string(data))
+
+		parsed := &TokenAccess{}
+
+		require.NoError(t, json.Unmarshal(data, parsed))
+		require.Equal(t, access.Actions, parsed.Actions)
+		require.Equal(t, access.Identifier, parsed.Identifier)
+		require.Equal(t, []interface{}{"name"}, parsed.Extensions["subject-keys"])
+
+		require.True(t, parsed.IsSubsetOf(access))
+		require.True(t, access.IsSubsetOf(parsed))
+	})
+
+	t.Run("builders modify parsed descriptor", func(t *testing.T) {
+		parsed := &TokenAccess{}
+
+		require.NoError(t, json.Unmarshal([]byte(`{"type":"photo-api","actions":["read"],"foo":"bar"}`), parsed))
+
+		data, err := json.Marshal(parsed.WithActions("write").WithIdentifier("album-123").WithExtension("baz", 1))
+		require.NoError(t, err)
+
+		require.JSONEq(t, `{
+	"type": "photo-api",
+	"actions": ["read", "write"],
+	"identifier": "album-123",
+	"foo": "bar",
+	"baz": 1
+}`, string(data))
+	})
+
+	t.Run("fields of parsed descriptor set directly", func(t *testing.T) {
+		parsed := &TokenAccess{}
+
+		require.NoError(t, json.Unmarshal([]byte(`{"type":"photo-api","actions":["read"],"foo":"bar"}`), parsed))
+
+		parsed.Actions = []string{"write"}
+		parsed.Locations = []string{"https://server.example.net/"}
+
+		data, err := json.Marshal(parsed)
+		require.NoError(t, err)
+
+		require.JSONEq(t, `{
+	"type": "photo-api",
+	"actions": ["write"],
+	"locations": ["https://server.example.net/"],
+	"foo": "bar"
+}`, string(data))
+	})
+
+	t.Run("reference", func(t *testing.T) {
+		data, err := json.Marshal(NewTokenAccessRef("foo"))
+		require.NoError(t, err)
+		require.Equal(t, `"foo"`, string(data))
+	})
+}
+
+func TestTokenAccess_IsSubsetOf_typed(t *testing.T) {
+	super := NewTokenAccess("photo-api").
+		WithActions("read", "write").
+		WithLocations("https://server.example.net/").
+		WithIdentifier("album-123")
+
+	testcases := []struct {
+		name   string
+		sub    *TokenAccess
+		result bool
+	}{
+		{
+			name:   "fewer actions",
+			sub:    NewTokenAccess("photo-api").WithActions("read"),
+			result: true,
+		},
+		{
+			name:   "extra action",
+			sub:    NewTokenAccess("photo-api").WithActions("read", "delete"),
+			result: false,
+		},
+		{
+			name:   "member missing from superset",
+			sub:    NewTokenAccess("photo-api").WithDatatypes("images"),
+			result: false,
+		},
+		{
+			name:   "same identifier",
+			sub:    NewTokenAccess("photo-api").WithIdentifier("album-123"),
+			result: true,
+		},
+		{
+			name:   "different identifier",
+			sub:    NewTokenAccess("photo-api").WithIdentifier("album-456"),
+			result: false,
+		},
+		{
+			name:   "reference",
+			sub:    NewTokenAccessRef("photo-api"),
+			result: false,
+		},
+	}
+
+	for _, tt := range testcases {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.result, tc.sub.IsSubsetOf(super))
+		})
+	}
+
+	t.Run("references", func(t *testing.T) {
+		require.True(t, NewTokenAccessRef("foo").IsSubsetOf(NewTokenAccessRef("foo")))
+		require.False(t, NewTokenAccessRef("foo").IsSubsetOf(NewTokenAccessRef("bar")))
+		require.False(t, NewTokenAccess("foo").IsSubsetOf(NewTokenAccessRef("foo")))
+	})
+}
+
+func TestTokenAccess_IsSubsetOf(t *testing.T) {
+	testcases := []struct {
+		name   string
+		super  map[string]interface{}
+		sub    map[string]interface{}
+		result bool
+	}{
+		{
+			name: "empty set is subset of empty set",
+			super: map[string]interface{}{
+				"type": "foo",
+			},
+			sub: map[string]interface{}{
+				"type": "bar",
+			},
+			result: true,
+		},
+		{
+			name: "subset field missing from superset",
+			super: map[string]interface{}{
+				"type": "foo",
+			},
+			sub: map[string]interface{}{
+				"type": "bar",
+				"foo":  "foo",
+			},
+			result: false,
+		},
+		{
+			name: "field has unsupported type",
+			super: map[string]interface{}{
+				"type": "foo",
+				"foo":  5,
+			},
+			sub: map[string]interface{}{
+				"type": "bar",
+				"foo":  5,
+			},
+			result: false,
+		},
+		{
+			name: "superset field is not string",
+			super: map[string]interface{}{
+				"type": "foo",
+				"foo":  []interface{}{"foo"},
+			},
+			sub: map[string]interface{}{
+				"type": "bar",
+				"foo":  "foo",
+			},
+			result: false,
+		},
+		{
+			name: "superset string has different value",
+			super: map[string]interface{}{
+				"type": "foo",
+				"foo":  "foo",
+			},
+			sub: map[string]interface{}{
+				"type": "bar",
+				"foo":  "bar",
+			},
+			result: false,
+		},
+		{
+			name: "superset field is not []interface{}",
+			super: map[string]interface{}{
+				"type": "foo",
+				"foo":  "foo",
+			},
+			sub: map[string]interface{}{
+				"type": "bar",
+				"foo":  []interface{}{"bar"},
+			},
+			result: false,
+		},
+		{
+			name: "subset array contains non-strings",
+			super: map[string]interface{}{
+				"type": "foo",
+				"foo":  []interface{}{"foo"},
+			},
+			sub: map[string]interface{}{
+				"type": "bar",
+				"foo":  []interface{}{123},
+			},
+			result: false,
+		},
+		{
+			name: "superset array contains non-strings",
+			super: map[string]interface{}{
+				"type": "foo",
+				"foo":  []interface{}{123},
+			},
+			sub: map[string]interface{}{
+				"type": "bar",
+				"foo":  []interface{}{"bar"},
+			},
+			result: false,
+		},
+		{
+			name: "subset array not subset of superset's",
+			super: map[string]interface{}{
+				"type": "foo",
+				"foo":  []interface{}{"foo", "blah"},
+			},
+			sub: map[string]interface{}{
+				"type": "bar",
+				"foo":  []interface{}{"foo", "bar"},
+			},
+			result: false,
+		},
+		{
+			name: "subset array ignores order",
+			super: map[string]interface{}{
+				"type": "foo",
+				"foo":  []interface{}{"bar", "foo"},
+			},
+			sub: map[string]interface{}{
+				"type": "bar",
+				"foo":  []interface{}{"foo", "bar"},
+			},
+			result: true,
+		},
+		{
+			name: "example pass",
+			super: map[string]interface{}{
+				"type":      "rw-all-dbs",
+				"actions":   []interface{}{"read", "write", "delete"},
+				"databases": []interface{}{"secret-store", "userdata", "localdb"},
+				"userkey":   "foo123",
+			},
+			sub: map[string]interface{}{
+				"type":      "read-localdb",
+				"actions":   []interface{}{"read"},
+				"databases": []interface{}{"localdb"},
+				"userkey":   "foo123",
+			},
+			result: true,
+		},
+		{
+			name: "example fail",
+			super: map[string]interface{}{
+				"type":      "read-localdb",
+				"actions":   []interface{}{"read"},
+				"databases": []interface{}{"localdb"},
+				"userkey":   "foo123",
+			},
+			sub: map[string]interface{}{
+				"type":      "rw-all-dbs",
+				"actions":   []interface{}{"read", "write", "delete"},
+				"databases": []interface{}{"secret-store", "userdata", "localdb"},
+				"userkey":   "foo123",
+			},
+			result: false,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			super := toTokenAccess(t, tc.super)
+			sub := toTokenAccess(t, tc.sub)
+
+			require.Equal(t, tc.result, sub.IsSubsetOf(super))
+			require.Equal(t, tc.result, super.IsSupersetOf(sub))
+		})
+	}
+}
+
+func toTokenAccess(t *testing.T, m map[string]interface{}) *TokenAccess {
+	t.Helper()
+
+	data, err := json.Marshal(m)
+	require.NoError(t, err)
+
+	out := &TokenAccess{}
+
+	require.NoError(t, json.Unmarshal(data, out))
+
+	return out
+}
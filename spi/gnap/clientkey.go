@@ -0,0 +1,142 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gnap
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk"
+	"github.com/square/go-jose/v3"
+
+	"github.com/trustbloc/auth/spi/gnap/internal/did"
+)
+
+// Certificate parses the X.509 certificate that the client key is bound to: its cert, or the first certificate of
+// its x5c chain.
+func (k *ClientKey) Certificate() (*x509.Certificate, error) {
+	chain, err := k.CertificateChain()
+	if err != nil {
+		return nil, err
+	}
+
+	return chain[0], nil
+}
+
+// CertificateChain parses the X.509 certificate chain that the client key is bound to, starting with the key's
+// certificate.
+func (k *ClientKey) CertificateChain() ([]*x509.Certificate, error) {
+	encoded := k.X5C
+
+	if k.Cert != "" {
+		if len(k.X5C) > 0 {
+			return nil, errors.New("client key can't have both a cert and an x5c chain")
+		}
+
+		encoded = []string{k.Cert}
+	}
+
+	if len(encoded) == 0 {
+		return nil, errors.New("client key is not bound to a certificate")
+	}
+
+	chain := make([]*x509.Certificate, len(encoded))
+
+	for i, c := range encoded {
+		der, err := base64.StdEncoding.DecodeString(c)
+		if err != nil {
+			return nil, fmt.Errorf("decoding client certificate: %w", err)
+		}
+
+		chain[i], err = x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("parsing client certificate: %w", err)
+		}
+	}
+
+	return chain, nil
+}
+
+// PublicKey returns the public key of the client key: the key of its certificate, if it's bound to one, the key of
+// its DID, if it's identified by one, and the key of its JWK otherwise.
+func (k *ClientKey) PublicKey() (crypto.PublicKey, error) {
+	if !k.isBound() {
+		if k.JWK.Key == nil {
+			return nil, errors.New("client key has no jwk, certificate or did")
+		}
+
+		return k.JWK.Public().Key, nil
+	}
+
+	key, err := k.boundKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return key.Key, nil
+}
+
+// VerificationKey returns the JWK that verifies the client's request signatures. This is the client key's JWK, unless
+// the key is bound to a certificate or identified by a DID, in which case it's the key of the certificate or DID.
+// The key's JWK may then be omitted, but if present it must hold the same public key.
+func (k *ClientKey) VerificationKey() (*jwk.JWK, error) {
+	if !k.isBound() {
+		return &k.JWK, nil
+	}
+
+	key, err := k.boundKey()
+	if err != nil {
+		return nil, err
+	}
+
+	if k.JWK.Key != nil {
+		pub, ok := key.Key.(publicKey)
+		if !ok || !pub.Equal(k.JWK.Public().Key) {
+			return nil, errors.New("client key jwk does not match its certificate or did")
+		}
+
+		key.KeyID = k.JWK.KeyID
+		key.Algorithm = k.JWK.Algorithm
+	}
+
+	return key, nil
+}
+
+type publicKey interface {
+	Equal(crypto.PublicKey) bool
+}
+
+// isBound returns true iff the client key is bound to a certificate or identified by a DID.
+func (k *ClientKey) isBound() bool {
+	return k.Cert != "" || len(k.X5C) > 0 || k.DID != ""
+}
+
+// boundKey returns the key of the certificate that the client key is bound to, or of the DID that identifies it.
+func (k *ClientKey) boundKey() (*jwk.JWK, error) {
+	if k.DID != "" {
+		if k.Cert != "" || len(k.X5C) > 0 {
+			return nil, errors.New("client key can't have both a certificate and a did")
+		}
+
+		key, err := did.ResolveKey(k.DID)
+		if err != nil {
+			return nil, fmt.Errorf("resolving client did: %w", err)
+		}
+
+		return key, nil
+	}
+
+	cert, err := k.Certificate()
+	if err != nil {
+		return nil, err
+	}
+
+	return &jwk.JWK{JSONWebKey: jose.JSONWebKey{Key: cert.PublicKey}}, nil
+}
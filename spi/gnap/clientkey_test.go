@@ -0,0 +1,196 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gnap
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk"
+	"github.com/square/go-jose/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientKey_PublicKey(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	t.Run("from jwk", func(t *testing.T) {
+		key := &ClientKey{
+			JWK: jwk.JWK{
+				JSONWebKey: jose.JSONWebKey{Key: priv},
+				Kty:        "EC",
+				Crv:        "P-256",
+			},
+		}
+
+		pub, err := key.PublicKey()
+		require.NoError(t, err)
+		require.Equal(t, &priv.PublicKey, pub)
+	})
+
+	t.Run("from certificate", func(t *testing.T) {
+		key := &ClientKey{
+			Cert: selfSignedCert(t, priv),
+		}
+
+		pub, err := key.PublicKey()
+		require.NoError(t, err)
+		require.True(t, priv.PublicKey.Equal(pub))
+	})
+
+	t.Run("from certificate chain", func(t *testing.T) {
+		caPriv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		key := &ClientKey{
+			X5C: []string{selfSignedCert(t, priv), selfSignedCert(t, caPriv)},
+		}
+
+		pub, err := key.PublicKey()
+		require.NoError(t, err)
+		require.True(t, priv.PublicKey.Equal(pub))
+
+		chain, err := key.CertificateChain()
+		require.NoError(t, err)
+		require.Len(t, chain, 2)
+	})
+
+	t.Run("from did", func(t *testing.T) {
+		key := &ClientKey{
+			DID: didKey(t, priv),
+		}
+
+		pub, err := key.PublicKey()
+		require.NoError(t, err)
+		require.True(t, priv.PublicKey.Equal(pub))
+	})
+
+	t.Run("unresolvable did", func(t *testing.T) {
+		_, err := (&ClientKey{DID: "did:web:example.com"}).PublicKey()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "resolving client did")
+	})
+
+	t.Run("both certificate and x5c", func(t *testing.T) {
+		_, err := (&ClientKey{Cert: "Zm9v", X5C: []string{"Zm9v"}}).PublicKey()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "both a cert and an x5c chain")
+	})
+
+	t.Run("both certificate and did", func(t *testing.T) {
+		_, err := (&ClientKey{Cert: "Zm9v", DID: didKey(t, priv)}).PublicKey()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "both a certificate and a did")
+	})
+
+	t.Run("empty key", func(t *testing.T) {
+		_, err := (&ClientKey{}).PublicKey()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "no jwk, certificate or did")
+	})
+
+	t.Run("certificate not base64", func(t *testing.T) {
+		_, err := (&ClientKey{Cert: "!"}).PublicKey()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "decoding client certificate")
+	})
+
+	t.Run("malformed certificate", func(t *testing.T) {
+		_, err := (&ClientKey{Cert: "Zm9v"}).PublicKey()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "parsing client certificate")
+	})
+
+	t.Run("not bound to a certificate", func(t *testing.T) {
+		_, err := (&ClientKey{}).Certificate()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "not bound to a certificate")
+	})
+}
+
+func TestClientKey_VerificationKey(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	pubJWK := jwk.JWK{
+		JSONWebKey: jose.JSONWebKey{Key: &priv.PublicKey, KeyID: "key1", Algorithm: "ES256"},
+		Kty:        "EC",
+		Crv:        "P-256",
+	}
+
+	t.Run("jwk", func(t *testing.T) {
+		key := &ClientKey{JWK: pubJWK}
+
+		verKey, err := key.VerificationKey()
+		require.NoError(t, err)
+		require.Equal(t, &key.JWK, verKey)
+	})
+
+	t.Run("certificate without jwk", func(t *testing.T) {
+		verKey, err := (&ClientKey{Cert: selfSignedCert(t, priv)}).VerificationKey()
+		require.NoError(t, err)
+		require.True(t, priv.PublicKey.Equal(verKey.Key))
+	})
+
+	t.Run("did with matching jwk", func(t *testing.T) {
+		verKey, err := (&ClientKey{DID: didKey(t, priv), JWK: pubJWK}).VerificationKey()
+		require.NoError(t, err)
+		require.True(t, priv.PublicKey.Equal(verKey.Key))
+		require.Equal(t, "key1", verKey.KeyID)
+		require.Equal(t, "ES256", verKey.Algorithm)
+	})
+
+	t.Run("jwk doesn't match certificate", func(t *testing.T) {
+		other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		_, err = (&ClientKey{Cert: selfSignedCert(t, other), JWK: pubJWK}).VerificationKey()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "does not match")
+	})
+
+	t.Run("invalid certificate", func(t *testing.T) {
+		_, err := (&ClientKey{Cert: "Zm9v"}).VerificationKey()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "parsing client certificate")
+	})
+}
+
+func selfSignedCert(t *testing.T, priv *ecdsa.PrivateKey) string {
+	t.Helper()
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "client"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &priv.PublicKey, priv)
+	require.NoError(t, err)
+
+	return base64.StdEncoding.EncodeToString(der)
+}
+
+func didKey(t *testing.T, priv *ecdsa.PrivateKey) string {
+	t.Helper()
+
+	pubJWK := &jwk.JWK{JSONWebKey: jose.JSONWebKey{Key: &priv.PublicKey}, Kty: "EC", Crv: "P-256"}
+
+	data, err := pubJWK.MarshalJSON()
+	require.NoError(t, err)
+
+	return "did:jwk:" + base64.RawURLEncoding.EncodeToString(data)
+}
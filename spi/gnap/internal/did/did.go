@@ -0,0 +1,126 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package did
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk"
+	"github.com/square/go-jose/v3"
+)
+
+const (
+	didKeyPrefix = "did:key:"
+	didJWKPrefix = "did:jwk:"
+
+	// multicodec codes of the supported did:key public key types,
+	// see https://github.com/multiformats/multicodec/blob/master/table.csv.
+	ed25519Codec = 0xed
+	p256Codec    = 0x1200
+	p384Codec    = 0x1201
+	p521Codec    = 0x1202
+)
+
+// ResolveKey resolves the public key of the given did:key or did:jwk DID, which may be a DID URL referencing the
+// DID's verification method. These DID methods encode the key in the DID itself, so they are resolved without
+// network access.
+func ResolveKey(did string) (*jwk.JWK, error) {
+	// did:key and did:jwk DIDs have a single verification method, so the fragment is ignored.
+	if i := strings.IndexByte(did, '#'); i >= 0 {
+		did = did[:i]
+	}
+
+	switch {
+	case strings.HasPrefix(did, didKeyPrefix):
+		return resolveDIDKey(strings.TrimPrefix(did, didKeyPrefix))
+	case strings.HasPrefix(did, didJWKPrefix):
+		return resolveDIDJWK(strings.TrimPrefix(did, didJWKPrefix))
+	}
+
+	return nil, fmt.Errorf("unsupported did method in %s", did)
+}
+
+// resolveDIDKey decodes a did:key method-specific id: a base58-btc multibase encoding of the multicodec key type and
+// the raw public key, see https://w3c-ccg.github.io/did-method-key/#format.
+func resolveDIDKey(id string) (*jwk.JWK, error) {
+	if len(id) < 2 || id[0] != 'z' {
+		return nil, errors.New("did:key must use base58-btc multibase encoding")
+	}
+
+	mc := base58.Decode(id[1:])
+
+	code, n := binary.Uvarint(mc)
+	if n <= 0 {
+		return nil, errors.New("did:key has invalid multicodec prefix")
+	}
+
+	raw := mc[n:]
+
+	switch code {
+	case ed25519Codec:
+		if len(raw) != ed25519.PublicKeySize {
+			return nil, errors.New("did:key has invalid ed25519 public key")
+		}
+
+		return &jwk.JWK{
+			JSONWebKey: jose.JSONWebKey{Key: ed25519.PublicKey(raw)},
+			Kty:        "OKP",
+			Crv:        "Ed25519",
+		}, nil
+	case p256Codec:
+		return ecdsaKey(elliptic.P256(), raw)
+	case p384Codec:
+		return ecdsaKey(elliptic.P384(), raw)
+	case p521Codec:
+		return ecdsaKey(elliptic.P521(), raw)
+	}
+
+	return nil, fmt.Errorf("did:key has unsupported key type 0x%x", code)
+}
+
+func ecdsaKey(curve elliptic.Curve, raw []byte) (*jwk.JWK, error) {
+	x, y := elliptic.UnmarshalCompressed(curve, raw)
+	if x == nil {
+		return nil, fmt.Errorf("did:key has invalid %s public key", curve.Params().Name)
+	}
+
+	return &jwk.JWK{
+		JSONWebKey: jose.JSONWebKey{Key: &ecdsa.PublicKey{Curve: curve, X: x, Y: y}},
+		Kty:        "EC",
+		Crv:        curve.Params().Name,
+	}, nil
+}
+
+// resolveDIDJWK decodes a did:jwk method-specific id: the base64url encoding of the JWK,
+// see https://github.com/quartzjer/did-jwk/blob/main/spec.md.
+func resolveDIDJWK(id string) (*jwk.JWK, error) {
+	data, err := base64.RawURLEncoding.DecodeString(id)
+	if err != nil {
+		return nil, fmt.Errorf("decoding did:jwk: %w", err)
+	}
+
+	key := &jwk.JWK{}
+
+	err = key.UnmarshalJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing did:jwk: %w", err)
+	}
+
+	if !key.IsPublic() {
+		return nil, errors.New("did:jwk must hold a public key")
+	}
+
+	return key, nil
+}
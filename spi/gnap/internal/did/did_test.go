@@ -0,0 +1,126 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package did
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"testing"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk"
+	"github.com/square/go-jose/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveKey(t *testing.T) {
+	t.Run("did:key spec examples", func(t *testing.T) {
+		for _, did := range []string{
+			"did:key:z6MkhaXgBZDvotDkL5257faiztiGiC2QtKLGpbnnEGta2doK",
+			"did:key:zDnaerDaTF5BXEavCrfRZEk316dpbLsfPDZ3WJ5hRTPFU2169",
+			"did:key:z82Lm1MpAkeJcix9K8TMiLd5NMAhnwkjjCBeWHXyu3U4oT2MVJJKXkcVBgjGhnLBn2Kaau9",
+		} {
+			key, err := ResolveKey(did)
+			require.NoError(t, err, did)
+			require.True(t, key.IsPublic(), did)
+		}
+	})
+
+	t.Run("did:key", func(t *testing.T) {
+		edPub, _, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		key, err := ResolveKey(didKey(ed25519Codec, edPub) + "#key-1")
+		require.NoError(t, err)
+		require.Equal(t, edPub, key.Key)
+
+		for code, curve := range map[uint64]elliptic.Curve{
+			p256Codec: elliptic.P256(),
+			p384Codec: elliptic.P384(),
+			p521Codec: elliptic.P521(),
+		} {
+			priv, err := ecdsa.GenerateKey(curve, rand.Reader)
+			require.NoError(t, err)
+
+			key, err := ResolveKey(didKey(code, elliptic.MarshalCompressed(curve, priv.X, priv.Y)))
+			require.NoError(t, err)
+			require.True(t, priv.PublicKey.Equal(key.Key))
+			require.Equal(t, curve.Params().Name, key.Crv)
+		}
+	})
+
+	t.Run("did:jwk", func(t *testing.T) {
+		priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		pubJWK := &jwk.JWK{JSONWebKey: jose.JSONWebKey{Key: &priv.PublicKey}, Kty: "EC", Crv: "P-256"}
+
+		data, err := pubJWK.MarshalJSON()
+		require.NoError(t, err)
+
+		key, err := ResolveKey("did:jwk:" + base64.RawURLEncoding.EncodeToString(data) + "#0")
+		require.NoError(t, err)
+		require.True(t, priv.PublicKey.Equal(key.Key))
+	})
+
+	t.Run("invalid did", func(t *testing.T) {
+		privJWK := &jwk.JWK{JSONWebKey: jose.JSONWebKey{Key: ed25519.NewKeyFromSeed(make([]byte, 32))}}
+
+		privData, err := privJWK.MarshalJSON()
+		require.NoError(t, err)
+
+		tests := []struct {
+			name string
+			did  string
+			err  string
+		}{
+			{name: "unsupported method", did: "did:web:example.com", err: "unsupported did method"},
+			{name: "not base58-btc", did: "did:key:mfoo", err: "base58-btc"},
+			{name: "invalid multicodec", did: "did:key:z", err: "base58-btc"},
+			{
+				name: "truncated multicodec",
+				did:  "did:key:z" + base58.Encode([]byte{0x80}),
+				err:  "invalid multicodec prefix",
+			},
+			{name: "unsupported key type", did: didKey(0xec, make([]byte, 32)), err: "unsupported key type 0xec"},
+			{name: "short ed25519 key", did: didKey(ed25519Codec, []byte("foo")), err: "invalid ed25519"},
+			{name: "invalid p-256 key", did: didKey(p256Codec, []byte("foo")), err: "invalid P-256"},
+			{name: "did:jwk not base64url", did: "did:jwk:!", err: "decoding did:jwk"},
+			{
+				name: "did:jwk not a jwk",
+				did:  "did:jwk:" + base64.RawURLEncoding.EncodeToString([]byte("{}")),
+				err:  "parsing did:jwk",
+			},
+			{
+				name: "did:jwk private key",
+				did:  "did:jwk:" + base64.RawURLEncoding.EncodeToString(privData),
+				err:  "must hold a public key",
+			},
+		}
+
+		for _, tt := range tests {
+			tc := tt
+
+			t.Run(tc.name, func(t *testing.T) {
+				_, err := ResolveKey(tc.did)
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.err)
+			})
+		}
+	})
+}
+
+func didKey(code uint64, raw []byte) string {
+	buf := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(buf, code)
+
+	return "did:key:z" + base58.Encode(append(buf[:n], raw...))
+}
@@ -0,0 +1,39 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package jwksignature
+
+import (
+	"crypto/ed25519"
+	"errors"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk"
+)
+
+func eddsaSign(msg []byte, privateKey ed25519.PrivateKey) ([]byte, error) {
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return nil, errors.New("invalid ed25519 private key size")
+	}
+
+	return ed25519.Sign(privateKey, msg), nil
+}
+
+func eddsaVerifier(pubKeyJWK *jwk.JWK, msg, signature []byte) error {
+	pubKey, ok := pubKeyJWK.Key.(ed25519.PublicKey)
+	if !ok {
+		return errors.New("invalid public key type")
+	}
+
+	if len(pubKey) != ed25519.PublicKeySize {
+		return errors.New("invalid ed25519 public key size")
+	}
+
+	if !ed25519.Verify(pubKey, msg, signature) {
+		return errors.New("invalid signature")
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package jwksignature
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk"
+	"github.com/square/go-jose/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_eddsaSignVerify(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	pubJWK := &jwk.JWK{
+		JSONWebKey: jose.JSONWebKey{
+			Key:       pub,
+			Algorithm: algEdDSA,
+		},
+		Kty: "OKP",
+		Crv: "Ed25519",
+	}
+
+	msg := []byte("the quick brown fox jumps over the lazy dog")
+
+	t.Run("success", func(t *testing.T) {
+		sig, err := eddsaSign(msg, priv)
+		require.NoError(t, err)
+
+		require.NoError(t, eddsaVerifier(pubJWK, msg, sig))
+	})
+
+	t.Run("invalid private key size", func(t *testing.T) {
+		_, err := eddsaSign(msg, priv[:10])
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid ed25519 private key size")
+	})
+
+	t.Run("invalid key type", func(t *testing.T) {
+		err := eddsaVerifier(&jwk.JWK{JSONWebKey: jose.JSONWebKey{Key: []byte{}}}, msg, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid public key type")
+	})
+
+	t.Run("invalid public key size", func(t *testing.T) {
+		err := eddsaVerifier(&jwk.JWK{JSONWebKey: jose.JSONWebKey{Key: pub[:10]}}, msg, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid ed25519 public key size")
+	})
+
+	t.Run("invalid signature", func(t *testing.T) {
+		err := eddsaVerifier(pubJWK, msg, make([]byte, ed25519.SignatureSize))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid signature")
+	})
+}
@@ -0,0 +1,103 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package jwksignature
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk"
+)
+
+const (
+	algEdDSA = "EdDSA"
+	// rsa keys without an explicit alg default to rsa-pss-sha512, as recommended for GNAP http signatures.
+	defaultRSAAlg = "PS512"
+)
+
+// Algorithm returns the JWA signature algorithm of the given key: its declared alg if it has one,
+// and otherwise the algorithm inferred from the key type.
+func Algorithm(key *jwk.JWK) (string, error) {
+	if key.Algorithm != "" {
+		return key.Algorithm, nil
+	}
+
+	switch k := key.Key.(type) {
+	case ed25519.PublicKey, ed25519.PrivateKey:
+		return algEdDSA, nil
+	case *rsa.PublicKey, *rsa.PrivateKey:
+		return defaultRSAAlg, nil
+	case *ecdsa.PrivateKey:
+		return ecdsaAlg(k.Curve.Params().BitSize)
+	case *ecdsa.PublicKey:
+		return ecdsaAlg(k.Curve.Params().BitSize)
+	}
+
+	return "", fmt.Errorf("can't infer signature algorithm for key type %T", key.Key)
+}
+
+//nolint:gomnd
+func ecdsaAlg(bitSize int) (string, error) {
+	switch bitSize {
+	case 256:
+		return "ES256", nil
+	case 384:
+		return "ES384", nil
+	case 521:
+		return "ES512", nil
+	}
+
+	return "", fmt.Errorf("unsupported ecdsa curve size %d", bitSize)
+}
+
+// Sign signs the given message with the given private key.
+func Sign(msg []byte, privateKey *jwk.JWK) ([]byte, error) {
+	alg, err := Algorithm(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	switch key := privateKey.Key.(type) {
+	case *ecdsa.PrivateKey:
+		return ecdsaSign(msg, key, alg)
+	case ed25519.PrivateKey:
+		if alg != algEdDSA {
+			return nil, errors.New("alg not supported")
+		}
+
+		return eddsaSign(msg, key)
+	case *rsa.PrivateKey:
+		return rsaPSSSign(msg, key, alg)
+	}
+
+	return nil, fmt.Errorf("unsupported private key type %T", privateKey.Key)
+}
+
+// Verify verifies that the given signature over the given message is made by the given public key.
+func Verify(pubKey *jwk.JWK, msg, signature []byte) error {
+	alg, err := Algorithm(pubKey)
+	if err != nil {
+		return err
+	}
+
+	verKey := *pubKey
+	verKey.Algorithm = alg
+
+	switch alg {
+	case "ES256", "ES384", "ES512":
+		return ecdsaVerifier(&verKey, msg, signature)
+	case algEdDSA:
+		return eddsaVerifier(&verKey, msg, signature)
+	case "PS256", "PS384", "PS512":
+		return rsaPSSVerifier(&verKey, msg, signature)
+	}
+
+	return fmt.Errorf("alg %s not supported", alg)
+}
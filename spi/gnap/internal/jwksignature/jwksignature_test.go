@@ -0,0 +1,103 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package jwksignature
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk"
+	"github.com/square/go-jose/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignVerify(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		key    crypto.Signer
+		alg    string
+		expAlg string
+	}{
+		{name: "ecdsa with inferred alg", key: ecKey, expAlg: es384Alg},
+		{name: "ed25519 with inferred alg", key: edKey, expAlg: "EdDSA"},
+		{name: "ed25519", key: edKey, alg: "EdDSA", expAlg: "EdDSA"},
+		{name: "rsa with inferred alg", key: rsaKey, expAlg: "PS512"},
+		{name: "rsa-pss sha-256", key: rsaKey, alg: "PS256", expAlg: "PS256"},
+	}
+
+	msg := []byte("the quick brown fox jumps over the lazy dog")
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			privJWK := &jwk.JWK{JSONWebKey: jose.JSONWebKey{Key: tc.key, Algorithm: tc.alg}}
+			pubJWK := &jwk.JWK{JSONWebKey: jose.JSONWebKey{Key: tc.key.Public(), Algorithm: tc.alg}}
+
+			alg, err := Algorithm(pubJWK)
+			require.NoError(t, err)
+			require.Equal(t, tc.expAlg, alg)
+
+			sig, err := Sign(msg, privJWK)
+			require.NoError(t, err)
+
+			require.NoError(t, Verify(pubJWK, msg, sig))
+			require.Error(t, Verify(pubJWK, []byte("foo"), sig))
+		})
+	}
+
+	t.Run("unsupported key type", func(t *testing.T) {
+		key := &jwk.JWK{JSONWebKey: jose.JSONWebKey{Key: []byte("foo")}}
+
+		_, err := Sign(msg, key)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "can't infer signature algorithm")
+
+		err = Verify(key, msg, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "can't infer signature algorithm")
+
+		key.Algorithm = "foo"
+
+		_, err = Sign(msg, key)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unsupported private key type")
+
+		err = Verify(key, msg, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "alg foo not supported")
+	})
+
+	t.Run("ed25519 key with mismatched alg", func(t *testing.T) {
+		_, err := Sign(msg, &jwk.JWK{JSONWebKey: jose.JSONWebKey{Key: edKey, Algorithm: es256Alg}})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "alg not supported")
+	})
+
+	t.Run("unsupported ecdsa curve", func(t *testing.T) {
+		key, err := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+		require.NoError(t, err)
+
+		_, err = Algorithm(&jwk.JWK{JSONWebKey: jose.JSONWebKey{Key: key}})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unsupported ecdsa curve size")
+	})
+}
@@ -0,0 +1,79 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package jwksignature
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk"
+)
+
+func rsaPSSHash(alg string) (crypto.Hash, error) {
+	switch alg {
+	case "PS256":
+		return crypto.SHA256, nil
+	case "PS384":
+		return crypto.SHA384, nil
+	case "PS512":
+		return crypto.SHA512, nil
+	}
+
+	return 0, errors.New("alg not supported")
+}
+
+func rsaPSSSign(msg []byte, privateKey *rsa.PrivateKey, alg string) ([]byte, error) {
+	hash, err := rsaPSSHash(alg)
+	if err != nil {
+		return nil, err
+	}
+
+	hasher := hash.New()
+
+	_, err = hasher.Write(msg)
+	if err != nil {
+		return nil, fmt.Errorf("rsa-pss hash error: %w", err)
+	}
+
+	sig, err := rsa.SignPSS(rand.Reader, privateKey, hash, hasher.Sum(nil),
+		&rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	if err != nil {
+		return nil, fmt.Errorf("error signing with rsa-pss: %w", err)
+	}
+
+	return sig, nil
+}
+
+func rsaPSSVerifier(pubKeyJWK *jwk.JWK, msg, signature []byte) error {
+	hash, err := rsaPSSHash(pubKeyJWK.Algorithm)
+	if err != nil {
+		return fmt.Errorf("rsa-pss %w", err)
+	}
+
+	rsaPubKey, ok := pubKeyJWK.Key.(*rsa.PublicKey)
+	if !ok {
+		return errors.New("invalid public key type")
+	}
+
+	hasher := hash.New()
+
+	_, err = hasher.Write(msg)
+	if err != nil {
+		return errors.New("hash error")
+	}
+
+	err = rsa.VerifyPSS(rsaPubKey, hash, hasher.Sum(nil), signature,
+		&rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	if err != nil {
+		return errors.New("invalid signature")
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package jwksignature
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk"
+	"github.com/square/go-jose/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_rsaPSSSignVerify(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	msg := []byte("the quick brown fox jumps over the lazy dog")
+
+	for _, a := range []string{"PS256", "PS384", "PS512"} {
+		alg := a
+
+		t.Run(fmt.Sprintf("success %s", alg), func(t *testing.T) {
+			sig, err := rsaPSSSign(msg, priv, alg)
+			require.NoError(t, err)
+
+			require.NoError(t, rsaPSSVerifier(&jwk.JWK{
+				JSONWebKey: jose.JSONWebKey{
+					Key:       &priv.PublicKey,
+					Algorithm: alg,
+				},
+				Kty: "RSA",
+			}, msg, sig))
+		})
+	}
+
+	t.Run("alg not supported", func(t *testing.T) {
+		_, err := rsaPSSSign(msg, priv, "RS256")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "alg not supported")
+
+		err = rsaPSSVerifier(&jwk.JWK{JSONWebKey: jose.JSONWebKey{Algorithm: "RS256"}}, msg, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "alg not supported")
+	})
+
+	t.Run("signing error", func(t *testing.T) {
+		_, err := rsaPSSSign(msg, &rsa.PrivateKey{PublicKey: rsa.PublicKey{N: priv.N, E: 0}}, "PS256")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "error signing with rsa-pss")
+	})
+
+	t.Run("invalid key type", func(t *testing.T) {
+		err := rsaPSSVerifier(&jwk.JWK{JSONWebKey: jose.JSONWebKey{Key: []byte{}, Algorithm: "PS256"}}, msg, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid public key type")
+	})
+
+	t.Run("invalid signature", func(t *testing.T) {
+		err := rsaPSSVerifier(&jwk.JWK{
+			JSONWebKey: jose.JSONWebKey{
+				Key:       &priv.PublicKey,
+				Algorithm: "PS256",
+			},
+		}, msg, []byte("foo"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid signature")
+	})
+}
@@ -0,0 +1,113 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package jwksignature
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk"
+)
+
+// SignWithSigner signs the given message with a crypto.Signer, such as a PKCS#11 or KMS-backed key, so the private
+// key never needs to be held in memory. The signature algorithm is that of the given public key, and the signature
+// uses its JWS encoding.
+func SignWithSigner(msg []byte, signer crypto.Signer, publicKey *jwk.JWK) ([]byte, error) {
+	alg, err := Algorithm(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	switch alg {
+	case algEdDSA:
+		return signer.Sign(rand.Reader, msg, crypto.Hash(0))
+	case "ES256", "ES384", "ES512":
+		hash := ecdsaHash(alg)
+
+		sig, e := signer.Sign(rand.Reader, digest(msg, hash), hash)
+		if e != nil {
+			return nil, fmt.Errorf("error signing with ecdsa: %w", e)
+		}
+
+		return RawECDSASignature(sig, publicKey)
+	case "PS256", "PS384", "PS512":
+		hash, e := rsaPSSHash(alg)
+		if e != nil {
+			return nil, e
+		}
+
+		sig, e := signer.Sign(rand.Reader, digest(msg, hash),
+			&rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: hash})
+		if e != nil {
+			return nil, fmt.Errorf("error signing with rsa-pss: %w", e)
+		}
+
+		return sig, nil
+	}
+
+	return nil, fmt.Errorf("alg %s not supported", alg)
+}
+
+// RawECDSASignature returns the JWS encoding of an ecdsa signature made by the given public key's private key: the
+// fixed-size concatenation of r and s. ASN.1 DER signatures, as produced by crypto.Signer and most KMSs, are
+// converted, and signatures that are already in JWS encoding are returned unchanged.
+func RawECDSASignature(sig []byte, publicKey *jwk.JWK) ([]byte, error) {
+	pubKey, ok := publicKey.Key.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("expected ecdsa public key, got %T", publicKey.Key)
+	}
+
+	keyBytes := (pubKey.Curve.Params().BitSize + 7) / 8 // nolint:gomnd
+
+	if len(sig) == 2*keyBytes {
+		return sig, nil
+	}
+
+	var esig struct {
+		R, S *big.Int
+	}
+
+	rest, err := asn1.Unmarshal(sig, &esig)
+	if err != nil {
+		return nil, fmt.Errorf("asn.1 unmarshal: %w", err)
+	}
+
+	if len(rest) > 0 || esig.R.Sign() <= 0 || esig.S.Sign() <= 0 ||
+		esig.R.BitLen() > 8*keyBytes || esig.S.BitLen() > 8*keyBytes {
+		return nil, errors.New("invalid ecdsa signature")
+	}
+
+	raw := make([]byte, 2*keyBytes)
+	esig.R.FillBytes(raw[:keyBytes])
+	esig.S.FillBytes(raw[keyBytes:])
+
+	return raw, nil
+}
+
+func ecdsaHash(alg string) crypto.Hash {
+	switch alg {
+	case "ES384":
+		return crypto.SHA384
+	case "ES512":
+		return crypto.SHA512
+	}
+
+	return crypto.SHA256
+}
+
+func digest(msg []byte, hash crypto.Hash) []byte {
+	hasher := hash.New()
+	hasher.Write(msg) // nolint:errcheck,gosec // hash writes never fail
+
+	return hasher.Sum(nil)
+}
@@ -0,0 +1,139 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package jwksignature
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk"
+	"github.com/square/go-jose/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignWithSigner(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	ec521Key, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	require.NoError(t, err)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		key  crypto.Signer
+		alg  string
+	}{
+		{name: "ecdsa p-256", key: ecKey},
+		{name: "ecdsa p-521", key: ec521Key},
+		{name: "ed25519", key: edKey},
+		{name: "rsa-pss sha-512", key: rsaKey},
+		{name: "rsa-pss sha-256", key: rsaKey, alg: "PS256"},
+	}
+
+	msg := []byte("the quick brown fox jumps over the lazy dog")
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			pubJWK := &jwk.JWK{JSONWebKey: jose.JSONWebKey{Key: tc.key.Public(), Algorithm: tc.alg}}
+
+			sig, err := SignWithSigner(msg, tc.key, pubJWK)
+			require.NoError(t, err)
+
+			require.NoError(t, Verify(pubJWK, msg, sig))
+		})
+	}
+
+	t.Run("unsupported alg", func(t *testing.T) {
+		pubJWK := &jwk.JWK{JSONWebKey: jose.JSONWebKey{Key: ecKey.Public(), Algorithm: "foo"}}
+
+		_, err := SignWithSigner(msg, ecKey, pubJWK)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "alg foo not supported")
+	})
+
+	t.Run("unknown key type", func(t *testing.T) {
+		_, err := SignWithSigner(msg, ecKey, &jwk.JWK{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "can't infer signature algorithm")
+	})
+
+	t.Run("signer error", func(t *testing.T) {
+		expectErr := errors.New("expected error")
+
+		for _, key := range []crypto.Signer{ecKey, rsaKey} {
+			pubJWK := &jwk.JWK{JSONWebKey: jose.JSONWebKey{Key: key.Public()}}
+
+			_, err := SignWithSigner(msg, &failingSigner{Signer: key, err: expectErr}, pubJWK)
+			require.ErrorIs(t, err, expectErr)
+		}
+	})
+}
+
+func TestRawECDSASignature(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+
+	pubJWK := &jwk.JWK{JSONWebKey: jose.JSONWebKey{Key: ecKey.Public()}}
+
+	msg := []byte("the quick brown fox jumps over the lazy dog")
+
+	t.Run("der signature", func(t *testing.T) {
+		der, err := ecKey.Sign(rand.Reader, digest(msg, crypto.SHA384), crypto.SHA384)
+		require.NoError(t, err)
+
+		raw, err := RawECDSASignature(der, pubJWK)
+		require.NoError(t, err)
+		require.Len(t, raw, 96)
+
+		require.NoError(t, Verify(pubJWK, msg, raw))
+	})
+
+	t.Run("raw signature", func(t *testing.T) {
+		raw, err := ecdsaSign(msg, ecKey, "ES384")
+		require.NoError(t, err)
+
+		out, err := RawECDSASignature(raw, pubJWK)
+		require.NoError(t, err)
+		require.Equal(t, raw, out)
+	})
+
+	t.Run("not an ecdsa key", func(t *testing.T) {
+		_, err := RawECDSASignature([]byte("foo"), &jwk.JWK{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "expected ecdsa public key")
+	})
+
+	t.Run("malformed signature", func(t *testing.T) {
+		_, err := RawECDSASignature([]byte("foo"), pubJWK)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "asn.1 unmarshal")
+	})
+}
+
+type failingSigner struct {
+	crypto.Signer
+	err error
+}
+
+func (s *failingSigner) Sign(io.Reader, []byte, crypto.SignerOpts) ([]byte, error) {
+	return nil, s.err
+}
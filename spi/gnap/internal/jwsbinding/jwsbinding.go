@@ -0,0 +1,106 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package jwsbinding
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/square/go-jose/v3"
+)
+
+// Protected header parameters binding a GNAP JWS to its http request.
+const (
+	HTMHeader     jose.HeaderKey = "htm"
+	URIHeader     jose.HeaderKey = "uri"
+	CreatedHeader jose.HeaderKey = "created"
+	ATHHeader     jose.HeaderKey = "ath"
+)
+
+// DetachedHeaderName is the http header that holds a detached JWS.
+const DetachedHeaderName = "Detached-JWS"
+
+// DefaultClockSkew is the default largest allowed difference between a JWS's created time and the current time.
+const DefaultClockSkew = 5 * time.Minute
+
+const gnapAuthScheme = "GNAP "
+
+// SignerOptions returns JWS signer options with protected headers of the given type, binding
+// the JWS to the given request.
+func SignerOptions(req *http.Request, typ string) *jose.SignerOptions {
+	opts := (&jose.SignerOptions{}).
+		WithType(jose.ContentType(typ)).
+		WithHeader(HTMHeader, req.Method).
+		WithHeader(URIHeader, req.URL.String()).
+		WithHeader(CreatedHeader, time.Now().Unix())
+
+	if token := AccessToken(req); token != "" {
+		opts = opts.WithHeader(ATHHeader, HashB64([]byte(token)))
+	}
+
+	return opts
+}
+
+// VerifyHeaders verifies that the given protected JWS headers have the given type, and bind the JWS to the given
+// request. The JWS's created time must be within the given clock skew of the current time.
+func VerifyHeaders(
+	req *http.Request, headers map[jose.HeaderKey]interface{}, typ string, clockSkew time.Duration,
+) error {
+	if t, _ := headers[jose.HeaderType].(string); t != typ {
+		return fmt.Errorf("unexpected jws type '%s'", t)
+	}
+
+	if htm, _ := headers[HTMHeader].(string); htm != req.Method {
+		return fmt.Errorf("signed method '%s' does not match request method", htm)
+	}
+
+	if uri, _ := headers[URIHeader].(string); uri != req.URL.String() {
+		return fmt.Errorf("signed uri '%s' does not match request uri", uri)
+	}
+
+	created, ok := headers[CreatedHeader].(float64)
+	if !ok {
+		return errors.New("missing created time")
+	}
+
+	skew := time.Since(time.Unix(int64(created), 0))
+	if math.Abs(float64(skew)) > float64(clockSkew) {
+		return errors.New("signature created time outside of allowed window")
+	}
+
+	ath, _ := headers[ATHHeader].(string)
+
+	if token := AccessToken(req); token != "" && ath != HashB64([]byte(token)) {
+		return errors.New("signature is not bound to the request access token")
+	}
+
+	return nil
+}
+
+// HashB64 returns the base64url-encoded sha-256 hash of the given data.
+func HashB64(data []byte) string {
+	h := sha256.Sum256(data)
+
+	return base64.RawURLEncoding.EncodeToString(h[:])
+}
+
+// AccessToken returns the GNAP access token that the request is bound to, if any.
+func AccessToken(req *http.Request) string {
+	authHeader := strings.TrimSpace(req.Header.Get("Authorization"))
+
+	if !strings.HasPrefix(authHeader, gnapAuthScheme) {
+		return ""
+	}
+
+	return strings.TrimSpace(authHeader[len(gnapAuthScheme):])
+}
@@ -7,33 +7,65 @@ SPDX-License-Identifier: Apache-2.0
 package gnap
 
 import (
-	"encoding/json"
-
 	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk"
 )
 
-// AuthRequest https://www.ietf.org/archive/id/draft-ietf-gnap-core-protocol-09.html#section-2
+// AuthRequest https://www.rfc-editor.org/rfc/rfc9635.html#section-2
 type AuthRequest struct {
 	// TODO: single TokenRequest is treated like a slice of one element.
 	AccessToken []*TokenRequest  `json:"access_token,omitempty"`
+	Subject     *RequestSubject  `json:"subject,omitempty"`
 	Client      *RequestClient   `json:"client,omitempty"`
+	User        *RequestUser     `json:"user,omitempty"`
 	Interact    *RequestInteract `json:"interact,omitempty"`
 }
 
-// RequestClient https://www.ietf.org/archive/id/draft-ietf-gnap-core-protocol-09.html#section-2.3
+// RequestClient https://www.rfc-editor.org/rfc/rfc9635.html#section-2.3
 type RequestClient struct {
 	IsReference bool
 	Ref         string
-	Key         *ClientKey `json:"key"`
+	Key         *ClientKey     `json:"key"`
+	ClassID     string         `json:"class_id,omitempty"`
+	Display     *ClientDisplay `json:"display,omitempty"`
+}
+
+// ClientDisplay https://www.rfc-editor.org/rfc/rfc9635.html#section-2.3.2
+type ClientDisplay struct {
+	Name    string `json:"name,omitempty"`
+	URI     string `json:"uri,omitempty"`
+	LogoURI string `json:"logo_uri,omitempty"`
 }
 
-// ClientKey https://www.ietf.org/archive/id/draft-ietf-gnap-core-protocol-09.html#section-7.1.1
+// RequestSubject https://www.rfc-editor.org/rfc/rfc9635.html#section-2.2
+type RequestSubject struct {
+	SubIDFormats     []string    `json:"sub_id_formats,omitempty"`
+	AssertionFormats []string    `json:"assertion_formats,omitempty"`
+	SubIDs           []SubjectID `json:"sub_ids,omitempty"`
+}
+
+// RequestUser https://www.rfc-editor.org/rfc/rfc9635.html#section-2.4
+type RequestUser struct {
+	IsReference bool
+	Ref         string
+	SubIDs      []SubjectID        `json:"sub_ids,omitempty"`
+	Assertions  []SubjectAssertion `json:"assertions,omitempty"`
+}
+
+// ClientKey https://www.rfc-editor.org/rfc/rfc9635.html#section-7.1
+//
+// The proof method may be sent either as a string or as an object, in which case only its method is kept.
 type ClientKey struct {
 	Proof string  `json:"proof"`
 	JWK   jwk.JWK `json:"jwk"`
+	// Cert is the base64-encoded DER X.509 certificate the key is bound to.
+	Cert string `json:"cert,omitempty"`
+	// X5C is the base64-encoded DER X.509 certificate chain the key is bound to, starting with the key's certificate.
+	X5C []string `json:"x5c,omitempty"`
+	// DID is the did:key or did:jwk DID, or DID URL, that identifies the key.
+	DID string `json:"did,omitempty"`
 }
 
-// TokenRequest https://www.ietf.org/archive/id/draft-ietf-gnap-core-protocol-09.html#section-2.1
+// TokenRequest https://www.rfc-editor.org/rfc/rfc9635.html#section-2.1
 type TokenRequest struct {
 	Access []TokenAccess `json:"access"`
 	Label  string        `json:"label,omitempty"`
@@ -42,84 +74,155 @@ type TokenRequest struct {
 
 // TokenAccess represents a GNAP token access descriptor, either as a string reference or as an object.
 //
-// see: https://www.ietf.org/archive/id/draft-ietf-gnap-core-protocol-09.html#section-8
+// see: https://www.rfc-editor.org/rfc/rfc9635.html#section-8
 type TokenAccess struct {
 	IsReference bool
 	Ref         string
-	Type        string `json:"type"`
-	Raw         json.RawMessage
+	Type        string   `json:"type"`
+	Actions     []string `json:"actions,omitempty"`
+	Locations   []string `json:"locations,omitempty"`
+	Datatypes   []string `json:"datatypes,omitempty"`
+	Identifier  string   `json:"identifier,omitempty"`
+	Privileges  []string `json:"privileges,omitempty"`
+	// Extensions holds the API-specific members of the descriptor, as parsed by encoding/json. They're marshaled
+	// along with the typed fields.
+	Extensions map[string]interface{}
 }
 
-// RequestInteract https://www.ietf.org/archive/id/draft-ietf-gnap-core-protocol-09.html#section-2.5
+// RequestInteract https://www.rfc-editor.org/rfc/rfc9635.html#section-2.5
 type RequestInteract struct {
-	Start  []string      `json:"start"`
-	Finish RequestFinish `json:"finish"`
+	Start  []string       `json:"start"`
+	Finish *RequestFinish `json:"finish,omitempty"`
+	Hints  *InteractHints `json:"hints,omitempty"`
 }
 
-// RequestFinish https://www.ietf.org/archive/id/draft-ietf-gnap-core-protocol-09.html#section-2.5.2
+// RequestFinish https://www.rfc-editor.org/rfc/rfc9635.html#section-2.5.2
 type RequestFinish struct {
 	Method string `json:"method"`
-	URI    string `json:"uri"`
+	URI    string `json:"uri,omitempty"`
 	Nonce  string `json:"nonce"`
+	// HashMethod is the hash algorithm of the interaction finish hash, from the IANA Named Information Hash
+	// Algorithm Registry. If empty, the hash method is sha-256. Parsing a draft-09 request sets the draft-09 default
+	// of sha3-512 explicitly.
+	HashMethod string `json:"hash_method,omitempty"`
 }
 
-// AuthResponse https://www.ietf.org/archive/id/draft-ietf-gnap-core-protocol-09.html#section-3
+// InteractHints https://www.rfc-editor.org/rfc/rfc9635.html#section-2.5.3
+type InteractHints struct {
+	UILocales []string `json:"ui_locales,omitempty"`
+}
+
+// AuthResponse https://www.rfc-editor.org/rfc/rfc9635.html#section-3
 type AuthResponse struct {
-	Continue    ResponseContinue `json:"continue,omitempty"`
-	AccessToken []AccessToken    `json:"access_token,omitempty"`
-	Interact    ResponseInteract `json:"interact,omitempty"`
-	InstanceID  string           `json:"instance_id,omitempty"`
-	Subject     Subject          `json:"subject,omitempty"`
+	Continue    *ResponseContinue `json:"continue,omitempty"`
+	AccessToken []AccessToken     `json:"access_token,omitempty"`
+	Interact    *ResponseInteract `json:"interact,omitempty"`
+	Subject     *Subject          `json:"subject,omitempty"`
+	InstanceID  string            `json:"instance_id,omitempty"`
+	// Denied is the requested access that the user didn't approve. It's an extension of the GNAP response, which
+	// lets the client tell denied access apart from access that the AS grants later.
+	Denied *DeniedAccess `json:"denied,omitempty"`
+}
+
+// DeniedAccess is the part of a grant request that the user didn't approve.
+type DeniedAccess struct {
+	// AccessToken are the requested access tokens that weren't granted.
+	AccessToken []TokenRequest `json:"access_token,omitempty"`
+	// SubjectKeys are the keys of the user's subject data that isn't released to the client.
+	SubjectKeys []string `json:"subject_keys,omitempty"`
 }
 
-// ResponseContinue https://www.ietf.org/archive/id/draft-ietf-gnap-core-protocol-09.html#section-3.1
+// ResponseContinue https://www.rfc-editor.org/rfc/rfc9635.html#section-3.1
 type ResponseContinue struct {
-	URI         string      `json:"uri,omitempty"`
-	AccessToken AccessToken `json:"access_token,omitempty"`
+	URI         string      `json:"uri"`
+	AccessToken AccessToken `json:"access_token"`
 	Wait        int         `json:"wait,omitempty"`
 }
 
-// ResponseInteract https://www.ietf.org/archive/id/draft-ietf-gnap-core-protocol-09.html#section-3.3
+// ResponseInteract https://www.rfc-editor.org/rfc/rfc9635.html#section-3.3
 type ResponseInteract struct {
 	Redirect string `json:"redirect,omitempty"`
-	Finish   string `json:"finish,omitempty"`
+	// App is a url that launches an application on the user's device, which runs the interaction.
+	App    string `json:"app,omitempty"`
+	Finish string `json:"finish,omitempty"`
+	// UserCode is a short code that the user types in at a page of the AS to start the interaction.
+	UserCode string `json:"user_code,omitempty"`
+	// UserCodeURI is a short code and the url of the page where the user types it in.
+	UserCodeURI *UserCodeURI `json:"user_code_uri,omitempty"`
+	// ExpiresIn is the number of seconds that the interaction can be started in.
+	ExpiresIn int64 `json:"expires_in,omitempty"`
+}
+
+// UserCodeURI https://www.rfc-editor.org/rfc/rfc9635.html#section-3.3.3
+type UserCodeURI struct {
+	Code string `json:"code"`
+	URI  string `json:"uri"`
 }
 
-// Subject https://www.ietf.org/archive/id/draft-ietf-gnap-core-protocol-09.html#section-3.4
+// Subject https://www.rfc-editor.org/rfc/rfc9635.html#section-3.4
 type Subject struct {
 	SubIDs     []SubjectID        `json:"sub_ids,omitempty"`
 	Assertions []SubjectAssertion `json:"assertions,omitempty"`
+	// UpdatedAt is the RFC 3339 timestamp of when the subject's information was last updated.
+	UpdatedAt string `json:"updated_at,omitempty"`
 }
 
-// SubjectID https://www.ietf.org/archive/id/draft-ietf-secevent-subject-identifiers-09.txt
+// SubjectID https://www.rfc-editor.org/rfc/rfc9493.html#section-3
+//
+// The members that are set depend on the Format: id for opaque, iss and sub for iss_sub, email for email, and
+// url for did.
 type SubjectID struct {
 	ID     string `json:"id,omitempty"`
 	Format string `json:"format,omitempty"`
+	Iss    string `json:"iss,omitempty"`
+	Sub    string `json:"sub,omitempty"`
+	Email  string `json:"email,omitempty"`
+	URL    string `json:"url,omitempty"`
 }
 
-// SubjectAssertion https://www.ietf.org/archive/id/draft-ietf-gnap-core-protocol-09.html#section-3.4
+// SubjectAssertion https://www.rfc-editor.org/rfc/rfc9635.html#section-3.4
 type SubjectAssertion struct {
 	Value  string `json:"value,omitempty"`
 	Format string `json:"format,omitempty"`
 }
 
-// AccessToken https://www.ietf.org/archive/id/draft-ietf-gnap-core-protocol-09.html#section-3.2.1
+// AccessToken https://www.rfc-editor.org/rfc/rfc9635.html#section-3.2.1
 type AccessToken struct {
-	Value   string        `json:"value,omitempty"`
-	Label   string        `json:"label,omitempty"`
-	Manage  string        `json:"manage,omitempty"`
-	Access  []TokenAccess `json:"access,omitempty"`
-	Expires int64         `json:"expires_in,omitempty"` // integer value in seconds.
-	Key     string        `json:"key,omitempty"`
-	Flags   []AccessFlag  `json:"flags,omitempty"`
+	Value   string           `json:"value,omitempty"`
+	Label   string           `json:"label,omitempty"`
+	Manage  *TokenManagement `json:"manage,omitempty"`
+	Access  []TokenAccess    `json:"access,omitempty"`
+	Expires int64            `json:"expires_in,omitempty"` // integer value in seconds.
+	Key     *TokenKey        `json:"key,omitempty"`
+	Flags   []AccessFlag     `json:"flags,omitempty"`
+}
+
+// TokenManagement https://www.rfc-editor.org/rfc/rfc9635.html#section-3.2.1
+//
+// A draft-09 management URI sent as a string is parsed into URI.
+type TokenManagement struct {
+	URI         string      `json:"uri"`
+	AccessToken AccessToken `json:"access_token"`
 }
 
-// ContinueRequest https://www.ietf.org/archive/id/draft-ietf-gnap-core-protocol-09.html#section-5.1
+// TokenKey is the key an access token is bound to, either as a key object or as a key reference.
+//
+// see: https://www.rfc-editor.org/rfc/rfc9635.html#section-3.2.1
+type TokenKey struct {
+	IsReference bool
+	Ref         string
+	Key         *ClientKey
+}
+
+// ContinueRequest https://www.rfc-editor.org/rfc/rfc9635.html#section-5.1
 type ContinueRequest struct {
-	InteractRef string `json:"interact_ref"`
+	InteractRef string `json:"interact_ref,omitempty"`
 }
 
-// ErrorResponse https://www.ietf.org/archive/id/draft-ietf-gnap-core-protocol-09.html#section-3.6
+// ErrorResponse https://www.rfc-editor.org/rfc/rfc9635.html#section-3.6
+//
+// It's sent as an error object, and parsed from either an error object, or a draft-09 error code string with a
+// separate error_description.
 type ErrorResponse struct {
 	Error       string `json:"error"`
 	Description string `json:"error_description,omitempty"`
@@ -140,6 +243,8 @@ type IntrospectResponse struct {
 	Key         *ClientKey        `json:"key,omitempty"`
 	Flags       []AccessFlag      `json:"flags,omitempty"`
 	SubjectData map[string]string `json:"subject_data,omitempty"`
+	// InstanceID is the instance identifier of the client that the access token was issued to.
+	InstanceID string `json:"instance_id,omitempty"`
 }
 
 type AccessFlag string
@@ -12,11 +12,15 @@ import (
 	"errors"
 	"fmt"
 	"io"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk"
 )
 
 type rawAuthRequest struct {
 	AccessToken json.RawMessage  `json:"access_token,omitempty"`
+	Subject     *RequestSubject  `json:"subject,omitempty"`
 	Client      *RequestClient   `json:"client,omitempty"`
+	User        *RequestUser     `json:"user,omitempty"`
 	Interact    *RequestInteract `json:"interact,omitempty"`
 }
 
@@ -31,6 +35,12 @@ func (a *AuthRequest) UnmarshalJSON(data []byte) error {
 
 	a.Interact = raw.Interact
 	a.Client = raw.Client
+	a.Subject = raw.Subject
+	a.User = raw.User
+
+	if a.Interact != nil && a.Interact.Finish != nil {
+		a.Interact.Finish.HashMethod = finishHashMethod(a.Interact.Finish.HashMethod, isDraft09Request(data))
+	}
 
 	tokList, err := unmarshalTokenList(raw.AccessToken)
 	if err != nil {
@@ -42,6 +52,71 @@ func (a *AuthRequest) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// draft09HashMethods maps the interaction finish hash methods of draft-09 to their names in the IANA Named Information
+// Hash Algorithm Registry.
+var draft09HashMethods = map[string]string{ // nolint:gochecknoglobals
+	"sha3": "sha3-512",
+	"sha2": "sha-512",
+}
+
+// finishHashMethod returns the registry name of the interaction finish hash method that the client requests. The
+// default hash method of draft-09 requests is sha3-512, and the RFC 9635 default of sha-256 is left implicit.
+func finishHashMethod(method string, draft09 bool) string {
+	if registered, ok := draft09HashMethods[method]; ok {
+		return registered
+	}
+
+	if method == "" && draft09 {
+		return draft09HashMethods["sha3"]
+	}
+
+	return method
+}
+
+type rawDraft09Subject struct {
+	SubIDs     json.RawMessage `json:"sub_ids,omitempty"`
+	Assertions []string        `json:"assertions,omitempty"`
+}
+
+type rawDraft09Finish struct {
+	HashMethod string `json:"hash_method,omitempty"`
+}
+
+type rawDraft09Interact struct {
+	Finish *rawDraft09Finish `json:"finish,omitempty"`
+}
+
+type rawDraft09Request struct {
+	Subject  *rawDraft09Subject  `json:"subject,omitempty"`
+	Interact *rawDraft09Interact `json:"interact,omitempty"`
+}
+
+// isDraft09Request returns true iff the request holds members in their draft-09 form: subject identifier formats in
+// subject.sub_ids, assertion formats in subject.assertions, or a draft-09 finish hash method.
+func isDraft09Request(data []byte) bool {
+	raw := &rawDraft09Request{}
+
+	if json.Unmarshal(data, raw) != nil {
+		return false
+	}
+
+	if raw.Subject != nil {
+		var formats []string
+
+		if len(raw.Subject.Assertions) > 0 || json.Unmarshal(raw.Subject.SubIDs, &formats) == nil {
+			return true
+		}
+	}
+
+	if raw.Interact != nil && raw.Interact.Finish != nil {
+		_, ok := draft09HashMethods[raw.Interact.Finish.HashMethod]
+
+		return ok
+	}
+
+	return false
+}
+
 func unmarshalTokenList(data []byte) ([]*TokenRequest, error) {
 	dec := json.NewDecoder(bytes.NewReader(data))
 
@@ -101,7 +176,9 @@ func (a *AuthRequest) MarshalJSON() ([]byte, error) {
 
 	raw := &rawAuthRequest{
 		AccessToken: rawTok,
+		Subject:     a.Subject,
 		Client:      a.Client,
+		User:        a.User,
 		Interact:    a.Interact,
 	}
 
@@ -109,7 +186,9 @@ func (a *AuthRequest) MarshalJSON() ([]byte, error) {
 }
 
 type rawRequestClient struct {
-	Key *ClientKey `json:"key"`
+	Key     *ClientKey     `json:"key"`
+	ClassID string         `json:"class_id,omitempty"`
+	Display *ClientDisplay `json:"display,omitempty"`
 }
 
 // UnmarshalJSON implements json.Unmarshaler.
@@ -143,6 +222,8 @@ func (r *RequestClient) UnmarshalJSON(data []byte) error {
 		r.IsReference = false
 		r.Ref = ""
 		r.Key = raw.Key
+		r.ClassID = raw.ClassID
+		r.Display = raw.Display
 
 		return nil
 	}
@@ -157,14 +238,205 @@ func (r *RequestClient) MarshalJSON() ([]byte, error) {
 	}
 
 	raw := &rawRequestClient{
-		Key: r.Key,
+		Key:     r.Key,
+		ClassID: r.ClassID,
+		Display: r.Display,
+	}
+
+	return json.Marshal(raw)
+}
+
+type rawRequestSubject struct {
+	SubIDFormats     []string        `json:"sub_id_formats,omitempty"`
+	AssertionFormats []string        `json:"assertion_formats,omitempty"`
+	SubIDs           json.RawMessage `json:"sub_ids,omitempty"`
+	// Assertions holds the requested assertion formats of a draft-09 request.
+	Assertions []string `json:"assertions,omitempty"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler. A draft-09 request lists the requested subject identifier and assertion
+// formats in sub_ids and assertions, which are parsed into SubIDFormats and AssertionFormats.
+func (r *RequestSubject) UnmarshalJSON(data []byte) error {
+	raw := &rawRequestSubject{}
+
+	err := json.Unmarshal(data, raw)
+	if err != nil {
+		return fmt.Errorf("parsing request.subject: %w", err)
+	}
+
+	r.SubIDFormats = raw.SubIDFormats
+	r.AssertionFormats = append(raw.AssertionFormats, raw.Assertions...)
+	r.SubIDs = nil
+
+	if len(r.AssertionFormats) == 0 {
+		r.AssertionFormats = nil
+	}
+
+	if len(raw.SubIDs) == 0 {
+		return nil
+	}
+
+	var formats []string
+
+	if json.Unmarshal(raw.SubIDs, &formats) == nil {
+		r.SubIDFormats = append(r.SubIDFormats, formats...)
+
+		return nil
+	}
+
+	err = json.Unmarshal(raw.SubIDs, &r.SubIDs)
+	if err != nil {
+		return fmt.Errorf("parsing request.subject.sub_ids: %w", err)
+	}
+
+	return nil
+}
+
+type rawRequestUser struct {
+	SubIDs     []SubjectID        `json:"sub_ids,omitempty"`
+	Assertions []SubjectAssertion `json:"assertions,omitempty"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (u *RequestUser) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	tok, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("parsing request.user: %w", err)
+	}
+
+	switch val := tok.(type) {
+	case string:
+		*u = RequestUser{IsReference: true, Ref: val}
+
+		return nil
+	case json.Delim:
+		if val != '{' {
+			break
+		}
+
+		raw := &rawRequestUser{}
+
+		err = json.Unmarshal(data, raw)
+		if err != nil {
+			return fmt.Errorf("parsing request.user as object: %w", err)
+		}
+
+		*u = RequestUser{SubIDs: raw.SubIDs, Assertions: raw.Assertions}
+
+		return nil
+	}
+
+	return fmt.Errorf("parsing request.user expected either string or object, got '%v'", tok)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (u *RequestUser) MarshalJSON() ([]byte, error) {
+	if u.IsReference {
+		return json.Marshal(u.Ref)
+	}
+
+	return json.Marshal(&rawRequestUser{
+		SubIDs:     u.SubIDs,
+		Assertions: u.Assertions,
+	})
+}
+
+type rawClientKey struct {
+	Proof json.RawMessage `json:"proof"`
+	JWK   *jwk.JWK        `json:"jwk,omitempty"`
+	Cert  string          `json:"cert,omitempty"`
+	X5C   []string        `json:"x5c,omitempty"`
+	DID   string          `json:"did,omitempty"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (k *ClientKey) UnmarshalJSON(data []byte) error {
+	raw := &rawClientKey{}
+
+	err := json.Unmarshal(data, raw)
+	if err != nil {
+		return fmt.Errorf("parsing client key: %w", err)
+	}
+
+	k.Proof, err = unmarshalProof(raw.Proof)
+	if err != nil {
+		return err
+	}
+
+	k.Cert = raw.Cert
+	k.X5C = raw.X5C
+	k.DID = raw.DID
+	k.JWK = jwk.JWK{}
+
+	if raw.JWK != nil {
+		k.JWK = *raw.JWK
+	}
+
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (k *ClientKey) MarshalJSON() ([]byte, error) {
+	proof, err := json.Marshal(k.Proof)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling client key proof: %w", err)
+	}
+
+	raw := &rawClientKey{
+		Proof: proof,
+		Cert:  k.Cert,
+		X5C:   k.X5C,
+		DID:   k.DID,
+	}
+
+	// a key bound to a certificate or identified by a DID doesn't need to carry a jwk
+	if k.JWK.Key != nil || (k.Cert == "" && len(k.X5C) == 0 && k.DID == "") {
+		raw.JWK = &k.JWK
 	}
 
 	return json.Marshal(raw)
 }
 
+type rawProof struct {
+	Method string `json:"method"`
+}
+
+// unmarshalProof parses a key proof method, which is either a string or an object with a method.
+func unmarshalProof(data []byte) (string, error) {
+	if len(data) == 0 {
+		return "", nil
+	}
+
+	var method string
+
+	if json.Unmarshal(data, &method) == nil {
+		return method, nil
+	}
+
+	raw := &rawProof{}
+
+	err := json.Unmarshal(data, raw)
+	if err != nil {
+		return "", fmt.Errorf("parsing client key proof: expected either string or object: %w", err)
+	}
+
+	return raw.Method, nil
+}
+
 type rawTokenAccess struct {
-	Type string `json:"type"`
+	Type       string   `json:"type"`
+	Actions    []string `json:"actions,omitempty"`
+	Locations  []string `json:"locations,omitempty"`
+	Datatypes  []string `json:"datatypes,omitempty"`
+	Identifier string   `json:"identifier,omitempty"`
+	Privileges []string `json:"privileges,omitempty"`
+}
+
+// tokenAccessMembers are the members of an access descriptor that aren't extensions.
+var tokenAccessMembers = []string{ // nolint:gochecknoglobals
+	"type", "actions", "locations", "datatypes", "identifier", "privileges",
 }
 
 // UnmarshalJSON implements json.Unmarshaler.
@@ -178,10 +450,7 @@ func (t *TokenAccess) UnmarshalJSON(data []byte) error {
 
 	switch val := tok.(type) {
 	case string:
-		t.Ref = val
-		t.Type = ""
-		t.Raw = nil
-		t.IsReference = true
+		*t = TokenAccess{IsReference: true, Ref: val}
 
 		return nil
 	case json.Delim:
@@ -196,10 +465,30 @@ func (t *TokenAccess) UnmarshalJSON(data []byte) error {
 			return fmt.Errorf("parsing token access descriptor as object: %w", err)
 		}
 
-		t.IsReference = false
-		t.Ref = ""
-		t.Type = raw.Type
-		t.Raw = data
+		extensions := map[string]interface{}{}
+
+		err = json.Unmarshal(data, &extensions)
+		if err != nil {
+			return fmt.Errorf("parsing token access descriptor as object: %w", err)
+		}
+
+		for _, member := range tokenAccessMembers {
+			delete(extensions, member)
+		}
+
+		if len(extensions) == 0 {
+			extensions = nil
+		}
+
+		*t = TokenAccess{
+			Type:       raw.Type,
+			Actions:    raw.Actions,
+			Locations:  raw.Locations,
+			Datatypes:  raw.Datatypes,
+			Identifier: raw.Identifier,
+			Privileges: raw.Privileges,
+			Extensions: extensions,
+		}
 
 		return nil
 	}
@@ -213,5 +502,240 @@ func (t *TokenAccess) MarshalJSON() ([]byte, error) {
 		return json.Marshal(t.Ref)
 	}
 
-	return t.Raw, nil
+	descriptor := map[string]interface{}{}
+
+	for name, value := range t.Extensions {
+		descriptor[name] = value
+	}
+
+	members, err := json.Marshal(&rawTokenAccess{
+		Type:       t.Type,
+		Actions:    t.Actions,
+		Locations:  t.Locations,
+		Datatypes:  t.Datatypes,
+		Identifier: t.Identifier,
+		Privileges: t.Privileges,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshaling token access descriptor: %w", err)
+	}
+
+	err = json.Unmarshal(members, &descriptor)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling token access descriptor: %w", err)
+	}
+
+	return json.Marshal(descriptor)
+}
+
+type rawAuthResponse struct {
+	Continue    *ResponseContinue `json:"continue,omitempty"`
+	AccessToken json.RawMessage   `json:"access_token,omitempty"`
+	Interact    *ResponseInteract `json:"interact,omitempty"`
+	Subject     *Subject          `json:"subject,omitempty"`
+	InstanceID  string            `json:"instance_id,omitempty"`
+	Denied      *DeniedAccess     `json:"denied,omitempty"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Draft-09 servers send empty continue, interact and subject objects in
+// place of omitting them, which are parsed as absent.
+func (a *AuthResponse) UnmarshalJSON(data []byte) error {
+	raw := &rawAuthResponse{}
+
+	err := json.Unmarshal(data, raw)
+	if err != nil {
+		return fmt.Errorf("parsing response: %w", err)
+	}
+
+	*a = AuthResponse{
+		Continue:   raw.Continue,
+		Interact:   raw.Interact,
+		Subject:    raw.Subject,
+		InstanceID: raw.InstanceID,
+		Denied:     raw.Denied,
+	}
+
+	if a.Continue != nil && a.Continue.URI == "" {
+		a.Continue = nil
+	}
+
+	if a.Interact != nil && *a.Interact == (ResponseInteract{}) {
+		a.Interact = nil
+	}
+
+	if a.Subject != nil && len(a.Subject.SubIDs) == 0 && len(a.Subject.Assertions) == 0 && a.Subject.UpdatedAt == "" {
+		a.Subject = nil
+	}
+
+	a.AccessToken, err = unmarshalAccessTokens(raw.AccessToken)
+	if err != nil {
+		return fmt.Errorf("parsing response.access_token: %w", err)
+	}
+
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (a *AuthResponse) MarshalJSON() ([]byte, error) {
+	var (
+		rawTok json.RawMessage
+		err    error
+	)
+
+	if len(a.AccessToken) != 0 {
+		rawTok, err = json.Marshal(a.AccessToken)
+		if err != nil {
+			return nil, fmt.Errorf("marshaling access_token: %w", err)
+		}
+	}
+
+	return json.Marshal(&rawAuthResponse{
+		Continue:    a.Continue,
+		AccessToken: rawTok,
+		Interact:    a.Interact,
+		Subject:     a.Subject,
+		InstanceID:  a.InstanceID,
+		Denied:      a.Denied,
+	})
+}
+
+func unmarshalAccessTokens(data []byte) ([]AccessToken, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	tok, err := dec.Token()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		token := AccessToken{}
+
+		err = json.Unmarshal(data, &token)
+		if err != nil {
+			return nil, fmt.Errorf("parsing as object: %w", err)
+		}
+
+		return []AccessToken{token}, nil
+	case json.Delim('['):
+		var tokens []AccessToken
+
+		err = json.Unmarshal(data, &tokens)
+		if err != nil {
+			return nil, fmt.Errorf("parsing as array: %w", err)
+		}
+
+		return tokens, nil
+	}
+
+	return nil, fmt.Errorf("expected to be either an object or array")
+}
+
+type rawTokenManagement TokenManagement
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (m *TokenManagement) UnmarshalJSON(data []byte) error {
+	var uri string
+
+	if json.Unmarshal(data, &uri) == nil {
+		*m = TokenManagement{URI: uri}
+
+		return nil
+	}
+
+	raw := rawTokenManagement{}
+
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return fmt.Errorf("parsing access token management: %w", err)
+	}
+
+	*m = TokenManagement(raw)
+
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (k *TokenKey) UnmarshalJSON(data []byte) error {
+	var ref string
+
+	if json.Unmarshal(data, &ref) == nil {
+		*k = TokenKey{IsReference: true, Ref: ref}
+
+		return nil
+	}
+
+	key := &ClientKey{}
+
+	err := json.Unmarshal(data, key)
+	if err != nil {
+		return fmt.Errorf("parsing access token key: %w", err)
+	}
+
+	*k = TokenKey{Key: key}
+
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (k *TokenKey) MarshalJSON() ([]byte, error) {
+	if k.IsReference {
+		return json.Marshal(k.Ref)
+	}
+
+	return json.Marshal(k.Key)
+}
+
+type rawError struct {
+	Code        string `json:"code"`
+	Description string `json:"description,omitempty"`
+}
+
+type rawErrorResponse struct {
+	Error       json.RawMessage `json:"error"`
+	Description string          `json:"error_description,omitempty"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (e *ErrorResponse) UnmarshalJSON(data []byte) error {
+	raw := &rawErrorResponse{}
+
+	err := json.Unmarshal(data, raw)
+	if err != nil {
+		return fmt.Errorf("parsing error response: %w", err)
+	}
+
+	var code string
+
+	if json.Unmarshal(raw.Error, &code) == nil {
+		*e = ErrorResponse{Error: code, Description: raw.Description}
+
+		return nil
+	}
+
+	rawErr := &rawError{}
+
+	err = json.Unmarshal(raw.Error, rawErr)
+	if err != nil {
+		return fmt.Errorf("parsing error response: expected error to be either string or object: %w", err)
+	}
+
+	*e = ErrorResponse{Error: rawErr.Code, Description: rawErr.Description}
+
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (e *ErrorResponse) MarshalJSON() ([]byte, error) {
+	rawErr, err := json.Marshal(&rawError{
+		Code:        e.Error,
+		Description: e.Description,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshaling error: %w", err)
+	}
+
+	return json.Marshal(&rawErrorResponse{Error: rawErr})
 }
@@ -92,6 +92,91 @@ func TestAuthRequest_MarshalUnmarshal(t *testing.T) {
 			"nonce": "baz"
 		}
 	}
+}`,
+		},
+		{
+			name: "client key bound to a certificate",
+			src: `
+{
+	"client": {
+		"key": {
+			"proof": "mtls",
+			"cert": "MIIBLzCB1qADAgECAgEBMAoGCCqGSM49BAMCMBExDzANBgNVBAMTBmNsaWVudA=="
+		}
+	}
+}`,
+		},
+		{
+			name: "client key bound to a certificate chain",
+			src: `
+{
+	"client": {
+		"key": {
+			"proof": "httpsig",
+			"x5c": [
+				"MIIBLzCB1qADAgECAgEBMAoGCCqGSM49BAMCMBExDzANBgNVBAMTBmNsaWVudA==",
+				"MIIBKzCB0qADAgECAgEBMAoGCCqGSM49BAMCMA8xDTALBgNVBAMTBHJvb3Q="
+			]
+		}
+	}
+}`,
+		},
+		{
+			name: "client key identified by a did",
+			src: `
+{
+	"client": {
+		"key": {
+			"proof": "httpsig",
+			"did": "did:key:z6MkhaXgBZDvotDkL5257faiztiGiC2QtKLGpbnnEGta2doK"
+		}
+	}
+}`,
+		},
+		{
+			name: "subject, user and client display",
+			src: `
+{
+	"subject": {
+		"sub_id_formats": ["opaque", "iss_sub"],
+		"assertion_formats": ["id_token"],
+		"sub_ids": [{"format": "opaque", "id": "foo"}]
+	},
+	"client": {
+		"key": {
+			"proof": "httpsig",
+			"did": "did:key:z6MkhaXgBZDvotDkL5257faiztiGiC2QtKLGpbnnEGta2doK"
+		},
+		"class_id": "wallet",
+		"display": {
+			"name": "Wallet",
+			"uri": "https://wallet.example.com",
+			"logo_uri": "https://wallet.example.com/logo.png"
+		}
+	},
+	"user": {
+		"sub_ids": [{"format": "opaque", "id": "foo"}],
+		"assertions": [{"format": "id_token", "value": "eyJ..."}]
+	},
+	"interact": {
+		"start": ["redirect"],
+		"finish": {
+			"method": "redirect",
+			"uri": "https://wallet.example.com/finish",
+			"nonce": "foo",
+			"hash_method": "sha-256"
+		},
+		"hints": {
+			"ui_locales": ["en-US", "fr"]
+		}
+	}
+}`,
+		},
+		{
+			name: "user reference",
+			src: `
+{
+	"user": "foo-reference"
 }`,
 		},
 	}
@@ -118,6 +203,147 @@ func TestAuthRequest_MarshalUnmarshal(t *testing.T) {
 	}
 }
 
+func TestAuthRequest_UnmarshalDraft09(t *testing.T) {
+	t.Run("subject formats", func(t *testing.T) {
+		req := &AuthRequest{}
+
+		err := json.Unmarshal([]byte(`{"subject":{"sub_ids":["opaque"],"assertions":["id_token"]}}`), req)
+		require.NoError(t, err)
+
+		require.Equal(t, &RequestSubject{
+			SubIDFormats:     []string{"opaque"},
+			AssertionFormats: []string{"id_token"},
+		}, req.Subject)
+	})
+
+	t.Run("finish hash method", func(t *testing.T) {
+		for src, method := range map[string]string{
+			`{"interact":{"finish":{"method":"redirect"}}}`:                                         "",
+			`{"interact":{"finish":{"method":"redirect","hash_method":"sha-256"}}}`:                 "sha-256",
+			`{"interact":{"finish":{"method":"redirect","hash_method":"sha3"}}}`:                    "sha3-512",
+			`{"interact":{"finish":{"method":"redirect","hash_method":"sha2"}}}`:                    "sha-512",
+			`{"subject":{"sub_ids":["opaque"]},"interact":{"finish":{"method":"redirect"}}}`:        "sha3-512",
+			`{"subject":{"assertions":["id_token"]},"interact":{"finish":{"method":"redirect"}}}`:   "sha3-512",
+			`{"subject":{"sub_id_formats":["opaque"]},"interact":{"finish":{"method":"redirect"}}}`: "",
+		} {
+			req := &AuthRequest{}
+
+			require.NoError(t, json.Unmarshal([]byte(src), req))
+			require.Equal(t, method, req.Interact.Finish.HashMethod, src)
+		}
+	})
+
+	t.Run("proof method", func(t *testing.T) {
+		for _, src := range []string{
+			`{"client":{"key":{"proof":"httpsig"}}}`,
+			`{"client":{"key":{"proof":{"method":"httpsig","alg":"ecdsa-p256-sha256"}}}}`,
+		} {
+			req := &AuthRequest{}
+
+			require.NoError(t, json.Unmarshal([]byte(src), req))
+			require.Equal(t, "httpsig", req.Client.Key.Proof)
+		}
+	})
+}
+
+func TestAuthResponse_MarshalUnmarshal(t *testing.T) {
+	t.Run("absent members are omitted", func(t *testing.T) {
+		out, err := json.Marshal(&AuthResponse{InstanceID: "foo"})
+		require.NoError(t, err)
+		require.JSONEq(t, `{"instance_id":"foo"}`, string(out))
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		src := `
+{
+	"continue": {
+		"uri": "https://as.example.com/continue",
+		"access_token": {"value": "foo"},
+		"wait": 5
+	},
+	"access_token": [{
+		"value": "bar",
+		"manage": {
+			"uri": "https://as.example.com/token/bar",
+			"access_token": {"value": "baz"}
+		},
+		"access": ["foo"],
+		"expires_in": 3600,
+		"key": "key-reference"
+	}],
+	"interact": {
+		"redirect": "https://as.example.com/interact",
+		"app": "https://app.example.com/interact?ref=quux",
+		"finish": "qux",
+		"user_code": "BCDF-GHJK",
+		"user_code_uri": {"code": "LMNP-QRST", "uri": "https://as.example.com/device"},
+		"expires_in": 600
+	},
+	"subject": {
+		"sub_ids": [{"format": "opaque", "id": "foo"}],
+		"updated_at": "2023-01-01T00:00:00Z"
+	},
+	"instance_id": "foo",
+	"denied": {
+		"access_token": [{"access": ["baz"], "label": "baz"}],
+		"subject_keys": ["email"]
+	}
+}`
+
+		resp := &AuthResponse{}
+
+		require.NoError(t, json.Unmarshal([]byte(src), resp))
+		require.True(t, resp.AccessToken[0].Key.IsReference)
+
+		out, err := json.Marshal(resp)
+		require.NoError(t, err)
+		require.JSONEq(t, src, string(out))
+	})
+
+	t.Run("single access token with bound key", func(t *testing.T) {
+		resp := &AuthResponse{}
+
+		err := json.Unmarshal([]byte(`{"access_token":{"value":"foo","key":{"proof":"httpsig","did":"did:key:z6Mk"}}}`), resp)
+		require.NoError(t, err)
+		require.Len(t, resp.AccessToken, 1)
+		require.Equal(t, "httpsig", resp.AccessToken[0].Key.Key.Proof)
+	})
+
+	t.Run("draft-09 response", func(t *testing.T) {
+		src := `
+{
+	"continue": {"access_token": {}},
+	"access_token": [{"value": "foo", "manage": "https://as.example.com/token/foo"}],
+	"interact": {},
+	"subject": {}
+}`
+
+		resp := &AuthResponse{}
+
+		require.NoError(t, json.Unmarshal([]byte(src), resp))
+		require.Nil(t, resp.Continue)
+		require.Nil(t, resp.Interact)
+		require.Nil(t, resp.Subject)
+		require.Equal(t, "https://as.example.com/token/foo", resp.AccessToken[0].Manage.URI)
+	})
+}
+
+func TestErrorResponse_MarshalUnmarshal(t *testing.T) {
+	out, err := json.Marshal(&ErrorResponse{Error: "request_denied", Description: "foo"})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"error":{"code":"request_denied","description":"foo"}}`, string(out))
+
+	for _, src := range []string{
+		string(out),
+		`{"error":"request_denied","error_description":"foo"}`,
+	} {
+		resp := &ErrorResponse{}
+
+		require.NoError(t, json.Unmarshal([]byte(src), resp))
+		require.Equal(t, &ErrorResponse{Error: "request_denied", Description: "foo"}, resp)
+	}
+}
+
 func Test_ParseErrors(t *testing.T) {
 	t.Run("fail to parse request", func(t *testing.T) {
 		src := []byte(`"foo"`)
@@ -210,4 +436,71 @@ func Test_ParseErrors(t *testing.T) {
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "parsing token access descriptor as object")
 	})
+
+	t.Run("user type must be string or object", func(t *testing.T) {
+		req := &AuthRequest{}
+
+		err := json.Unmarshal([]byte(`{"user":123}`), req)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "request.user expected either string or object")
+
+		err = json.Unmarshal([]byte(`{"user":{"sub_ids":"foo"}}`), req)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "parsing request.user as object")
+	})
+
+	t.Run("fail to parse subject", func(t *testing.T) {
+		req := &AuthRequest{}
+
+		err := json.Unmarshal([]byte(`{"subject":{"sub_ids":[123]}}`), req)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "parsing request.subject.sub_ids")
+
+		err = json.Unmarshal([]byte(`{"subject":[]}`), req)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "parsing request.subject")
+	})
+
+	t.Run("proof must be string or object", func(t *testing.T) {
+		req := &AuthRequest{}
+
+		err := json.Unmarshal([]byte(`{"client":{"key":{"proof":123}}}`), req)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "client key proof")
+	})
+
+	t.Run("fail to parse response", func(t *testing.T) {
+		tests := []struct {
+			name string
+			src  string
+			err  string
+		}{
+			{name: "not an object", src: `"foo"`, err: "parsing response"},
+			{name: "access_token string", src: `{"access_token":"foo"}`, err: "either an object or array"},
+			{name: "access_token object", src: `{"access_token":{"value":1}}`, err: "parsing as object"},
+			{name: "access_token array", src: `{"access_token":[1]}`, err: "parsing as array"},
+			{name: "token management", src: `{"access_token":{"manage":1}}`, err: "access token management"},
+			{name: "token key", src: `{"access_token":{"key":1}}`, err: "access token key"},
+		}
+
+		for _, tt := range tests {
+			tc := tt
+
+			t.Run(tc.name, func(t *testing.T) {
+				err := json.Unmarshal([]byte(tc.src), &AuthResponse{})
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.err)
+			})
+		}
+	})
+
+	t.Run("fail to parse error response", func(t *testing.T) {
+		err := json.Unmarshal([]byte(`"foo"`), &ErrorResponse{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "parsing error response")
+
+		err = json.Unmarshal([]byte(`{"error":123}`), &ErrorResponse{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "either string or object")
+	})
 }
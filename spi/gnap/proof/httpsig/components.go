@@ -0,0 +1,64 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package httpsig
+
+import (
+	"strings"
+
+	"github.com/yaronf/httpsign"
+)
+
+const (
+	componentMethod        = "@method"
+	componentTargetURI     = "@target-uri"
+	componentAuthorization = "authorization"
+	componentContentDigest = "content-digest"
+)
+
+// coveredComponents builds the set of components covered by a GNAP request signature: the method and target uri,
+// the authorization and content-digest headers if the request has them, and the given extra components.
+//
+// Extra components are derived components if they start with '@', and header names otherwise. If requireExtra is
+// set, extra headers must be present and covered, as when verifying a required components policy. Otherwise they are
+// only covered if the request has them, so a signer configured with e.g. content-type can still sign GET requests.
+func coveredComponents(hasAuthorization, hasBody bool, extra []string, requireExtra bool) httpsign.Fields {
+	fields := httpsign.Headers(componentMethod, componentTargetURI)
+
+	seen := map[string]bool{
+		componentMethod:    true,
+		componentTargetURI: true,
+	}
+
+	if hasAuthorization {
+		fields.AddHeader(componentAuthorization)
+
+		seen[componentAuthorization] = true
+	}
+
+	if hasBody {
+		fields.AddHeader(componentContentDigest)
+
+		seen[componentContentDigest] = true
+	}
+
+	for _, c := range extra {
+		name := strings.ToLower(strings.TrimSpace(c))
+		if name == "" || seen[name] {
+			continue
+		}
+
+		seen[name] = true
+
+		if strings.HasPrefix(name, "@") || requireExtra {
+			fields.AddHeader(name)
+		} else {
+			fields.AddHeaderExt(name, true, false)
+		}
+	}
+
+	return fields
+}
@@ -0,0 +1,123 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package httpsig
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+
+	"github.com/trustbloc/auth/spi/gnap/internal/digest"
+	"github.com/trustbloc/auth/spi/gnap/internal/jwksignature"
+)
+
+// KMSCrypto signs messages using KMS key handles. It is implemented by the aries crypto.Crypto.
+type KMSCrypto interface {
+	Sign(msg []byte, kh interface{}) ([]byte, error)
+}
+
+// KeySigner signs GNAP http requests using http-signature, with a private key that isn't held in memory, such as
+// a key in an aries KMS or a PKCS#11 token.
+type KeySigner struct {
+	// PublicKey is the client's public key, which determines the key id and signature algorithm.
+	PublicKey *jwk.JWK
+	// Label is the label of the signature in the Signature and Signature-Input headers. Defaults to "sig1".
+	Label string
+	// Components are covered components to sign in addition to the method, target uri, authorization and
+	// content-digest, e.g. "@authority" or "content-type".
+	Components []string
+
+	signMessage func(msg []byte) ([]byte, error)
+}
+
+// NewCryptoSigner creates a KeySigner that signs requests using the given crypto.Signer, whose public key is
+// given as a JWK.
+func NewCryptoSigner(signer crypto.Signer, publicKey *jwk.JWK) *KeySigner {
+	return &KeySigner{
+		PublicKey: publicKey,
+		signMessage: func(msg []byte) ([]byte, error) {
+			return jwksignature.SignWithSigner(msg, signer, publicKey)
+		},
+	}
+}
+
+// NewKMSSigner creates a KeySigner that signs requests using the given KMS key handle, whose public key is given as
+// a JWK.
+func NewKMSSigner(kmsCrypto KMSCrypto, kh interface{}, publicKey *jwk.JWK) *KeySigner {
+	return &KeySigner{
+		PublicKey: publicKey,
+		signMessage: func(msg []byte) ([]byte, error) {
+			sig, err := kmsCrypto.Sign(msg, kh)
+			if err != nil {
+				return nil, fmt.Errorf("signing with kms: %w", err)
+			}
+
+			alg, err := jwksignature.Algorithm(publicKey)
+			if err != nil {
+				return nil, err
+			}
+
+			// KMSs usually produce DER-encoded ecdsa signatures, while http-signature uses the JWS encoding.
+			if strings.HasPrefix(alg, "ES") {
+				return jwksignature.RawECDSASignature(sig, publicKey)
+			}
+
+			return sig, nil
+		},
+	}
+}
+
+// ProofType returns "httpsig", the GNAP proof type of the http-signature proof method.
+func (s *KeySigner) ProofType() string {
+	return "httpsig"
+}
+
+// Sign signs the given request using sha-256 for a content digest, and http-signature to sign headers.
+func (s *KeySigner) Sign(request *http.Request, requestBody []byte) (*http.Request, error) {
+	if s.PublicKey == nil || s.signMessage == nil {
+		return nil, errors.New("creating signer: key signer must be created with NewCryptoSigner or NewKMSSigner")
+	}
+
+	if _, err := jwksignature.Algorithm(s.PublicKey); err != nil {
+		return nil, fmt.Errorf("creating signer: %w", err)
+	}
+
+	label := s.Label
+	if label == "" {
+		label = defaultSignatureName
+	}
+
+	// httpsign only signs with in-memory keys or crypto.Signers, which sign a digest rather than the message,
+	// except for EdDSA which passes the whole signature base to the crypto.Signer. So the EdDSA signer is used for
+	// all algorithms, with a crypto.Signer that signs the signature base using the key's actual algorithm. The
+	// algorithm isn't part of the signature input, so this doesn't change the signature.
+	return sign(request, requestBody, &requestKey{
+		alg:   jwa.EdDSA,
+		keyID: s.PublicKey.KeyID,
+		key:   &messageSigner{publicKey: s.PublicKey.Key, sign: s.signMessage},
+	}, digest.SHA256, label, s.Components)
+}
+
+// messageSigner is a crypto.Signer that signs whole messages.
+type messageSigner struct {
+	publicKey crypto.PublicKey
+	sign      func(msg []byte) ([]byte, error)
+}
+
+func (m *messageSigner) Public() crypto.PublicKey {
+	return m.publicKey
+}
+
+func (m *messageSigner) Sign(_ io.Reader, msg []byte, _ crypto.SignerOpts) ([]byte, error) {
+	return m.sign(msg)
+}
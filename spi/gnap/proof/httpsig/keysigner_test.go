@@ -0,0 +1,121 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package httpsig
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/auth/spi/gnap"
+)
+
+func TestKeySigner(t *testing.T) {
+	body := []byte("foo bar baz")
+
+	t.Run("crypto signer", func(t *testing.T) {
+		for _, alg := range []string{"ES256", "ES384", "EdDSA", "PS256"} {
+			priv, pub := jwkPair(t, alg, curve(alg))
+
+			signer := NewCryptoSigner(priv.Key.(crypto.Signer), &pub)
+			require.Equal(t, "httpsig", signer.ProofType())
+
+			req := httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", bytes.NewReader(body))
+			req.Header.Add("Authorization", "GNAP foo")
+
+			req, err := signer.Sign(req, body)
+			require.NoError(t, err, alg)
+
+			require.NoError(t, NewVerifier(req).Verify(&gnap.ClientKey{JWK: pub}), alg)
+		}
+	})
+
+	t.Run("kms signer", func(t *testing.T) {
+		priv, pub := jwkPairECDSA(t, "ES256", elliptic.P256())
+
+		signer := NewKMSSigner(&mockKMSCrypto{}, priv.Key, &pub)
+		signer.Label = "client"
+		signer.Components = []string{"@authority"}
+
+		req := httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", bytes.NewReader(body))
+
+		req, err := signer.Sign(req, body)
+		require.NoError(t, err)
+
+		err = NewVerifier(req).WithLabel("client").WithRequiredComponents("@authority").Verify(&gnap.ClientKey{JWK: pub})
+		require.NoError(t, err)
+	})
+
+	t.Run("kms signing error", func(t *testing.T) {
+		_, pub := jwkPairECDSA(t, "ES256", elliptic.P256())
+
+		expectErr := errors.New("expected error")
+
+		signer := NewKMSSigner(&mockKMSCrypto{err: expectErr}, nil, &pub)
+
+		req := httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", bytes.NewReader(body))
+
+		_, err := signer.Sign(req, body)
+		require.ErrorIs(t, err, expectErr)
+	})
+
+	t.Run("unsupported public key", func(t *testing.T) {
+		signer := NewCryptoSigner(nil, &jwk.JWK{})
+
+		req := httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", bytes.NewReader(body))
+
+		_, err := signer.Sign(req, body)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "creating signer")
+	})
+
+	t.Run("not created by constructor", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", bytes.NewReader(body))
+
+		_, err := (&KeySigner{}).Sign(req, body)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "must be created with")
+	})
+}
+
+func curve(alg string) elliptic.Curve {
+	if alg == "ES384" {
+		return elliptic.P384()
+	}
+
+	return elliptic.P256()
+}
+
+// mockKMSCrypto signs like an aries crypto.Crypto with an ecdsa key handle, producing DER-encoded signatures.
+type mockKMSCrypto struct {
+	err error
+}
+
+func (m *mockKMSCrypto) Sign(msg []byte, kh interface{}) ([]byte, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	key, ok := kh.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("invalid key handle")
+	}
+
+	digest := sha256.Sum256(msg)
+
+	return ecdsa.SignASN1(rand.Reader, key, digest[:])
+}
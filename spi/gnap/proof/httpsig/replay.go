@@ -0,0 +1,259 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package httpsig
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+)
+
+const (
+	replayStoreName  = "gnap_httpsig_replay"
+	replayExpiresTag = "expires"
+
+	// replayBucketSize is the granularity of the expiry tag of replay cache entries. Entries are tagged with the end
+	// of the bucket that their expiry falls in, so a purge queries the buckets that ended since the last purge.
+	replayBucketSize = time.Minute
+
+	// DefaultReplayPurgeInterval is how often a ReplayCache deletes its expired entries, by default.
+	DefaultReplayPurgeInterval = time.Minute
+)
+
+// ErrReplayedSignature is returned when a request signature was already seen by the ReplayCache.
+var ErrReplayedSignature = errors.New("replayed signature")
+
+// ReplayCache records the request signatures seen by Verifiers, so signed requests can't be replayed.
+//
+// Entries are kept until their signature would be rejected as stale anyway, and then purged. The cache is backed by
+// an aries storage.Provider, so a shared database lets replicas detect replays made against each other. Entries are
+// inserted with the IsNewKey put option, so that stores which enforce it (such as MongoDB) reject a signature that
+// two replicas try to record at the same time. Within a replica, checks are serialized.
+type ReplayCache struct {
+	store         storage.Store
+	mu            sync.Mutex
+	now           func() time.Time
+	purgeInterval time.Duration
+	nextPurge     time.Time
+	purgedUntil   time.Time
+}
+
+type replayEntry struct {
+	Expires time.Time `json:"expires"`
+}
+
+// NewReplayCache creates a ReplayCache that stores seen signatures in the given storage provider. Entries left
+// expired by a previous run are deleted.
+func NewReplayCache(provider storage.Provider) (*ReplayCache, error) {
+	store, err := provider.OpenStore(replayStoreName)
+	if err != nil {
+		return nil, fmt.Errorf("opening replay cache store: %w", err)
+	}
+
+	err = provider.SetStoreConfig(replayStoreName, storage.StoreConfiguration{TagNames: []string{replayExpiresTag}})
+	if err != nil {
+		return nil, fmt.Errorf("configuring replay cache store: %w", err)
+	}
+
+	c := &ReplayCache{
+		store:         store,
+		now:           time.Now,
+		purgeInterval: DefaultReplayPurgeInterval,
+	}
+
+	c.purgedUntil = c.now().Truncate(replayBucketSize)
+	c.nextPurge = c.now().Add(c.purgeInterval)
+
+	// only the first purge scans every entry, as the buckets of earlier runs are unknown.
+	err = c.deleteExpired(replayExpiresTag, c.purgedUntil)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Check records the given signature ID as seen until the given expiry time. It returns ErrReplayedSignature if the
+// signature was already seen, and hasn't expired yet.
+func (c *ReplayCache) Check(sigID string, expires time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	err := c.purgeExpired()
+	if err != nil {
+		return err
+	}
+
+	key := replayKey(sigID)
+
+	data, err := c.store.Get(key)
+
+	switch {
+	case err == nil:
+		err = c.replaceStale(key, data)
+		if err != nil {
+			return err
+		}
+	case !errors.Is(err, storage.ErrDataNotFound):
+		return fmt.Errorf("reading replay cache: %w", err)
+	}
+
+	data, err = json.Marshal(&replayEntry{Expires: expires})
+	if err != nil {
+		return fmt.Errorf("marshaling replay cache entry: %w", err)
+	}
+
+	err = c.store.Batch([]storage.Operation{{
+		Key:        key,
+		Value:      data,
+		Tags:       []storage.Tag{{Name: replayExpiresTag, Value: expiryBucket(expires)}},
+		PutOptions: &storage.PutOptions{IsNewKey: true},
+	}})
+	if errors.Is(err, storage.ErrDuplicateKey) {
+		return ErrReplayedSignature
+	}
+
+	if err != nil {
+		return fmt.Errorf("saving replay cache entry: %w", err)
+	}
+
+	return nil
+}
+
+// replaceStale returns ErrReplayedSignature if the given entry hasn't expired. Otherwise, the signature is stale
+// and the entry is deleted, so the signature ID can be recorded again.
+func (c *ReplayCache) replaceStale(key string, data []byte) error {
+	entry := &replayEntry{}
+
+	err := json.Unmarshal(data, entry)
+	if err == nil && !entry.Expires.Before(c.now()) {
+		return ErrReplayedSignature
+	}
+
+	err = c.store.Delete(key)
+	if err != nil {
+		return fmt.Errorf("deleting stale replay cache entry: %w", err)
+	}
+
+	return nil
+}
+
+// purgeExpired deletes the entries whose expiry bucket ended since the last purge, at most once per purge interval.
+func (c *ReplayCache) purgeExpired() error {
+	now := c.now()
+
+	if now.Before(c.nextPurge) {
+		return nil
+	}
+
+	c.nextPurge = now.Add(c.purgeInterval)
+
+	for bucket := c.purgedUntil.Add(replayBucketSize); !bucket.After(now); bucket = bucket.Add(replayBucketSize) {
+		err := c.deleteExpired(replayExpiresTag+":"+strconv.FormatInt(bucket.Unix(), 10), bucket)
+		if err != nil {
+			return err
+		}
+
+		c.purgedUntil = bucket
+	}
+
+	return nil
+}
+
+// deleteExpired deletes the entries matching the given query whose expiry bucket ended by the given time.
+func (c *ReplayCache) deleteExpired(query string, until time.Time) error {
+	expired, err := c.expiredKeys(query, until)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range expired {
+		err = c.store.Delete(key)
+		if err != nil {
+			return fmt.Errorf("deleting expired replay cache entry: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// expiredKeys lists the keys of the entries matching the given query whose expiry bucket ended by the given time.
+func (c *ReplayCache) expiredKeys(query string, until time.Time) ([]string, error) {
+	iter, err := c.store.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("querying replay cache: %w", err)
+	}
+
+	defer iter.Close() // nolint:errcheck // nothing to do on failure
+
+	var expired []string
+
+	for {
+		more, err := iter.Next()
+		if err != nil {
+			return nil, fmt.Errorf("iterating replay cache: %w", err)
+		}
+
+		if !more {
+			return expired, nil
+		}
+
+		tags, err := iter.Tags()
+		if err != nil {
+			return nil, fmt.Errorf("reading replay cache tags: %w", err)
+		}
+
+		if !entryExpired(tags, until) {
+			continue
+		}
+
+		key, err := iter.Key()
+		if err != nil {
+			return nil, fmt.Errorf("reading replay cache key: %w", err)
+		}
+
+		expired = append(expired, key)
+	}
+}
+
+func entryExpired(tags []storage.Tag, until time.Time) bool {
+	for _, tag := range tags {
+		if tag.Name != replayExpiresTag {
+			continue
+		}
+
+		bucket, err := strconv.ParseInt(tag.Value, 10, 64)
+
+		return err != nil || bucket <= until.Unix()
+	}
+
+	return false
+}
+
+// expiryBucket returns the end of the bucket that the given expiry falls in, as a tag value.
+func expiryBucket(expires time.Time) string {
+	bucket := expires.Truncate(replayBucketSize)
+	if bucket.Before(expires) {
+		bucket = bucket.Add(replayBucketSize)
+	}
+
+	return strconv.FormatInt(bucket.Unix(), 10)
+}
+
+// replayKey hashes the signature ID, to bound the length of store keys.
+func replayKey(sigID string) string {
+	h := sha256.Sum256([]byte(sigID))
+
+	return base64.RawURLEncoding.EncodeToString(h[:])
+}
@@ -0,0 +1,304 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package httpsig
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk"
+	mockstorage "github.com/hyperledger/aries-framework-go/pkg/mock/storage"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/stretchr/testify/require"
+	"github.com/yaronf/httpsign"
+
+	"github.com/trustbloc/auth/spi/gnap"
+)
+
+func TestReplayCache(t *testing.T) {
+	t.Run("fail to open store", func(t *testing.T) {
+		_, err := NewReplayCache(&mockstorage.MockStoreProvider{ErrOpenStoreHandle: errors.New("expected error")})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "opening replay cache store")
+	})
+
+	t.Run("detects replays until expiry", func(t *testing.T) {
+		cache, err := NewReplayCache(mockstorage.NewMockStoreProvider())
+		require.NoError(t, err)
+
+		now := time.Now()
+
+		cache.now = func() time.Time { return now }
+
+		require.NoError(t, cache.Check("foo", now.Add(time.Minute)))
+		require.ErrorIs(t, cache.Check("foo", now.Add(time.Minute)), ErrReplayedSignature)
+		require.NoError(t, cache.Check("bar", now.Add(time.Minute)))
+
+		now = now.Add(2 * time.Minute)
+
+		require.NoError(t, cache.Check("foo", now.Add(time.Minute)))
+	})
+
+	t.Run("fail to configure store", func(t *testing.T) {
+		_, err := NewReplayCache(&mockstorage.MockStoreProvider{
+			Store:             &mockstorage.MockStore{Store: map[string]mockstorage.DBEntry{}},
+			ErrSetStoreConfig: errors.New("expected error"),
+		})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "configuring replay cache store")
+	})
+
+	t.Run("purges expired entries", func(t *testing.T) {
+		store := &mockstorage.MockStore{Store: map[string]mockstorage.DBEntry{}}
+
+		cache, err := NewReplayCache(mockstorage.NewCustomMockStoreProvider(store))
+		require.NoError(t, err)
+
+		now := time.Now()
+
+		cache.now = func() time.Time { return now }
+
+		require.NoError(t, cache.Check("foo", now.Add(time.Minute)))
+		require.NoError(t, cache.Check("bar", now.Add(3*time.Minute)))
+		require.Len(t, store.Store, 2)
+
+		now = now.Add(2 * time.Minute)
+
+		require.NoError(t, cache.Check("baz", now.Add(time.Minute)))
+		require.Len(t, store.Store, 2)
+		require.Contains(t, store.Store, replayKey("bar"))
+	})
+
+	t.Run("concurrent checks accept a signature once", func(t *testing.T) {
+		cache, err := NewReplayCache(mockstorage.NewMockStoreProvider())
+		require.NoError(t, err)
+
+		expires := time.Now().Add(time.Minute)
+		accepted := make(chan bool)
+
+		for i := 0; i < 10; i++ {
+			go func() {
+				accepted <- cache.Check("foo", expires) == nil
+			}()
+		}
+
+		var count int
+
+		for i := 0; i < 10; i++ {
+			if <-accepted {
+				count++
+			}
+		}
+
+		require.Equal(t, 1, count)
+	})
+
+	t.Run("duplicate key on insert", func(t *testing.T) {
+		store := &mockstorage.MockStore{
+			Store:    map[string]mockstorage.DBEntry{},
+			ErrBatch: fmt.Errorf("batch: %w", storage.ErrDuplicateKey),
+		}
+
+		cache, err := NewReplayCache(mockstorage.NewCustomMockStoreProvider(store))
+		require.NoError(t, err)
+
+		require.ErrorIs(t, cache.Check("foo", time.Now()), ErrReplayedSignature)
+	})
+
+	t.Run("store errors", func(t *testing.T) {
+		store := &mockstorage.MockStore{Store: map[string]mockstorage.DBEntry{}}
+
+		cache, err := NewReplayCache(mockstorage.NewCustomMockStoreProvider(store))
+		require.NoError(t, err)
+
+		store.ErrGet = errors.New("get error")
+
+		err = cache.Check("foo", time.Now())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "reading replay cache")
+
+		store.ErrGet = nil
+		store.ErrBatch = errors.New("batch error")
+
+		err = cache.Check("foo", time.Now())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "saving replay cache entry")
+
+		store.ErrBatch = nil
+		store.ErrDelete = errors.New("delete error")
+		store.Store[replayKey("foo")] = mockstorage.DBEntry{Value: []byte("{}")}
+
+		err = cache.Check("foo", time.Now())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "deleting stale replay cache entry")
+
+		store.ErrDelete = nil
+		store.ErrQuery = errors.New("query error")
+		cache.purgeInterval = 0
+		cache.nextPurge = time.Time{}
+		cache.purgedUntil = cache.purgedUntil.Add(-replayBucketSize)
+
+		err = cache.Check("foo", time.Now())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "querying replay cache")
+
+		store.ErrQuery = nil
+		store.ErrDelete = errors.New("delete error")
+		store.Store["bar"] = mockstorage.DBEntry{Tags: []storage.Tag{{
+			Name:  replayExpiresTag,
+			Value: strconv.FormatInt(cache.purgedUntil.Add(replayBucketSize).Unix(), 10),
+		}}}
+
+		err = cache.Check("foo", time.Now())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "deleting expired replay cache entry")
+	})
+
+	t.Run("purges entries of previous runs", func(t *testing.T) {
+		store := &mockstorage.MockStore{Store: map[string]mockstorage.DBEntry{
+			"foo": {Tags: []storage.Tag{{Name: replayExpiresTag, Value: expiryBucket(time.Now().Add(-time.Hour))}}},
+			"bar": {Tags: []storage.Tag{{Name: replayExpiresTag, Value: expiryBucket(time.Now().Add(time.Hour))}}},
+			"baz": {Tags: []storage.Tag{{Name: replayExpiresTag, Value: "baz"}}},
+		}}
+
+		_, err := NewReplayCache(mockstorage.NewCustomMockStoreProvider(store))
+		require.NoError(t, err)
+		require.Len(t, store.Store, 1)
+		require.Contains(t, store.Store, "bar")
+	})
+
+	t.Run("fail to purge entries of previous runs", func(t *testing.T) {
+		newStore := func() *mockstorage.MockStore {
+			return &mockstorage.MockStore{Store: map[string]mockstorage.DBEntry{
+				"foo": {Tags: []storage.Tag{{Name: replayExpiresTag, Value: "foo"}}},
+			}}
+		}
+
+		store := newStore()
+		store.ErrQuery = errors.New("query error")
+
+		_, err := NewReplayCache(mockstorage.NewCustomMockStoreProvider(store))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "querying replay cache")
+
+		store = newStore()
+		store.ErrNext = errors.New("next error")
+
+		_, err = NewReplayCache(mockstorage.NewCustomMockStoreProvider(store))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "iterating replay cache")
+
+		store = newStore()
+		store.ErrKey = errors.New("key error")
+
+		_, err = NewReplayCache(mockstorage.NewCustomMockStoreProvider(store))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "reading replay cache key")
+	})
+}
+
+func TestVerify_Replay(t *testing.T) {
+	priv, pub := jwkPairECDSA(t, "ES256", elliptic.P256())
+
+	cache, err := NewReplayCache(mockstorage.NewMockStoreProvider())
+	require.NoError(t, err)
+
+	body := []byte("foo bar baz")
+
+	t.Run("replayed request", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", bytes.NewReader(body))
+
+		req, err := Sign(req, body, priv, "sha-256")
+		require.NoError(t, err)
+
+		require.NoError(t, NewVerifier(req).WithReplayCache(cache).Verify(&gnap.ClientKey{JWK: pub}))
+
+		err = NewVerifier(req).WithReplayCache(cache).Verify(&gnap.ClientKey{JWK: pub})
+		require.ErrorIs(t, err, ErrReplayedSignature)
+	})
+
+	t.Run("fresh signatures of the same request", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			req := httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", bytes.NewReader(body))
+
+			req, err := Sign(req, body, priv, "sha-256")
+			require.NoError(t, err)
+
+			require.NoError(t, NewVerifier(req).WithReplayCache(cache).Verify(&gnap.ClientKey{JWK: pub}))
+		}
+	})
+
+	t.Run("replayed request without nonce", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "http://foo.bar/baz", nil)
+
+		signer, err := httpsign.NewJWSSigner(jwa.ES256, priv.KeyID, priv.Key,
+			httpsign.NewSignConfig().SignAlg(false), httpsign.Headers("@method", "@target-uri"))
+		require.NoError(t, err)
+
+		sigInput, sig, err := httpsign.SignRequest(defaultSignatureName, *signer, req)
+		require.NoError(t, err)
+
+		req.Header.Add("Signature", sig)
+		req.Header.Add("Signature-Input", sigInput)
+
+		require.NoError(t, NewVerifier(req).WithReplayCache(cache).Verify(&gnap.ClientKey{JWK: pub}))
+
+		err = NewVerifier(req).WithReplayCache(cache).Verify(&gnap.ClientKey{JWK: pub})
+		require.ErrorIs(t, err, ErrReplayedSignature)
+	})
+	t.Run("nonce reused with different timestamps", func(t *testing.T) {
+		for i, expires := range []int64{time.Now().Add(time.Minute).Unix(), time.Now().Add(time.Hour).Unix()} {
+			req := signWithNonce(t, priv, "reused-nonce", expires)
+
+			err := NewVerifier(req).WithReplayCache(cache).Verify(&gnap.ClientKey{JWK: pub})
+			if i == 0 {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, ErrReplayedSignature)
+			}
+		}
+	})
+
+	t.Run("same nonce from different clients", func(t *testing.T) {
+		otherPriv, otherPub := jwkPairECDSA(t, "ES256", elliptic.P256())
+
+		expires := time.Now().Add(time.Minute).Unix()
+
+		req := signWithNonce(t, priv, "shared-nonce", expires)
+		require.NoError(t, NewVerifier(req).WithReplayCache(cache).Verify(&gnap.ClientKey{JWK: pub}))
+
+		req = signWithNonce(t, otherPriv, "shared-nonce", expires)
+		require.NoError(t, NewVerifier(req).WithReplayCache(cache).Verify(&gnap.ClientKey{JWK: otherPub}))
+	})
+}
+
+func signWithNonce(t *testing.T, priv *jwk.JWK, nonce string, expires int64) *http.Request {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, "http://foo.bar/baz", nil)
+
+	signer, err := httpsign.NewJWSSigner(jwa.ES256, priv.KeyID, priv.Key,
+		httpsign.NewSignConfig().SignAlg(false).SetNonce(nonce).SetExpires(expires),
+		httpsign.Headers("@method", "@target-uri"))
+	require.NoError(t, err)
+
+	sigInput, sig, err := httpsign.SignRequest(defaultSignatureName, *signer, req)
+	require.NoError(t, err)
+
+	req.Header.Add("Signature", sig)
+	req.Header.Add("Signature-Input", sigInput)
+
+	return req
+}
@@ -9,8 +9,10 @@ package httpsig
 import (
 	"bytes"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/rsa"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -46,6 +48,20 @@ func TestSignVerify(t *testing.T) {
 			alg:        "ES512",
 			digestName: "sha-512",
 		},
+		{
+			alg:        "EdDSA",
+			digestName: "sha-256",
+			body:       []byte("foo bar baz"),
+		},
+		{
+			alg:        "PS256",
+			digestName: "sha-256",
+			body:       []byte("foo bar baz"),
+		},
+		{
+			alg:        "PS512",
+			digestName: "sha-512",
+		},
 	}
 
 	for _, tt := range tests {
@@ -61,7 +77,7 @@ func TestSignVerify(t *testing.T) {
 
 			req.Header.Add("Authorization", "Bearer OPEN-SESAME")
 
-			privJWK, pubJWK := jwkPairECDSA(t, tc.alg, tc.crv)
+			privJWK, pubJWK := jwkPair(t, tc.alg, tc.crv)
 
 			req, err := Sign(req, tc.body, privJWK, tc.digestName)
 			require.NoError(t, err)
@@ -77,6 +93,97 @@ func TestSignVerify(t *testing.T) {
 	}
 }
 
+func TestSignVerify_InferredAlgorithm(t *testing.T) {
+	body := []byte("foo bar baz")
+
+	for _, a := range []string{"EdDSA", "PS512"} {
+		alg := a
+
+		t.Run(alg, func(t *testing.T) {
+			privJWK, pubJWK := jwkPair(t, alg, nil)
+
+			// client keys don't need to declare an alg
+			privJWK.Algorithm = ""
+			pubJWK.Algorithm = ""
+
+			req := httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", bytes.NewReader(body))
+
+			req, err := Sign(req, body, privJWK, "sha-256")
+			require.NoError(t, err)
+
+			require.NoError(t, NewVerifier(req).Verify(&gnap.ClientKey{
+				Proof: "httpsig",
+				JWK:   pubJWK,
+			}))
+		})
+	}
+}
+
+func jwkPair(t *testing.T, alg string, crv elliptic.Curve) (*jwk.JWK, jwk.JWK) {
+	t.Helper()
+
+	switch alg {
+	case "EdDSA":
+		return jwkPairEd25519(t)
+	case "PS256", "PS512":
+		return jwkPairRSA(t, alg)
+	default:
+		return jwkPairECDSA(t, alg, crv)
+	}
+}
+
+func jwkPairEd25519(t *testing.T) (*jwk.JWK, jwk.JWK) {
+	t.Helper()
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	privJWK := &jwk.JWK{
+		JSONWebKey: jose.JSONWebKey{
+			Key:       priv,
+			KeyID:     "key1",
+			Algorithm: "EdDSA",
+		},
+		Kty: "OKP",
+		Crv: "Ed25519",
+	}
+
+	pubJWK := jwk.JWK{
+		JSONWebKey: jose.JSONWebKey{
+			Key:       pub,
+			KeyID:     "key1",
+			Algorithm: "EdDSA",
+		},
+		Kty: "OKP",
+		Crv: "Ed25519",
+	}
+
+	return privJWK, pubJWK
+}
+
+func jwkPairRSA(t *testing.T, alg string) (*jwk.JWK, jwk.JWK) {
+	t.Helper()
+
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	privJWK := &jwk.JWK{
+		JSONWebKey: jose.JSONWebKey{
+			Key:       priv,
+			KeyID:     "key1",
+			Algorithm: alg,
+		},
+		Kty: "RSA",
+	}
+
+	pubJWK := jwk.JWK{
+		JSONWebKey: privJWK.Public(),
+		Kty:        "RSA",
+	}
+
+	return privJWK, pubJWK
+}
+
 func jwkPairECDSA(t *testing.T, alg string, crv elliptic.Curve) (*jwk.JWK, jwk.JWK) {
 	t.Helper()
 
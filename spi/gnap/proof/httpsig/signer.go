@@ -8,6 +8,8 @@ package httpsig
 
 import (
 	"bytes"
+	"crypto/rand"
+	"encoding/base64"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -17,14 +19,23 @@ import (
 	"github.com/yaronf/httpsign"
 
 	"github.com/trustbloc/auth/spi/gnap/internal/digest"
+	"github.com/trustbloc/auth/spi/gnap/internal/jwksignature"
 )
 
 // Signer signs GNAP http requests using http-signature.
 type Signer struct {
 	SigningKey *jwk.JWK
+	// Label is the label of the signature in the Signature and Signature-Input headers. Defaults to "sig1".
+	Label string
+	// Components are covered components to sign in addition to the method, target uri, authorization and
+	// content-digest, e.g. "@authority" or "content-type".
+	Components []string
 }
 
-const defaultSignatureName = "sig1"
+const (
+	defaultSignatureName = "sig1"
+	nonceSize            = 16
+)
 
 // ProofType returns "httpsig", the GNAP proof type of the http-signature proof method.
 func (s *Signer) ProofType() string {
@@ -33,45 +44,72 @@ func (s *Signer) ProofType() string {
 
 // Sign signs the given request using sha-256 for a content digest, and http-signature to sign headers.
 func (s *Signer) Sign(request *http.Request, requestBody []byte) (*http.Request, error) {
-	return Sign(request, requestBody, s.SigningKey, digest.SHA256)
+	label := s.Label
+	if label == "" {
+		label = defaultSignatureName
+	}
+
+	alg, err := jwksignature.Algorithm(s.SigningKey)
+	if err != nil {
+		return nil, fmt.Errorf("creating signer: %w", err)
+	}
+
+	return sign(request, requestBody, &requestKey{
+		alg:   jwa.SignatureAlgorithm(alg),
+		keyID: s.SigningKey.KeyID,
+		key:   s.SigningKey.Key,
+	}, digest.SHA256, label, s.Components)
 }
 
+// Sign signs the given request with the given key, using the given digest algorithm for the content digest.
 func Sign(req *http.Request, bodyBytes []byte, signingKey *jwk.JWK, digestName string) (*http.Request, error) {
-	conf := httpsign.NewSignConfig().SignAlg(false)
+	alg, err := jwksignature.Algorithm(signingKey)
+	if err != nil {
+		return nil, fmt.Errorf("creating signer: %w", err)
+	}
+
+	return sign(req, bodyBytes, &requestKey{
+		alg:   jwa.SignatureAlgorithm(alg),
+		keyID: signingKey.KeyID,
+		key:   signingKey.Key,
+	}, digestName, defaultSignatureName, nil)
+}
+
+// requestKey holds the key passed to httpsign: a private key, or a crypto.Signer.
+type requestKey struct {
+	alg   jwa.SignatureAlgorithm
+	keyID string
+	key   interface{}
+}
+
+func sign(req *http.Request, bodyBytes []byte, key *requestKey, digestName, label string,
+	components []string) (*http.Request, error) {
+	nonce, err := newNonce()
+	if err != nil {
+		return nil, err
+	}
 
-	fields := httpsign.Headers("@method", "@target-uri")
+	conf := httpsign.NewSignConfig().SignAlg(false).SetNonce(nonce)
 
 	if len(bodyBytes) > 0 {
 		body := ioutil.NopCloser(bytes.NewBuffer(bodyBytes))
 
-		cdHeader, err := httpsign.GenerateContentDigestHeader(&body, []string{digestName})
-		if err != nil {
-			return nil, err
+		cdHeader, e := httpsign.GenerateContentDigestHeader(&body, []string{digestName})
+		if e != nil {
+			return nil, e
 		}
 
 		req.Header.Add("content-digest", cdHeader)
-
-		fields.AddHeader("content-digest")
 	}
 
-	authHeader := req.Header.Get("Authorization")
-
-	if authHeader != "" {
-		fields.AddHeader("Authorization")
-	}
+	fields := coveredComponents(req.Header.Get("Authorization") != "", len(bodyBytes) > 0, components, false)
 
-	signer, err := httpsign.NewJWSSigner(
-		jwa.SignatureAlgorithm(signingKey.Algorithm),
-		signingKey.KeyID,
-		signingKey.Key,
-		conf,
-		fields,
-	)
+	signer, err := httpsign.NewJWSSigner(key.alg, key.keyID, key.key, conf, fields)
 	if err != nil {
 		return nil, fmt.Errorf("creating signer: %w", err)
 	}
 
-	sigInput, sig, err := httpsign.SignRequest(defaultSignatureName, *signer, req)
+	sigInput, sig, err := httpsign.SignRequest(label, *signer, req)
 	if err != nil {
 		return nil, fmt.Errorf("signing request: %w", err)
 	}
@@ -81,3 +119,15 @@ func Sign(req *http.Request, bodyBytes []byte, signingKey *jwk.JWK, digestName s
 
 	return req, nil
 }
+
+// newNonce generates a random signature nonce, allowing verifiers to detect replayed requests.
+func newNonce() (string, error) {
+	nonce := make([]byte, nonceSize)
+
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return "", fmt.Errorf("generating signature nonce: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(nonce), nil
+}
@@ -11,6 +11,7 @@ import (
 	"crypto/elliptic"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk"
@@ -44,6 +45,33 @@ func TestSign(t *testing.T) {
 		require.NotEmpty(t, sig)
 		sigParams := signedReq.Header.Get("signature-input")
 		require.NotEmpty(t, sigParams)
+		require.Contains(t, sigParams, ";nonce=")
+	})
+
+	t.Run("label and covered components", func(t *testing.T) {
+		priv, _ := jwkPairECDSA(t, "ES256", elliptic.P256())
+
+		body := []byte("foo bar baz")
+
+		req := httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", bytes.NewReader(body))
+		req.Header.Add("Authorization", "FOO bar")
+		req.Header.Set("Content-Type", "text/plain")
+
+		signer := Signer{
+			SigningKey: priv,
+			Label:      "client",
+			// duplicates of default components, and headers missing from the request, are skipped
+			Components: []string{"@Authority", " Content-Type ", "", "authorization", "@method", "x-missing"},
+		}
+
+		signedReq, err := signer.Sign(req, body)
+		require.NoError(t, err)
+
+		sigParams := signedReq.Header.Get("signature-input")
+		require.True(t, strings.HasPrefix(sigParams,
+			`client=("@method" "@target-uri" "authorization" "content-digest" "@authority" "content-type");`),
+			sigParams)
+		require.True(t, strings.HasPrefix(signedReq.Header.Get("signature"), "client=:"))
 	})
 
 	t.Run("fail to create signer", func(t *testing.T) {
@@ -8,63 +8,336 @@ package httpsig
 
 import (
 	"bytes"
+	"crypto"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/dunglas/httpsfv"
 	"github.com/lestrrat-go/jwx/v2/jwa"
-	"github.com/trustbloc/auth/spi/gnap"
 	"github.com/yaronf/httpsign"
+
+	"github.com/trustbloc/auth/spi/gnap"
+	"github.com/trustbloc/auth/spi/gnap/internal/digest"
+	"github.com/trustbloc/auth/spi/gnap/internal/jwksignature"
 )
 
+// DefaultClockSkew is the default largest allowed difference between the current time and a signature's
+// created time, and the default grace period after a signature's expiry time.
+const DefaultClockSkew = 5 * time.Minute
+
+// Errors returned by Verifier.Verify, identifying why a request was rejected.
+var (
+	// ErrInvalidContentDigest is returned when the request's Content-Digest is missing, unsupported, or doesn't
+	// match the request body.
+	ErrInvalidContentDigest = errors.New("invalid content-digest")
+	// ErrStaleSignature is returned when the signature was created too long ago, or has expired.
+	ErrStaleSignature = errors.New("stale signature")
+	// ErrFutureSignature is returned when the signature's created time is in the future.
+	ErrFutureSignature = errors.New("signature created in the future")
+	// ErrInvalidSignature is returned when the signature is malformed, or isn't made by the client key.
+	ErrInvalidSignature = errors.New("invalid signature")
+)
+
+// nolint:gochecknoglobals
+var acceptedDigests = []string{digest.SHA256, digest.SHA512}
+
 // Verifier verifies that the client request is signed by the client key, using http-signature verification.
 type Verifier struct {
-	req *http.Request
+	req         *http.Request
+	clockSkew   time.Duration
+	replayCache *ReplayCache
+	label       string
+	components  []string
+	now         func() time.Time
 }
 
 // NewVerifier initializes an http-signature Verifier on the given client request.
 func NewVerifier(req *http.Request) *Verifier {
-	return &Verifier{req: req}
+	return &Verifier{
+		req:       req,
+		clockSkew: DefaultClockSkew,
+		now:       time.Now,
+	}
 }
 
-// Verify verifies that the Verifier's client request is signed by the client key, using http-signature verification.
-func (v *Verifier) Verify(key *gnap.ClientKey) error {
-	verKey := key.JWK
+// WithClockSkew sets the largest allowed difference between the current time and the signature's created time,
+// which is also the grace period allowed after the signature's expiry time.
+func (v *Verifier) WithClockSkew(skew time.Duration) *Verifier {
+	v.clockSkew = skew
 
-	fields := httpsign.Headers("@method", "@target-uri")
+	return v
+}
 
-	if v.req.Header.Get("Authorization") != "" {
-		fields.AddHeader("Authorization")
-	}
+// WithReplayCache sets a ReplayCache that the Verifier uses to reject requests whose signature was already seen.
+func (v *Verifier) WithReplayCache(cache *ReplayCache) *Verifier {
+	v.replayCache = cache
 
-	if v.req.Body != nil {
-		bodyBytes, err := ioutil.ReadAll(v.req.Body)
-		if err != nil {
-			return err
-		}
+	return v
+}
 
-		v.req.Body = ioutil.NopCloser(bytes.NewBuffer(bodyBytes))
+// WithLabel restricts the Verifier to the signature with the given label. By default, the Verifier accepts the
+// first signature in the request that is valid and covers the required components.
+func (v *Verifier) WithLabel(label string) *Verifier {
+	v.label = label
 
-		if len(bodyBytes) > 0 {
-			fields.AddHeader("content-digest")
-		}
+	return v
+}
+
+// WithRequiredComponents sets covered components that request signatures must include, in addition to the method,
+// target uri, authorization and content-digest. Derived components start with '@', e.g. "@authority" or "@query".
+// Other components are header names: requests without that header are rejected.
+func (v *Verifier) WithRequiredComponents(components ...string) *Verifier {
+	v.components = components
+
+	return v
+}
+
+// Verify verifies that the Verifier's client request is signed by the client key, using http-signature verification.
+func (v *Verifier) Verify(key *gnap.ClientKey) error {
+	verKey, err := key.VerificationKey()
+	if err != nil {
+		return fmt.Errorf("creating verifier: %w", err)
+	}
+
+	fields, err := v.coveredFields()
+	if err != nil {
+		return err
+	}
+
+	alg, err := jwksignature.Algorithm(verKey)
+	if err != nil {
+		return fmt.Errorf("creating verifier: %w", err)
 	}
 
+	// signature freshness is checked by verifyTimestamps, to allow for clock skew.
+	conf := httpsign.NewVerifyConfig().SetVerifyCreated(false).SetRejectExpired(false)
+
 	verifier, err := httpsign.NewJWSVerifier(
-		jwa.SignatureAlgorithm(verKey.Algorithm),
+		jwa.SignatureAlgorithm(alg),
 		verKey.Key,
 		verKey.KeyID,
-		nil,
+		conf,
 		fields,
 	)
 	if err != nil {
 		return fmt.Errorf("creating verifier: %w", err)
 	}
 
-	err = httpsign.VerifyRequest(defaultSignatureName, *verifier, v.req)
+	sigInputs, err := httpsfv.UnmarshalDictionary(v.req.Header.Values("Signature-Input"))
+	if err != nil {
+		return fmt.Errorf("verifying request: %w: parsing signature-input: %s", ErrInvalidSignature, err.Error())
+	}
+
+	labels := sigInputs.Names()
+	if v.label != "" {
+		labels = []string{v.label}
+	}
+
+	if len(labels) == 0 {
+		return fmt.Errorf("verifying request: %w: missing signature-input", ErrInvalidSignature)
+	}
+
+	// replayed signatures are scoped to the client key, so clients don't need to coordinate their nonces.
+	var keyScope string
+
+	if v.replayCache != nil {
+		thumbprint, e := verKey.Thumbprint(crypto.SHA256)
+		if e != nil {
+			return fmt.Errorf("computing key thumbprint: %w", e)
+		}
+
+		keyScope = base64.RawURLEncoding.EncodeToString(thumbprint)
+	}
+
+	// with multiple signatures, the first one that verifies is accepted.
+	for _, label := range labels {
+		err = v.verifySignature(label, sigInputs, verifier, keyScope)
+		if err == nil || errors.Is(err, ErrReplayedSignature) {
+			break
+		}
+	}
+
 	if err != nil {
 		return fmt.Errorf("verifying request: %w", err)
 	}
 
 	return nil
 }
+
+// KeyID returns the keyid parameter of the request signature, which identifies the key of a client that sends
+// its instance identifier by reference. If the request has multiple signatures and no label is configured, the
+// keyid of the first signature is returned. The keyid is empty if the signature doesn't have one.
+func (v *Verifier) KeyID() (string, error) {
+	sigInputs, err := httpsfv.UnmarshalDictionary(v.req.Header.Values("Signature-Input"))
+	if err != nil {
+		return "", fmt.Errorf("%w: parsing signature-input: %s", ErrInvalidSignature, err.Error())
+	}
+
+	label := v.label
+	if label == "" {
+		if len(sigInputs.Names()) == 0 {
+			return "", fmt.Errorf("%w: missing signature-input", ErrInvalidSignature)
+		}
+
+		label = sigInputs.Names()[0]
+	}
+
+	member, ok := sigInputs.Get(label)
+	if !ok {
+		return "", fmt.Errorf("%w: missing signature-input for %s", ErrInvalidSignature, label)
+	}
+
+	list, ok := member.(httpsfv.InnerList)
+	if !ok {
+		return "", fmt.Errorf("%w: malformed signature-input for %s", ErrInvalidSignature, label)
+	}
+
+	keyID, ok := list.Params.Get("keyid")
+	if !ok {
+		return "", nil
+	}
+
+	keyIDStr, ok := keyID.(string)
+	if !ok {
+		return "", fmt.Errorf("%w: malformed keyid parameter", ErrInvalidSignature)
+	}
+
+	return keyIDStr, nil
+}
+
+// verifySignature verifies the request signature with the given label, recording it in the replay cache under the
+// given key scope.
+func (v *Verifier) verifySignature(
+	label string, sigInputs *httpsfv.Dictionary, verifier *httpsign.Verifier, keyScope string,
+) error {
+	member, ok := sigInputs.Get(label)
+	if !ok {
+		return fmt.Errorf("%w: missing signature-input for %s", ErrInvalidSignature, label)
+	}
+
+	list, ok := member.(httpsfv.InnerList)
+	if !ok {
+		return fmt.Errorf("%w: malformed signature-input for %s", ErrInvalidSignature, label)
+	}
+
+	created, err := v.verifyTimestamps(list.Params)
+	if err != nil {
+		return err
+	}
+
+	err = httpsign.VerifyRequest(label, *verifier, v.req)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidSignature, err.Error())
+	}
+
+	if v.replayCache != nil {
+		return v.replayCache.Check(keyScope+":"+v.signatureID(label, list.Params, created), created.Add(v.clockSkew))
+	}
+
+	return nil
+}
+
+// coveredFields returns the request components that the signature must cover, verifying the request's
+// content-digest if the request has a body.
+func (v *Verifier) coveredFields() (httpsign.Fields, error) {
+	var bodyBytes []byte
+
+	if v.req.Body != nil {
+		var err error
+
+		bodyBytes, err = ioutil.ReadAll(v.req.Body)
+		if err != nil {
+			return httpsign.Fields{}, err
+		}
+
+		v.req.Body = ioutil.NopCloser(bytes.NewBuffer(bodyBytes))
+	}
+
+	if len(bodyBytes) > 0 {
+		err := verifyContentDigest(v.req.Header.Values("Content-Digest"), bodyBytes)
+		if err != nil {
+			return httpsign.Fields{}, err
+		}
+	}
+
+	return coveredComponents(v.req.Header.Get("Authorization") != "", len(bodyBytes) > 0, v.components, true), nil
+}
+
+// signatureID identifies the request signature for replay detection: by its label and nonce if it has one, and
+// otherwise by the signature value and its created time. The signature's timestamps aren't part of a nonce's ID, so
+// a nonce can't be reused by signing it with a different created or expires time.
+func (v *Verifier) signatureID(label string, params *httpsfv.Params, created time.Time) string {
+	if nonce, ok := params.Get("nonce"); ok {
+		if n, ok := nonce.(string); ok && n != "" {
+			return fmt.Sprintf("nonce:%s:%s", label, n)
+		}
+	}
+
+	return fmt.Sprintf("sig:%s:%s:%d", label, strings.Join(v.req.Header.Values("Signature"), ","), created.Unix())
+}
+
+func verifyContentDigest(received []string, body []byte) error {
+	if len(received) == 0 {
+		return fmt.Errorf("%w: missing content-digest header", ErrInvalidContentDigest)
+	}
+
+	rc := ioutil.NopCloser(bytes.NewReader(body))
+
+	err := httpsign.ValidateContentDigestHeader(received, &rc, acceptedDigests)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidContentDigest, err.Error())
+	}
+
+	return nil
+}
+
+// verifyTimestamps checks the created and expires parameters of the request signature against the current time,
+// returning the signature's created time.
+func (v *Verifier) verifyTimestamps(params *httpsfv.Params) (time.Time, error) {
+	now := v.now()
+
+	created, ok := params.Get("created")
+	if !ok {
+		return time.Time{}, fmt.Errorf("%w: missing created parameter", ErrInvalidSignature)
+	}
+
+	createdTime, err := paramTime(created)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if createdTime.After(now.Add(v.clockSkew)) {
+		return time.Time{}, fmt.Errorf("%w: created at %s", ErrFutureSignature, createdTime)
+	}
+
+	if createdTime.Before(now.Add(-v.clockSkew)) {
+		return time.Time{}, fmt.Errorf("%w: created at %s", ErrStaleSignature, createdTime)
+	}
+
+	if expires, ok := params.Get("expires"); ok {
+		expiresTime, err := paramTime(expires)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		if expiresTime.Before(now.Add(-v.clockSkew)) {
+			return time.Time{}, fmt.Errorf("%w: expired at %s", ErrStaleSignature, expiresTime)
+		}
+	}
+
+	return createdTime, nil
+}
+
+func paramTime(param interface{}) (time.Time, error) {
+	ts, ok := param.(int64)
+	if !ok {
+		return time.Time{}, fmt.Errorf("%w: malformed timestamp parameter", ErrInvalidSignature)
+	}
+
+	return time.Unix(ts, 0), nil
+}
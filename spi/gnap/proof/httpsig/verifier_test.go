@@ -9,14 +9,20 @@ package httpsig
 import (
 	"bytes"
 	"crypto/elliptic"
+	"encoding/base64"
 	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk"
+	"github.com/lestrrat-go/jwx/v2/jwa"
 	"github.com/stretchr/testify/require"
+	"github.com/yaronf/httpsign"
+
 	"github.com/trustbloc/auth/spi/gnap"
+	"github.com/trustbloc/auth/spi/gnap/internal/digest"
 )
 
 func TestVerify(t *testing.T) {
@@ -44,6 +50,31 @@ func TestVerify(t *testing.T) {
 		}))
 	})
 
+	t.Run("success with did key", func(t *testing.T) {
+		priv, pub := jwkPairECDSA(t, "ES256", elliptic.P256())
+
+		pubBytes, err := pub.MarshalJSON()
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", nil)
+
+		signedReq, err := (&Signer{SigningKey: priv}).Sign(req, nil)
+		require.NoError(t, err)
+
+		require.NoError(t, NewVerifier(signedReq).Verify(&gnap.ClientKey{
+			DID: "did:jwk:" + base64.RawURLEncoding.EncodeToString(pubBytes),
+		}))
+
+		_, otherPub := jwkPairECDSA(t, "ES256", elliptic.P256())
+
+		err = NewVerifier(signedReq).Verify(&gnap.ClientKey{
+			DID: "did:jwk:" + base64.RawURLEncoding.EncodeToString(pubBytes),
+			JWK: otherPub,
+		})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "does not match")
+	})
+
 	t.Run("fail to read malformed body", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", badBody("fail to read body"))
 
@@ -84,6 +115,268 @@ func TestVerify(t *testing.T) {
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "verifying request")
 	})
+
+	t.Run("content-digest", func(t *testing.T) {
+		priv, pub := jwkPairECDSA(t, "ES256", elliptic.P256())
+
+		body := []byte("foo bar baz")
+
+		t.Run("body modified after signing", func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", bytes.NewReader([]byte("foo")))
+
+			req, err := Sign(req, body, priv, digest.SHA512)
+			require.NoError(t, err)
+
+			err = NewVerifier(req).Verify(&gnap.ClientKey{JWK: pub})
+			require.ErrorIs(t, err, ErrInvalidContentDigest)
+			require.Contains(t, err.Error(), "digest mismatch")
+		})
+
+		t.Run("missing", func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", bytes.NewReader(body))
+
+			err := NewVerifier(req).Verify(&gnap.ClientKey{JWK: pub})
+			require.ErrorIs(t, err, ErrInvalidContentDigest)
+			require.Contains(t, err.Error(), "missing content-digest header")
+		})
+
+		t.Run("unsupported digest", func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", bytes.NewReader(body))
+			req.Header.Set("Content-Digest", "md5=:rL0Y20zC+Fzt72VPzMSk2A==:")
+
+			err := NewVerifier(req).Verify(&gnap.ClientKey{JWK: pub})
+			require.ErrorIs(t, err, ErrInvalidContentDigest)
+		})
+	})
+
+	t.Run("signature freshness", func(t *testing.T) {
+		priv, pub := jwkPairECDSA(t, "ES256", elliptic.P256())
+
+		tests := []struct {
+			name    string
+			skew    time.Duration
+			now     time.Time
+			expires int64
+			err     error
+		}{
+			{
+				name: "within clock skew",
+				skew: time.Minute,
+				now:  time.Now().Add(-30 * time.Second),
+			},
+			{
+				name: "stale",
+				skew: time.Minute,
+				now:  time.Now().Add(2 * time.Minute),
+				err:  ErrStaleSignature,
+			},
+			{
+				name: "future-dated",
+				skew: time.Minute,
+				now:  time.Now().Add(-2 * time.Minute),
+				err:  ErrFutureSignature,
+			},
+			{
+				name:    "expired",
+				skew:    DefaultClockSkew,
+				now:     time.Now(),
+				expires: time.Now().Add(-time.Hour).Unix(),
+				err:     ErrStaleSignature,
+			},
+			{
+				name:    "expired within clock skew",
+				skew:    DefaultClockSkew,
+				now:     time.Now(),
+				expires: time.Now().Add(-time.Minute).Unix(),
+			},
+		}
+
+		for _, tt := range tests {
+			tc := tt
+
+			t.Run(tc.name, func(t *testing.T) {
+				req := httptest.NewRequest(http.MethodGet, "http://foo.bar/baz", nil)
+
+				signer, err := httpsign.NewJWSSigner(
+					jwa.ES256,
+					priv.KeyID,
+					priv.Key,
+					httpsign.NewSignConfig().SignAlg(false).SetExpires(tc.expires),
+					httpsign.Headers("@method", "@target-uri"),
+				)
+				require.NoError(t, err)
+
+				sigInput, sig, err := httpsign.SignRequest(defaultSignatureName, *signer, req)
+				require.NoError(t, err)
+
+				req.Header.Add("Signature", sig)
+				req.Header.Add("Signature-Input", sigInput)
+
+				verifier := NewVerifier(req).WithClockSkew(tc.skew)
+				verifier.now = func() time.Time { return tc.now }
+
+				err = verifier.Verify(&gnap.ClientKey{JWK: pub})
+				if tc.err == nil {
+					require.NoError(t, err)
+				} else {
+					require.ErrorIs(t, err, tc.err)
+				}
+			})
+		}
+	})
+
+	t.Run("signature label", func(t *testing.T) {
+		priv, pub := jwkPairECDSA(t, "ES256", elliptic.P256())
+		otherPriv, _ := jwkPairECDSA(t, "ES256", elliptic.P256())
+
+		newRequest := func(t *testing.T) *http.Request {
+			t.Helper()
+
+			req := httptest.NewRequest(http.MethodGet, "http://foo.bar/baz", nil)
+
+			// a signature from another party, e.g. a proxy, precedes the client's signature
+			req, err := (&Signer{SigningKey: otherPriv, Label: "proxy"}).Sign(req, nil)
+			require.NoError(t, err)
+
+			req, err = (&Signer{SigningKey: priv, Label: "client"}).Sign(req, nil)
+			require.NoError(t, err)
+
+			return req
+		}
+
+		t.Run("any label", func(t *testing.T) {
+			require.NoError(t, NewVerifier(newRequest(t)).Verify(&gnap.ClientKey{JWK: pub}))
+		})
+
+		t.Run("configured label", func(t *testing.T) {
+			require.NoError(t, NewVerifier(newRequest(t)).WithLabel("client").Verify(&gnap.ClientKey{JWK: pub}))
+		})
+
+		t.Run("signature with configured label is not by client key", func(t *testing.T) {
+			err := NewVerifier(newRequest(t)).WithLabel("proxy").Verify(&gnap.ClientKey{JWK: pub})
+			require.ErrorIs(t, err, ErrInvalidSignature)
+		})
+
+		t.Run("missing configured label", func(t *testing.T) {
+			err := NewVerifier(newRequest(t)).WithLabel("sig1").Verify(&gnap.ClientKey{JWK: pub})
+			require.ErrorIs(t, err, ErrInvalidSignature)
+			require.Contains(t, err.Error(), "missing signature-input for sig1")
+		})
+	})
+
+	t.Run("required components", func(t *testing.T) {
+		priv, pub := jwkPairECDSA(t, "ES256", elliptic.P256())
+
+		body := []byte(`{"foo":"bar"}`)
+
+		newRequest := func() *http.Request {
+			req := httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+
+			return req
+		}
+
+		t.Run("signature covers required components", func(t *testing.T) {
+			signer := &Signer{SigningKey: priv, Components: []string{"@authority", "Content-Type"}}
+
+			req, err := signer.Sign(newRequest(), body)
+			require.NoError(t, err)
+
+			err = NewVerifier(req).WithRequiredComponents("@authority", "content-type").Verify(&gnap.ClientKey{JWK: pub})
+			require.NoError(t, err)
+		})
+
+		t.Run("signature doesn't cover required header", func(t *testing.T) {
+			req, err := (&Signer{SigningKey: priv}).Sign(newRequest(), body)
+			require.NoError(t, err)
+
+			err = NewVerifier(req).WithRequiredComponents("content-type").Verify(&gnap.ClientKey{JWK: pub})
+			require.ErrorIs(t, err, ErrInvalidSignature)
+		})
+
+		t.Run("signature doesn't cover required derived component", func(t *testing.T) {
+			req, err := (&Signer{SigningKey: priv}).Sign(newRequest(), body)
+			require.NoError(t, err)
+
+			err = NewVerifier(req).WithRequiredComponents("@authority").Verify(&gnap.ClientKey{JWK: pub})
+			require.ErrorIs(t, err, ErrInvalidSignature)
+		})
+
+		t.Run("required header not in request", func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://foo.bar/baz", nil)
+
+			req, err := (&Signer{SigningKey: priv}).Sign(req, nil)
+			require.NoError(t, err)
+
+			err = NewVerifier(req).WithRequiredComponents("content-type").Verify(&gnap.ClientKey{JWK: pub})
+			require.ErrorIs(t, err, ErrInvalidSignature)
+		})
+	})
+
+	t.Run("malformed signature-input", func(t *testing.T) {
+		_, pub := jwkPairECDSA(t, "ES256", elliptic.P256())
+
+		for _, sigInput := range []string{"sig1=foo", "sig1=()", "sig1=();created=foo", "sig1=(", "sig2=()"} {
+			req := httptest.NewRequest(http.MethodGet, "http://foo.bar/baz", nil)
+			req.Header.Set("Signature-Input", sigInput)
+
+			err := NewVerifier(req).Verify(&gnap.ClientKey{JWK: pub})
+			require.ErrorIs(t, err, ErrInvalidSignature, sigInput)
+		}
+	})
+}
+
+func TestKeyID(t *testing.T) {
+	priv, _ := jwkPairECDSA(t, "ES256", elliptic.P256())
+
+	t.Run("success", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "http://foo.bar/baz", nil)
+
+		req, err := (&Signer{SigningKey: priv}).Sign(req, nil)
+		require.NoError(t, err)
+
+		keyID, err := NewVerifier(req).KeyID()
+		require.NoError(t, err)
+		require.Equal(t, "key1", keyID)
+	})
+
+	t.Run("configured label", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "http://foo.bar/baz", nil)
+		req.Header.Set("Signature-Input", `proxy=();keyid="proxy-key", client=();keyid="client-key"`)
+
+		keyID, err := NewVerifier(req).KeyID()
+		require.NoError(t, err)
+		require.Equal(t, "proxy-key", keyID)
+
+		keyID, err = NewVerifier(req).WithLabel("client").KeyID()
+		require.NoError(t, err)
+		require.Equal(t, "client-key", keyID)
+	})
+
+	t.Run("no keyid", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "http://foo.bar/baz", nil)
+		req.Header.Set("Signature-Input", "sig1=();created=1")
+
+		keyID, err := NewVerifier(req).KeyID()
+		require.NoError(t, err)
+		require.Empty(t, keyID)
+	})
+
+	t.Run("malformed signature-input", func(t *testing.T) {
+		for _, sigInput := range []string{"", "sig1=(", "sig1=foo", "sig1=();keyid=1"} {
+			req := httptest.NewRequest(http.MethodGet, "http://foo.bar/baz", nil)
+			req.Header.Set("Signature-Input", sigInput)
+
+			_, err := NewVerifier(req).KeyID()
+			require.ErrorIs(t, err, ErrInvalidSignature, sigInput)
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "http://foo.bar/baz", nil)
+		req.Header.Set("Signature-Input", "sig1=()")
+
+		_, err := NewVerifier(req).WithLabel("client").KeyID()
+		require.ErrorIs(t, err, ErrInvalidSignature)
+	})
 }
 
 type badBody string
@@ -0,0 +1,86 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package jws
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk"
+	"github.com/square/go-jose/v3"
+
+	"github.com/trustbloc/auth/spi/gnap/internal/jwsbinding"
+)
+
+// Signer signs GNAP http requests by wrapping the request body in an attached JWS.
+type Signer struct {
+	SigningKey *jwk.JWK
+}
+
+const (
+	// ContentType is the content type of a request body wrapped in a compact JWS.
+	ContentType = "application/jose"
+
+	jwsType = "gnap-binding-jws"
+)
+
+// ProofType returns "jws", the GNAP proof type of the attached-JWS proof method.
+func (s *Signer) ProofType() string {
+	return "jws"
+}
+
+// Sign signs the given request by replacing its body with a compact JWS that has the body as its payload.
+func (s *Signer) Sign(request *http.Request, requestBody []byte) (*http.Request, error) {
+	return Sign(request, requestBody, s.SigningKey)
+}
+
+// Sign signs the given request with a JWS covering the request method, target URI, creation time, bound access
+// token and the request body. The request body is replaced by the compact JWS. If the request has no body, the
+// JWS is calculated over an empty payload and sent in the Detached-JWS header instead.
+func Sign(req *http.Request, bodyBytes []byte, signingKey *jwk.JWK) (*http.Request, error) {
+	signer, err := jose.NewSigner(jose.SigningKey{
+		Algorithm: jose.SignatureAlgorithm(signingKey.Algorithm),
+		Key:       signingKey.JSONWebKey,
+	}, jwsbinding.SignerOptions(req, jwsType))
+	if err != nil {
+		return nil, fmt.Errorf("creating signer: %w", err)
+	}
+
+	if len(bodyBytes) == 0 {
+		sig, e := signer.Sign([]byte{})
+		if e != nil {
+			return nil, fmt.Errorf("signing request: %w", e)
+		}
+
+		detached, e := sig.DetachedCompactSerialize()
+		if e != nil {
+			return nil, fmt.Errorf("serializing jws: %w", e)
+		}
+
+		req.Header.Set(jwsbinding.DetachedHeaderName, detached)
+
+		return req, nil
+	}
+
+	sig, err := signer.Sign(bodyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("signing request: %w", err)
+	}
+
+	compact, err := sig.CompactSerialize()
+	if err != nil {
+		return nil, fmt.Errorf("serializing jws: %w", err)
+	}
+
+	req.Body = ioutil.NopCloser(bytes.NewBufferString(compact))
+	req.ContentLength = int64(len(compact))
+	req.Header.Set("Content-Type", ContentType)
+
+	return req, nil
+}
@@ -0,0 +1,97 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package jws
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk"
+	"github.com/square/go-jose/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/auth/spi/gnap/internal/jwsbinding"
+)
+
+func TestProofType(t *testing.T) {
+	require.Equal(t, "jws", (&Signer{}).ProofType())
+}
+
+func TestSign(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		priv, _ := jwkPairECDSA(t, "ES256", elliptic.P256())
+
+		body := []byte(`{"foo":"bar"}`)
+
+		req := httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		signer := Signer{
+			SigningKey: priv,
+		}
+
+		signedReq, err := signer.Sign(req, body)
+		require.NoError(t, err)
+
+		require.Equal(t, ContentType, signedReq.Header.Get("Content-Type"))
+
+		signedBody, err := ioutil.ReadAll(signedReq.Body)
+		require.NoError(t, err)
+		require.Equal(t, int64(len(signedBody)), signedReq.ContentLength)
+
+		parsed, err := jose.ParseSigned(string(signedBody))
+		require.NoError(t, err)
+
+		headers := parsed.Signatures[0].Protected.ExtraHeaders
+		require.Equal(t, jwsType, headers[jose.HeaderType])
+		require.Equal(t, http.MethodPost, headers[jwsbinding.HTMHeader])
+
+		payload, err := UnverifiedPayload(signedBody)
+		require.NoError(t, err)
+		require.Equal(t, body, payload)
+	})
+
+	t.Run("success without body", func(t *testing.T) {
+		priv, _ := jwkPairECDSA(t, "ES256", elliptic.P256())
+
+		req := httptest.NewRequest(http.MethodGet, "http://foo.bar/baz", nil)
+
+		signedReq, err := Sign(req, nil, priv)
+		require.NoError(t, err)
+		require.NotEmpty(t, signedReq.Header.Get(jwsbinding.DetachedHeaderName))
+	})
+
+	t.Run("fail to create signer", func(t *testing.T) {
+		priv := &jwk.JWK{
+			JSONWebKey: jose.JSONWebKey{
+				Algorithm: "foo",
+			},
+		}
+
+		body := []byte("foo bar baz")
+
+		req := httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", bytes.NewReader(body))
+
+		signer := Signer{
+			SigningKey: priv,
+		}
+
+		_, err := signer.Sign(req, body)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "creating signer")
+	})
+}
+
+func TestUnverifiedPayload(t *testing.T) {
+	_, err := UnverifiedPayload([]byte("foo"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "parsing jws")
+}
@@ -0,0 +1,125 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package jws
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/square/go-jose/v3"
+
+	"github.com/trustbloc/auth/spi/gnap"
+	"github.com/trustbloc/auth/spi/gnap/internal/jwsbinding"
+)
+
+// Verifier verifies that the client request is signed by the client key, using attached-JWS verification.
+type Verifier struct {
+	req       *http.Request
+	clockSkew time.Duration
+}
+
+// NewVerifier initializes an attached-JWS Verifier on the given client request.
+func NewVerifier(req *http.Request) *Verifier {
+	return &Verifier{req: req, clockSkew: jwsbinding.DefaultClockSkew}
+}
+
+// WithClockSkew sets the largest allowed difference between the current time and the JWS's created time.
+func (v *Verifier) WithClockSkew(skew time.Duration) *Verifier {
+	v.clockSkew = skew
+
+	return v
+}
+
+// Verify verifies that the Verifier's client request is signed by the client key, using attached-JWS verification.
+func (v *Verifier) Verify(key *gnap.ClientKey) error {
+	sig, err := v.parse()
+	if err != nil {
+		return err
+	}
+
+	verKey, err := key.VerificationKey()
+	if err != nil {
+		return fmt.Errorf("verifying request: %w", err)
+	}
+
+	_, err = sig.Verify(verKey.Key)
+	if err != nil {
+		return fmt.Errorf("verifying request: %w", err)
+	}
+
+	return jwsbinding.VerifyHeaders(v.req, sig.Signatures[0].Protected.ExtraHeaders, jwsType, v.clockSkew)
+}
+
+// KeyID returns the kid header of the request's JWS, which identifies the key of a client that sends its instance
+// identifier by reference. The kid is empty if the JWS header doesn't have one.
+func (v *Verifier) KeyID() (string, error) {
+	sig, err := v.parse()
+	if err != nil {
+		return "", err
+	}
+
+	return sig.Signatures[0].Header.KeyID, nil
+}
+
+// parse parses the request's JWS: the request body, or the detached JWS header for requests without a body.
+func (v *Verifier) parse() (*jose.JSONWebSignature, error) {
+	var bodyBytes []byte
+
+	if v.req.Body != nil {
+		var err error
+
+		bodyBytes, err = ioutil.ReadAll(v.req.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		v.req.Body = ioutil.NopCloser(bytes.NewBuffer(bodyBytes))
+	}
+
+	var (
+		sig *jose.JSONWebSignature
+		err error
+	)
+
+	if len(bodyBytes) == 0 {
+		sigHeader := v.req.Header.Get(jwsbinding.DetachedHeaderName)
+		if sigHeader == "" {
+			return nil, errors.New("missing jws")
+		}
+
+		sig, err = jose.ParseDetached(sigHeader, []byte{})
+	} else {
+		sig, err = jose.ParseSigned(string(bodyBytes))
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("parsing jws: %w", err)
+	}
+
+	if len(sig.Signatures) != 1 {
+		return nil, errors.New("jws must have exactly one signature")
+	}
+
+	return sig, nil
+}
+
+// UnverifiedPayload returns the payload of the given compact JWS request body, without verifying the signature.
+//
+// Callers use this to parse the GNAP request that carries the client key, and must verify the request with a
+// Verifier before trusting the payload.
+func UnverifiedPayload(body []byte) ([]byte, error) {
+	sig, err := jose.ParseSigned(string(body))
+	if err != nil {
+		return nil, fmt.Errorf("parsing jws: %w", err)
+	}
+
+	return sig.UnsafePayloadWithoutVerification(), nil
+}
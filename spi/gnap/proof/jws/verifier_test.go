@@ -0,0 +1,154 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package jws
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/auth/spi/gnap"
+	"github.com/trustbloc/auth/spi/gnap/internal/jwsbinding"
+)
+
+func TestVerify(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		priv, pub := jwkPairECDSA(t, "ES256", elliptic.P256())
+
+		body := []byte("foo bar baz")
+
+		req := httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", bytes.NewReader(body))
+
+		// include an access token to be verified as well
+		req.Header.Add("Authorization", "GNAP foo")
+
+		signer := Signer{
+			SigningKey: priv,
+		}
+
+		signedReq, err := signer.Sign(req, body)
+		require.NoError(t, err)
+
+		verifier := NewVerifier(signedReq)
+
+		require.NoError(t, verifier.Verify(&gnap.ClientKey{
+			JWK: pub,
+		}))
+	})
+
+	t.Run("missing jws", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "http://foo.bar/baz", nil)
+
+		err := NewVerifier(req).Verify(&gnap.ClientKey{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "missing jws")
+	})
+
+	t.Run("fail to read malformed body", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", badBody("fail to read body"))
+
+		err := NewVerifier(req).Verify(&gnap.ClientKey{
+			JWK: jwk.JWK{},
+		})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "fail to read body")
+	})
+
+	t.Run("fail to parse jws body", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", bytes.NewReader([]byte("foo")))
+
+		err := NewVerifier(req).Verify(&gnap.ClientKey{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "parsing jws")
+	})
+
+	t.Run("signed with a different key", func(t *testing.T) {
+		priv, _ := jwkPairECDSA(t, "ES256", elliptic.P256())
+		_, pub := jwkPairECDSA(t, "ES256", elliptic.P256())
+
+		body := []byte("foo bar baz")
+
+		req := httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", bytes.NewReader(body))
+
+		req, err := Sign(req, body, priv)
+		require.NoError(t, err)
+
+		err = NewVerifier(req).Verify(&gnap.ClientKey{JWK: pub})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "verifying request")
+	})
+
+	t.Run("signed for a different uri", func(t *testing.T) {
+		priv, pub := jwkPairECDSA(t, "ES256", elliptic.P256())
+
+		body := []byte("foo bar baz")
+
+		req := httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", bytes.NewReader(body))
+
+		req, err := Sign(req, body, priv)
+		require.NoError(t, err)
+
+		req.URL.Path = "/qux"
+
+		err = NewVerifier(req).Verify(&gnap.ClientKey{JWK: pub})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "does not match request uri")
+	})
+
+	t.Run("detached jws not bound to access token", func(t *testing.T) {
+		priv, pub := jwkPairECDSA(t, "ES256", elliptic.P256())
+
+		req := httptest.NewRequest(http.MethodGet, "http://foo.bar/baz", nil)
+
+		req, err := Sign(req, nil, priv)
+		require.NoError(t, err)
+
+		req.Header.Set("Authorization", "GNAP foo")
+
+		err = NewVerifier(req).Verify(&gnap.ClientKey{JWK: pub})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "not bound to the request access token")
+		require.NotEmpty(t, req.Header.Get(jwsbinding.DetachedHeaderName))
+	})
+}
+
+func TestKeyID(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		priv, _ := jwkPairECDSA(t, "ES256", elliptic.P256())
+
+		body := []byte("foo bar baz")
+
+		req := httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", bytes.NewReader(body))
+
+		signedReq, err := (&Signer{SigningKey: priv}).Sign(req, body)
+		require.NoError(t, err)
+
+		keyID, err := NewVerifier(signedReq).KeyID()
+		require.NoError(t, err)
+		require.Equal(t, "key1", keyID)
+	})
+
+	t.Run("missing jws", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "http://foo.bar/baz", nil)
+
+		_, err := NewVerifier(req).KeyID()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "missing jws")
+	})
+}
+
+type badBody string
+
+func (b badBody) Read([]byte) (int, error) {
+	return 0, errors.New(string(b))
+}
@@ -0,0 +1,75 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package jwsd
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk"
+	"github.com/square/go-jose/v3"
+
+	"github.com/trustbloc/auth/spi/gnap/internal/jwsbinding"
+)
+
+// Signer signs GNAP http requests using a detached JWS.
+type Signer struct {
+	SigningKey *jwk.JWK
+}
+
+const (
+	// HeaderName is the http header that holds the detached JWS of a signed request.
+	HeaderName = jwsbinding.DetachedHeaderName
+
+	jwsType = "gnap-binding-jwsd"
+)
+
+// ProofType returns "jwsd", the GNAP proof type of the detached-JWS proof method.
+func (s *Signer) ProofType() string {
+	return "jwsd"
+}
+
+// Sign signs the given request with a detached JWS over the sha-256 hash of the request body.
+func (s *Signer) Sign(request *http.Request, requestBody []byte) (*http.Request, error) {
+	return Sign(request, requestBody, s.SigningKey)
+}
+
+// Sign signs the given request with a detached JWS, covering the request method, target URI,
+// creation time, bound access token and the hash of the request body.
+func Sign(req *http.Request, bodyBytes []byte, signingKey *jwk.JWK) (*http.Request, error) {
+	signer, err := jose.NewSigner(jose.SigningKey{
+		Algorithm: jose.SignatureAlgorithm(signingKey.Algorithm),
+		Key:       signingKey.JSONWebKey,
+	}, jwsbinding.SignerOptions(req, jwsType))
+	if err != nil {
+		return nil, fmt.Errorf("creating signer: %w", err)
+	}
+
+	sig, err := signer.Sign(payload(bodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("signing request: %w", err)
+	}
+
+	detached, err := sig.DetachedCompactSerialize()
+	if err != nil {
+		return nil, fmt.Errorf("serializing detached jws: %w", err)
+	}
+
+	req.Header.Set(HeaderName, detached)
+
+	return req, nil
+}
+
+// payload returns the JWS payload for the given request body: the base64url-encoded
+// sha-256 hash of the body, or an empty payload if the request has no body.
+func payload(bodyBytes []byte) []byte {
+	if len(bodyBytes) == 0 {
+		return []byte{}
+	}
+
+	return []byte(jwsbinding.HashB64(bodyBytes))
+}
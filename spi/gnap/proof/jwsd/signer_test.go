@@ -0,0 +1,83 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package jwsd
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk"
+	"github.com/square/go-jose/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/auth/spi/gnap/internal/jwsbinding"
+)
+
+func TestProofType(t *testing.T) {
+	require.Equal(t, "jwsd", (&Signer{}).ProofType())
+}
+
+func TestSign(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		priv, _ := jwkPairECDSA(t, "ES256", elliptic.P256())
+
+		body := []byte("foo bar baz")
+
+		req := httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", bytes.NewReader(body))
+
+		// bind the signature to an access token as well
+		req.Header.Add("Authorization", "GNAP foo")
+
+		signer := Signer{
+			SigningKey: priv,
+		}
+
+		signedReq, err := signer.Sign(req, body)
+		require.NoError(t, err)
+
+		sig := signedReq.Header.Get(HeaderName)
+		require.NotEmpty(t, sig)
+
+		parts := strings.Split(sig, ".")
+		require.Len(t, parts, 3)
+		require.Empty(t, parts[1], "payload must be detached")
+
+		parsed, err := jose.ParseSigned(sig)
+		require.NoError(t, err)
+
+		headers := parsed.Signatures[0].Protected.ExtraHeaders
+		require.Equal(t, jwsType, headers[jose.HeaderType])
+		require.Equal(t, http.MethodPost, headers[jwsbinding.HTMHeader])
+		require.Equal(t, "http://foo.bar/baz", headers[jwsbinding.URIHeader])
+		require.Equal(t, jwsbinding.HashB64([]byte("foo")), headers[jwsbinding.ATHHeader])
+		require.NotNil(t, headers[jwsbinding.CreatedHeader])
+	})
+
+	t.Run("fail to create signer", func(t *testing.T) {
+		priv := &jwk.JWK{
+			JSONWebKey: jose.JSONWebKey{
+				Algorithm: "foo",
+			},
+		}
+
+		body := []byte("foo bar baz")
+
+		req := httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", bytes.NewReader(body))
+
+		signer := Signer{
+			SigningKey: priv,
+		}
+
+		_, err := signer.Sign(req, body)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "creating signer")
+	})
+}
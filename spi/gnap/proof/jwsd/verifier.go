@@ -0,0 +1,106 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package jwsd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/square/go-jose/v3"
+
+	"github.com/trustbloc/auth/spi/gnap"
+	"github.com/trustbloc/auth/spi/gnap/internal/jwsbinding"
+)
+
+// Verifier verifies that the client request is signed by the client key, using detached-JWS verification.
+type Verifier struct {
+	req       *http.Request
+	clockSkew time.Duration
+}
+
+// NewVerifier initializes a detached-JWS Verifier on the given client request.
+func NewVerifier(req *http.Request) *Verifier {
+	return &Verifier{req: req, clockSkew: jwsbinding.DefaultClockSkew}
+}
+
+// WithClockSkew sets the largest allowed difference between the current time and the JWS's created time.
+func (v *Verifier) WithClockSkew(skew time.Duration) *Verifier {
+	v.clockSkew = skew
+
+	return v
+}
+
+// Verify verifies that the Verifier's client request is signed by the client key, using detached-JWS verification.
+func (v *Verifier) Verify(key *gnap.ClientKey) error {
+	sigHeader := v.req.Header.Get(HeaderName)
+	if sigHeader == "" {
+		return errors.New("missing detached jws header")
+	}
+
+	var bodyBytes []byte
+
+	if v.req.Body != nil {
+		var err error
+
+		bodyBytes, err = ioutil.ReadAll(v.req.Body)
+		if err != nil {
+			return err
+		}
+
+		v.req.Body = ioutil.NopCloser(bytes.NewBuffer(bodyBytes))
+	}
+
+	sig, err := parseDetached(sigHeader)
+	if err != nil {
+		return err
+	}
+
+	verKey, err := key.VerificationKey()
+	if err != nil {
+		return fmt.Errorf("verifying request: %w", err)
+	}
+
+	err = sig.DetachedVerify(payload(bodyBytes), verKey.Key)
+	if err != nil {
+		return fmt.Errorf("verifying request: %w", err)
+	}
+
+	return jwsbinding.VerifyHeaders(v.req, sig.Signatures[0].Protected.ExtraHeaders, jwsType, v.clockSkew)
+}
+
+// KeyID returns the kid header of the request's detached JWS, which identifies the key of a client that sends its
+// instance identifier by reference. The kid is empty if the JWS header doesn't have one.
+func (v *Verifier) KeyID() (string, error) {
+	sigHeader := v.req.Header.Get(HeaderName)
+	if sigHeader == "" {
+		return "", errors.New("missing detached jws header")
+	}
+
+	sig, err := parseDetached(sigHeader)
+	if err != nil {
+		return "", err
+	}
+
+	return sig.Signatures[0].Header.KeyID, nil
+}
+
+func parseDetached(sigHeader string) (*jose.JSONWebSignature, error) {
+	sig, err := jose.ParseSigned(sigHeader)
+	if err != nil {
+		return nil, fmt.Errorf("parsing detached jws: %w", err)
+	}
+
+	if len(sig.Signatures) != 1 {
+		return nil, errors.New("detached jws must have exactly one signature")
+	}
+
+	return sig, nil
+}
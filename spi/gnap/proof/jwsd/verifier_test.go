@@ -0,0 +1,251 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package jwsd
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk"
+	"github.com/square/go-jose/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/auth/spi/gnap"
+	"github.com/trustbloc/auth/spi/gnap/internal/jwsbinding"
+)
+
+func TestVerify(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		priv, pub := jwkPairECDSA(t, "ES256", elliptic.P256())
+
+		body := []byte("foo bar baz")
+
+		req := httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", bytes.NewReader(body))
+
+		// include an access token to be verified as well
+		req.Header.Add("Authorization", "GNAP foo")
+
+		signer := Signer{
+			SigningKey: priv,
+		}
+
+		signedReq, err := signer.Sign(req, body)
+		require.NoError(t, err)
+
+		verifier := NewVerifier(signedReq)
+
+		require.NoError(t, verifier.Verify(&gnap.ClientKey{
+			JWK: pub,
+		}))
+	})
+
+	t.Run("missing detached jws header", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", nil)
+
+		err := NewVerifier(req).Verify(&gnap.ClientKey{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "missing detached jws header")
+	})
+
+	t.Run("fail to read malformed body", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", badBody("fail to read body"))
+		req.Header.Set(HeaderName, "foo")
+
+		err := NewVerifier(req).Verify(&gnap.ClientKey{
+			JWK: jwk.JWK{},
+		})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "fail to read body")
+	})
+
+	t.Run("fail to parse detached jws", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", nil)
+		req.Header.Set(HeaderName, "foo")
+
+		err := NewVerifier(req).Verify(&gnap.ClientKey{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "parsing detached jws")
+	})
+
+	t.Run("body modified after signing", func(t *testing.T) {
+		priv, pub := jwkPairECDSA(t, "ES256", elliptic.P256())
+
+		req := httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", bytes.NewReader([]byte("foo")))
+
+		req, err := Sign(req, []byte("bar"), priv)
+		require.NoError(t, err)
+
+		err = NewVerifier(req).Verify(&gnap.ClientKey{JWK: pub})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "verifying request")
+	})
+
+	t.Run("signed with a different key", func(t *testing.T) {
+		priv, _ := jwkPairECDSA(t, "ES256", elliptic.P256())
+		_, pub := jwkPairECDSA(t, "ES256", elliptic.P256())
+
+		req := httptest.NewRequest(http.MethodGet, "http://foo.bar/baz", nil)
+
+		req, err := Sign(req, nil, priv)
+		require.NoError(t, err)
+
+		err = NewVerifier(req).Verify(&gnap.ClientKey{JWK: pub})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "verifying request")
+	})
+
+	t.Run("header mismatch", func(t *testing.T) {
+		priv, pub := jwkPairECDSA(t, "ES256", elliptic.P256())
+
+		tests := []struct {
+			name    string
+			headers map[jose.HeaderKey]interface{}
+			errText string
+		}{
+			{
+				name:    "wrong type",
+				headers: map[jose.HeaderKey]interface{}{jose.HeaderType: "foo"},
+				errText: "unexpected jws type",
+			},
+			{
+				name:    "wrong method",
+				headers: map[jose.HeaderKey]interface{}{jwsbinding.HTMHeader: http.MethodPost},
+				errText: "does not match request method",
+			},
+			{
+				name:    "wrong uri",
+				headers: map[jose.HeaderKey]interface{}{jwsbinding.URIHeader: "http://foo.bar/qux"},
+				errText: "does not match request uri",
+			},
+			{
+				name:    "missing created",
+				headers: map[jose.HeaderKey]interface{}{jwsbinding.CreatedHeader: nil},
+				errText: "missing created time",
+			},
+			{
+				name:    "stale created",
+				headers: map[jose.HeaderKey]interface{}{jwsbinding.CreatedHeader: time.Now().Add(-time.Hour).Unix()},
+				errText: "outside of allowed window",
+			},
+			{
+				name:    "wrong access token hash",
+				headers: map[jose.HeaderKey]interface{}{jwsbinding.ATHHeader: jwsbinding.HashB64([]byte("bar"))},
+				errText: "not bound to the request access token",
+			},
+		}
+
+		for _, tt := range tests {
+			tc := tt
+
+			t.Run(tc.name, func(t *testing.T) {
+				req := httptest.NewRequest(http.MethodGet, "http://foo.bar/baz", nil)
+				req.Header.Add("Authorization", "GNAP foo")
+
+				headers := map[jose.HeaderKey]interface{}{
+					jose.HeaderType:          jwsType,
+					jwsbinding.HTMHeader:     req.Method,
+					jwsbinding.URIHeader:     req.URL.String(),
+					jwsbinding.CreatedHeader: time.Now().Unix(),
+					jwsbinding.ATHHeader:     jwsbinding.HashB64([]byte("foo")),
+				}
+
+				for k, v := range tc.headers {
+					if v == nil {
+						delete(headers, k)
+					} else {
+						headers[k] = v
+					}
+				}
+
+				signer, err := jose.NewSigner(jose.SigningKey{
+					Algorithm: jose.ES256,
+					Key:       priv.JSONWebKey,
+				}, &jose.SignerOptions{ExtraHeaders: headers})
+				require.NoError(t, err)
+
+				sig, err := signer.Sign([]byte{})
+				require.NoError(t, err)
+
+				detached, err := sig.DetachedCompactSerialize()
+				require.NoError(t, err)
+
+				req.Header.Set(HeaderName, detached)
+
+				err = NewVerifier(req).Verify(&gnap.ClientKey{JWK: pub})
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.errText)
+			})
+		}
+	})
+
+	t.Run("configured clock skew", func(t *testing.T) {
+		priv, pub := jwkPairECDSA(t, "ES256", elliptic.P256())
+
+		req := httptest.NewRequest(http.MethodGet, "http://foo.bar/baz", nil)
+
+		signer, err := jose.NewSigner(jose.SigningKey{
+			Algorithm: jose.ES256,
+			Key:       priv.JSONWebKey,
+		}, &jose.SignerOptions{ExtraHeaders: map[jose.HeaderKey]interface{}{
+			jose.HeaderType:          jwsType,
+			jwsbinding.HTMHeader:     req.Method,
+			jwsbinding.URIHeader:     req.URL.String(),
+			jwsbinding.CreatedHeader: time.Now().Add(-10 * time.Minute).Unix(),
+		}})
+		require.NoError(t, err)
+
+		sig, err := signer.Sign([]byte{})
+		require.NoError(t, err)
+
+		detached, err := sig.DetachedCompactSerialize()
+		require.NoError(t, err)
+
+		req.Header.Set(HeaderName, detached)
+
+		err = NewVerifier(req).Verify(&gnap.ClientKey{JWK: pub})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "outside of allowed window")
+
+		require.NoError(t, NewVerifier(req).WithClockSkew(time.Hour).Verify(&gnap.ClientKey{JWK: pub}))
+	})
+}
+
+func TestKeyID(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		priv, _ := jwkPairECDSA(t, "ES256", elliptic.P256())
+
+		body := []byte("foo bar baz")
+
+		req := httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", bytes.NewReader(body))
+
+		signedReq, err := (&Signer{SigningKey: priv}).Sign(req, body)
+		require.NoError(t, err)
+
+		keyID, err := NewVerifier(signedReq).KeyID()
+		require.NoError(t, err)
+		require.Equal(t, "key1", keyID)
+	})
+
+	t.Run("missing detached jws header", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "http://foo.bar/baz", nil)
+
+		_, err := NewVerifier(req).KeyID()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "missing detached jws header")
+	})
+}
+
+type badBody string
+
+func (b badBody) Read([]byte) (int, error) {
+	return 0, errors.New(string(b))
+}
@@ -0,0 +1,27 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package mtls
+
+import (
+	"net/http"
+)
+
+const proofType = "mtls"
+
+// Signer is a gnap.Signer for mutual-TLS proofs. The request is proven by the TLS client
+// certificate presented on the connection, so the request itself is left unchanged.
+type Signer struct{}
+
+// ProofType returns mtls.
+func (s *Signer) ProofType() string {
+	return proofType
+}
+
+// Sign returns the request unchanged, as mtls proofs are made by the TLS connection.
+func (s *Signer) Sign(req *http.Request, _ []byte) (*http.Request, error) {
+	return req, nil
+}
@@ -0,0 +1,27 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package mtls
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProofType(t *testing.T) {
+	require.Equal(t, "mtls", (&Signer{}).ProofType())
+}
+
+func TestSign(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", nil)
+
+	signedReq, err := (&Signer{}).Sign(req, nil)
+	require.NoError(t, err)
+	require.Equal(t, req, signedReq)
+}
@@ -0,0 +1,54 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package mtls
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/trustbloc/auth/spi/gnap"
+)
+
+// Verifier verifies that the client request was made over a TLS connection authenticated by the client key.
+type Verifier struct {
+	req *http.Request
+}
+
+// NewVerifier initializes a mutual-TLS Verifier on the given client request.
+func NewVerifier(req *http.Request) *Verifier {
+	return &Verifier{req: req}
+}
+
+type publicKey interface {
+	Equal(crypto.PublicKey) bool
+}
+
+// Verify verifies that the Verifier's client request was made with a TLS client certificate
+// holding the public key of the given client key.
+func (v *Verifier) Verify(key *gnap.ClientKey) error {
+	if v.req.TLS == nil || len(v.req.TLS.PeerCertificates) == 0 {
+		return errors.New("request has no tls client certificate")
+	}
+
+	boundKey, err := key.PublicKey()
+	if err != nil {
+		return fmt.Errorf("getting client public key: %w", err)
+	}
+
+	peerKey, ok := v.req.TLS.PeerCertificates[0].PublicKey.(publicKey)
+	if !ok {
+		return errors.New("unsupported tls client certificate key type")
+	}
+
+	if !peerKey.Equal(boundKey) {
+		return errors.New("tls client certificate does not match the client key")
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package mtls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk"
+	"github.com/square/go-jose/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/auth/spi/gnap"
+)
+
+func TestVerify(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		cert, _ := selfSignedCert(t)
+
+		req := tlsRequest(cert)
+
+		require.NoError(t, NewVerifier(req).Verify(&gnap.ClientKey{
+			Proof: proofType,
+			Cert:  base64.StdEncoding.EncodeToString(cert.Raw),
+		}))
+	})
+
+	t.Run("success with jwk bound key", func(t *testing.T) {
+		cert, priv := selfSignedCert(t)
+
+		req := tlsRequest(cert)
+
+		require.NoError(t, NewVerifier(req).Verify(&gnap.ClientKey{
+			Proof: proofType,
+			JWK: jwk.JWK{
+				JSONWebKey: jose.JSONWebKey{Key: &priv.PublicKey},
+				Kty:        "EC",
+				Crv:        "P-256",
+			},
+		}))
+	})
+
+	t.Run("no tls connection", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "http://foo.bar/baz", nil)
+
+		err := NewVerifier(req).Verify(&gnap.ClientKey{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "no tls client certificate")
+	})
+
+	t.Run("invalid bound certificate", func(t *testing.T) {
+		cert, _ := selfSignedCert(t)
+
+		req := tlsRequest(cert)
+
+		err := NewVerifier(req).Verify(&gnap.ClientKey{
+			Cert: "foo",
+		})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "getting client public key")
+	})
+
+	t.Run("certificate for a different key", func(t *testing.T) {
+		cert, _ := selfSignedCert(t)
+		other, _ := selfSignedCert(t)
+
+		req := tlsRequest(cert)
+
+		err := NewVerifier(req).Verify(&gnap.ClientKey{
+			Cert: base64.StdEncoding.EncodeToString(other.Raw),
+		})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "does not match the client key")
+	})
+}
+
+func tlsRequest(cert *x509.Certificate) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "https://foo.bar/baz", nil)
+	req.TLS = &tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{cert},
+	}
+
+	return req
+}
+
+func selfSignedCert(t *testing.T) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "client"},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &priv.PublicKey, priv)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return cert, priv
+}
@@ -169,7 +169,7 @@ func (s *Steps) txnRequest() error {
 		},
 		Interact: &gnap.RequestInteract{
 			Start: []string{"redirect"},
-			Finish: gnap.RequestFinish{
+			Finish: &gnap.RequestFinish{
 				Method: "redirect",
 				URI:    mockClientFinishURI,
 			},
@@ -174,7 +174,7 @@ func (m *MockWallet) gnapReqAccess() error {
 		},
 		Interact: &gnap.RequestInteract{
 			Start: []string{"redirect"},
-			Finish: gnap.RequestFinish{
+			Finish: &gnap.RequestFinish{
 				Method: "redirect",
 				URI:    mockClientFinishURI,
 			},
@@ -340,7 +340,7 @@ func (m *MockWallet) gnapContinueRequest() error {
 		m.UserData = &UserClaims{}
 	}
 
-	if len(authResp.Subject.SubIDs) > 0 {
+	if authResp.Subject != nil && len(authResp.Subject.SubIDs) > 0 {
 		m.UserData.Sub = authResp.Subject.SubIDs[0].ID
 	}
 